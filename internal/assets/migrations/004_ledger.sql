@@ -0,0 +1,104 @@
+-- +migrate Up
+CREATE TABLE journal_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_fkey UUID REFERENCES transactions(id),
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- side: 0 - debit, 1 - credit
+CREATE TABLE postings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    journal_entry_fkey UUID NOT NULL REFERENCES journal_entries(id),
+    account_fkey UUID REFERENCES accounts(id),
+    system_account VARCHAR(64),
+    side INTEGER NOT NULL CHECK (side IN (0, 1)),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK ((account_fkey IS NULL) <> (system_account IS NULL))
+);
+
+CREATE INDEX idx_journal_entries_transaction ON journal_entries(transaction_fkey);
+CREATE INDEX idx_postings_journal_entry ON postings(journal_entry_fkey);
+CREATE INDEX idx_postings_account ON postings(account_fkey) WHERE account_fkey IS NOT NULL;
+CREATE INDEX idx_postings_system_account ON postings(system_account) WHERE system_account IS NOT NULL;
+
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced()
+    RETURNS TRIGGER AS $$
+DECLARE
+    imbalance BIGINT;
+BEGIN
+    SELECT COALESCE(SUM(CASE WHEN side = 0 THEN amount ELSE -amount END), 0)
+    INTO imbalance
+    FROM postings
+    WHERE journal_entry_fkey = NEW.journal_entry_fkey;
+
+    IF imbalance <> 0 THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.journal_entry_fkey;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+-- Checked at commit, so the postings of an entry may be inserted by several statements
+CREATE CONSTRAINT TRIGGER journal_entry_balanced
+    AFTER INSERT OR UPDATE ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- Backfill the ledger with the existing transactions
+INSERT INTO journal_entries (transaction_fkey, description, created_at)
+SELECT id,
+       CASE type WHEN 0 THEN 'deposit' WHEN 1 THEN 'withdrawal' ELSE 'transfer' END,
+       COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM transactions;
+
+INSERT INTO postings (journal_entry_fkey, account_fkey, system_account, side, amount, created_at)
+SELECT j.id, t.recipient_fkey, NULL, 1, t.amount, j.created_at
+FROM transactions t JOIN journal_entries j ON j.transaction_fkey = t.id
+WHERE t.type IN (0, 2)
+UNION ALL
+SELECT j.id, t.sender_fkey, NULL, 0, t.amount, j.created_at
+FROM transactions t JOIN journal_entries j ON j.transaction_fkey = t.id
+WHERE t.type IN (1, 2)
+UNION ALL
+SELECT j.id, NULL, 'atm_cash_clearing', CASE t.type WHEN 0 THEN 0 ELSE 1 END, t.amount, j.created_at
+FROM transactions t JOIN journal_entries j ON j.transaction_fkey = t.id
+WHERE t.type IN (0, 1);
+
+-- Balances that can't be explained by the transactions become opening balances
+CREATE TEMPORARY TABLE opening_balances ON COMMIT DROP AS
+SELECT a.id AS account_id,
+       COALESCE(a.balance, 0) - COALESCE(SUM(CASE p.side WHEN 1 THEN p.amount ELSE -p.amount END), 0) AS difference,
+       gen_random_uuid() AS journal_entry_id
+FROM accounts a LEFT JOIN postings p ON p.account_fkey = a.id
+GROUP BY a.id, a.balance;
+
+DELETE FROM opening_balances WHERE difference = 0;
+
+INSERT INTO journal_entries (id, description)
+SELECT journal_entry_id, 'opening balance' FROM opening_balances;
+
+INSERT INTO postings (journal_entry_fkey, account_fkey, system_account, side, amount)
+SELECT journal_entry_id, account_id, NULL, CASE WHEN difference > 0 THEN 1 ELSE 0 END, ABS(difference)
+FROM opening_balances
+UNION ALL
+SELECT journal_entry_id, NULL, 'opening_balance_equity', CASE WHEN difference > 0 THEN 0 ELSE 1 END, ABS(difference)
+FROM opening_balances;
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS journal_entry_balanced ON postings;
+DROP FUNCTION IF EXISTS check_journal_entry_balanced();
+
+DROP INDEX IF EXISTS idx_postings_system_account;
+DROP INDEX IF EXISTS idx_postings_account;
+DROP INDEX IF EXISTS idx_postings_journal_entry;
+DROP INDEX IF EXISTS idx_journal_entries_transaction;
+
+DROP TABLE IF EXISTS postings;
+DROP TABLE IF EXISTS journal_entries;
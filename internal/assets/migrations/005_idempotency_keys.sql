@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE idempotency_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_fkey UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INTEGER,
+    content_type VARCHAR(255),
+    response_body BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT unique_idempotency_key_per_customer UNIQUE (customer_fkey, key)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS idempotency_keys;
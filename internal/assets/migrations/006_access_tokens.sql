@@ -0,0 +1,23 @@
+-- +migrate Up notransaction
+CREATE TABLE IF NOT EXISTS access_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_fkey UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_access_tokens_customer ON access_tokens(customer_fkey);
+
+-- ALTER TYPE ... ADD VALUE can't be used inside a transaction block
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'access_token_created';
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'access_token_revoked';
+
+-- +migrate Down
+-- Postgres can't drop enum values, the audit actions are kept
+DROP INDEX IF EXISTS idx_access_tokens_customer;
+DROP TABLE IF EXISTS access_tokens;
@@ -0,0 +1,23 @@
+-- +migrate Up
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_fkey UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    refresh_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    previous_refresh_token_hash VARCHAR(64),
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    rotated_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_sessions_customer ON sessions(customer_fkey);
+CREATE INDEX idx_sessions_previous_refresh_token ON sessions(previous_refresh_token_hash)
+    WHERE previous_refresh_token_hash IS NOT NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_sessions_previous_refresh_token;
+DROP INDEX IF EXISTS idx_sessions_customer;
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,27 @@
+-- +migrate Up notransaction
+CREATE TABLE IF NOT EXISTS totp_credentials (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_fkey UUID NOT NULL UNIQUE REFERENCES customers(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    confirmed_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_fkey UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT unique_recovery_code_per_customer UNIQUE (customer_fkey, code_hash)
+);
+
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'two_factor_enabled';
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'two_factor_disabled';
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'two_factor_failed';
+
+-- +migrate Down
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS totp_credentials;
@@ -0,0 +1,20 @@
+-- +migrate Up notransaction
+CREATE TABLE IF NOT EXISTS login_failures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_fkey UUID REFERENCES customers(id) ON DELETE CASCADE,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_customer ON login_failures(customer_fkey, created_at)
+    WHERE customer_fkey IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_login_failures_ip ON login_failures(ip, created_at);
+
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'login_failed';
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'login_unlocked';
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_login_failures_ip;
+DROP INDEX IF EXISTS idx_login_failures_customer;
+DROP TABLE IF EXISTS login_failures;
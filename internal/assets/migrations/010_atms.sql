@@ -0,0 +1,39 @@
+-- +migrate Up
+CREATE TYPE atm_status_enum AS ENUM (
+    'active',
+    'disabled'
+);
+
+CREATE TABLE IF NOT EXISTS atms (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    location VARCHAR(255) NOT NULL,
+    status atm_status_enum NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS atm_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    atm_fkey UUID NOT NULL REFERENCES atms(id) ON DELETE CASCADE,
+    public_key TEXT NOT NULL,
+    valid_from TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    valid_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (valid_until IS NULL OR valid_until > valid_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_atm_keys_atm ON atm_keys(atm_fkey);
+
+-- The deposits made before the registry are not linked to an ATM
+ALTER TABLE transactions ADD COLUMN atm_fkey UUID REFERENCES atms(id) ON DELETE RESTRICT;
+
+ALTER TABLE transactions ADD CONSTRAINT transactions_atm_check
+    CHECK (atm_fkey IS NULL OR type = 0);
+
+-- +migrate Down
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_atm_check;
+ALTER TABLE transactions DROP COLUMN IF EXISTS atm_fkey;
+DROP INDEX IF EXISTS idx_atm_keys_atm;
+DROP TABLE IF EXISTS atm_keys;
+DROP TABLE IF EXISTS atms;
+DROP TYPE IF EXISTS atm_status_enum;
@@ -0,0 +1,17 @@
+-- +migrate Up
+-- The signatures are malleable, so the replays are detected by the nonce of
+-- the signed payload, which is unique per ATM
+ALTER TABLE transactions ADD COLUMN atm_nonce VARCHAR(64) NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS unique_atm_signature_for_deposits;
+
+CREATE UNIQUE INDEX unique_atm_nonce ON transactions (atm_fkey, atm_nonce)
+    WHERE atm_fkey IS NOT NULL AND atm_nonce <> '';
+
+-- +migrate Down
+DROP INDEX IF EXISTS unique_atm_nonce;
+
+CREATE UNIQUE INDEX unique_atm_signature_for_deposits ON transactions (atm_signature)
+    WHERE type = 0;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS atm_nonce;
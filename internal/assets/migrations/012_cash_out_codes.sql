@@ -0,0 +1,50 @@
+-- +migrate Up notransaction
+CREATE TYPE cash_out_code_status_enum AS ENUM (
+    'pending',
+    'completed',
+    'expired',
+    'cancelled'
+);
+
+CREATE TABLE IF NOT EXISTS cash_out_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_fkey UUID NOT NULL REFERENCES customers(id),
+    account_fkey UUID NOT NULL REFERENCES accounts(id),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    code_hash VARCHAR(64) NOT NULL,
+    status cash_out_code_status_enum NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMP NOT NULL,
+    atm_fkey UUID REFERENCES atms(id),
+    transaction_fkey UUID REFERENCES transactions(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK ((status = 'completed') = (transaction_fkey IS NOT NULL))
+);
+
+-- The ATM finds the code by its hash, so the pending codes must not collide
+CREATE UNIQUE INDEX unique_pending_cash_out_code ON cash_out_codes (code_hash)
+    WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_cash_out_codes_account ON cash_out_codes(account_fkey, created_at);
+CREATE INDEX IF NOT EXISTS idx_cash_out_codes_pending_expires_at ON cash_out_codes(expires_at)
+    WHERE status = 'pending';
+
+-- The withdrawals are paid out by the ATMs as well
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_atm_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_atm_check
+    CHECK (atm_fkey IS NULL OR type IN (0, 1));
+
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'cash_out_code_issued';
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'cash_out_code_cancelled';
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'cash_out_code_expired';
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_cash_out_codes_pending_expires_at;
+DROP INDEX IF EXISTS idx_cash_out_codes_account;
+DROP INDEX IF EXISTS unique_pending_cash_out_code;
+DROP TABLE IF EXISTS cash_out_codes;
+DROP TYPE IF EXISTS cash_out_code_status_enum;
+
+-- The withdrawals made by the ATMs are kept, so the old check applies only to the new rows
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_atm_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_atm_check
+    CHECK (atm_fkey IS NULL OR type = 0) NOT VALID;
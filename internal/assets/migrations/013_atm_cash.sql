@@ -0,0 +1,39 @@
+-- +migrate Up notransaction
+-- The cash the ATM is expected to hold, starting from the deposits made and
+-- the withdrawals paid out by it so far
+ALTER TABLE atms ADD COLUMN IF NOT EXISTS cash_balance BIGINT NOT NULL DEFAULT 0;
+
+UPDATE atms SET cash_balance = cash.balance
+FROM (
+    SELECT atm_fkey, SUM(CASE WHEN type = 0 THEN amount ELSE -amount END) AS balance
+    FROM transactions
+    WHERE atm_fkey IS NOT NULL
+    GROUP BY atm_fkey
+) AS cash
+WHERE atms.id = cash.atm_fkey;
+
+CREATE TABLE IF NOT EXISTS atm_reconciliations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    atm_fkey UUID NOT NULL REFERENCES atms(id) ON DELETE RESTRICT,
+    business_date DATE NOT NULL,
+    expected BIGINT NOT NULL,
+    counted BIGINT NOT NULL CHECK (counted >= 0),
+    discrepancy BIGINT NOT NULL,
+    atm_nonce VARCHAR(64) NOT NULL,
+    atm_signature TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (atm_fkey, business_date),
+    CHECK (discrepancy = counted - expected)
+);
+
+CREATE INDEX IF NOT EXISTS idx_atm_reconciliations_business_date ON atm_reconciliations(business_date);
+
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'atm_cash_replenished';
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'atm_cash_collected';
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'atm_cash_reconciled';
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_atm_reconciliations_business_date;
+DROP TABLE IF EXISTS atm_reconciliations;
+ALTER TABLE atms DROP COLUMN IF EXISTS cash_balance;
@@ -0,0 +1,18 @@
+-- +migrate Up
+CREATE TYPE atm_signature_format_enum AS ENUM (
+    'base64',
+    'jws'
+);
+
+-- The keys registered so far are ECDSA keys signing in base64 DER
+ALTER TABLE atm_keys ADD COLUMN signature_format atm_signature_format_enum NOT NULL DEFAULT 'base64';
+
+-- The compact JWS doesn't fit the base64 DER signature length, the padding of
+-- CHAR is dropped by the conversion
+ALTER TABLE transactions ALTER COLUMN atm_signature TYPE VARCHAR(1024);
+
+-- +migrate Down
+-- Fails if there are signatures longer than the base64 DER ones
+ALTER TABLE transactions ALTER COLUMN atm_signature TYPE CHAR(100);
+ALTER TABLE atm_keys DROP COLUMN IF EXISTS signature_format;
+DROP TYPE IF EXISTS atm_signature_format_enum;
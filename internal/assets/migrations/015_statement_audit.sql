@@ -0,0 +1,4 @@
+-- +migrate Up notransaction
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'statement_generated';
+
+-- +migrate Down
@@ -0,0 +1,52 @@
+-- +migrate Up notransaction
+CREATE TYPE report_job_status_enum AS ENUM (
+    'pending',
+    'running',
+    'completed',
+    'failed',
+    'expired'
+);
+
+CREATE TABLE IF NOT EXISTS report_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_fkey UUID NOT NULL REFERENCES customers(id),
+    -- NULL for the report of all the accounts of the customer
+    account_fkey UUID REFERENCES accounts(id),
+    format VARCHAR(16) NOT NULL,
+    period_from TIMESTAMP,
+    period_to TIMESTAMP NOT NULL,
+    status report_job_status_enum NOT NULL DEFAULT 'pending',
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (period_from IS NULL OR period_from < period_to),
+    CHECK (status NOT IN ('completed', 'expired') OR expires_at IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_jobs_customer ON report_jobs(customer_fkey, created_at);
+CREATE INDEX IF NOT EXISTS idx_report_jobs_pending ON report_jobs(created_at)
+    WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_report_jobs_completed_expires_at ON report_jobs(expires_at)
+    WHERE status = 'completed';
+
+-- The results of the jobs when the reports are stored in the database
+CREATE TABLE IF NOT EXISTS report_files (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_fkey UUID NOT NULL UNIQUE REFERENCES report_jobs(id) ON DELETE CASCADE,
+    content BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'report_requested';
+ALTER TYPE audit_action_enum ADD VALUE IF NOT EXISTS 'report_downloaded';
+
+-- +migrate Down
+DROP TABLE IF EXISTS report_files;
+DROP INDEX IF EXISTS idx_report_jobs_completed_expires_at;
+DROP INDEX IF EXISTS idx_report_jobs_pending;
+DROP INDEX IF EXISTS idx_report_jobs_customer;
+DROP TABLE IF EXISTS report_jobs;
+DROP TYPE IF EXISTS report_job_status_enum;
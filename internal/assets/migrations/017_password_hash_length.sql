@@ -0,0 +1,7 @@
+-- +migrate Up
+-- The Argon2id hashes in the PHC format are longer than the bcrypt ones
+ALTER TABLE customers ALTER COLUMN password_hash TYPE VARCHAR(255);
+
+-- +migrate Down
+-- The hashes longer than the bcrypt ones are cut, such customers can't log in
+ALTER TABLE customers ALTER COLUMN password_hash TYPE CHAR(60) USING LEFT(password_hash, 60);
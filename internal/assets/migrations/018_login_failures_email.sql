@@ -0,0 +1,10 @@
+-- +migrate Up
+-- The failures are counted by the email, so the unknown emails are throttled
+-- the same way as the ones of the customers
+ALTER TABLE login_failures ADD COLUMN email_hash VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_email_hash ON login_failures(email_hash, created_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_login_failures_email_hash;
+ALTER TABLE login_failures DROP COLUMN IF EXISTS email_hash;
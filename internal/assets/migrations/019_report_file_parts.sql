@@ -0,0 +1,14 @@
+-- +migrate Up
+-- The reports are stored in parts, so neither the worker nor the download
+-- keeps the whole report in memory
+ALTER TABLE report_files DROP CONSTRAINT IF EXISTS report_files_job_fkey_key;
+ALTER TABLE report_files ADD COLUMN part INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE report_files ADD CONSTRAINT report_files_job_fkey_part_key UNIQUE (job_fkey, part);
+
+-- +migrate Down
+-- The reports stored in several parts can't be kept in a single row, they are
+-- expired for the customer
+DELETE FROM report_files WHERE job_fkey IN (SELECT job_fkey FROM report_files WHERE part > 0);
+ALTER TABLE report_files DROP CONSTRAINT IF EXISTS report_files_job_fkey_part_key;
+ALTER TABLE report_files DROP COLUMN IF EXISTS part;
+ALTER TABLE report_files ADD CONSTRAINT report_files_job_fkey_key UNIQUE (job_fkey);
@@ -0,0 +1,23 @@
+-- +migrate Up
+-- The changes of the cash the ATMs are expected to hold, so the cash at the
+-- end of the business day is known when the count comes later
+CREATE TABLE IF NOT EXISTS atm_cash_movements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    atm_fkey UUID NOT NULL REFERENCES atms(id) ON DELETE RESTRICT,
+    amount BIGINT NOT NULL,
+    moved_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_atm_cash_movements_atm_moved_at ON atm_cash_movements(atm_fkey, moved_at);
+
+-- The deposits and the withdrawals made so far, the cash loaded and taken out
+-- before the upgrade is only in the audit log
+INSERT INTO atm_cash_movements (atm_fkey, amount, moved_at)
+SELECT atm_fkey, CASE WHEN type = 0 THEN amount ELSE -amount END, COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM transactions
+WHERE atm_fkey IS NOT NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_atm_cash_movements_atm_moved_at;
+DROP TABLE IF EXISTS atm_cash_movements;
@@ -0,0 +1,103 @@
+package atm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BusinessDateLayout is the encoding of the business date of the cash count.
+const BusinessDateLayout = time.DateOnly
+
+var ErrorInvalidBusinessDate = errors.New("invalid business date")
+
+// CashCountPayload is the end-of-day count of the cash in the ATM, in cents,
+// for the business date. Like the deposits, the nonce is unique per ATM and
+// the payload can't be submitted after it expires.
+type CashCountPayload struct {
+	Version      int
+	ATMID        uuid.UUID
+	BusinessDate time.Time
+	Counted      uint
+	Nonce        string
+	IssuedAt     time.Time
+	ExpiresAt    time.Time
+}
+
+// NewCashCountPayload returns the payload of the current version with a random
+// nonce, valid from now for the given lifetime.
+func NewCashCountPayload(atmID uuid.UUID, businessDate time.Time, counted uint, lifetime time.Duration) (*CashCountPayload, error) {
+	nonce, err := NewNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	return &CashCountPayload{
+		Version:      PayloadVersion,
+		ATMID:        atmID,
+		BusinessDate: BusinessDate(businessDate),
+		Counted:      counted,
+		Nonce:        nonce,
+		IssuedAt:     now,
+		ExpiresAt:    now.Add(lifetime),
+	}, nil
+}
+
+// BusinessDate returns the UTC midnight of the day of t.
+func BusinessDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// ParseBusinessDate parses the date in the BusinessDateLayout.
+func ParseBusinessDate(value string) (time.Time, error) {
+	date, err := time.Parse(BusinessDateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrorInvalidBusinessDate, value)
+	}
+
+	return date, nil
+}
+
+// Canonical returns the bytes signed by the ATM, encoded like the deposit
+// payload, see DepositPayload.Canonical, from the following lines:
+//
+//	ilab1-atm-cash-count
+//	v=1
+//	atm_id=<lowercase hyphenated UUID>
+//	business_date=<YYYY-MM-DD>
+//	counted=<decimal amount in cents, no leading zeros>
+//	nonce=<32 lowercase hex digits>
+//	issued_at=<decimal Unix seconds>
+//	expires_at=<decimal Unix seconds>
+func (p *CashCountPayload) Canonical() []byte {
+	lines := []string{
+		cashCountDomain,
+		"v=" + strconv.Itoa(p.Version),
+		"atm_id=" + p.ATMID.String(),
+		"business_date=" + p.BusinessDate.Format(BusinessDateLayout),
+		"counted=" + strconv.FormatUint(uint64(p.Counted), 10),
+		"nonce=" + p.Nonce,
+		"issued_at=" + strconv.FormatInt(p.IssuedAt.Unix(), 10),
+		"expires_at=" + strconv.FormatInt(p.ExpiresAt.Unix(), 10),
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// Validate checks the version and the nonce of the payload, that it's valid
+// at the given time and that the business day has started when the cash was
+// counted.
+func (p *CashCountPayload) Validate(now time.Time) error {
+	if p.BusinessDate.After(p.IssuedAt) {
+		return fmt.Errorf("%w: %s is after the count", ErrorInvalidBusinessDate, p.BusinessDate.Format(BusinessDateLayout))
+	}
+
+	return validate(p.Version, p.Nonce, p.IssuedAt, p.ExpiresAt, now)
+}
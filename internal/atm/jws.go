@@ -0,0 +1,87 @@
+package atm
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jws"
+)
+
+// jwsAlgorithm returns the only JWS algorithm accepted for the key.
+func jwsAlgorithm(key any) (jwa.SignatureAlgorithm, error) {
+	switch key.(type) {
+	case *ecdsa.PrivateKey, *ecdsa.PublicKey:
+		return jwa.ES256(), nil
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return jwa.EdDSA(), nil
+	}
+
+	return jwa.EmptySignatureAlgorithm(), fmt.Errorf("%w: key is neither ECDSA nor Ed25519", ErrorInvalidKey)
+}
+
+// signJWS returns the compact JWS of the message with the payload detached,
+// the service knows the canonical payload anyway.
+func signJWS(privateKey crypto.Signer, message []byte) (string, error) {
+	alg, err := jwsAlgorithm(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := jws.Sign(nil, jws.WithKey(alg, privateKey), jws.WithDetachedPayload(message))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign: %w", err)
+	}
+
+	return string(signed), nil
+}
+
+// verifyJWS checks the compact JWS is signed with the algorithm of the key
+// and its payload, unless detached, is the message itself.
+func verifyJWS(publicKey crypto.PublicKey, message []byte, signature string) error {
+	alg, err := jwsAlgorithm(publicKey)
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(signature, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: not a compact JWS", ErrorInvalidSignature)
+	}
+
+	options := []jws.VerifyOption{jws.WithKey(alg, publicKey), jws.WithCompact()}
+
+	// The payload is detached if the middle part of the JWS is empty
+	detached := parts[1] == ""
+	if detached {
+		options = append(options, jws.WithDetachedPayload(message))
+	}
+
+	msg := new(jws.Message)
+	options = append(options, jws.WithMessage(msg))
+
+	payload, err := jws.Verify([]byte(signature), options...)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrorInvalidSignature, err)
+	}
+
+	// The key is used with its algorithm only, whatever the header says
+	signatures := msg.Signatures()
+	if len(signatures) != 1 {
+		return fmt.Errorf("%w: expected a single signature", ErrorInvalidSignature)
+	}
+
+	if headerAlg, ok := signatures[0].ProtectedHeaders().Algorithm(); !ok || headerAlg != alg {
+		return fmt.Errorf("%w: expected %s algorithm", ErrorInvalidSignature, alg)
+	}
+
+	if !detached && !bytes.Equal(payload, message) {
+		return fmt.Errorf("%w: payload is not the canonical one", ErrorInvalidSignature)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package atm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+const (
+	ecPrivateKeyPEMType    = "EC PRIVATE KEY"
+	pkcs8PrivateKeyPEMType = "PRIVATE KEY"
+	publicKeyPEMType       = "PUBLIC KEY"
+)
+
+// KeyType is the algorithm of the ATM signing key.
+type KeyType string
+
+const (
+	KeyTypeECDSA   KeyType = "ecdsa"
+	KeyTypeEd25519 KeyType = "ed25519"
+)
+
+var ErrorInvalidKey = errors.New("invalid key")
+
+// KeyTypes lists the supported key types.
+func KeyTypes() []string {
+	return []string{string(KeyTypeECDSA), string(KeyTypeEd25519)}
+}
+
+// KeyTypeOf returns the type of the ECDSA or Ed25519 public key, or an empty
+// KeyType for any other key.
+func KeyTypeOf(publicKey crypto.PublicKey) KeyType {
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return KeyTypeECDSA
+	case ed25519.PublicKey:
+		return KeyTypeEd25519
+	}
+
+	return ""
+}
+
+// GenerateKey returns a new P-256 signing key of the ATM.
+func GenerateKey() (*ecdsa.PrivateKey, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	return privateKey, nil
+}
+
+// GenerateEd25519Key returns a new Ed25519 signing key of the ATM.
+func GenerateEd25519Key() (ed25519.PrivateKey, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	return privateKey, nil
+}
+
+// GenerateKeyOfType returns a new signing key of the type, see GenerateKey
+// and GenerateEd25519Key.
+func GenerateKeyOfType(keyType KeyType) (crypto.Signer, error) {
+	switch keyType {
+	case KeyTypeECDSA:
+		return GenerateKey()
+	case KeyTypeEd25519:
+		return GenerateEd25519Key()
+	}
+
+	return nil, fmt.Errorf("%w: unsupported key type %q", ErrorInvalidKey, keyType)
+}
+
+// MarshalPrivateKey returns the PEM encoded SEC 1 private key for ECDSA and
+// PKCS #8 private key for Ed25519.
+func MarshalPrivateKey(privateKey crypto.Signer) ([]byte, error) {
+	var block *pem.Block
+
+	switch key := privateKey.(type) {
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal private key: %w", err)
+		}
+
+		block = &pem.Block{Type: ecPrivateKeyPEMType, Bytes: der}
+	case ed25519.PrivateKey:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal private key: %w", err)
+		}
+
+		block = &pem.Block{Type: pkcs8PrivateKeyPEMType, Bytes: der}
+	default:
+		return nil, fmt.Errorf("%w: private key is neither ECDSA nor Ed25519", ErrorInvalidKey)
+	}
+
+	return pem.EncodeToMemory(block), nil
+}
+
+// ParsePrivateKey parses the private key encoded by MarshalPrivateKey, the
+// PKCS #8 encoded ECDSA keys are accepted as well.
+func ParsePrivateKey(privateKeyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("%w: failed to decode PEM block", ErrorInvalidKey)
+	}
+
+	switch block.Type {
+	case ecPrivateKeyPEMType:
+		privateKey, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrorInvalidKey, err)
+		}
+
+		return privateKey, nil
+	case pkcs8PrivateKeyPEMType:
+		genericPrivateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrorInvalidKey, err)
+		}
+
+		switch privateKey := genericPrivateKey.(type) {
+		case *ecdsa.PrivateKey:
+			return privateKey, nil
+		case ed25519.PrivateKey:
+			return privateKey, nil
+		}
+
+		return nil, fmt.Errorf("%w: private key is neither ECDSA nor Ed25519", ErrorInvalidKey)
+	}
+
+	return nil, fmt.Errorf("%w: unexpected %s PEM block", ErrorInvalidKey, block.Type)
+}
+
+// MarshalPublicKey returns the PEM encoded PKIX public key, the form the ATM
+// keys are registered in.
+func MarshalPublicKey(publicKey crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: publicKeyPEMType, Bytes: der}), nil
+}
+
+// ParsePublicKey parses the ECDSA or Ed25519 public key encoded by
+// MarshalPublicKey, it returns either *ecdsa.PublicKey or ed25519.PublicKey.
+func ParsePublicKey(publicKeyPEM []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(publicKeyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("%w: failed to decode PEM block", ErrorInvalidKey)
+	}
+
+	genericPublicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidKey, err)
+	}
+
+	switch publicKey := genericPublicKey.(type) {
+	case *ecdsa.PublicKey:
+		return publicKey, nil
+	case ed25519.PublicKey:
+		return publicKey, nil
+	}
+
+	return nil, fmt.Errorf("%w: public key is neither ECDSA nor Ed25519", ErrorInvalidKey)
+}
@@ -0,0 +1,149 @@
+// Package atm defines the deposit, withdrawal and cash count payloads signed by the ATMs
+// and their canonical encodings, shared by the service verifying the signatures
+// and the tools producing them.
+package atm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PayloadVersion is the only supported version of the payload encoding.
+const PayloadVersion = 1
+
+const (
+	// The domains separate the signatures of the payloads of each kind from
+	// each other and from any other signatures made with the same key.
+	depositDomain    = "ilab1-atm-deposit"
+	withdrawalDomain = "ilab1-atm-withdrawal"
+	cashCountDomain  = "ilab1-atm-cash-count"
+
+	nonceLength = 16
+
+	// MaxPayloadLifetime limits the expires_at - issued_at of the payload.
+	MaxPayloadLifetime = 15 * time.Minute
+	// MaxClockSkew is the tolerance for the ATM clock running ahead of the service.
+	MaxClockSkew = time.Minute
+)
+
+var (
+	ErrorUnsupportedVersion = errors.New("unsupported payload version")
+	ErrorInvalidNonce       = errors.New("invalid nonce")
+	ErrorPayloadExpired     = errors.New("payload expired")
+	ErrorPayloadNotYetValid = errors.New("payload issued in the future")
+	ErrorInvalidLifetime    = errors.New("invalid payload lifetime")
+)
+
+var nonceRegexp = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// DepositPayload is the deposit as signed by the ATM. The nonce is unique per
+// ATM, so the same payload can't be deposited twice, and the payload can't be
+// deposited after it expires.
+type DepositPayload struct {
+	Version   int
+	ATMID     uuid.UUID
+	AccountID uuid.UUID
+	Amount    uint
+	Nonce     string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// NewDepositPayload returns the payload of the current version with a random
+// nonce, valid from now for the given lifetime.
+func NewDepositPayload(atmID, accountID uuid.UUID, amount uint, lifetime time.Duration) (*DepositPayload, error) {
+	nonce, err := NewNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	return &DepositPayload{
+		Version:   PayloadVersion,
+		ATMID:     atmID,
+		AccountID: accountID,
+		Amount:    amount,
+		Nonce:     nonce,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(lifetime),
+	}, nil
+}
+
+// NewNonce returns 16 random bytes encoded as lowercase hex.
+func NewNonce() (string, error) {
+	nonce := make([]byte, nonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return hex.EncodeToString(nonce), nil
+}
+
+// Canonical returns the bytes signed by the ATM. The encoding of version 1 is
+// the following lines joined with a single "\n", without a trailing newline:
+//
+//	ilab1-atm-deposit
+//	v=1
+//	atm_id=<lowercase hyphenated UUID>
+//	account_id=<lowercase hyphenated UUID>
+//	amount=<decimal amount in cents, no leading zeros>
+//	nonce=<32 lowercase hex digits>
+//	issued_at=<decimal Unix seconds>
+//	expires_at=<decimal Unix seconds>
+//
+// None of the values can contain a newline, so the encoding is unambiguous.
+func (p *DepositPayload) Canonical() []byte {
+	lines := []string{
+		depositDomain,
+		"v=" + strconv.Itoa(p.Version),
+		"atm_id=" + p.ATMID.String(),
+		"account_id=" + p.AccountID.String(),
+		"amount=" + strconv.FormatUint(uint64(p.Amount), 10),
+		"nonce=" + p.Nonce,
+		"issued_at=" + strconv.FormatInt(p.IssuedAt.Unix(), 10),
+		"expires_at=" + strconv.FormatInt(p.ExpiresAt.Unix(), 10),
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// Validate checks the version and the nonce of the payload and that it's
+// valid at the given time.
+func (p *DepositPayload) Validate(now time.Time) error {
+	return validate(p.Version, p.Nonce, p.IssuedAt, p.ExpiresAt, now)
+}
+
+// validate checks the fields common to all the payloads.
+func validate(version int, nonce string, issuedAt, expiresAt, now time.Time) error {
+	if version != PayloadVersion {
+		return fmt.Errorf("%w: %d", ErrorUnsupportedVersion, version)
+	}
+
+	if !nonceRegexp.MatchString(nonce) {
+		return ErrorInvalidNonce
+	}
+
+	lifetime := expiresAt.Sub(issuedAt)
+	if lifetime <= 0 || lifetime > MaxPayloadLifetime {
+		return fmt.Errorf("%w: %s, at most %s", ErrorInvalidLifetime, lifetime, MaxPayloadLifetime)
+	}
+
+	if issuedAt.After(now.Add(MaxClockSkew)) {
+		return ErrorPayloadNotYetValid
+	}
+
+	if !now.Before(expiresAt) {
+		return ErrorPayloadExpired
+	}
+
+	return nil
+}
@@ -0,0 +1,279 @@
+package atm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepositPayloadCanonical(t *testing.T) {
+	payload := &DepositPayload{
+		Version:   1,
+		ATMID:     uuid.MustParse("6F1E2B3C-4D5E-4F60-8A7B-9C0D1E2F3A4B"),
+		AccountID: uuid.MustParse("9eb95936-3e59-433d-9f71-a054a89f7cd3"),
+		Amount:    1000,
+		Nonce:     "00112233445566778899aabbccddeeff",
+		IssuedAt:  time.Unix(1700000000, 0),
+		ExpiresAt: time.Unix(1700000300, 0),
+	}
+
+	assert.Equal(t, "ilab1-atm-deposit\n"+
+		"v=1\n"+
+		"atm_id=6f1e2b3c-4d5e-4f60-8a7b-9c0d1e2f3a4b\n"+
+		"account_id=9eb95936-3e59-433d-9f71-a054a89f7cd3\n"+
+		"amount=1000\n"+
+		"nonce=00112233445566778899aabbccddeeff\n"+
+		"issued_at=1700000000\n"+
+		"expires_at=1700000300", string(payload.Canonical()))
+}
+
+func TestDepositPayloadValidate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	valid := func() *DepositPayload {
+		return &DepositPayload{
+			Version:   PayloadVersion,
+			Nonce:     "00112233445566778899aabbccddeeff",
+			IssuedAt:  now.Add(-time.Minute),
+			ExpiresAt: now.Add(time.Minute),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(p *DepositPayload)
+		wantErr error
+	}{
+		{"valid", func(p *DepositPayload) {}, nil},
+		{"unsupported version", func(p *DepositPayload) { p.Version = 2 }, ErrorUnsupportedVersion},
+		{"uppercase nonce", func(p *DepositPayload) { p.Nonce = "00112233445566778899AABBCCDDEEFF" }, ErrorInvalidNonce},
+		{"short nonce", func(p *DepositPayload) { p.Nonce = "0011" }, ErrorInvalidNonce},
+		{"expired", func(p *DepositPayload) { p.ExpiresAt = now }, ErrorPayloadExpired},
+		{"issued in the future", func(p *DepositPayload) {
+			p.IssuedAt = now.Add(2 * MaxClockSkew)
+			p.ExpiresAt = p.IssuedAt.Add(time.Minute)
+		}, ErrorPayloadNotYetValid},
+		{"clock skew", func(p *DepositPayload) {
+			p.IssuedAt = now.Add(MaxClockSkew / 2)
+			p.ExpiresAt = p.IssuedAt.Add(time.Minute)
+		}, nil},
+		{"too long lifetime", func(p *DepositPayload) { p.ExpiresAt = p.IssuedAt.Add(MaxPayloadLifetime + time.Second) }, ErrorInvalidLifetime},
+		{"expires before issued", func(p *DepositPayload) { p.ExpiresAt = p.IssuedAt.Add(-time.Second) }, ErrorInvalidLifetime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := valid()
+			tt.modify(payload)
+
+			err := payload.Validate(now)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestWithdrawalPayloadCanonical(t *testing.T) {
+	payload := &WithdrawalPayload{
+		Version:   1,
+		ATMID:     uuid.MustParse("6f1e2b3c-4d5e-4f60-8a7b-9c0d1e2f3a4b"),
+		Code:      "01234567",
+		Amount:    2500,
+		Nonce:     "00112233445566778899aabbccddeeff",
+		IssuedAt:  time.Unix(1700000000, 0),
+		ExpiresAt: time.Unix(1700000300, 0),
+	}
+
+	assert.Equal(t, "ilab1-atm-withdrawal\n"+
+		"v=1\n"+
+		"atm_id=6f1e2b3c-4d5e-4f60-8a7b-9c0d1e2f3a4b\n"+
+		"code=01234567\n"+
+		"amount=2500\n"+
+		"nonce=00112233445566778899aabbccddeeff\n"+
+		"issued_at=1700000000\n"+
+		"expires_at=1700000300", string(payload.Canonical()))
+}
+
+func TestWithdrawalPayloadValidate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	for code, wantErr := range map[string]error{
+		"01234567":  nil,
+		"0123456":   ErrorInvalidCashOutCode,
+		"012345678": ErrorInvalidCashOutCode,
+		"0123456a":  ErrorInvalidCashOutCode,
+		"":          ErrorInvalidCashOutCode,
+	} {
+		payload := &WithdrawalPayload{
+			Version:   PayloadVersion,
+			Code:      code,
+			Nonce:     "00112233445566778899aabbccddeeff",
+			IssuedAt:  now.Add(-time.Minute),
+			ExpiresAt: now.Add(time.Minute),
+		}
+
+		err := payload.Validate(now)
+		if wantErr == nil {
+			require.NoError(t, err, "code %q", code)
+			continue
+		}
+
+		require.ErrorIs(t, err, wantErr, "code %q", code)
+	}
+}
+
+func TestCashCountPayloadCanonical(t *testing.T) {
+	payload := &CashCountPayload{
+		Version:      1,
+		ATMID:        uuid.MustParse("6f1e2b3c-4d5e-4f60-8a7b-9c0d1e2f3a4b"),
+		BusinessDate: time.Date(2023, time.November, 14, 0, 0, 0, 0, time.UTC),
+		Counted:      0,
+		Nonce:        "00112233445566778899aabbccddeeff",
+		IssuedAt:     time.Unix(1700000000, 0),
+		ExpiresAt:    time.Unix(1700000300, 0),
+	}
+
+	assert.Equal(t, "ilab1-atm-cash-count\n"+
+		"v=1\n"+
+		"atm_id=6f1e2b3c-4d5e-4f60-8a7b-9c0d1e2f3a4b\n"+
+		"business_date=2023-11-14\n"+
+		"counted=0\n"+
+		"nonce=00112233445566778899aabbccddeeff\n"+
+		"issued_at=1700000000\n"+
+		"expires_at=1700000300", string(payload.Canonical()))
+
+	require.NoError(t, payload.Validate(time.Unix(1700000100, 0)))
+
+	payload.BusinessDate = payload.BusinessDate.AddDate(0, 0, 1)
+	require.ErrorIs(t, payload.Validate(time.Unix(1700000100, 0)), ErrorInvalidBusinessDate)
+}
+
+func TestSignature(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	message := []byte("message")
+	halfOrder := new(big.Int).Rsh(elliptic.P256().Params().N, 1)
+
+	for i := 0; i < 20; i++ {
+		signature, err := Sign(privateKey, message)
+		require.NoError(t, err)
+		require.NoError(t, Verify(&privateKey.PublicKey, message, signature))
+
+		der, err := base64.StdEncoding.DecodeString(signature)
+		require.NoError(t, err)
+
+		var sig ecdsaSignature
+		_, err = asn1.Unmarshal(der, &sig)
+		require.NoError(t, err)
+		require.True(t, sig.S.Cmp(halfOrder) <= 0, "signature must be in the low S form")
+
+		malleated, err := asn1.Marshal(ecdsaSignature{R: sig.R, S: new(big.Int).Sub(elliptic.P256().Params().N, sig.S)})
+		require.NoError(t, err)
+		require.ErrorIs(t, Verify(&privateKey.PublicKey, message, base64.StdEncoding.EncodeToString(malleated)),
+			ErrorInvalidSignature, "high S form must be rejected")
+
+		require.ErrorIs(t, Verify(&privateKey.PublicKey, message, base64.StdEncoding.EncodeToString(append(der, 0))),
+			ErrorInvalidSignature, "trailing data must be rejected")
+	}
+
+	signature, err := Sign(privateKey, message)
+	require.NoError(t, err)
+	require.ErrorIs(t, Verify(&privateKey.PublicKey, []byte("other message"), signature), ErrorInvalidSignature)
+}
+
+func TestSignedDeposit(t *testing.T) {
+	for _, keyType := range []KeyType{KeyTypeECDSA, KeyTypeEd25519} {
+		for _, format := range []SignatureFormat{SignatureFormatBase64, SignatureFormatJWS} {
+			t.Run(string(keyType)+"/"+string(format), func(t *testing.T) {
+				privateKey, err := GenerateKeyOfType(keyType)
+				require.NoError(t, err)
+
+				privateKeyPEM, err := MarshalPrivateKey(privateKey)
+				require.NoError(t, err)
+				parsedPrivateKey, err := ParsePrivateKey(privateKeyPEM)
+				require.NoError(t, err)
+
+				publicKeyPEM, err := MarshalPublicKey(parsedPrivateKey.Public())
+				require.NoError(t, err)
+				publicKey, err := ParsePublicKey(publicKeyPEM)
+				require.NoError(t, err)
+				assert.Equal(t, keyType, KeyTypeOf(publicKey))
+
+				_, err = ParsePrivateKey(publicKeyPEM)
+				require.ErrorIs(t, err, ErrorInvalidKey)
+
+				signer, err := NewSigner(parsedPrivateKey, format)
+				require.NoError(t, err)
+
+				payload, err := NewDepositPayload(uuid.New(), uuid.New(), 1000, time.Minute)
+				require.NoError(t, err)
+
+				signed, err := SignDeposit(signer, payload)
+				require.NoError(t, err)
+				assert.LessOrEqual(t, len(signed.ATMSignature), 1024, "signature must fit the request")
+
+				assert.Equal(t, payload, signed.Payload(), "the payload must survive the request encoding")
+				require.NoError(t, VerifySignature(publicKey, format, signed.Payload().Canonical(), signed.ATMSignature))
+
+				other := SignatureFormatBase64
+				if format == SignatureFormatBase64 {
+					other = SignatureFormatJWS
+				}
+				require.ErrorIs(t, VerifySignature(publicKey, other, signed.Payload().Canonical(), signed.ATMSignature),
+					ErrorInvalidSignature, "signature must be verified in the format of the key only")
+
+				signed.Amount++
+				require.ErrorIs(t, VerifySignature(publicKey, format, signed.Payload().Canonical(), signed.ATMSignature),
+					ErrorInvalidSignature)
+			})
+		}
+	}
+}
+
+func TestJWSSignature(t *testing.T) {
+	privateKey, err := GenerateEd25519Key()
+	require.NoError(t, err)
+
+	message := []byte("message")
+
+	detached, err := signJWS(privateKey, message)
+	require.NoError(t, err)
+	assert.Contains(t, detached, "..", "the payload must be detached")
+
+	embedded, err := jws.Sign(message, jws.WithKey(jwa.EdDSA(), privateKey))
+	require.NoError(t, err)
+
+	publicKey := privateKey.Public()
+	require.NoError(t, VerifySignature(publicKey, SignatureFormatJWS, message, detached))
+	require.NoError(t, VerifySignature(publicKey, SignatureFormatJWS, message, string(embedded)))
+	require.ErrorIs(t, VerifySignature(publicKey, SignatureFormatJWS, []byte("other message"), string(embedded)),
+		ErrorInvalidSignature, "embedded payload must be the message")
+
+	otherKey, err := GenerateEd25519Key()
+	require.NoError(t, err)
+	require.ErrorIs(t, VerifySignature(otherKey.Public(), SignatureFormatJWS, message, detached), ErrorInvalidSignature)
+
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	_, err = NewSigner(p384Key, SignatureFormatJWS)
+	require.ErrorIs(t, err, ErrorInvalidKey, "ES256 needs a P-256 key")
+
+	_, err = NewSigner(privateKey, "raw")
+	require.ErrorIs(t, err, ErrorUnsupportedSignatureFormat)
+}
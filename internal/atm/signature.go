@@ -0,0 +1,209 @@
+package atm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// SignatureFormat is the encoding of the ATM signatures, chosen per ATM key.
+type SignatureFormat string
+
+const (
+	// SignatureFormatBase64 is the base64 encoded signature of the canonical
+	// payload: the low-S ASN.1 DER ECDSA signature of its SHA-256 hash, see
+	// Sign, or the 64 bytes Ed25519 signature.
+	SignatureFormatBase64 SignatureFormat = "base64"
+	// SignatureFormatJWS is the compact JWS of the canonical payload, signed
+	// with ES256 by the P-256 keys or EdDSA by the Ed25519 keys. The payload
+	// may be detached, as in RFC 7515 Appendix F.
+	SignatureFormatJWS SignatureFormat = "jws"
+)
+
+var ErrorInvalidSignature = errors.New("invalid signature")
+var ErrorUnsupportedSignatureFormat = errors.New("unsupported signature format")
+
+// SignatureFormats lists the supported signature formats.
+func SignatureFormats() []string {
+	return []string{string(SignatureFormatBase64), string(SignatureFormatJWS)}
+}
+
+func (f SignatureFormat) IsValid() bool {
+	return f == SignatureFormatBase64 || f == SignatureFormatJWS
+}
+
+// CheckKey checks the public key can verify the signatures of the format.
+func CheckKey(publicKey crypto.PublicKey, format SignatureFormat) error {
+	if !format.IsValid() {
+		return fmt.Errorf("%w: %q", ErrorUnsupportedSignatureFormat, format)
+	}
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if format == SignatureFormatJWS && key.Curve != elliptic.P256() {
+			return fmt.Errorf("%w: ES256 needs a P-256 key", ErrorInvalidKey)
+		}
+
+		return nil
+	case ed25519.PublicKey:
+		return nil
+	}
+
+	return fmt.Errorf("%w: public key is neither ECDSA nor Ed25519", ErrorInvalidKey)
+}
+
+// Signer signs the canonical payloads with the ATM key in the signature
+// format the key is registered with.
+type Signer struct {
+	key    crypto.Signer
+	format SignatureFormat
+}
+
+// NewSigner returns the signer of the ECDSA or Ed25519 private key.
+func NewSigner(privateKey crypto.Signer, format SignatureFormat) (*Signer, error) {
+	if err := CheckKey(privateKey.Public(), format); err != nil {
+		return nil, err
+	}
+
+	return &Signer{
+		key:    privateKey,
+		format: format,
+	}, nil
+}
+
+func (s *Signer) Format() SignatureFormat {
+	return s.format
+}
+
+func (s *Signer) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// Sign returns the signature of the message in the format of the signer.
+func (s *Signer) Sign(message []byte) (string, error) {
+	if s.format == SignatureFormatJWS {
+		return signJWS(s.key, message)
+	}
+
+	switch key := s.key.(type) {
+	case *ecdsa.PrivateKey:
+		return Sign(key, message)
+	case ed25519.PrivateKey:
+		return base64.StdEncoding.EncodeToString(ed25519.Sign(key, message)), nil
+	}
+
+	return "", fmt.Errorf("%w: private key is neither ECDSA nor Ed25519", ErrorInvalidKey)
+}
+
+// VerifySignature checks the signature of the message in the format, made
+// with the private key of the ECDSA or Ed25519 public key.
+func VerifySignature(publicKey crypto.PublicKey, format SignatureFormat, message []byte, signature string) error {
+	if err := CheckKey(publicKey, format); err != nil {
+		return err
+	}
+
+	if format == SignatureFormatJWS {
+		return verifyJWS(publicKey, message, signature)
+	}
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return Verify(key, message, signature)
+	case ed25519.PublicKey:
+		return verifyEd25519(key, message, signature)
+	}
+
+	return fmt.Errorf("%w: public key is neither ECDSA nor Ed25519", ErrorInvalidKey)
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// Sign returns the base64 encoded ASN.1 DER ECDSA signature of the SHA-256
+// hash of the message. The signature is normalized to the low S form, the
+// only one accepted by Verify.
+func Sign(privateKey *ecdsa.PrivateKey, message []byte) (string, error) {
+	hash := sha256.Sum256(message)
+
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign: %w", err)
+	}
+
+	halfOrder := new(big.Int).Rsh(privateKey.Curve.Params().N, 1)
+	if s.Cmp(halfOrder) > 0 {
+		s.Sub(privateKey.Curve.Params().N, s)
+	}
+
+	der, err := asn1.Marshal(ecdsaSignature{R: r, S: s})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal signature: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(der), nil
+}
+
+// Verify checks the signature made by Sign. Both (r, s) and (r, N-s) are valid
+// ECDSA signatures of the same message, so the high S form is rejected, as
+// well as any encoding other than the canonical DER and base64, so that
+// every signed message has exactly one accepted signature string per nonce.
+func Verify(publicKey *ecdsa.PublicKey, message []byte, signature string) error {
+	der, err := base64.StdEncoding.Strict().DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: failed to decode: %v", ErrorInvalidSignature, err)
+	}
+
+	var sig ecdsaSignature
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return fmt.Errorf("%w: failed to unmarshal: %v", ErrorInvalidSignature, err)
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("%w: trailing data", ErrorInvalidSignature)
+	}
+
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+		return fmt.Errorf("%w: non-positive r or s", ErrorInvalidSignature)
+	}
+
+	halfOrder := new(big.Int).Rsh(publicKey.Curve.Params().N, 1)
+	if sig.S.Cmp(halfOrder) > 0 {
+		return fmt.Errorf("%w: high S", ErrorInvalidSignature)
+	}
+
+	hash := sha256.Sum256(message)
+	if !ecdsa.Verify(publicKey, hash[:], sig.R, sig.S) {
+		return ErrorInvalidSignature
+	}
+
+	return nil
+}
+
+// verifyEd25519 checks the base64 encoded Ed25519 signature. Unlike ECDSA, the
+// Ed25519 signatures are not malleable, but the encoding must be canonical all
+// the same.
+func verifyEd25519(publicKey ed25519.PublicKey, message []byte, signature string) error {
+	sig, err := base64.StdEncoding.Strict().DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: failed to decode: %v", ErrorInvalidSignature, err)
+	}
+
+	if len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: %d bytes, expected %d", ErrorInvalidSignature, len(sig), ed25519.SignatureSize)
+	}
+
+	if !ed25519.Verify(publicKey, message, sig) {
+		return ErrorInvalidSignature
+	}
+
+	return nil
+}
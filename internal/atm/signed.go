@@ -0,0 +1,147 @@
+package atm
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SignedDeposit is the body of the deposit request: the fields of the
+// DepositPayload with the ATM signature of its canonical encoding, in the
+// format of the ATM key. The service validates the request with the tags and
+// verifies the signature of Payload.
+type SignedDeposit struct {
+	AccountID      uuid.UUID `json:"account_id" validate:"required,uuid4"`
+	Amount         uint      `json:"amount" validate:"required,gt=0"`
+	ATMID          uuid.UUID `json:"atm_id" validate:"required"`
+	PayloadVersion int       `json:"payload_version" validate:"required"`
+	Nonce          string    `json:"nonce" validate:"required,len=32,hexadecimal,lowercase"`
+	IssuedAt       int64     `json:"issued_at" validate:"required,gt=0"`
+	ExpiresAt      int64     `json:"expires_at" validate:"required,gtfield=IssuedAt"`
+	ATMSignature   string    `json:"atm_signature" validate:"required,max=1024"`
+}
+
+// SignDeposit signs the canonical encoding of the payload.
+func SignDeposit(signer *Signer, payload *DepositPayload) (*SignedDeposit, error) {
+	signature, err := signer.Sign(payload.Canonical())
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignedDeposit{
+		AccountID:      payload.AccountID,
+		Amount:         payload.Amount,
+		ATMID:          payload.ATMID,
+		PayloadVersion: payload.Version,
+		Nonce:          payload.Nonce,
+		IssuedAt:       payload.IssuedAt.Unix(),
+		ExpiresAt:      payload.ExpiresAt.Unix(),
+		ATMSignature:   signature,
+	}, nil
+}
+
+// Payload returns the payload the signature is made for.
+func (d *SignedDeposit) Payload() *DepositPayload {
+	return &DepositPayload{
+		Version:   d.PayloadVersion,
+		ATMID:     d.ATMID,
+		AccountID: d.AccountID,
+		Amount:    d.Amount,
+		Nonce:     d.Nonce,
+		IssuedAt:  time.Unix(d.IssuedAt, 0).UTC(),
+		ExpiresAt: time.Unix(d.ExpiresAt, 0).UTC(),
+	}
+}
+
+// SignedWithdrawal is the body of the ATM withdrawal request, see SignedDeposit.
+type SignedWithdrawal struct {
+	ATMID          uuid.UUID `json:"atm_id" validate:"required"`
+	Code           string    `json:"code" validate:"required,len=8,numeric"`
+	Amount         uint      `json:"amount" validate:"required,gt=0"`
+	PayloadVersion int       `json:"payload_version" validate:"required"`
+	Nonce          string    `json:"nonce" validate:"required,len=32,hexadecimal,lowercase"`
+	IssuedAt       int64     `json:"issued_at" validate:"required,gt=0"`
+	ExpiresAt      int64     `json:"expires_at" validate:"required,gtfield=IssuedAt"`
+	ATMSignature   string    `json:"atm_signature" validate:"required,max=1024"`
+}
+
+// SignWithdrawal signs the canonical encoding of the payload.
+func SignWithdrawal(signer *Signer, payload *WithdrawalPayload) (*SignedWithdrawal, error) {
+	signature, err := signer.Sign(payload.Canonical())
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignedWithdrawal{
+		ATMID:          payload.ATMID,
+		Code:           payload.Code,
+		Amount:         payload.Amount,
+		PayloadVersion: payload.Version,
+		Nonce:          payload.Nonce,
+		IssuedAt:       payload.IssuedAt.Unix(),
+		ExpiresAt:      payload.ExpiresAt.Unix(),
+		ATMSignature:   signature,
+	}, nil
+}
+
+// Payload returns the payload the signature is made for.
+func (w *SignedWithdrawal) Payload() *WithdrawalPayload {
+	return &WithdrawalPayload{
+		Version:   w.PayloadVersion,
+		ATMID:     w.ATMID,
+		Code:      w.Code,
+		Amount:    w.Amount,
+		Nonce:     w.Nonce,
+		IssuedAt:  time.Unix(w.IssuedAt, 0).UTC(),
+		ExpiresAt: time.Unix(w.ExpiresAt, 0).UTC(),
+	}
+}
+
+// SignedCashCount is the body of the ATM cash count request, see SignedDeposit.
+type SignedCashCount struct {
+	ATMID          uuid.UUID `json:"atm_id" validate:"required"`
+	BusinessDate   string    `json:"business_date" validate:"required,datetime=2006-01-02"`
+	Counted        uint      `json:"counted"`
+	PayloadVersion int       `json:"payload_version" validate:"required"`
+	Nonce          string    `json:"nonce" validate:"required,len=32,hexadecimal,lowercase"`
+	IssuedAt       int64     `json:"issued_at" validate:"required,gt=0"`
+	ExpiresAt      int64     `json:"expires_at" validate:"required,gtfield=IssuedAt"`
+	ATMSignature   string    `json:"atm_signature" validate:"required,max=1024"`
+}
+
+// SignCashCount signs the canonical encoding of the payload.
+func SignCashCount(signer *Signer, payload *CashCountPayload) (*SignedCashCount, error) {
+	signature, err := signer.Sign(payload.Canonical())
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignedCashCount{
+		ATMID:          payload.ATMID,
+		BusinessDate:   payload.BusinessDate.Format(BusinessDateLayout),
+		Counted:        payload.Counted,
+		PayloadVersion: payload.Version,
+		Nonce:          payload.Nonce,
+		IssuedAt:       payload.IssuedAt.Unix(),
+		ExpiresAt:      payload.ExpiresAt.Unix(),
+		ATMSignature:   signature,
+	}, nil
+}
+
+// Payload returns the payload the signature is made for.
+func (c *SignedCashCount) Payload() (*CashCountPayload, error) {
+	businessDate, err := ParseBusinessDate(c.BusinessDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CashCountPayload{
+		Version:      c.PayloadVersion,
+		ATMID:        c.ATMID,
+		BusinessDate: businessDate,
+		Counted:      c.Counted,
+		Nonce:        c.Nonce,
+		IssuedAt:     time.Unix(c.IssuedAt, 0).UTC(),
+		ExpiresAt:    time.Unix(c.ExpiresAt, 0).UTC(),
+	}, nil
+}
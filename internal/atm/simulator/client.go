@@ -0,0 +1,248 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/jsonapi"
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/responses"
+)
+
+const (
+	csrfCookieName       = "csrf_token"
+	csrfHeaderName       = "X-CSRF-Token"
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	requestTimeout = 30 * time.Second
+)
+
+var ErrorTwoFactorRequired = errors.New("two-factor login is not supported, use an access token")
+
+var accountType = reflect.TypeOf(new(responses.Account))
+
+// Client calls the service as the browser of the logged in customer does, or
+// with the personal access token.
+type Client struct {
+	baseURL     *url.URL
+	http        *http.Client
+	accessToken string
+}
+
+func NewClient(baseURL string) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+
+	return &Client{
+		baseURL: base,
+		http: &http.Client{
+			Jar:     jar,
+			Timeout: requestTimeout,
+			// The login responds with a redirect carrying the session cookies
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+// UseAccessToken authenticates the API requests with the personal access
+// token, which needs the accounts:read and transactions:write scopes.
+func (c *Client) UseAccessToken(token string) {
+	c.accessToken = token
+}
+
+// Login starts the browser session of the customer.
+func (c *Client) Login(email, password string) error {
+	// The login form is protected from CSRF like any other, the page sets the token
+	resp, err := c.do(http.MethodGet, "/auth", nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	form := url.Values{"email": {email}, "password": {password}}
+	resp, err = c.do(http.MethodPost, "/auth/login", strings.NewReader(form.Encode()), http.Header{
+		"Content-Type": {"application/x-www-form-urlencoded"},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSeeOther {
+		return fmt.Errorf("failed to login: %s", responseError(resp))
+	}
+
+	if location := resp.Header.Get("Location"); location != "/" {
+		return ErrorTwoFactorRequired
+	}
+
+	return nil
+}
+
+// Account returns the account of the customer.
+func (c *Client) Account(accountID uuid.UUID) (*responses.Account, error) {
+	resp, err := c.do(http.MethodGet, "/api/v1/accounts/"+accountID.String(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get account: %s", responseError(resp))
+	}
+
+	account := new(responses.Account)
+	if err = jsonapi.UnmarshalPayload(resp.Body, account); err != nil {
+		return nil, fmt.Errorf("failed to decode account: %w", err)
+	}
+
+	return account, nil
+}
+
+// Accounts returns the accounts of the customer.
+func (c *Client) Accounts() ([]*responses.Account, error) {
+	resp, err := c.do(http.MethodGet, "/api/v1/accounts", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get accounts: %s", responseError(resp))
+	}
+
+	list, err := jsonapi.UnmarshalManyPayload(resp.Body, accountType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode accounts: %w", err)
+	}
+
+	accounts := make([]*responses.Account, len(list))
+	for i, account := range list {
+		accounts[i] = account.(*responses.Account)
+	}
+
+	return accounts, nil
+}
+
+// DepositResult is the response of the service to the deposit. Rejected
+// deposits are results too, the scenarios expect some of them.
+type DepositResult struct {
+	Status  int
+	Balance int
+	Error   string
+}
+
+func (r *DepositResult) OK() bool {
+	return r.Status == http.StatusOK
+}
+
+func (r *DepositResult) String() string {
+	if r.OK() {
+		return fmt.Sprintf("%d, balance %d", r.Status, r.Balance)
+	}
+
+	return fmt.Sprintf("%d, %s", r.Status, r.Error)
+}
+
+// Deposit submits the signed deposit, with the Idempotency-Key if it's not empty.
+func (c *Client) Deposit(deposit *atm.SignedDeposit, idempotencyKey string) (*DepositResult, error) {
+	body, err := json.Marshal(deposit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal deposit: %w", err)
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	if idempotencyKey != "" {
+		header.Set(idempotencyKeyHeader, idempotencyKey)
+	}
+
+	resp, err := c.do(http.MethodPost, "/api/v1/transactions/deposit", bytes.NewReader(body), header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &DepositResult{Status: resp.StatusCode}
+	if !result.OK() {
+		result.Error = responseError(resp)
+		return result, nil
+	}
+
+	var balance responses.TransactionResult
+	if err = json.NewDecoder(resp.Body).Decode(&balance); err != nil {
+		return nil, fmt.Errorf("failed to decode deposit result: %w", err)
+	}
+
+	result.Balance = balance.NewBalance
+	return result, nil
+}
+
+func (c *Client) do(method, path string, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.baseURL.String()+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	if c.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	} else if method != http.MethodGet {
+		// The double-submit CSRF token of the session, see controllers.CSRF
+		for _, cookie := range c.http.Jar.Cookies(c.baseURL) {
+			if cookie.Name == csrfCookieName {
+				req.Header.Set(csrfHeaderName, cookie.Value)
+			}
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s %s: %w", method, path, err)
+	}
+
+	return resp, nil
+}
+
+// responseError returns the details of the JSON:API errors of the response,
+// or its status if there are none.
+func responseError(resp *http.Response) string {
+	var payload jsonapi.ErrorsPayload
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil || len(payload.Errors) == 0 {
+		return resp.Status
+	}
+
+	details := make([]string, 0, len(payload.Errors))
+	for _, e := range payload.Errors {
+		detail := e.Detail
+		if detail == "" {
+			detail = e.Title
+		}
+
+		details = append(details, detail)
+	}
+
+	return strings.Join(details, "; ")
+}
@@ -0,0 +1,438 @@
+// Package simulator drives the deposit API of a running service the way the
+// ATMs and the customers do: it signs the deposits with the ATM key, submits
+// them as the logged in customer and checks the responses and the account
+// balance against the expected outcome of each scenario.
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+)
+
+// payloadLifetime is how long the deposits signed by the simulator are valid.
+const payloadLifetime = 5 * time.Minute
+
+var ErrorUnknownScenario = errors.New("unknown scenario")
+
+// Scenario is the deposits submitted to the service and the check of the
+// outcome, it returns an error describing the deviation from the expected one.
+type Scenario struct {
+	Name        string
+	Description string
+
+	run func(s *Simulator) error
+}
+
+// Scenarios lists the scenarios in the order they are run by default.
+var Scenarios = []Scenario{
+	{
+		Name:        "deposit",
+		Description: "a signed deposit is credited once",
+		run:         (*Simulator).deposit,
+	},
+	{
+		Name:        "replay",
+		Description: "the same signed deposit submitted again is rejected",
+		run:         (*Simulator).replay,
+	},
+	{
+		Name:        "bad-signature",
+		Description: "deposits with a tampered amount or signed by an unknown key are rejected",
+		run:         (*Simulator).badSignature,
+	},
+	{
+		Name:        "expired",
+		Description: "an expired deposit is rejected",
+		run:         (*Simulator).expired,
+	},
+	{
+		Name:        "idempotent-retry",
+		Description: "a retry with the same Idempotency-Key returns the first response",
+		run:         (*Simulator).idempotentRetry,
+	},
+	{
+		Name:        "concurrent",
+		Description: "concurrent deposits are all credited",
+		run:         (*Simulator).concurrent,
+	},
+	{
+		Name:        "concurrent-replay",
+		Description: "the same signed deposit submitted concurrently is credited once",
+		run:         (*Simulator).concurrentReplay,
+	},
+}
+
+// Result is the outcome of the scenario, Err is nil if it passed.
+type Result struct {
+	Scenario string
+	Duration time.Duration
+	Err      error
+}
+
+func (r *Result) Passed() bool {
+	return r.Err == nil
+}
+
+// Simulator is the ATM depositing to the account of the customer.
+type Simulator struct {
+	client      *Client
+	atmID       uuid.UUID
+	signer      *atm.Signer
+	accountID   uuid.UUID
+	amount      uint
+	concurrency int
+}
+
+func New(client *Client, atmID uuid.UUID, signer *atm.Signer, accountID uuid.UUID, amount uint, concurrency int) *Simulator {
+	return &Simulator{
+		client:      client,
+		atmID:       atmID,
+		signer:      signer,
+		accountID:   accountID,
+		amount:      amount,
+		concurrency: max(concurrency, 2),
+	}
+}
+
+// Run runs the scenarios by name, all of them if none is given, one after
+// another until the context is done.
+func (s *Simulator) Run(ctx context.Context, names ...string) ([]Result, error) {
+	scenarios := Scenarios
+	if len(names) > 0 {
+		scenarios = make([]Scenario, 0, len(names))
+		for _, name := range names {
+			scenario, ok := findScenario(name)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrorUnknownScenario, name)
+			}
+
+			scenarios = append(scenarios, scenario)
+		}
+	}
+
+	results := make([]Result, 0, len(scenarios))
+	for _, scenario := range scenarios {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		start := time.Now()
+		err := scenario.run(s)
+
+		results = append(results, Result{
+			Scenario: scenario.Name,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+	}
+
+	return results, nil
+}
+
+// ScenarioNames returns the names of the Scenarios.
+func ScenarioNames() []string {
+	names := make([]string, len(Scenarios))
+	for i, scenario := range Scenarios {
+		names[i] = scenario.Name
+	}
+
+	return names
+}
+
+func findScenario(name string) (Scenario, bool) {
+	for _, scenario := range Scenarios {
+		if scenario.Name == name {
+			return scenario, true
+		}
+	}
+
+	return Scenario{}, false
+}
+
+func (s *Simulator) deposit() error {
+	return s.expectCredited(s.amount, func() error {
+		deposit, err := s.sign(s.signer, s.amount)
+		if err != nil {
+			return err
+		}
+
+		result, err := s.client.Deposit(deposit, uuid.NewString())
+		if err != nil {
+			return err
+		}
+
+		return expectOK("deposit", result)
+	})
+}
+
+func (s *Simulator) replay() error {
+	return s.expectCredited(s.amount, func() error {
+		deposit, err := s.sign(s.signer, s.amount)
+		if err != nil {
+			return err
+		}
+
+		result, err := s.client.Deposit(deposit, uuid.NewString())
+		if err != nil {
+			return err
+		}
+		if err = expectOK("deposit", result); err != nil {
+			return err
+		}
+
+		// Another Idempotency-Key, so that the replay reaches the nonce check
+		result, err = s.client.Deposit(deposit, uuid.NewString())
+		if err != nil {
+			return err
+		}
+
+		return expectRejected("replay", result, "nonce")
+	})
+}
+
+func (s *Simulator) badSignature() error {
+	return s.expectCredited(0, func() error {
+		tampered, err := s.sign(s.signer, s.amount)
+		if err != nil {
+			return err
+		}
+		tampered.Amount *= 100
+
+		result, err := s.client.Deposit(tampered, uuid.NewString())
+		if err != nil {
+			return err
+		}
+		if err = expectRejected("tampered amount", result, "signature"); err != nil {
+			return err
+		}
+
+		// The unknown key differs from the ATM key in the key only
+		unknownKey, err := atm.GenerateKeyOfType(atm.KeyTypeOf(s.signer.Public()))
+		if err != nil {
+			return err
+		}
+
+		unknown, err := atm.NewSigner(unknownKey, s.signer.Format())
+		if err != nil {
+			return err
+		}
+
+		foreign, err := s.sign(unknown, s.amount)
+		if err != nil {
+			return err
+		}
+
+		result, err = s.client.Deposit(foreign, uuid.NewString())
+		if err != nil {
+			return err
+		}
+
+		return expectRejected("unknown key", result, "signature")
+	})
+}
+
+func (s *Simulator) expired() error {
+	return s.expectCredited(0, func() error {
+		payload, err := atm.NewDepositPayload(s.atmID, s.accountID, s.amount, payloadLifetime)
+		if err != nil {
+			return err
+		}
+
+		payload.IssuedAt = payload.IssuedAt.Add(-2 * payloadLifetime)
+		payload.ExpiresAt = payload.ExpiresAt.Add(-2 * payloadLifetime)
+
+		deposit, err := atm.SignDeposit(s.signer, payload)
+		if err != nil {
+			return err
+		}
+
+		result, err := s.client.Deposit(deposit, uuid.NewString())
+		if err != nil {
+			return err
+		}
+
+		return expectRejected("expired deposit", result, "expired")
+	})
+}
+
+func (s *Simulator) idempotentRetry() error {
+	return s.expectCredited(s.amount, func() error {
+		deposit, err := s.sign(s.signer, s.amount)
+		if err != nil {
+			return err
+		}
+
+		key := uuid.NewString()
+
+		first, err := s.client.Deposit(deposit, key)
+		if err != nil {
+			return err
+		}
+		if err = expectOK("deposit", first); err != nil {
+			return err
+		}
+
+		retry, err := s.client.Deposit(deposit, key)
+		if err != nil {
+			return err
+		}
+		if err = expectOK("retry", retry); err != nil {
+			return err
+		}
+
+		if retry.Balance != first.Balance {
+			return fmt.Errorf("retry returned balance %d, the first response %d", retry.Balance, first.Balance)
+		}
+
+		return nil
+	})
+}
+
+func (s *Simulator) concurrent() error {
+	deposits := make([]*atm.SignedDeposit, s.concurrency)
+	for i := range deposits {
+		deposit, err := s.sign(s.signer, s.amount)
+		if err != nil {
+			return err
+		}
+
+		deposits[i] = deposit
+	}
+
+	return s.expectCredited(uint(s.concurrency)*s.amount, func() error {
+		results, err := s.submitConcurrently(deposits)
+		if err != nil {
+			return err
+		}
+
+		for i, result := range results {
+			if err = expectOK(fmt.Sprintf("deposit %d", i), result); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *Simulator) concurrentReplay() error {
+	deposit, err := s.sign(s.signer, s.amount)
+	if err != nil {
+		return err
+	}
+
+	deposits := make([]*atm.SignedDeposit, s.concurrency)
+	for i := range deposits {
+		deposits[i] = deposit
+	}
+
+	return s.expectCredited(s.amount, func() error {
+		results, err := s.submitConcurrently(deposits)
+		if err != nil {
+			return err
+		}
+
+		accepted := 0
+		for i, result := range results {
+			if result.OK() {
+				accepted++
+				continue
+			}
+
+			if err = expectRejected(fmt.Sprintf("replay %d", i), result, "nonce"); err != nil {
+				return err
+			}
+		}
+
+		if accepted != 1 {
+			return fmt.Errorf("%d of %d submissions accepted, expected 1", accepted, len(results))
+		}
+
+		return nil
+	})
+}
+
+// submitConcurrently submits the deposits at once, each with its own
+// Idempotency-Key, and returns the results in the same order.
+func (s *Simulator) submitConcurrently(deposits []*atm.SignedDeposit) ([]*DepositResult, error) {
+	results := make([]*DepositResult, len(deposits))
+	errs := make([]error, len(deposits))
+
+	start := make(chan struct{})
+	wg := new(sync.WaitGroup)
+
+	for i, deposit := range deposits {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			results[i], errs[i] = s.client.Deposit(deposit, uuid.NewString())
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
+
+// expectCredited runs the submissions and checks the account balance changed
+// by the amount.
+func (s *Simulator) expectCredited(amount uint, submit func() error) error {
+	before, err := s.client.Account(s.accountID)
+	if err != nil {
+		return err
+	}
+
+	if err = submit(); err != nil {
+		return err
+	}
+
+	after, err := s.client.Account(s.accountID)
+	if err != nil {
+		return err
+	}
+
+	if credited := after.Balance - before.Balance; credited != int(amount) {
+		return fmt.Errorf("balance changed by %d, expected %d", credited, amount)
+	}
+
+	return nil
+}
+
+func (s *Simulator) sign(signer *atm.Signer, amount uint) (*atm.SignedDeposit, error) {
+	payload, err := atm.NewDepositPayload(s.atmID, s.accountID, amount, payloadLifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	return atm.SignDeposit(signer, payload)
+}
+
+func expectOK(what string, result *DepositResult) error {
+	if !result.OK() {
+		return fmt.Errorf("%s rejected: %s", what, result)
+	}
+
+	return nil
+}
+
+// expectRejected checks the deposit is rejected as a bad request for the
+// reason containing the substring.
+func expectRejected(what string, result *DepositResult, reason string) error {
+	if result.Status != http.StatusBadRequest || !strings.Contains(result.Error, reason) {
+		return fmt.Errorf("%s: got %s, expected %d with %q", what, result, http.StatusBadRequest, reason)
+	}
+
+	return nil
+}
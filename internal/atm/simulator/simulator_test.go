@@ -0,0 +1,172 @@
+package simulator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/jsonapi"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/responses"
+)
+
+const testSession = "session"
+
+// fakeBank is the deposit API of the service reduced to what the scenarios
+// check, with the nonce check optional to see the simulator catch its absence.
+type fakeBank struct {
+	publicKey  *ecdsa.PublicKey
+	checkNonce bool
+
+	mu          sync.Mutex
+	balance     int
+	nonces      map[string]bool
+	idempotency map[string]int
+}
+
+func (b *fakeBank) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /auth", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: csrfCookieName, Value: "csrf", Path: "/"})
+	})
+	mux.HandleFunc("POST /auth/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(csrfHeaderName) != "csrf" || r.FormValue("password") != "password" {
+			renderError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: "jwt", Value: testSession, Path: "/"})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+	mux.HandleFunc("GET /api/v1/accounts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		w.Header().Set("Content-Type", jsonapi.MediaType)
+		_ = jsonapi.MarshalPayload(w, &responses.Account{ID: r.PathValue("id"), Balance: b.balance})
+	})
+	mux.HandleFunc("POST /api/v1/transactions/deposit", b.deposit)
+
+	return mux
+}
+
+func (b *fakeBank) deposit(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie("jwt"); err != nil || cookie.Value != testSession || r.Header.Get(csrfHeaderName) != "csrf" {
+		renderError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var deposit atm.SignedDeposit
+	if err := json.NewDecoder(r.Body).Decode(&deposit); err != nil {
+		renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := r.Header.Get(idempotencyKeyHeader)
+	if balance, ok := b.idempotency[key]; ok {
+		_ = json.NewEncoder(w).Encode(responses.TransactionResult{NewBalance: balance})
+		return
+	}
+
+	payload := deposit.Payload()
+	if err := payload.Validate(time.Now()); err != nil {
+		renderError(w, http.StatusBadRequest, "invalid ATM payload: "+err.Error())
+		return
+	}
+
+	if err := atm.Verify(b.publicKey, payload.Canonical(), deposit.ATMSignature); err != nil {
+		renderError(w, http.StatusBadRequest, "invalid ATM signature")
+		return
+	}
+
+	if b.checkNonce && b.nonces[deposit.Nonce] {
+		renderError(w, http.StatusBadRequest, "ATM nonce already used")
+		return
+	}
+
+	b.nonces[deposit.Nonce] = true
+	b.balance += int(deposit.Amount)
+	b.idempotency[key] = b.balance
+
+	_ = json.NewEncoder(w).Encode(responses.TransactionResult{NewBalance: b.balance})
+}
+
+func renderError(w http.ResponseWriter, status int, detail string) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(jsonapi.ErrorsPayload{Errors: []*jsonapi.ErrorObject{{
+		Status: fmt.Sprint(status),
+		Detail: detail,
+	}}})
+}
+
+func newTestSimulator(t *testing.T, checkNonce bool) (*Simulator, *fakeBank) {
+	t.Helper()
+
+	atmKey, err := atm.GenerateKey()
+	require.NoError(t, err)
+
+	bank := &fakeBank{
+		publicKey:   &atmKey.PublicKey,
+		checkNonce:  checkNonce,
+		nonces:      make(map[string]bool),
+		idempotency: make(map[string]int),
+	}
+
+	server := httptest.NewServer(bank.handler())
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(server.URL)
+	require.NoError(t, err)
+
+	require.Error(t, client.Login("customer@bank.local", "wrong"))
+	require.NoError(t, client.Login("customer@bank.local", "password"))
+
+	signer, err := atm.NewSigner(atmKey, atm.SignatureFormatBase64)
+	require.NoError(t, err)
+
+	return New(client, uuid.New(), signer, uuid.New(), 100, 4), bank
+}
+
+func TestSimulator(t *testing.T) {
+	sim, bank := newTestSimulator(t, true)
+
+	results, err := sim.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, len(Scenarios))
+
+	for _, result := range results {
+		assert.True(t, result.Passed(), "%s: %v", result.Scenario, result.Err)
+	}
+
+	// deposit, replay, idempotent-retry, concurrent and concurrent-replay
+	assert.Equal(t, (1+1+1+4+1)*100, bank.balance)
+
+	_, err = sim.Run(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrorUnknownScenario)
+}
+
+func TestSimulatorDetectsReplays(t *testing.T) {
+	sim, _ := newTestSimulator(t, false)
+
+	results, err := sim.Run(context.Background(), "deposit", "replay", "concurrent-replay")
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.True(t, results[0].Passed(), results[0].Err)
+	assert.False(t, results[1].Passed(), "the replay must be detected")
+	assert.False(t, results[2].Passed(), "the concurrent replay must be detected")
+}
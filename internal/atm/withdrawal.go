@@ -0,0 +1,88 @@
+package atm
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CashOutCodeDigits is the length of the one-time cash-out code entered at the ATM.
+const CashOutCodeDigits = 8
+
+var ErrorInvalidCashOutCode = errors.New("invalid cash-out code")
+
+var cashOutCodeRegexp = regexp.MustCompile(`^[0-9]{` + strconv.Itoa(CashOutCodeDigits) + `}$`)
+
+// WithdrawalPayload is the ATM confirmation of paying out the amount of the
+// cash-out code. Like the deposits, the nonce is unique per ATM and the payload
+// can't be submitted after it expires.
+type WithdrawalPayload struct {
+	Version   int
+	ATMID     uuid.UUID
+	Code      string
+	Amount    uint
+	Nonce     string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// NewWithdrawalPayload returns the payload of the current version with a random
+// nonce, valid from now for the given lifetime.
+func NewWithdrawalPayload(atmID uuid.UUID, code string, amount uint, lifetime time.Duration) (*WithdrawalPayload, error) {
+	nonce, err := NewNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	return &WithdrawalPayload{
+		Version:   PayloadVersion,
+		ATMID:     atmID,
+		Code:      code,
+		Amount:    amount,
+		Nonce:     nonce,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(lifetime),
+	}, nil
+}
+
+// Canonical returns the bytes signed by the ATM, encoded like the deposit
+// payload, see DepositPayload.Canonical, from the following lines:
+//
+//	ilab1-atm-withdrawal
+//	v=1
+//	atm_id=<lowercase hyphenated UUID>
+//	code=<8 decimal digits>
+//	amount=<decimal amount in cents, no leading zeros>
+//	nonce=<32 lowercase hex digits>
+//	issued_at=<decimal Unix seconds>
+//	expires_at=<decimal Unix seconds>
+func (p *WithdrawalPayload) Canonical() []byte {
+	lines := []string{
+		withdrawalDomain,
+		"v=" + strconv.Itoa(p.Version),
+		"atm_id=" + p.ATMID.String(),
+		"code=" + p.Code,
+		"amount=" + strconv.FormatUint(uint64(p.Amount), 10),
+		"nonce=" + p.Nonce,
+		"issued_at=" + strconv.FormatInt(p.IssuedAt.Unix(), 10),
+		"expires_at=" + strconv.FormatInt(p.ExpiresAt.Unix(), 10),
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// Validate checks the code, the version and the nonce of the payload and that
+// it's valid at the given time.
+func (p *WithdrawalPayload) Validate(now time.Time) error {
+	if !cashOutCodeRegexp.MatchString(p.Code) {
+		return ErrorInvalidCashOutCode
+	}
+
+	return validate(p.Version, p.Nonce, p.IssuedAt, p.ExpiresAt, now)
+}
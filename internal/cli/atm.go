@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/config"
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/data/postgres"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+)
+
+// ATMRegister adds an active ATM with the PEM encoded public key from the file,
+// verifying the signatures in the format.
+func ATMRegister(cfg config.Config, location, publicKeyPath string, format atm.SignatureFormat) error {
+	publicKeyPEM, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	machine, key, err := models.NewATMs(postgres.NewMainQ(cfg.DB())).Register(location, publicKeyPEM, format)
+	if err != nil {
+		return fmt.Errorf("failed to register ATM: %w", err)
+	}
+
+	cfg.Log().WithFields(logan.F{
+		"atm_id":           machine.ID,
+		"key_id":           key.ID,
+		"location":         machine.Location,
+		"signature_format": key.SignatureFormat,
+	}).Info("ATM registered")
+	return nil
+}
+
+// ATMList logs the registered ATMs with their keys.
+func ATMList(cfg config.Config) error {
+	atms := models.NewATMs(postgres.NewMainQ(cfg.DB()))
+
+	list, err := atms.GetATMs()
+	if err != nil {
+		return err
+	}
+
+	for _, machine := range list {
+		keys, err := atms.GetKeys(machine.ID)
+		if err != nil {
+			return err
+		}
+
+		cfg.Log().WithFields(logan.F{
+			"atm_id":       machine.ID,
+			"location":     machine.Location,
+			"status":       machine.Status,
+			"cash_balance": machine.CashBalance,
+		}).Info("ATM")
+
+		for _, key := range keys {
+			fields := logan.F{
+				"atm_id":           machine.ID,
+				"key_id":           key.ID,
+				"signature_format": key.SignatureFormat,
+				"valid_from":       key.ValidFrom.Format(time.RFC3339),
+			}
+			if key.ValidUntil != nil {
+				fields["valid_until"] = key.ValidUntil.Format(time.RFC3339)
+			}
+
+			cfg.Log().WithFields(fields).Info("ATM key")
+		}
+	}
+
+	return nil
+}
+
+// ATMSetStatus enables or disables the ATM.
+func ATMSetStatus(cfg config.Config, atmID string, status data.ATMStatus) error {
+	id, err := uuid.Parse(atmID)
+	if err != nil {
+		return fmt.Errorf("invalid ATM id: %w", err)
+	}
+
+	if err = models.NewATMs(postgres.NewMainQ(cfg.DB())).SetStatus(id, status); err != nil {
+		return fmt.Errorf("failed to set ATM status: %w", err)
+	}
+
+	cfg.Log().WithField("atm_id", id).WithField("status", status).Info("ATM status changed")
+	return nil
+}
+
+// ATMKeyAdd adds the public key from the file to the ATM, verifying the
+// signatures in the format. To rotate the key, or move the ATM to another key
+// type or signature format, the new key is added first and the old one is
+// revoked once the ATM signs with the new key.
+func ATMKeyAdd(cfg config.Config, atmID, publicKeyPath string, format atm.SignatureFormat, validFrom, validUntil string) error {
+	id, err := uuid.Parse(atmID)
+	if err != nil {
+		return fmt.Errorf("invalid ATM id: %w", err)
+	}
+
+	publicKeyPEM, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	from := time.Now()
+	if validFrom != "" {
+		if from, err = time.Parse(time.RFC3339, validFrom); err != nil {
+			return fmt.Errorf("invalid valid-from: %w", err)
+		}
+	}
+
+	var until *time.Time
+	if validUntil != "" {
+		parsed, err := time.Parse(time.RFC3339, validUntil)
+		if err != nil {
+			return fmt.Errorf("invalid valid-until: %w", err)
+		}
+
+		parsed = parsed.UTC()
+		until = &parsed
+	}
+
+	key, err := models.NewATMs(postgres.NewMainQ(cfg.DB())).AddKey(id, publicKeyPEM, format, from, until)
+	if err != nil {
+		return fmt.Errorf("failed to add ATM key: %w", err)
+	}
+
+	cfg.Log().WithField("atm_id", id).WithField("key_id", key.ID).Info("ATM key added")
+	return nil
+}
+
+// ATMKeyRevoke ends the validity of the ATM key now.
+func ATMKeyRevoke(cfg config.Config, keyID string) error {
+	id, err := uuid.Parse(keyID)
+	if err != nil {
+		return fmt.Errorf("invalid key id: %w", err)
+	}
+
+	if err = models.NewATMs(postgres.NewMainQ(cfg.DB())).RevokeKey(id, time.Now()); err != nil {
+		return fmt.Errorf("failed to revoke ATM key: %w", err)
+	}
+
+	cfg.Log().WithField("key_id", id).Info("ATM key revoked")
+	return nil
+}
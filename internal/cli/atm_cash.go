@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/config"
+	"github.com/omegatymbjiep/ilab1/internal/data/postgres"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+)
+
+// ATMCashReplenish records the cash loaded into the ATM.
+func ATMCashReplenish(cfg config.Config, atmID string, amount uint) error {
+	id, err := uuid.Parse(atmID)
+	if err != nil {
+		return fmt.Errorf("invalid ATM id: %w", err)
+	}
+
+	machine, err := newATMCash(cfg).Replenish(id, amount)
+	if err != nil {
+		return fmt.Errorf("failed to replenish ATM cash: %w", err)
+	}
+
+	cfg.Log().WithFields(logan.F{
+		"atm_id":       machine.ID,
+		"amount":       amount,
+		"cash_balance": machine.CashBalance,
+	}).Info("ATM cash replenished")
+	return nil
+}
+
+// ATMCashCollect records the cash taken out of the ATM.
+func ATMCashCollect(cfg config.Config, atmID string, amount uint) error {
+	id, err := uuid.Parse(atmID)
+	if err != nil {
+		return fmt.Errorf("invalid ATM id: %w", err)
+	}
+
+	machine, err := newATMCash(cfg).Collect(id, amount)
+	if err != nil {
+		return fmt.Errorf("failed to collect ATM cash: %w", err)
+	}
+
+	cfg.Log().WithFields(logan.F{
+		"atm_id":       machine.ID,
+		"amount":       amount,
+		"cash_balance": machine.CashBalance,
+	}).Info("ATM cash collected")
+	return nil
+}
+
+// ATMSignCashCount prints the body of the cash count request signed with the
+// ATM key, the way the ATM does it at the end of the business day.
+func ATMSignCashCount(keyPath string, format atm.SignatureFormat, atmID, businessDate string, counted uint, lifetime time.Duration) error {
+	signer, err := readATMSigner(keyPath, format)
+	if err != nil {
+		return err
+	}
+
+	machineID, err := uuid.Parse(atmID)
+	if err != nil {
+		return fmt.Errorf("invalid ATM id: %w", err)
+	}
+
+	date := time.Now()
+	if businessDate != "" {
+		if date, err = atm.ParseBusinessDate(businessDate); err != nil {
+			return err
+		}
+	}
+
+	payload, err := atm.NewCashCountPayload(machineID, date, counted, lifetime)
+	if err != nil {
+		return err
+	}
+
+	if err = payload.Validate(time.Now()); err != nil {
+		return fmt.Errorf("invalid cash count: %w", err)
+	}
+
+	signed, err := atm.SignCashCount(signer, payload)
+	if err != nil {
+		return fmt.Errorf("failed to sign cash count: %w", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(signed)
+}
+
+// ATMReconciliationReport prints the reconciliations of the business days
+// from and to, inclusive, with the expected and counted cash and their totals.
+func ATMReconciliationReport(cfg config.Config, from, to, atmID string, discrepancyOnly bool) error {
+	filter := models.ReconciliationFilter{DiscrepancyOnly: discrepancyOnly}
+
+	var err error
+	if filter.From, err = atm.ParseBusinessDate(from); err != nil {
+		return fmt.Errorf("invalid from: %w", err)
+	}
+
+	filter.To = atm.BusinessDate(time.Now())
+	if to != "" {
+		if filter.To, err = atm.ParseBusinessDate(to); err != nil {
+			return fmt.Errorf("invalid to: %w", err)
+		}
+	}
+
+	if atmID != "" {
+		id, err := uuid.Parse(atmID)
+		if err != nil {
+			return fmt.Errorf("invalid ATM id: %w", err)
+		}
+
+		filter.ATMID = &id
+	}
+
+	reconciliations, err := newATMCash(cfg).Reconciliations(filter)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "business date\tATM\texpected\tcounted\tdiscrepancy\t")
+
+	var expected, counted, discrepancy int
+	for _, r := range reconciliations {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n",
+			r.BusinessDate.Format(atm.BusinessDateLayout), r.ATMID,
+			formatCents(r.Expected), formatCents(r.Counted), formatCents(r.Discrepancy))
+
+		expected += r.Expected
+		counted += r.Counted
+		discrepancy += r.Discrepancy
+	}
+
+	fmt.Fprintf(w, "total\t%d reconciliations\t%s\t%s\t%s\t\n",
+		len(reconciliations), formatCents(expected), formatCents(counted), formatCents(discrepancy))
+
+	return w.Flush()
+}
+
+func newATMCash(cfg config.Config) *models.ATMCash {
+	db := postgres.NewMainQ(cfg.DB())
+	return models.NewATMCash(db, models.NewAuditService(db), models.NewATMs(db))
+}
+
+// formatCents formats the amount in cents as a decimal with two fraction digits.
+func formatCents(amount int) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/config"
+	"github.com/omegatymbjiep/ilab1/internal/data/postgres"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+)
+
+// ATMKeygen writes a new ATM signing key of the type and its public key, the
+// one to register, to the files. Existing files are never overwritten.
+func ATMKeygen(cfg config.Config, keyType atm.KeyType, keyPath, publicKeyPath string) error {
+	privateKey, err := atm.GenerateKeyOfType(keyType)
+	if err != nil {
+		return err
+	}
+
+	privateKeyPEM, err := atm.MarshalPrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	publicKeyPEM, err := atm.MarshalPublicKey(privateKey.Public())
+	if err != nil {
+		return err
+	}
+
+	if err = writeNewFile(keyPath, privateKeyPEM, 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+
+	if err = writeNewFile(publicKeyPath, publicKeyPEM, 0644); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+
+	cfg.Log().WithFields(logan.F{
+		"type":       keyType,
+		"key":        keyPath,
+		"public_key": publicKeyPath,
+	}).Info("ATM key generated")
+	return nil
+}
+
+// ATMPubkey prints the public key of the ATM signing key.
+func ATMPubkey(keyPath string) error {
+	privateKey, err := readATMKey(keyPath)
+	if err != nil {
+		return err
+	}
+
+	publicKeyPEM, err := atm.MarshalPublicKey(privateKey.Public())
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(publicKeyPEM)
+	return err
+}
+
+// ATMSign prints the body of the deposit request signed with the ATM key in
+// the signature format, the way the ATM does it.
+func ATMSign(keyPath string, format atm.SignatureFormat, atmID, accountID string, amount uint, lifetime time.Duration) error {
+	signer, err := readATMSigner(keyPath, format)
+	if err != nil {
+		return err
+	}
+
+	machineID, err := uuid.Parse(atmID)
+	if err != nil {
+		return fmt.Errorf("invalid ATM id: %w", err)
+	}
+
+	account, err := uuid.Parse(accountID)
+	if err != nil {
+		return fmt.Errorf("invalid account id: %w", err)
+	}
+
+	if amount == 0 {
+		return errors.New("amount must be positive")
+	}
+
+	payload, err := atm.NewDepositPayload(machineID, account, amount, lifetime)
+	if err != nil {
+		return err
+	}
+
+	if err = payload.Validate(time.Now()); err != nil {
+		return fmt.Errorf("invalid deposit: %w", err)
+	}
+
+	signed, err := atm.SignDeposit(signer, payload)
+	if err != nil {
+		return fmt.Errorf("failed to sign deposit: %w", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(signed)
+}
+
+// ATMVerify checks the signed deposit read from the file, or the standard
+// input if the path is empty or "-", is valid now and signed with the public
+// key from the file in the signature format. Without the public key file, the
+// deposit is verified against the registered keys of the ATM, like the
+// service does.
+func ATMVerify(cfg config.Config, path, publicKeyPath string, format atm.SignatureFormat) error {
+	var (
+		body []byte
+		err  error
+	)
+	if path == "" || path == "-" {
+		body, err = io.ReadAll(os.Stdin)
+	} else {
+		body, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read signed deposit: %w", err)
+	}
+
+	signed := new(atm.SignedDeposit)
+	if err = json.Unmarshal(body, signed); err != nil {
+		return fmt.Errorf("failed to decode signed deposit: %w", err)
+	}
+
+	payload := signed.Payload()
+	if err = payload.Validate(time.Now()); err != nil {
+		return fmt.Errorf("invalid deposit: %w", err)
+	}
+
+	if publicKeyPath == "" {
+		err = models.NewATMs(postgres.NewMainQ(cfg.DB())).VerifySignature(signed.ATMID, payload.Canonical(), signed.ATMSignature)
+	} else {
+		err = verifyWithPublicKeyFile(publicKeyPath, format, payload.Canonical(), signed.ATMSignature)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify deposit signature: %w", err)
+	}
+
+	cfg.Log().WithField("atm_id", signed.ATMID).WithField("nonce", signed.Nonce).Info("ATM signature is valid")
+	return nil
+}
+
+func verifyWithPublicKeyFile(publicKeyPath string, format atm.SignatureFormat, message []byte, signature string) error {
+	publicKeyPEM, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	publicKey, err := atm.ParsePublicKey(publicKeyPEM)
+	if err != nil {
+		return err
+	}
+
+	return atm.VerifySignature(publicKey, format, message, signature)
+}
+
+func readATMKey(keyPath string) (crypto.Signer, error) {
+	privateKeyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	return atm.ParsePrivateKey(privateKeyPEM)
+}
+
+// readATMSigner reads the ATM key signing in the signature format.
+func readATMSigner(keyPath string, format atm.SignatureFormat) (*atm.Signer, error) {
+	privateKey, err := readATMKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return atm.NewSigner(privateKey, format)
+}
+
+// writeNewFile is os.WriteFile failing if the file exists.
+func writeNewFile(path string, content []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
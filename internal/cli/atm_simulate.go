@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/atm/simulator"
+	"github.com/omegatymbjiep/ilab1/internal/config"
+)
+
+// ATMSimulateOptions are the flags of the atm simulate command.
+type ATMSimulateOptions struct {
+	URL         string
+	Email       string
+	Password    string
+	AccessToken string
+	ATMID       string
+	KeyPath     string
+	Format      atm.SignatureFormat
+	AccountID   string
+	Amount      uint
+	Concurrency int
+	Scenarios   []string
+}
+
+// ATMSimulate runs the deposit scenarios against the running service as the
+// customer, with the deposits signed by the registered ATM, and logs the
+// result of each scenario. It fails if any of them fails.
+func ATMSimulate(ctx context.Context, cfg config.Config, opts ATMSimulateOptions) error {
+	atmID, err := uuid.Parse(opts.ATMID)
+	if err != nil {
+		return fmt.Errorf("invalid ATM id: %w", err)
+	}
+
+	signer, err := readATMSigner(opts.KeyPath, opts.Format)
+	if err != nil {
+		return err
+	}
+
+	client, err := simulator.NewClient(opts.URL)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case opts.AccessToken != "":
+		client.UseAccessToken(opts.AccessToken)
+	case opts.Email != "" && opts.Password != "":
+		if err = client.Login(opts.Email, opts.Password); err != nil {
+			return err
+		}
+	default:
+		return errors.New("either the access token or the email and password must be provided")
+	}
+
+	accountID, err := simulatedAccount(client, opts.AccountID)
+	if err != nil {
+		return err
+	}
+
+	sim := simulator.New(client, atmID, signer, accountID, opts.Amount, opts.Concurrency)
+
+	results, err := sim.Run(ctx, opts.Scenarios...)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		entry := cfg.Log().WithFields(logan.F{
+			"scenario": result.Scenario,
+			"duration": result.Duration,
+		})
+
+		if !result.Passed() {
+			failed++
+			entry.WithError(result.Err).Error("scenario failed")
+			continue
+		}
+
+		entry.Info("scenario passed")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scenarios failed", failed, len(results))
+	}
+
+	cfg.Log().WithField("account_id", accountID).WithField("scenarios", len(results)).Info("all scenarios passed")
+	return nil
+}
+
+// simulatedAccount defaults to the first account of the customer.
+func simulatedAccount(client *simulator.Client, accountID string) (uuid.UUID, error) {
+	if accountID != "" {
+		id, err := uuid.Parse(accountID)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid account id: %w", err)
+		}
+
+		return id, nil
+	}
+
+	accounts, err := client.Accounts()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if len(accounts) == 0 {
+		return uuid.Nil, errors.New("the customer has no accounts")
+	}
+
+	return uuid.Parse(accounts[0].ID)
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/omegatymbjiep/ilab1/internal/config"
+	"github.com/omegatymbjiep/ilab1/internal/data/postgres"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+)
+
+// AuthUnlock lifts the login lockout of the customer with the email and/or of the IP.
+func AuthUnlock(cfg config.Config, email, ip string) error {
+	if email == "" && ip == "" {
+		return errors.New("either email or ip must be provided")
+	}
+
+	db := postgres.NewMainQ(cfg.DB())
+	loginGuard := models.NewLoginGuard(db, models.NewAuditService(db), models.LoginThrottlePolicy{})
+
+	if email != "" {
+		if err := loginGuard.UnlockCustomer(email); err != nil {
+			return fmt.Errorf("failed to unlock customer: %w", err)
+		}
+
+		cfg.Log().WithField("email", email).Info("customer login unlocked")
+	}
+
+	if ip != "" {
+		if err := loginGuard.UnlockIP(ip); err != nil {
+			return fmt.Errorf("failed to unlock IP: %w", err)
+		}
+
+		cfg.Log().WithField("ip", ip).Info("IP login unlocked")
+	}
+
+	return nil
+}
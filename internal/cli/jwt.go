@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+
+	"github.com/omegatymbjiep/ilab1/internal/config"
+)
+
+// JWTKeygen adds a new key to the key set. The key only verifies the tokens
+// until it's promoted, so it can be published in the JWKS beforehand. The
+// key of a new key set is promoted right away.
+func JWTKeygen(cfg config.Config, keySetPath string, promote bool) error {
+	keySetPath, err := jwtKeySetPath(cfg, keySetPath)
+	if err != nil {
+		return err
+	}
+
+	set, err := config.ReadJWTKeySet(keySetPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		set = &config.JWTKeySet{}
+		promote = true
+	case err != nil:
+		return fmt.Errorf("failed to read key set: %w", err)
+	}
+
+	key, err := config.NewJWTKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	if set.Keys == nil {
+		set.Keys = jwk.NewSet()
+	}
+
+	if err = set.Keys.AddKey(key); err != nil {
+		return fmt.Errorf("failed to add key: %w", err)
+	}
+
+	kid, _ := key.KeyID()
+	if promote {
+		set.ActiveKeyID = kid
+	}
+
+	if err = config.WriteJWTKeySet(keySetPath, set); err != nil {
+		return fmt.Errorf("failed to write key set: %w", err)
+	}
+
+	cfg.Log().WithField("kid", kid).WithField("active", promote).Info("JWT key generated")
+	return nil
+}
+
+// JWTPromote makes the key the signing key, the previously active key is
+// retired and only verifies the tokens. The service must be restarted to
+// pick up the change.
+func JWTPromote(cfg config.Config, keySetPath, kid string) error {
+	return updateJWTKeySet(cfg, keySetPath, func(set *config.JWTKeySet) error {
+		return set.Promote(kid)
+	})
+}
+
+// JWTRemove drops the retired key, it should be done once the tokens signed
+// with it have expired.
+func JWTRemove(cfg config.Config, keySetPath, kid string) error {
+	return updateJWTKeySet(cfg, keySetPath, func(set *config.JWTKeySet) error {
+		return set.Remove(kid)
+	})
+}
+
+func updateJWTKeySet(cfg config.Config, keySetPath string, update func(set *config.JWTKeySet) error) error {
+	keySetPath, err := jwtKeySetPath(cfg, keySetPath)
+	if err != nil {
+		return err
+	}
+
+	set, err := config.ReadJWTKeySet(keySetPath)
+	if err != nil {
+		return fmt.Errorf("failed to read key set: %w", err)
+	}
+
+	if err = update(set); err != nil {
+		return err
+	}
+
+	if err = config.WriteJWTKeySet(keySetPath, set); err != nil {
+		return fmt.Errorf("failed to write key set: %w", err)
+	}
+
+	cfg.Log().WithField("active_kid", set.ActiveKeyID).Info("JWT key set updated")
+	return nil
+}
+
+// jwtKeySetPath defaults to the key set the service is configured with, the
+// keys are not read, as the key set may not exist yet.
+func jwtKeySetPath(cfg config.Config, keySetPath string) (string, error) {
+	if keySetPath != "" {
+		return keySetPath, nil
+	}
+
+	if keySetPath = cfg.JWTKeySetPath(); keySetPath == "" {
+		return "", errors.New("jwt.key_set_path is not configured, the key set path must be provided")
+	}
+
+	return keySetPath, nil
+}
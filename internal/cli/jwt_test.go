@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/distributed_lab/kit/kv"
+
+	"github.com/omegatymbjiep/ilab1/internal/config"
+)
+
+// newTestConfig returns the config of the service using the key set at the path.
+func newTestConfig(t *testing.T, keySetPath string) config.Config {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(configPath, []byte(fmt.Sprintf(`
+log:
+  level: error
+  disable_sentry: true
+
+jwt:
+  key_set_path: %s
+  expiry: 900s
+`, keySetPath)), 0o600)
+	require.NoError(t, err)
+
+	return config.New(kv.NewViperFile(configPath))
+}
+
+func TestJWTKeygenBootstrap(t *testing.T) {
+	keySetPath := filepath.Join(t.TempDir(), "jwt_keys.json")
+	cfg := newTestConfig(t, keySetPath)
+
+	// The configured key set doesn't exist yet, the first key is promoted
+	require.NoError(t, JWTKeygen(cfg, "", false))
+
+	set, err := config.ReadJWTKeySet(keySetPath)
+	require.NoError(t, err)
+	require.Equal(t, 1, set.Keys.Len())
+
+	first, ok := set.Keys.Key(0)
+	require.True(t, ok)
+	firstKID, _ := first.KeyID()
+	assert.Equal(t, firstKID, set.ActiveKeyID)
+
+	// The next key only verifies the tokens until it's promoted
+	require.NoError(t, JWTKeygen(cfg, "", false))
+
+	set, err = config.ReadJWTKeySet(keySetPath)
+	require.NoError(t, err)
+	require.Equal(t, 2, set.Keys.Len())
+	assert.Equal(t, firstKID, set.ActiveKeyID)
+
+	second, ok := set.Keys.Key(1)
+	require.True(t, ok)
+	secondKID, _ := second.KeyID()
+
+	require.NoError(t, JWTPromote(cfg, "", secondKID))
+	require.NoError(t, JWTRemove(cfg, "", firstKID))
+
+	// The service starts with the created key set
+	jwt := newTestConfig(t, keySetPath).JWT()
+	assert.Equal(t, secondKID, jwt.KeySet.ActiveKeyID)
+	assert.Equal(t, 1, jwt.KeySet.Keys.Len())
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"fmt"
+
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/omegatymbjiep/ilab1/internal/config"
+	"github.com/omegatymbjiep/ilab1/internal/data/postgres"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+)
+
+func LedgerVerify(cfg config.Config) error {
+	mismatches, err := models.NewLedger(postgres.NewMainQ(cfg.DB())).VerifyBalances()
+	if err != nil {
+		return fmt.Errorf("failed to verify balances: %w", err)
+	}
+
+	for _, mismatch := range mismatches {
+		cfg.Log().WithFields(logan.F{
+			"account_id": mismatch.AccountID,
+			"stored":     mismatch.Stored,
+			"derived":    mismatch.Derived,
+		}).Error("account balance does not match the ledger")
+	}
+
+	if len(mismatches) != 0 {
+		return fmt.Errorf("%d account balances do not match the ledger", len(mismatches))
+	}
+
+	cfg.Log().Info("all account balances match the ledger")
+	return nil
+}
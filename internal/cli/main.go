@@ -11,7 +11,10 @@ import (
 	"gitlab.com/distributed_lab/kit/kv"
 	"gitlab.com/distributed_lab/logan/v3"
 
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/atm/simulator"
 	"github.com/omegatymbjiep/ilab1/internal/config"
+	"github.com/omegatymbjiep/ilab1/internal/data"
 	"github.com/omegatymbjiep/ilab1/internal/service"
 )
 
@@ -36,6 +39,93 @@ func Run(args []string) bool {
 	migrateUpCmd := migrateCmd.Command("up", "migrate db up")
 	migrateDownCmd := migrateCmd.Command("down", "migrate db down")
 
+	ledgerCmd := app.Command("ledger", "ledger command")
+	ledgerVerifyCmd := ledgerCmd.Command("verify", "verify account balances against the ledger")
+
+	authCmd := app.Command("auth", "auth command")
+	authUnlockCmd := authCmd.Command("unlock", "lift the login lockout of a customer or an IP")
+	authUnlockEmail := authUnlockCmd.Flag("email", "email of the customer to unlock").String()
+	authUnlockIP := authUnlockCmd.Flag("ip", "IP to unlock").String()
+
+	jwtCmd := app.Command("jwt", "JWT signing keys command")
+	jwtKeySet := jwtCmd.Flag("key-set", "JWK Set file, jwt.key_set_path by default").String()
+	jwtKeygenCmd := jwtCmd.Command("keygen", "add a new key to the key set")
+	jwtKeygenPromote := jwtKeygenCmd.Flag("promote", "make the new key the signing key").Bool()
+	jwtPromoteCmd := jwtCmd.Command("promote", "make the key the signing key, retiring the current one")
+	jwtPromoteKID := jwtPromoteCmd.Arg("kid", "key ID").Required().String()
+	jwtRemoveCmd := jwtCmd.Command("remove", "remove the retired key from the key set")
+	jwtRemoveKID := jwtRemoveCmd.Arg("kid", "key ID").Required().String()
+
+	atmCmd := app.Command("atm", "ATM registry command")
+	atmRegisterCmd := atmCmd.Command("register", "register an ATM with its public key")
+	atmRegisterLocation := atmRegisterCmd.Flag("location", "location of the ATM").Required().String()
+	atmRegisterPublicKey := atmRegisterCmd.Flag("public-key", "PEM encoded ECDSA or Ed25519 public key file").Required().String()
+	atmRegisterFormat := atmRegisterCmd.Flag("signature-format", "format of the signatures made with the key").Default(string(atm.SignatureFormatBase64)).Enum(atm.SignatureFormats()...)
+	atmListCmd := atmCmd.Command("list", "list the ATMs and their keys")
+	atmDisableCmd := atmCmd.Command("disable", "reject the deposits signed by the ATM")
+	atmDisableID := atmDisableCmd.Arg("atm-id", "ATM ID").Required().String()
+	atmEnableCmd := atmCmd.Command("enable", "accept the deposits signed by the ATM again")
+	atmEnableID := atmEnableCmd.Arg("atm-id", "ATM ID").Required().String()
+	atmKeyCmd := atmCmd.Command("key", "ATM keys command")
+	atmKeyAddCmd := atmKeyCmd.Command("add", "add a public key to the ATM")
+	atmKeyAddATMID := atmKeyAddCmd.Arg("atm-id", "ATM ID").Required().String()
+	atmKeyAddPublicKey := atmKeyAddCmd.Flag("public-key", "PEM encoded ECDSA or Ed25519 public key file").Required().String()
+	atmKeyAddFormat := atmKeyAddCmd.Flag("signature-format", "format of the signatures made with the key").Default(string(atm.SignatureFormatBase64)).Enum(atm.SignatureFormats()...)
+	atmKeyAddValidFrom := atmKeyAddCmd.Flag("valid-from", "RFC 3339 start of the key validity, now by default").String()
+	atmKeyAddValidUntil := atmKeyAddCmd.Flag("valid-until", "RFC 3339 end of the key validity, none by default").String()
+	atmKeyRevokeCmd := atmKeyCmd.Command("revoke", "end the validity of the ATM key now")
+	atmKeyRevokeID := atmKeyRevokeCmd.Arg("key-id", "ATM key ID").Required().String()
+	atmKeygenCmd := atmCmd.Command("keygen", "generate an ATM signing key")
+	atmKeygenType := atmKeygenCmd.Flag("type", "key type").Default(string(atm.KeyTypeECDSA)).Enum(atm.KeyTypes()...)
+	atmKeygenKey := atmKeygenCmd.Flag("key", "PEM encoded private key file to create").Required().String()
+	atmKeygenPublicKey := atmKeygenCmd.Flag("public-key", "PEM encoded public key file to create").Required().String()
+	atmPubkeyCmd := atmCmd.Command("pubkey", "print the public key of the ATM signing key")
+	atmPubkeyKey := atmPubkeyCmd.Flag("key", "PEM encoded private key file").Required().String()
+	atmSignCmd := atmCmd.Command("sign", "print the deposit signed with the ATM key")
+	atmSignATMID := atmSignCmd.Flag("atm", "ATM ID").Required().String()
+	atmSignAccount := atmSignCmd.Flag("account", "account ID").Required().String()
+	atmSignAmount := atmSignCmd.Flag("amount", "amount in cents").Required().Uint()
+	atmSignKey := atmSignCmd.Flag("key", "PEM encoded private key file").Required().String()
+	atmSignFormat := atmSignCmd.Flag("format", "signature format").Default(string(atm.SignatureFormatBase64)).Enum(atm.SignatureFormats()...)
+	atmSignLifetime := atmSignCmd.Flag("lifetime", "how long the deposit may be submitted").Default("5m").Duration()
+	atmVerifyCmd := atmCmd.Command("verify", "verify the signed deposit")
+	atmVerifyFile := atmVerifyCmd.Arg("file", "signed deposit file, standard input by default").String()
+	atmVerifyPublicKey := atmVerifyCmd.Flag("public-key", "PEM encoded public key file, the registered ATM keys by default").String()
+	atmVerifyFormat := atmVerifyCmd.Flag("format", "signature format with the public key file").Default(string(atm.SignatureFormatBase64)).Enum(atm.SignatureFormats()...)
+	atmCashCmd := atmCmd.Command("cash", "ATM cash command")
+	atmCashReplenishCmd := atmCashCmd.Command("replenish", "record the cash loaded into the ATM")
+	atmCashReplenishID := atmCashReplenishCmd.Arg("atm-id", "ATM ID").Required().String()
+	atmCashReplenishAmount := atmCashReplenishCmd.Flag("amount", "amount in cents").Required().Uint()
+	atmCashCollectCmd := atmCashCmd.Command("collect", "record the cash taken out of the ATM")
+	atmCashCollectID := atmCashCollectCmd.Arg("atm-id", "ATM ID").Required().String()
+	atmCashCollectAmount := atmCashCollectCmd.Flag("amount", "amount in cents").Required().Uint()
+	atmCashSignCmd := atmCashCmd.Command("sign", "print the end-of-day cash count signed with the ATM key")
+	atmCashSignATMID := atmCashSignCmd.Flag("atm", "ATM ID").Required().String()
+	atmCashSignDate := atmCashSignCmd.Flag("date", "business date, YYYY-MM-DD, today by default").String()
+	atmCashSignCounted := atmCashSignCmd.Flag("counted", "counted cash in cents").Required().Uint()
+	atmCashSignKey := atmCashSignCmd.Flag("key", "PEM encoded private key file").Required().String()
+	atmCashSignFormat := atmCashSignCmd.Flag("format", "signature format").Default(string(atm.SignatureFormatBase64)).Enum(atm.SignatureFormats()...)
+	atmCashSignLifetime := atmCashSignCmd.Flag("lifetime", "how long the count may be submitted").Default("5m").Duration()
+	atmReconciliationCmd := atmCmd.Command("reconciliation", "ATM cash reconciliation command")
+	atmReconciliationReportCmd := atmReconciliationCmd.Command("report", "print the expected and counted cash of the business days")
+	atmReconciliationFrom := atmReconciliationReportCmd.Flag("from", "first business date, YYYY-MM-DD").Required().String()
+	atmReconciliationTo := atmReconciliationReportCmd.Flag("to", "last business date, YYYY-MM-DD, today by default").String()
+	atmReconciliationATMID := atmReconciliationReportCmd.Flag("atm", "ATM ID, all ATMs by default").String()
+	atmReconciliationDiscrepancies := atmReconciliationReportCmd.Flag("discrepancies-only", "only the reconciliations with discrepancies").Bool()
+	atmSimulateCmd := atmCmd.Command("simulate", "run the deposit scenarios against the running service")
+	atmSimulate := ATMSimulateOptions{}
+	atmSimulateCmd.Flag("url", "base URL of the service").Default("http://localhost:8080").StringVar(&atmSimulate.URL)
+	atmSimulateCmd.Flag("email", "email of the customer to login").StringVar(&atmSimulate.Email)
+	atmSimulateCmd.Flag("password", "password of the customer").Envar("ATM_SIMULATE_PASSWORD").StringVar(&atmSimulate.Password)
+	atmSimulateCmd.Flag("token", "personal access token, instead of the login").Envar("ATM_SIMULATE_TOKEN").StringVar(&atmSimulate.AccessToken)
+	atmSimulateCmd.Flag("atm", "ATM ID").Required().StringVar(&atmSimulate.ATMID)
+	atmSimulateCmd.Flag("key", "PEM encoded private key file of the ATM").Required().StringVar(&atmSimulate.KeyPath)
+	atmSimulateCmd.Flag("format", "signature format of the ATM key").Default(string(atm.SignatureFormatBase64)).EnumVar((*string)(&atmSimulate.Format), atm.SignatureFormats()...)
+	atmSimulateCmd.Flag("account", "account ID, the first account of the customer by default").StringVar(&atmSimulate.AccountID)
+	atmSimulateCmd.Flag("amount", "amount of each deposit in cents").Default("100").UintVar(&atmSimulate.Amount)
+	atmSimulateCmd.Flag("concurrency", "number of the concurrent deposits").Default("5").IntVar(&atmSimulate.Concurrency)
+	atmSimulateCmd.Flag("scenario", "scenario to run, all by default").EnumsVar(&atmSimulate.Scenarios, simulator.ScenarioNames()...)
+
 	cmd, err := app.Parse(args[1:])
 	if err != nil {
 		log.WithError(err).Error("failed to parse arguments")
@@ -53,6 +143,46 @@ func Run(args []string) bool {
 		err = MigrateUp(cfg)
 	case migrateDownCmd.FullCommand():
 		err = MigrateDown(cfg)
+	case ledgerVerifyCmd.FullCommand():
+		err = LedgerVerify(cfg)
+	case authUnlockCmd.FullCommand():
+		err = AuthUnlock(cfg, *authUnlockEmail, *authUnlockIP)
+	case jwtKeygenCmd.FullCommand():
+		err = JWTKeygen(cfg, *jwtKeySet, *jwtKeygenPromote)
+	case jwtPromoteCmd.FullCommand():
+		err = JWTPromote(cfg, *jwtKeySet, *jwtPromoteKID)
+	case jwtRemoveCmd.FullCommand():
+		err = JWTRemove(cfg, *jwtKeySet, *jwtRemoveKID)
+	case atmRegisterCmd.FullCommand():
+		err = ATMRegister(cfg, *atmRegisterLocation, *atmRegisterPublicKey, atm.SignatureFormat(*atmRegisterFormat))
+	case atmListCmd.FullCommand():
+		err = ATMList(cfg)
+	case atmDisableCmd.FullCommand():
+		err = ATMSetStatus(cfg, *atmDisableID, data.ATMStatusDisabled)
+	case atmEnableCmd.FullCommand():
+		err = ATMSetStatus(cfg, *atmEnableID, data.ATMStatusActive)
+	case atmKeyAddCmd.FullCommand():
+		err = ATMKeyAdd(cfg, *atmKeyAddATMID, *atmKeyAddPublicKey, atm.SignatureFormat(*atmKeyAddFormat), *atmKeyAddValidFrom, *atmKeyAddValidUntil)
+	case atmKeyRevokeCmd.FullCommand():
+		err = ATMKeyRevoke(cfg, *atmKeyRevokeID)
+	case atmKeygenCmd.FullCommand():
+		err = ATMKeygen(cfg, atm.KeyType(*atmKeygenType), *atmKeygenKey, *atmKeygenPublicKey)
+	case atmPubkeyCmd.FullCommand():
+		err = ATMPubkey(*atmPubkeyKey)
+	case atmSignCmd.FullCommand():
+		err = ATMSign(*atmSignKey, atm.SignatureFormat(*atmSignFormat), *atmSignATMID, *atmSignAccount, *atmSignAmount, *atmSignLifetime)
+	case atmVerifyCmd.FullCommand():
+		err = ATMVerify(cfg, *atmVerifyFile, *atmVerifyPublicKey, atm.SignatureFormat(*atmVerifyFormat))
+	case atmCashReplenishCmd.FullCommand():
+		err = ATMCashReplenish(cfg, *atmCashReplenishID, *atmCashReplenishAmount)
+	case atmCashCollectCmd.FullCommand():
+		err = ATMCashCollect(cfg, *atmCashCollectID, *atmCashCollectAmount)
+	case atmCashSignCmd.FullCommand():
+		err = ATMSignCashCount(*atmCashSignKey, atm.SignatureFormat(*atmCashSignFormat), *atmCashSignATMID, *atmCashSignDate, *atmCashSignCounted, *atmCashSignLifetime)
+	case atmReconciliationReportCmd.FullCommand():
+		err = ATMReconciliationReport(cfg, *atmReconciliationFrom, *atmReconciliationTo, *atmReconciliationATMID, *atmReconciliationDiscrepancies)
+	case atmSimulateCmd.FullCommand():
+		err = ATMSimulate(ctx, cfg, atmSimulate)
 	default:
 		log.Errorf("unknown command %s", cmd)
 		return false
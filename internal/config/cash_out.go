@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+// CashOut configures the cash-out codes: a code can be used at an ATM within
+// CodeLifetime after it's issued, the holds of the codes expired by then are
+// released every ExpiryInterval.
+type CashOut struct {
+	CodeLifetime   time.Duration `fig:"code_lifetime"`
+	ExpiryInterval time.Duration `fig:"expiry_interval"`
+}
+
+func (c *config) CashOut() *CashOut {
+	return c.cashOut.Do(func() interface{} {
+		cfg := CashOut{
+			CodeLifetime:   15 * time.Minute,
+			ExpiryInterval: time.Minute,
+		}
+
+		err := figure.
+			Out(&cfg).
+			From(kv.MustGetStringMap(c.getter, "cash_out")).
+			Please()
+		if err != nil {
+			panic(fmt.Errorf("failed to figure out cash-out: %w", err))
+		}
+
+		if cfg.CodeLifetime <= 0 || cfg.ExpiryInterval <= 0 {
+			panic(fmt.Errorf("cash-out code lifetime and expiry interval must be positive"))
+		}
+
+		return &cfg
+	}).(*CashOut)
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+// Cookies are the attributes of all the cookies set by the service. Secure
+// must be enabled whenever the service is served over HTTPS.
+type Cookies struct {
+	Secure   bool
+	SameSite http.SameSite
+	Domain   string
+}
+
+type cookies struct {
+	Secure   bool   `fig:"secure"`
+	SameSite string `fig:"same_site"`
+	Domain   string `fig:"domain"`
+}
+
+func (c *config) Cookies() *Cookies {
+	return c.cookies.Do(func() interface{} {
+		cfg := cookies{
+			SameSite: "lax",
+		}
+
+		err := figure.
+			Out(&cfg).
+			From(kv.MustGetStringMap(c.getter, "cookies")).
+			Please()
+		if err != nil {
+			panic(fmt.Errorf("failed to figure out cookies: %w", err))
+		}
+
+		var sameSite http.SameSite
+		switch strings.ToLower(cfg.SameSite) {
+		case "lax":
+			sameSite = http.SameSiteLaxMode
+		case "strict":
+			sameSite = http.SameSiteStrictMode
+		case "none":
+			// Browsers reject SameSite=None cookies without Secure
+			if !cfg.Secure {
+				panic(fmt.Errorf("same_site none requires secure cookies"))
+			}
+			sameSite = http.SameSiteNoneMode
+		default:
+			panic(fmt.Errorf("unknown same_site %q, expected lax, strict or none", cfg.SameSite))
+		}
+
+		return &Cookies{
+			Secure:   cfg.Secure,
+			SameSite: sameSite,
+			Domain:   cfg.Domain,
+		}
+	}).(*Cookies)
+}
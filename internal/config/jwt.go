@@ -10,14 +10,26 @@ import (
 	"gitlab.com/distributed_lab/kit/kv"
 )
 
+// defaultRefreshExpiry is the lifetime of the session since its last refresh.
+const defaultRefreshExpiry = 30 * 24 * time.Hour
+
 type JWT struct {
-	SigningKey jwk.Key
-	Expiry     time.Duration
+	// KeySetPath is empty when the single key of signing_key_path is used.
+	KeySetPath string
+	KeySet     *JWTKeySet
+	// Expiry is the lifetime of the access tokens, which can't be revoked
+	// before they expire, so it should be short.
+	Expiry        time.Duration
+	RefreshExpiry time.Duration
 }
 
 type jwt struct {
-	SigningKeyPath string `fig:"signing_key_path,required"`
+	// KeySetPath is the JWK Set file maintained by the `jwt` CLI commands,
+	// SigningKeyPath is the PEM key used before the key rotation was supported.
+	KeySetPath     string `fig:"key_set_path"`
+	SigningKeyPath string `fig:"signing_key_path"`
 	Expiry         string `fig:"expiry,required"`
+	RefreshExpiry  string `fig:"refresh_expiry"`
 }
 
 func (c *config) JWT() *JWT {
@@ -32,14 +44,9 @@ func (c *config) JWT() *JWT {
 			panic(fmt.Errorf("failed to figure out jwt: %w", err))
 		}
 
-		signingKeyBytes, err := os.ReadFile(cfg.SigningKeyPath)
-		if err != nil {
-			panic(fmt.Errorf("failed to read JWT signing key: %w", err))
-		}
-
-		signingKey, err := jwk.ParseKey(signingKeyBytes, jwk.WithPEM(true))
+		keySet, err := readJWTKeys(cfg)
 		if err != nil {
-			panic(fmt.Errorf("failed to parse JWT signing key: %w", err))
+			panic(fmt.Errorf("failed to read JWT keys: %w", err))
 		}
 
 		expiry, err := time.ParseDuration(cfg.Expiry)
@@ -47,9 +54,57 @@ func (c *config) JWT() *JWT {
 			panic(fmt.Errorf("failed to parse JWT expiry: %w", err))
 		}
 
+		refreshExpiry := defaultRefreshExpiry
+		if cfg.RefreshExpiry != "" {
+			if refreshExpiry, err = time.ParseDuration(cfg.RefreshExpiry); err != nil {
+				panic(fmt.Errorf("failed to parse JWT refresh expiry: %w", err))
+			}
+		}
+
 		return &JWT{
-			SigningKey: signingKey,
-			Expiry:     expiry,
+			KeySetPath:    cfg.KeySetPath,
+			KeySet:        keySet,
+			Expiry:        expiry,
+			RefreshExpiry: refreshExpiry,
 		}
 	}).(*JWT)
 }
+
+// JWTKeySetPath returns the configured key set path without reading the keys,
+// so the key set can be created before the service is able to start.
+func (c *config) JWTKeySetPath() string {
+	var cfg struct {
+		KeySetPath string `fig:"key_set_path"`
+	}
+
+	err := figure.
+		Out(&cfg).
+		From(kv.MustGetStringMap(c.getter, "jwt")).
+		Please()
+	if err != nil {
+		panic(fmt.Errorf("failed to figure out jwt key set path: %w", err))
+	}
+
+	return cfg.KeySetPath
+}
+
+func readJWTKeys(cfg jwt) (*JWTKeySet, error) {
+	switch {
+	case cfg.KeySetPath != "":
+		return ReadJWTKeySet(cfg.KeySetPath)
+	case cfg.SigningKeyPath != "":
+		signingKeyBytes, err := os.ReadFile(cfg.SigningKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+
+		signingKey, err := jwk.ParseKey(signingKeyBytes, jwk.WithPEM(true))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key: %w", err)
+		}
+
+		return singleJWTKeySet(signingKey)
+	default:
+		return nil, fmt.Errorf("either key_set_path or signing_key_path must be set")
+	}
+}
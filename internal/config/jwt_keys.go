@@ -0,0 +1,203 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+)
+
+// activeKeyIDField is the member of the JWK Set file naming the signing key.
+const activeKeyIDField = "active_kid"
+
+var ErrorJWTKeyNotFound = errors.New("JWT key not found")
+
+// JWTKeySet is the JWK Set file with the private JWT keys. The active key
+// signs the new tokens, while all the keys verify them, so a new key can be
+// published before it's promoted and a retired key keeps verifying the tokens
+// issued before the rotation until they expire.
+type JWTKeySet struct {
+	Keys        jwk.Set
+	ActiveKeyID string
+}
+
+// ReadJWTKeySet reads the key set file, every key must have the kid and alg set.
+func ReadJWTKeySet(path string) (*JWTKeySet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key set: %w", err)
+	}
+
+	keys, err := jwk.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key set: %w", err)
+	}
+
+	set := &JWTKeySet{Keys: keys}
+	if err = keys.Get(activeKeyIDField, &set.ActiveKeyID); err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", activeKeyIDField, err)
+	}
+
+	if err = keys.Remove(activeKeyIDField); err != nil {
+		return nil, fmt.Errorf("failed to remove %s: %w", activeKeyIDField, err)
+	}
+
+	for i := 0; i < keys.Len(); i++ {
+		key, _ := keys.Key(i)
+
+		if _, ok := key.KeyID(); !ok {
+			return nil, fmt.Errorf("key #%d has no kid", i)
+		}
+
+		if _, ok := key.Algorithm(); !ok {
+			return nil, fmt.Errorf("key #%d has no alg", i)
+		}
+	}
+
+	if _, err = set.ActiveKey(); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// WriteJWTKeySet replaces the key set file, the file is readable only by its owner.
+func WriteJWTKeySet(path string, set *JWTKeySet) error {
+	raw, err := json.Marshal(set.Keys)
+	if err != nil {
+		return fmt.Errorf("failed to marshal key set: %w", err)
+	}
+
+	var file map[string]interface{}
+	if err = json.Unmarshal(raw, &file); err != nil {
+		return fmt.Errorf("failed to unmarshal key set: %w", err)
+	}
+	file[activeKeyIDField] = set.ActiveKeyID
+
+	if raw, err = json.MarshalIndent(file, "", "  "); err != nil {
+		return fmt.Errorf("failed to marshal key set file: %w", err)
+	}
+
+	// Written to a temporary file first, so the service never reads a partial key set
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write key set: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close key set: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace key set: %w", err)
+	}
+
+	return nil
+}
+
+// ActiveKey returns the signing key.
+func (s *JWTKeySet) ActiveKey() (jwk.Key, error) {
+	key, ok := s.Keys.LookupKeyID(s.ActiveKeyID)
+	if !ok {
+		return nil, fmt.Errorf("%w: active key %q", ErrorJWTKeyNotFound, s.ActiveKeyID)
+	}
+
+	return key, nil
+}
+
+// Promote makes the key with the kid the signing key.
+func (s *JWTKeySet) Promote(kid string) error {
+	if _, ok := s.Keys.LookupKeyID(kid); !ok {
+		return fmt.Errorf("%w: %q", ErrorJWTKeyNotFound, kid)
+	}
+
+	s.ActiveKeyID = kid
+	return nil
+}
+
+// Remove drops the retired key, the tokens signed with it are no longer accepted.
+func (s *JWTKeySet) Remove(kid string) error {
+	if kid == s.ActiveKeyID {
+		return fmt.Errorf("active key %q can't be removed", kid)
+	}
+
+	key, ok := s.Keys.LookupKeyID(kid)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrorJWTKeyNotFound, kid)
+	}
+
+	return s.Keys.RemoveKey(key)
+}
+
+// NewJWTKey generates an ES256 signing key identified by its thumbprint.
+func NewJWTKey() (jwk.Key, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	key, err := jwk.Import(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import key: %w", err)
+	}
+
+	if err = prepareJWTKey(key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// prepareJWTKey sets the kid to the key thumbprint and the alg to ES256, unless
+// they are already set.
+func prepareJWTKey(key jwk.Key) error {
+	if _, ok := key.KeyID(); !ok {
+		if err := jwk.AssignKeyID(key); err != nil {
+			return fmt.Errorf("failed to assign kid: %w", err)
+		}
+	}
+
+	if _, ok := key.Algorithm(); !ok {
+		if err := key.Set(jwk.AlgorithmKey, jwa.ES256()); err != nil {
+			return fmt.Errorf("failed to set alg: %w", err)
+		}
+	}
+
+	if err := key.Set(jwk.KeyUsageKey, jwk.ForSignature); err != nil {
+		return fmt.Errorf("failed to set use: %w", err)
+	}
+
+	return nil
+}
+
+// singleJWTKeySet wraps the key of the legacy signing_key_path into a key set.
+func singleJWTKeySet(key jwk.Key) (*JWTKeySet, error) {
+	if err := prepareJWTKey(key); err != nil {
+		return nil, err
+	}
+
+	keys := jwk.NewSet()
+	if err := keys.AddKey(key); err != nil {
+		return nil, fmt.Errorf("failed to add key: %w", err)
+	}
+
+	kid, _ := key.KeyID()
+
+	return &JWTKeySet{
+		Keys:        keys,
+		ActiveKeyID: kid,
+	}, nil
+}
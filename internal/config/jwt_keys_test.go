@@ -0,0 +1,55 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTKeySet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwt_keys.json")
+
+	first, err := NewJWTKey()
+	require.NoError(t, err)
+	second, err := NewJWTKey()
+	require.NoError(t, err)
+
+	firstKID, _ := first.KeyID()
+	secondKID, _ := second.KeyID()
+	require.NotEqual(t, firstKID, secondKID)
+
+	keys := jwk.NewSet()
+	require.NoError(t, keys.AddKey(first))
+	require.NoError(t, keys.AddKey(second))
+	require.NoError(t, WriteJWTKeySet(path, &JWTKeySet{Keys: keys, ActiveKeyID: firstKID}))
+
+	set, err := ReadJWTKeySet(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, set.Keys.Len())
+	assert.Equal(t, firstKID, set.ActiveKeyID)
+
+	active, err := set.ActiveKey()
+	require.NoError(t, err)
+	_, isPrivate := active.(jwk.ECDSAPrivateKey)
+	assert.True(t, isPrivate, "key set must keep the private keys")
+
+	require.ErrorIs(t, set.Promote("unknown"), ErrorJWTKeyNotFound)
+	require.NoError(t, set.Promote(secondKID))
+	require.Error(t, set.Remove(secondKID), "active key must not be removed")
+	require.NoError(t, set.Remove(firstKID))
+	require.NoError(t, WriteJWTKeySet(path, set))
+
+	set, err = ReadJWTKeySet(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, set.Keys.Len())
+	assert.Equal(t, secondKID, set.ActiveKeyID)
+
+	set.ActiveKeyID = firstKID
+	require.NoError(t, WriteJWTKeySet(path, set))
+
+	_, err = ReadJWTKeySet(path)
+	require.ErrorIs(t, err, ErrorJWTKeyNotFound, "key set without the active key must be rejected")
+}
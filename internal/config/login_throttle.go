@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+// LoginThrottle limits the failed login attempts counted within Window. Every
+// failure delays the next attempt for the account twice as long as the previous
+// one, and reaching the maximum locks the account or the IP for LockoutDuration.
+type LoginThrottle struct {
+	MaxAccountFailures uint64        `fig:"max_account_failures"`
+	MaxIPFailures      uint64        `fig:"max_ip_failures"`
+	Window             time.Duration `fig:"window"`
+	LockoutDuration    time.Duration `fig:"lockout_duration"`
+	BaseDelay          time.Duration `fig:"base_delay"`
+	MaxDelay           time.Duration `fig:"max_delay"`
+}
+
+func (c *config) LoginThrottle() *LoginThrottle {
+	return c.loginThrottle.Do(func() interface{} {
+		cfg := LoginThrottle{
+			MaxAccountFailures: 5,
+			MaxIPFailures:      20,
+			Window:             15 * time.Minute,
+			LockoutDuration:    15 * time.Minute,
+			BaseDelay:          time.Second,
+			MaxDelay:           30 * time.Second,
+		}
+
+		err := figure.
+			Out(&cfg).
+			From(kv.MustGetStringMap(c.getter, "login_throttle")).
+			Please()
+		if err != nil {
+			panic(fmt.Errorf("failed to figure out login throttle: %w", err))
+		}
+
+		if cfg.MaxAccountFailures == 0 || cfg.MaxIPFailures == 0 {
+			panic(fmt.Errorf("login throttle failure limits must be positive"))
+		}
+
+		return &cfg
+	}).(*LoginThrottle)
+}
@@ -14,7 +14,12 @@ type Config interface {
 
 	MVC() *MVC
 	JWT() *JWT
-	ATM() *ATM
+	JWTKeySetPath() string
+	Passwords() *Passwords
+	LoginThrottle() *LoginThrottle
+	Cookies() *Cookies
+	CashOut() *CashOut
+	Reports() *Reports
 	Listener() net.Listener
 }
 
@@ -22,10 +27,14 @@ type config struct {
 	comfig.Logger
 	pgdb.Databaser
 
-	listener comfig.Once
-	mvc      comfig.Once
-	jwt      comfig.Once
-	atm      comfig.Once
+	listener      comfig.Once
+	mvc           comfig.Once
+	jwt           comfig.Once
+	passwords     comfig.Once
+	loginThrottle comfig.Once
+	cookies       comfig.Once
+	cashOut       comfig.Once
+	reports       comfig.Once
 
 	getter kv.Getter
 }
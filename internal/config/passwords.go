@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"math"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+// Passwords is the policy the password hashes are created with. The hashes
+// created with another policy are upgraded on the next successful login.
+type Passwords struct {
+	Algorithm string `fig:"algorithm"`
+
+	// Argon2Memory is the memory used by Argon2id in KiB.
+	Argon2Memory      uint32 `fig:"argon2_memory"`
+	Argon2Iterations  uint32 `fig:"argon2_iterations"`
+	Argon2Parallelism uint32 `fig:"argon2_parallelism"`
+
+	BcryptCost int `fig:"bcrypt_cost"`
+}
+
+func (c *config) Passwords() *Passwords {
+	return c.passwords.Do(func() interface{} {
+		// OWASP recommended Argon2id parameters
+		cfg := Passwords{
+			Algorithm:         "argon2id",
+			Argon2Memory:      64 * 1024,
+			Argon2Iterations:  3,
+			Argon2Parallelism: 2,
+			BcryptCost:        12,
+		}
+
+		err := figure.
+			Out(&cfg).
+			From(kv.MustGetStringMap(c.getter, "passwords")).
+			Please()
+		if err != nil {
+			panic(fmt.Errorf("failed to figure out passwords: %w", err))
+		}
+
+		if cfg.Argon2Parallelism > math.MaxUint8 {
+			panic(fmt.Errorf("argon2 parallelism must not exceed %d", math.MaxUint8))
+		}
+
+		return &cfg
+	}).(*Passwords)
+}
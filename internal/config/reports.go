@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+const (
+	ReportStorageDisk = "disk"
+	ReportStorageDB   = "db"
+)
+
+// Reports configures the reports generated in the background: the worker
+// looks for the requested reports every PollInterval, the generated ones are
+// kept in the Storage ("disk" in Dir or "db") and can be downloaded within
+// Lifetime after they're completed. The jobs running longer than JobTimeout
+// are left by the crashed workers and are failed.
+type Reports struct {
+	Storage      string        `fig:"storage"`
+	Dir          string        `fig:"dir"`
+	Lifetime     time.Duration `fig:"lifetime"`
+	PollInterval time.Duration `fig:"poll_interval"`
+	JobTimeout   time.Duration `fig:"job_timeout"`
+}
+
+func (c *config) Reports() *Reports {
+	return c.reports.Do(func() interface{} {
+		cfg := Reports{
+			Storage:      ReportStorageDB,
+			Lifetime:     24 * time.Hour,
+			PollInterval: 5 * time.Second,
+			JobTimeout:   30 * time.Minute,
+		}
+
+		err := figure.
+			Out(&cfg).
+			From(kv.MustGetStringMap(c.getter, "reports")).
+			Please()
+		if err != nil {
+			panic(fmt.Errorf("failed to figure out reports: %w", err))
+		}
+
+		switch cfg.Storage {
+		case ReportStorageDB:
+		case ReportStorageDisk:
+			if cfg.Dir == "" {
+				panic(fmt.Errorf("reports dir is required for the disk storage"))
+			}
+		default:
+			panic(fmt.Errorf("unknown reports storage %q", cfg.Storage))
+		}
+
+		if cfg.Lifetime <= 0 || cfg.PollInterval <= 0 || cfg.JobTimeout <= 0 {
+			panic(fmt.Errorf("reports lifetime, poll interval and job timeout must be positive"))
+		}
+
+		return &cfg
+	}).(*Reports)
+}
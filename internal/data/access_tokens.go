@@ -0,0 +1,67 @@
+package data
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// AccessScope limits what a personal access token can be used for.
+type AccessScope string
+
+const (
+	AccessScopeAccountsRead      AccessScope = "accounts:read"
+	AccessScopeAccountsWrite     AccessScope = "accounts:write"
+	AccessScopeTransactionsWrite AccessScope = "transactions:write"
+	AccessScopeActivityRead      AccessScope = "activity:read"
+)
+
+// AccessScopes lists all the scopes a token can be granted.
+var AccessScopes = []AccessScope{
+	AccessScopeAccountsRead,
+	AccessScopeAccountsWrite,
+	AccessScopeTransactionsWrite,
+	AccessScopeActivityRead,
+}
+
+type AccessTokens interface {
+	CRUDQ[*AccessToken, uuid.UUID]
+
+	WhereID(id uuid.UUID) AccessTokens
+	WhereCustomerID(customerID uuid.UUID) AccessTokens
+	WhereTokenHash(tokenHash string) AccessTokens
+	OrderBy(orderBy ...string) AccessTokens
+
+	// Revoke marks the token as revoked at the given time.
+	Revoke(id uuid.UUID, at time.Time) error
+	// MarkUsed records the time the token was last used at.
+	MarkUsed(id uuid.UUID, at time.Time) error
+}
+
+// AccessToken is a personal access token, only the SHA-256 hash of the token is stored.
+type AccessToken struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	CustomerID uuid.UUID      `db:"customer_fkey" structs:"customer_fkey"`
+	Name       string         `db:"name"          structs:"name"`
+	TokenHash  string         `db:"token_hash"    structs:"token_hash"`
+	Scopes     pq.StringArray `db:"scopes"        structs:"scopes"`
+	ExpiresAt  *time.Time     `db:"expires_at"    structs:"expires_at"`
+	LastUsedAt *time.Time     `db:"last_used_at"  structs:"last_used_at"`
+	RevokedAt  *time.Time     `db:"revoked_at"    structs:"revoked_at"`
+}
+
+func (t *AccessToken) HasScope(scope AccessScope) bool {
+	return slices.Contains(t.Scopes, string(scope))
+}
+
+// IsActive reports whether the token is neither revoked nor expired at the given time.
+func (t *AccessToken) IsActive(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
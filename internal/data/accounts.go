@@ -11,6 +11,8 @@ type Accounts interface {
 
 	IsDeleted(bool) Accounts
 	WhereID(id ...uuid.UUID) Accounts
+	// ForUpdate locks the selected rows until the end of the transaction.
+	ForUpdate() Accounts
 	// LDelete - Logical Delete - marks the account as deleted.
 	LDelete(id uuid.UUID) error
 }
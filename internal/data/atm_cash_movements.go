@@ -0,0 +1,29 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ATMCashMovements interface {
+	CRUDQ[*ATMCashMovement, uuid.UUID]
+
+	WhereATMID(atmID uuid.UUID) ATMCashMovements
+	// WhereMovedFrom selects the movements made at or after from.
+	WhereMovedFrom(from time.Time) ATMCashMovements
+
+	// Sum returns the total amount of the selected movements.
+	Sum() (int, error)
+}
+
+// ATMCashMovement is the change of the cash the ATM is expected to hold:
+// positive for the deposits, the replenishments and the surpluses found by the
+// reconciliations, negative for the cash taken out.
+type ATMCashMovement struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	ATMID   uuid.UUID `db:"atm_fkey" structs:"atm_fkey"`
+	Amount  int       `db:"amount"   structs:"amount"`
+	MovedAt time.Time `db:"moved_at" structs:"moved_at"`
+}
@@ -0,0 +1,44 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ATMReconciliations interface {
+	CRUDQ[*ATMReconciliation, uuid.UUID]
+
+	WhereID(id ...uuid.UUID) ATMReconciliations
+	WhereATMID(atmID uuid.UUID) ATMReconciliations
+	// WhereBusinessDateBetween selects the reconciliations of the business
+	// days from and to, inclusive.
+	WhereBusinessDateBetween(from, to time.Time) ATMReconciliations
+	// WhereBusinessDateAfter selects the reconciliations of the business days
+	// after the date.
+	WhereBusinessDateAfter(date time.Time) ATMReconciliations
+	// WhereDiscrepancy selects the reconciliations where the counted cash
+	// differs from the expected one.
+	WhereDiscrepancy() ATMReconciliations
+
+	OrderBy(orderBy ...string) ATMReconciliations
+}
+
+// ATMReconciliation is the end-of-day count of the cash in the ATM, signed by
+// the ATM, compared with the cash the ATM is expected to hold. Discrepancy is
+// Counted - Expected, positive if there is more cash than expected.
+type ATMReconciliation struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	ATMID        uuid.UUID `db:"atm_fkey"      structs:"atm_fkey"`
+	BusinessDate time.Time `db:"business_date" structs:"business_date"`
+	Expected     int       `db:"expected"      structs:"expected"`
+	Counted      int       `db:"counted"       structs:"counted"`
+	Discrepancy  int       `db:"discrepancy"   structs:"discrepancy"`
+	ATMNonce     string    `db:"atm_nonce"     structs:"atm_nonce"`
+	ATMSignature string    `db:"atm_signature" structs:"atm_signature"`
+}
+
+func (r *ATMReconciliation) IsBalanced() bool {
+	return r.Discrepancy == 0
+}
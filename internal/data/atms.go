@@ -0,0 +1,71 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+)
+
+type ATMStatus string
+
+const (
+	ATMStatusActive   ATMStatus = "active"
+	ATMStatusDisabled ATMStatus = "disabled"
+)
+
+type ATMs interface {
+	CRUDQ[*ATM, uuid.UUID]
+
+	WhereID(id ...uuid.UUID) ATMs
+	WhereStatus(status ATMStatus) ATMs
+
+	ForUpdate() ATMs
+	OrderBy(orderBy ...string) ATMs
+}
+
+// ATM is a registered cash machine, only the active ATMs may sign the deposits.
+// CashBalance is the cash the ATM is expected to hold: the deposits and the
+// replenishments add to it, the withdrawals and the collections subtract from
+// it and the reconciliation sets it to the counted cash.
+type ATM struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	Location    string    `db:"location"     structs:"location"`
+	Status      ATMStatus `db:"status"       structs:"status"`
+	CashBalance int       `db:"cash_balance" structs:"cash_balance"`
+}
+
+func (a *ATM) IsActive() bool {
+	return a.Status == ATMStatusActive
+}
+
+type ATMKeys interface {
+	CRUDQ[*ATMKey, uuid.UUID]
+
+	WhereID(id ...uuid.UUID) ATMKeys
+	WhereATMID(atmID uuid.UUID) ATMKeys
+	// WhereValidAt selects the keys whose validity window contains the time.
+	WhereValidAt(t time.Time) ATMKeys
+
+	OrderBy(orderBy ...string) ATMKeys
+}
+
+// ATMKey is a PEM encoded ECDSA or Ed25519 public key of the ATM with the
+// format of the signatures made with it. The ATM may have several keys valid
+// at once, so a new key can be installed before the old one expires.
+type ATMKey struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	ATMID           uuid.UUID           `db:"atm_fkey"         structs:"atm_fkey"`
+	PublicKey       string              `db:"public_key"       structs:"public_key"`
+	SignatureFormat atm.SignatureFormat `db:"signature_format" structs:"signature_format"`
+	ValidFrom       time.Time           `db:"valid_from"       structs:"valid_from"`
+	ValidUntil      *time.Time          `db:"valid_until"      structs:"valid_until"`
+}
+
+// IsValidAt reports whether the validity window of the key contains the time.
+func (k *ATMKey) IsValidAt(t time.Time) bool {
+	return !k.ValidFrom.After(t) && (k.ValidUntil == nil || k.ValidUntil.After(t))
+}
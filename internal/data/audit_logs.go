@@ -15,6 +15,22 @@ const (
 	AuditActionWithdrawalMade       AuditAction = "withdrawal_made"
 	AuditActionTransferMade         AuditAction = "transfer_made"
 	AuditActionExcelReportGenerated AuditAction = "excel_report_generated"
+	AuditActionAccessTokenCreated   AuditAction = "access_token_created"
+	AuditActionAccessTokenRevoked   AuditAction = "access_token_revoked"
+	AuditActionTwoFactorEnabled     AuditAction = "two_factor_enabled"
+	AuditActionTwoFactorDisabled    AuditAction = "two_factor_disabled"
+	AuditActionTwoFactorFailed      AuditAction = "two_factor_failed"
+	AuditActionLoginFailed          AuditAction = "login_failed"
+	AuditActionLoginUnlocked        AuditAction = "login_unlocked"
+	AuditActionCashOutCodeIssued    AuditAction = "cash_out_code_issued"
+	AuditActionCashOutCodeCancelled AuditAction = "cash_out_code_cancelled"
+	AuditActionCashOutCodeExpired   AuditAction = "cash_out_code_expired"
+	AuditActionATMCashReplenished   AuditAction = "atm_cash_replenished"
+	AuditActionATMCashCollected     AuditAction = "atm_cash_collected"
+	AuditActionATMCashReconciled    AuditAction = "atm_cash_reconciled"
+	AuditActionStatementGenerated   AuditAction = "statement_generated"
+	AuditActionReportRequested      AuditAction = "report_requested"
+	AuditActionReportDownloaded     AuditAction = "report_downloaded"
 )
 
 type AuditLogs interface {
@@ -34,7 +50,7 @@ type AuditLogs interface {
 type AuditLog struct {
 	Entity[uuid.UUID] `structs:"-"`
 
-	CustomerID uuid.UUID       `db:"customer_id"  structs:"customer_id"`
+	CustomerID *uuid.UUID      `db:"customer_id"  structs:"customer_id"` // nil for the ATM cash operations
 	AccountID  *uuid.UUID      `db:"account_id"   structs:"account_id"`
 	Action     AuditAction     `db:"action"       structs:"action"`
 	Details    json.RawMessage `db:"details"      structs:"details"`
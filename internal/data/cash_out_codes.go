@@ -0,0 +1,56 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CashOutCodeStatus string
+
+const (
+	CashOutCodePending   CashOutCodeStatus = "pending"
+	CashOutCodeCompleted CashOutCodeStatus = "completed"
+	CashOutCodeExpired   CashOutCodeStatus = "expired"
+	CashOutCodeCancelled CashOutCodeStatus = "cancelled"
+)
+
+type CashOutCodes interface {
+	CRUDQ[*CashOutCode, uuid.UUID]
+
+	WhereID(id ...uuid.UUID) CashOutCodes
+	WhereCodeHash(codeHash string) CashOutCodes
+	WhereAccountID(accountID uuid.UUID) CashOutCodes
+	WhereStatus(status CashOutCodeStatus) CashOutCodes
+	// WhereExpiredAt selects the codes whose expires_at is not after the time.
+	WhereExpiredAt(t time.Time) CashOutCodes
+
+	ForUpdate() CashOutCodes
+	OrderBy(orderBy ...string) CashOutCodes
+}
+
+// CashOutCode is a one-time code for withdrawing the amount from the account at
+// any ATM. The amount is held on the withdrawal holds system account while the
+// code is pending, the ATM completes the code with the withdrawal transaction,
+// otherwise the hold is released when the code expires or is cancelled.
+type CashOutCode struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	CustomerID    uuid.UUID         `db:"customer_fkey"    structs:"customer_fkey"`
+	AccountID     uuid.UUID         `db:"account_fkey"     structs:"account_fkey"`
+	Amount        uint              `db:"amount"           structs:"amount"`
+	CodeHash      string            `db:"code_hash"        structs:"code_hash"`
+	Status        CashOutCodeStatus `db:"status"           structs:"status"`
+	ExpiresAt     time.Time         `db:"expires_at"       structs:"expires_at"`
+	ATMID         *uuid.UUID        `db:"atm_fkey"         structs:"atm_fkey"`
+	TransactionID *uuid.UUID        `db:"transaction_fkey" structs:"transaction_fkey"`
+}
+
+func (c *CashOutCode) IsPending() bool {
+	return c.Status == CashOutCodePending
+}
+
+// IsUsableAt reports whether the ATM may complete the code at the time.
+func (c *CashOutCode) IsUsableAt(t time.Time) bool {
+	return c.IsPending() && t.Before(c.ExpiresAt)
+}
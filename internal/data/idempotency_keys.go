@@ -0,0 +1,34 @@
+package data
+
+import (
+	"github.com/google/uuid"
+)
+
+type IdempotencyKeys interface {
+	CRUDQ[*IdempotencyKey, uuid.UUID]
+
+	WhereCustomerID(customerID uuid.UUID) IdempotencyKeys
+	WhereKey(key string) IdempotencyKeys
+
+	// Reserve inserts the key if the customer hasn't used it yet. It returns
+	// false, leaving the entity untouched, when the key is already taken.
+	Reserve(key *IdempotencyKey) (bool, error)
+}
+
+// IdempotencyKey stores the response rendered for the first request sent with
+// the key, the response fields are nil while that request is still in progress.
+type IdempotencyKey struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	CustomerID   uuid.UUID `db:"customer_fkey" structs:"customer_fkey"`
+	Key          string    `db:"key"           structs:"key"`
+	RequestHash  string    `db:"request_hash"  structs:"request_hash"`
+	StatusCode   *int      `db:"status_code"   structs:"status_code"`
+	ContentType  *string   `db:"content_type"  structs:"content_type"`
+	ResponseBody []byte    `db:"response_body" structs:"response_body"`
+}
+
+// IsCompleted reports whether the response of the request is stored.
+func (k *IdempotencyKey) IsCompleted() bool {
+	return k.StatusCode != nil
+}
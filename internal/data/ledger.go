@@ -0,0 +1,81 @@
+package data
+
+import (
+	"github.com/google/uuid"
+)
+
+// PostingSide is the side of the double-entry posting.
+type PostingSide int
+
+const (
+	Debit PostingSide = iota
+	Credit
+)
+
+func (s PostingSide) String() string {
+	switch s {
+	case Debit:
+		return "debit"
+	case Credit:
+		return "credit"
+	default:
+		return "unknown"
+	}
+}
+
+// SystemAccount is a bank-owned ledger account, which is the counterparty
+// of the customer accounts for the money entering or leaving the bank.
+type SystemAccount string
+
+const (
+	// SystemAccountATMCash is the cash held by the ATMs: deposits debit it, withdrawals credit it.
+	SystemAccountATMCash SystemAccount = "atm_cash_clearing"
+	// SystemAccountOpeningBalance holds the balances that existed before the ledger was introduced.
+	SystemAccountOpeningBalance SystemAccount = "opening_balance_equity"
+	// SystemAccountWithdrawalHolds holds the amounts of the pending cash-out codes until
+	// the ATM pays them out or the holds are released.
+	SystemAccountWithdrawalHolds SystemAccount = "withdrawal_holds"
+)
+
+type Ledger interface {
+	// Post records the journal entry with its postings, the sum of debits
+	// must be equal to the sum of credits. The balance is checked at commit,
+	// so Post must be called within a transaction.
+	Post(entry *JournalEntry, postings ...*Posting) error
+
+	// AccountBalance returns the balance of the customer account derived from
+	// the postings, that is credits minus debits.
+	AccountBalance(accountID uuid.UUID) (int, error)
+	// SystemAccountBalance returns the balance of the system account derived
+	// from the postings, that is debits minus credits.
+	SystemAccountBalance(account SystemAccount) (int, error)
+
+	Postings() Postings
+}
+
+type Postings interface {
+	CRUDQ[*Posting, uuid.UUID]
+
+	WhereJournalEntry(journalEntryID uuid.UUID) Postings
+	WhereAccount(accountID uuid.UUID) Postings
+	WhereSystemAccount(account SystemAccount) Postings
+}
+
+type JournalEntry struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	TransactionID *uuid.UUID `db:"transaction_fkey" structs:"transaction_fkey"`
+	Description   string     `db:"description"      structs:"description"`
+}
+
+// Posting moves the amount to one side of either a customer account or a
+// system account, exactly one of AccountID and SystemAccount is set.
+type Posting struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	JournalEntryID uuid.UUID      `db:"journal_entry_fkey" structs:"journal_entry_fkey"`
+	AccountID      *uuid.UUID     `db:"account_fkey"       structs:"account_fkey"`
+	SystemAccount  *SystemAccount `db:"system_account"     structs:"system_account"`
+	Side           PostingSide    `db:"side"               structs:"side"`
+	Amount         uint           `db:"amount"             structs:"amount"`
+}
@@ -0,0 +1,38 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LoginFailures interface {
+	CRUDQ[*LoginFailure, uuid.UUID]
+
+	WhereCustomerID(customerID uuid.UUID) LoginFailures
+	WhereEmailHash(emailHash string) LoginFailures
+	WhereIP(ip string) LoginFailures
+	WhereCreatedAfter(t time.Time) LoginFailures
+	OrderBy(orderBy ...string) LoginFailures
+
+	DeleteByEmailHash(emailHash string) error
+	DeleteByIP(ip string) error
+	// DeleteCreatedBefore deletes the failures created before the time.
+	DeleteCreatedBefore(t time.Time) error
+
+	// Lock serializes the attempts for the email hash and from the IP until
+	// the end of the transaction.
+	Lock(emailHash, ip string) error
+}
+
+// LoginFailure is a failed login attempt, CustomerID is nil when the email
+// doesn't belong to any customer. EmailHash is the SHA-256 of the normalized
+// email, the attempts are throttled by it whether the customer exists or not.
+type LoginFailure struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	CustomerID *uuid.UUID `db:"customer_fkey" structs:"customer_fkey"`
+	EmailHash  string     `db:"email_hash"    structs:"email_hash"`
+	IP         string     `db:"ip"            structs:"ip"`
+	UserAgent  string     `db:"user_agent"    structs:"user_agent"`
+}
@@ -6,13 +6,33 @@ import (
 )
 
 type MainQ interface {
+	// New returns a MainQ with its own connection state, so transactions can be
+	// run on it concurrently with the transactions of other instances.
+	New() MainQ
+
 	Customers() Customers
 	Accounts() Accounts
 	CustomersAccounts() CustomersAccounts
 	Transactions() Transactions
 	AuditLogs() AuditLogs
+	Ledger() Ledger
+	IdempotencyKeys() IdempotencyKeys
+	AccessTokens() AccessTokens
+	Sessions() Sessions
+	TOTPCredentials() TOTPCredentials
+	RecoveryCodes() RecoveryCodes
+	LoginFailures() LoginFailures
+	ATMs() ATMs
+	ATMKeys() ATMKeys
+	CashOutCodes() CashOutCodes
+	ATMReconciliations() ATMReconciliations
+	ATMCashMovements() ATMCashMovements
+	ReportJobs() ReportJobs
+	ReportFiles() ReportFiles
 
 	Transaction(func() error) error
+	// IsolatedTransaction runs the function in a transaction with the given
+	// isolation level, retrying it on serialization failures and deadlocks.
 	IsolatedTransaction(sql.IsolationLevel, func() error) error
 }
 
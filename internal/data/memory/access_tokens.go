@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const accessTokensTableName = "access_tokens"
+
+type accessTokensQ struct {
+	*crudQ[*data.AccessToken, uuid.UUID]
+}
+
+func newAccessTokensQ(q *mainQ) data.AccessTokens {
+	return &accessTokensQ{
+		newCRUDQ(q, func(s *store) *table[*data.AccessToken, uuid.UUID] { return s.accessTokens }),
+	}
+}
+
+func (q *accessTokensQ) WhereID(id uuid.UUID) data.AccessTokens {
+	q.where(func(t *data.AccessToken) bool { return t.ID == id })
+	return q
+}
+
+func (q *accessTokensQ) WhereCustomerID(customerID uuid.UUID) data.AccessTokens {
+	q.where(func(t *data.AccessToken) bool { return t.CustomerID == customerID })
+	return q
+}
+
+func (q *accessTokensQ) WhereTokenHash(tokenHash string) data.AccessTokens {
+	q.where(func(t *data.AccessToken) bool { return t.TokenHash == tokenHash })
+	return q
+}
+
+func (q *accessTokensQ) OrderBy(orderBy ...string) data.AccessTokens {
+	q.orderBy = append(q.orderBy, orderBy...)
+	return q
+}
+
+func (q *accessTokensQ) Revoke(id uuid.UUID, at time.Time) error {
+	return q.q.write(func(s *store) error {
+		s.accessTokens.modify(s, id, func(t *data.AccessToken) {
+			if t.RevokedAt == nil {
+				t.RevokedAt = &at
+			}
+		})
+		return nil
+	})
+}
+
+func (q *accessTokensQ) MarkUsed(id uuid.UUID, at time.Time) error {
+	return q.q.write(func(s *store) error {
+		s.accessTokens.modify(s, id, func(t *data.AccessToken) { t.LastUsedAt = &at })
+		return nil
+	})
+}
+
+func checkAccessToken(s *store, token *data.AccessToken) error {
+	if _, ok := s.customers.get(token.CustomerID); !ok {
+		return foreignKeyViolation(accessTokensTableName, "access_tokens_customer_fkey_fkey")
+	}
+
+	for _, t := range s.accessTokens.rows {
+		if t.ID != token.ID && t.TokenHash == token.TokenHash {
+			return uniqueViolation("access_tokens_token_hash_key")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const accountsTableName = "accounts"
+
+type accountsQ struct {
+	*crudQ[*data.Account, uuid.UUID]
+}
+
+func newAccountsQ(q *mainQ) data.Accounts {
+	return &accountsQ{
+		newCRUDQ(q, func(s *store) *table[*data.Account, uuid.UUID] { return s.accounts }),
+	}
+}
+
+func (q *accountsQ) WhereID(id ...uuid.UUID) data.Accounts {
+	q.where(func(a *data.Account) bool { return containsID(id, a.ID) })
+	return q
+}
+
+// ForUpdate is a no-op: transactions are serialized, so the rows read inside
+// a transaction can't be changed by others until it ends.
+func (q *accountsQ) ForUpdate() data.Accounts {
+	return q
+}
+
+func (q *accountsQ) LDelete(id uuid.UUID) error {
+	return q.q.write(func(s *store) error {
+		s.accounts.modify(s, id, func(a *data.Account) { a.IsDeleted = true })
+		return nil
+	})
+}
+
+func (q *accountsQ) IsDeleted(isDeleted bool) data.Accounts {
+	q.where(func(a *data.Account) bool { return a.IsDeleted == isDeleted })
+	return q
+}
+
+func deleteAccount(s *store, id uuid.UUID) error {
+	if s.customersAccounts.hasAccount(id) {
+		return restrictViolation(accountsTableName,
+			"customers_accounts_account_fkey_fkey", customersAccountsTableName)
+	}
+
+	for _, l := range s.auditLogs.rows {
+		if l.AccountID != nil && *l.AccountID == id {
+			return restrictViolation(accountsTableName, "audit_logs_account_id_fkey", auditLogsTableName)
+		}
+	}
+
+	for _, p := range s.postings.rows {
+		if p.AccountID != nil && *p.AccountID == id {
+			return restrictViolation(accountsTableName, "postings_account_fkey_fkey", postingsTableName)
+		}
+	}
+
+	for _, c := range s.cashOutCodes.rows {
+		if c.AccountID == id {
+			return restrictViolation(accountsTableName, "cash_out_codes_account_fkey_fkey", cashOutCodesTableName)
+		}
+	}
+
+	for _, j := range s.reportJobs.rows {
+		if j.AccountID != nil && *j.AccountID == id {
+			return restrictViolation(accountsTableName, "report_jobs_account_fkey_fkey", reportJobsTableName)
+		}
+	}
+
+	// transactions reference accounts with ON DELETE CASCADE
+	var cascade []uuid.UUID
+	for _, t := range s.transactions.rows {
+		if t.Sender == id || t.Recipient == id {
+			cascade = append(cascade, t.ID)
+		}
+	}
+
+	for _, transactionID := range cascade {
+		if err := s.transactions.delete(s, transactionID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
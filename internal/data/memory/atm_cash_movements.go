@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const atmCashMovementsTableName = "atm_cash_movements"
+
+type atmCashMovementsQ struct {
+	*crudQ[*data.ATMCashMovement, uuid.UUID]
+}
+
+func newATMCashMovementsQ(q *mainQ) data.ATMCashMovements {
+	return &atmCashMovementsQ{
+		newCRUDQ(q, func(s *store) *table[*data.ATMCashMovement, uuid.UUID] { return s.atmCashMovements }),
+	}
+}
+
+func (q *atmCashMovementsQ) WhereATMID(atmID uuid.UUID) data.ATMCashMovements {
+	q.where(func(m *data.ATMCashMovement) bool { return m.ATMID == atmID })
+	return q
+}
+
+func (q *atmCashMovementsQ) WhereMovedFrom(from time.Time) data.ATMCashMovements {
+	q.where(func(m *data.ATMCashMovement) bool { return !m.MovedAt.Before(from) })
+	return q
+}
+
+func (q *atmCashMovementsQ) Sum() (int, error) {
+	movements, err := q.Select()
+	if err != nil {
+		return 0, err
+	}
+
+	result := 0
+	for _, movement := range movements {
+		result += movement.Amount
+	}
+
+	return result, nil
+}
+
+func checkATMCashMovement(s *store, movement *data.ATMCashMovement) error {
+	if _, ok := s.atms.get(movement.ATMID); !ok {
+		return foreignKeyViolation(atmCashMovementsTableName, "atm_cash_movements_atm_fkey_fkey")
+	}
+
+	return nil
+}
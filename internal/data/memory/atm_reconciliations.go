@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const atmReconciliationsTableName = "atm_reconciliations"
+
+type atmReconciliationsQ struct {
+	*crudQ[*data.ATMReconciliation, uuid.UUID]
+}
+
+func newATMReconciliationsQ(q *mainQ) data.ATMReconciliations {
+	return &atmReconciliationsQ{
+		newCRUDQ(q, func(s *store) *table[*data.ATMReconciliation, uuid.UUID] { return s.atmReconciliations }),
+	}
+}
+
+func (q *atmReconciliationsQ) WhereID(id ...uuid.UUID) data.ATMReconciliations {
+	q.where(func(r *data.ATMReconciliation) bool { return containsID(id, r.ID) })
+	return q
+}
+
+func (q *atmReconciliationsQ) WhereATMID(atmID uuid.UUID) data.ATMReconciliations {
+	q.where(func(r *data.ATMReconciliation) bool { return r.ATMID == atmID })
+	return q
+}
+
+func (q *atmReconciliationsQ) WhereBusinessDateBetween(from, to time.Time) data.ATMReconciliations {
+	q.where(func(r *data.ATMReconciliation) bool {
+		return !r.BusinessDate.Before(from) && !r.BusinessDate.After(to)
+	})
+	return q
+}
+
+func (q *atmReconciliationsQ) WhereBusinessDateAfter(date time.Time) data.ATMReconciliations {
+	q.where(func(r *data.ATMReconciliation) bool { return r.BusinessDate.After(date) })
+	return q
+}
+
+func (q *atmReconciliationsQ) WhereDiscrepancy() data.ATMReconciliations {
+	q.where(func(r *data.ATMReconciliation) bool { return !r.IsBalanced() })
+	return q
+}
+
+func (q *atmReconciliationsQ) OrderBy(orderBy ...string) data.ATMReconciliations {
+	q.orderBy = append(q.orderBy, orderBy...)
+	return q
+}
+
+func checkATMReconciliation(s *store, reconciliation *data.ATMReconciliation) error {
+	if _, ok := s.atms.get(reconciliation.ATMID); !ok {
+		return foreignKeyViolation(atmReconciliationsTableName, "atm_reconciliations_atm_fkey_fkey")
+	}
+
+	if reconciliation.Counted < 0 {
+		return checkViolation(atmReconciliationsTableName, "atm_reconciliations_counted_check")
+	}
+
+	if reconciliation.Discrepancy != reconciliation.Counted-reconciliation.Expected {
+		return checkViolation(atmReconciliationsTableName, "atm_reconciliations_check")
+	}
+
+	for _, other := range s.atmReconciliations.rows {
+		if other.ID != reconciliation.ID && other.ATMID == reconciliation.ATMID &&
+			other.BusinessDate.Equal(reconciliation.BusinessDate) {
+			return uniqueViolation("atm_reconciliations_atm_fkey_business_date_key")
+		}
+	}
+
+	return nil
+}
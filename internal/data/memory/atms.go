@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	atmsTableName    = "atms"
+	atmKeysTableName = "atm_keys"
+)
+
+type atmsQ struct {
+	*crudQ[*data.ATM, uuid.UUID]
+}
+
+func newATMsQ(q *mainQ) data.ATMs {
+	return &atmsQ{
+		newCRUDQ(q, func(s *store) *table[*data.ATM, uuid.UUID] { return s.atms }),
+	}
+}
+
+func (q *atmsQ) WhereID(id ...uuid.UUID) data.ATMs {
+	q.where(func(a *data.ATM) bool { return containsID(id, a.ID) })
+	return q
+}
+
+func (q *atmsQ) WhereStatus(status data.ATMStatus) data.ATMs {
+	q.where(func(a *data.ATM) bool { return a.Status == status })
+	return q
+}
+
+// ForUpdate is a no-op: transactions are serialized, so the rows read inside
+// a transaction can't be changed by others until it ends.
+func (q *atmsQ) ForUpdate() data.ATMs {
+	return q
+}
+
+func (q *atmsQ) OrderBy(orderBy ...string) data.ATMs {
+	q.orderBy = append(q.orderBy, orderBy...)
+	return q
+}
+
+func checkATM(_ *store, atm *data.ATM) error {
+	if atm.Status != data.ATMStatusActive && atm.Status != data.ATMStatusDisabled {
+		return checkViolation(atmsTableName, "atms_status")
+	}
+
+	return nil
+}
+
+func deleteATM(s *store, id uuid.UUID) error {
+	for _, t := range s.transactions.rows {
+		if t.ATMID != nil && *t.ATMID == id {
+			return restrictViolation(atmsTableName, "transactions_atm_fkey_fkey", transactionsTableName)
+		}
+	}
+
+	for _, c := range s.cashOutCodes.rows {
+		if c.ATMID != nil && *c.ATMID == id {
+			return restrictViolation(atmsTableName, "cash_out_codes_atm_fkey_fkey", cashOutCodesTableName)
+		}
+	}
+
+	for _, r := range s.atmReconciliations.rows {
+		if r.ATMID == id {
+			return restrictViolation(atmsTableName, "atm_reconciliations_atm_fkey_fkey", atmReconciliationsTableName)
+		}
+	}
+
+	for _, m := range s.atmCashMovements.rows {
+		if m.ATMID == id {
+			return restrictViolation(atmsTableName, "atm_cash_movements_atm_fkey_fkey", atmCashMovementsTableName)
+		}
+	}
+
+	// ATM keys reference ATMs with ON DELETE CASCADE
+	return s.atmKeys.deleteWhere(s, func(k *data.ATMKey) bool { return k.ATMID == id })
+}
+
+type atmKeysQ struct {
+	*crudQ[*data.ATMKey, uuid.UUID]
+}
+
+func newATMKeysQ(q *mainQ) data.ATMKeys {
+	return &atmKeysQ{
+		newCRUDQ(q, func(s *store) *table[*data.ATMKey, uuid.UUID] { return s.atmKeys }),
+	}
+}
+
+func (q *atmKeysQ) WhereID(id ...uuid.UUID) data.ATMKeys {
+	q.where(func(k *data.ATMKey) bool { return containsID(id, k.ID) })
+	return q
+}
+
+func (q *atmKeysQ) WhereATMID(atmID uuid.UUID) data.ATMKeys {
+	q.where(func(k *data.ATMKey) bool { return k.ATMID == atmID })
+	return q
+}
+
+func (q *atmKeysQ) WhereValidAt(t time.Time) data.ATMKeys {
+	q.where(func(k *data.ATMKey) bool { return k.IsValidAt(t) })
+	return q
+}
+
+func (q *atmKeysQ) OrderBy(orderBy ...string) data.ATMKeys {
+	q.orderBy = append(q.orderBy, orderBy...)
+	return q
+}
+
+func checkATMKey(s *store, key *data.ATMKey) error {
+	if _, ok := s.atms.get(key.ATMID); !ok {
+		return foreignKeyViolation(atmKeysTableName, "atm_keys_atm_fkey_fkey")
+	}
+
+	if key.ValidUntil != nil && !key.ValidUntil.After(key.ValidFrom) {
+		return checkViolation(atmKeysTableName, "atm_keys_check")
+	}
+
+	if !key.SignatureFormat.IsValid() {
+		return checkViolation(atmKeysTableName, "atm_keys_signature_format")
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const auditLogsTableName = "audit_logs"
+
+type auditLogsQ struct {
+	*crudQ[*data.AuditLog, uuid.UUID]
+}
+
+func newAuditLogsQ(q *mainQ) data.AuditLogs {
+	return &auditLogsQ{
+		newCRUDQ(q, func(s *store) *table[*data.AuditLog, uuid.UUID] { return s.auditLogs }),
+	}
+}
+
+func (q *auditLogsQ) WhereCustomerID(customerID uuid.UUID) data.AuditLogs {
+	q.where(func(l *data.AuditLog) bool { return l.CustomerID != nil && *l.CustomerID == customerID })
+	return q
+}
+
+func (q *auditLogsQ) WhereAccountID(accountID uuid.UUID) data.AuditLogs {
+	q.where(func(l *data.AuditLog) bool { return l.AccountID != nil && *l.AccountID == accountID })
+	return q
+}
+
+func (q *auditLogsQ) WhereAction(action data.AuditAction) data.AuditLogs {
+	q.where(func(l *data.AuditLog) bool { return l.Action == action })
+	return q
+}
+
+func (q *auditLogsQ) Limit(limit uint64) data.AuditLogs {
+	q.limit = &limit
+	return q
+}
+
+func (q *auditLogsQ) Offset(offset uint64) data.AuditLogs {
+	q.offset = offset
+	return q
+}
+
+func (q *auditLogsQ) OrderBy(orderBy ...string) data.AuditLogs {
+	q.orderBy = append(q.orderBy, orderBy...)
+	return q
+}
+
+func checkAuditLog(s *store, log *data.AuditLog) error {
+	if log.CustomerID != nil {
+		if _, ok := s.customers.get(*log.CustomerID); !ok {
+			return foreignKeyViolation(auditLogsTableName, "audit_logs_customer_id_fkey")
+		}
+	}
+
+	if log.AccountID != nil {
+		if _, ok := s.accounts.get(*log.AccountID); !ok {
+			return foreignKeyViolation(auditLogsTableName, "audit_logs_account_id_fkey")
+		}
+	}
+
+	return nil
+}
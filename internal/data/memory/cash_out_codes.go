@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const cashOutCodesTableName = "cash_out_codes"
+
+type cashOutCodesQ struct {
+	*crudQ[*data.CashOutCode, uuid.UUID]
+}
+
+func newCashOutCodesQ(q *mainQ) data.CashOutCodes {
+	return &cashOutCodesQ{
+		newCRUDQ(q, func(s *store) *table[*data.CashOutCode, uuid.UUID] { return s.cashOutCodes }),
+	}
+}
+
+func (q *cashOutCodesQ) WhereID(id ...uuid.UUID) data.CashOutCodes {
+	q.where(func(c *data.CashOutCode) bool { return containsID(id, c.ID) })
+	return q
+}
+
+func (q *cashOutCodesQ) WhereCodeHash(codeHash string) data.CashOutCodes {
+	q.where(func(c *data.CashOutCode) bool { return c.CodeHash == codeHash })
+	return q
+}
+
+func (q *cashOutCodesQ) WhereAccountID(accountID uuid.UUID) data.CashOutCodes {
+	q.where(func(c *data.CashOutCode) bool { return c.AccountID == accountID })
+	return q
+}
+
+func (q *cashOutCodesQ) WhereStatus(status data.CashOutCodeStatus) data.CashOutCodes {
+	q.where(func(c *data.CashOutCode) bool { return c.Status == status })
+	return q
+}
+
+func (q *cashOutCodesQ) WhereExpiredAt(t time.Time) data.CashOutCodes {
+	q.where(func(c *data.CashOutCode) bool { return !c.ExpiresAt.After(t) })
+	return q
+}
+
+// ForUpdate is a no-op: transactions are serialized, so the rows read inside
+// a transaction can't be changed by others until it ends.
+func (q *cashOutCodesQ) ForUpdate() data.CashOutCodes {
+	return q
+}
+
+func (q *cashOutCodesQ) OrderBy(orderBy ...string) data.CashOutCodes {
+	q.orderBy = append(q.orderBy, orderBy...)
+	return q
+}
+
+func checkCashOutCode(s *store, code *data.CashOutCode) error {
+	switch code.Status {
+	case data.CashOutCodePending, data.CashOutCodeCompleted, data.CashOutCodeExpired, data.CashOutCodeCancelled:
+	default:
+		return checkViolation(cashOutCodesTableName, "cash_out_codes_status")
+	}
+
+	if code.Amount == 0 {
+		return checkViolation(cashOutCodesTableName, "cash_out_codes_amount_check")
+	}
+
+	if (code.Status == data.CashOutCodeCompleted) != (code.TransactionID != nil) {
+		return checkViolation(cashOutCodesTableName, "cash_out_codes_check")
+	}
+
+	if _, ok := s.customers.get(code.CustomerID); !ok {
+		return foreignKeyViolation(cashOutCodesTableName, "cash_out_codes_customer_fkey_fkey")
+	}
+
+	if _, ok := s.accounts.get(code.AccountID); !ok {
+		return foreignKeyViolation(cashOutCodesTableName, "cash_out_codes_account_fkey_fkey")
+	}
+
+	if code.ATMID != nil {
+		if _, ok := s.atms.get(*code.ATMID); !ok {
+			return foreignKeyViolation(cashOutCodesTableName, "cash_out_codes_atm_fkey_fkey")
+		}
+	}
+
+	if code.TransactionID != nil {
+		if _, ok := s.transactions.get(*code.TransactionID); !ok {
+			return foreignKeyViolation(cashOutCodesTableName, "cash_out_codes_transaction_fkey_fkey")
+		}
+	}
+
+	if !code.IsPending() {
+		return nil
+	}
+
+	for _, other := range s.cashOutCodes.rows {
+		if other.ID != code.ID && other.IsPending() && other.CodeHash == code.CodeHash {
+			return uniqueViolation("unique_pending_cash_out_code")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// columnsCache maps an entity type to the index paths of its fields by `db` tag.
+var columnsCache sync.Map
+
+func columns(t reflect.Type) map[string][]int {
+	if cached, ok := columnsCache.Load(t); ok {
+		return cached.(map[string][]int)
+	}
+
+	result := make(map[string][]int)
+	collectColumns(t, nil, result)
+
+	columnsCache.Store(t, result)
+	return result
+}
+
+func collectColumns(t reflect.Type, prefix []int, result map[string][]int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		path := append(append([]int{}, prefix...), i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectColumns(field.Type, path, result)
+			continue
+		}
+
+		if name := field.Tag.Get("db"); name != "" && name != "-" {
+			result[name] = path
+		}
+	}
+}
+
+// column returns the value of the field tagged with the given column name.
+func column(entity any, name string) (reflect.Value, bool) {
+	v := reflect.ValueOf(entity).Elem()
+
+	path, ok := columns(v.Type())[name]
+	if !ok {
+		return reflect.Value{}, false
+	}
+
+	return v.FieldByIndex(path), true
+}
+
+// setColumn sets the field tagged with the given column name, if the entity has it.
+func setColumn(entity any, name string, value any) {
+	field, ok := column(entity, name)
+	if !ok {
+		return
+	}
+
+	field.Set(reflect.ValueOf(value).Convert(field.Type()))
+}
+
+type orderTerm struct {
+	column string
+	desc   bool
+}
+
+func parseOrderBy(orderBy []string) ([]orderTerm, error) {
+	var terms []orderTerm
+
+	for _, clause := range orderBy {
+		for _, part := range strings.Split(clause, ",") {
+			fields := strings.Fields(part)
+			if len(fields) == 0 || len(fields) > 2 {
+				return nil, fmt.Errorf("unsupported order by clause %q", part)
+			}
+
+			term := orderTerm{column: fields[0]}
+			if len(fields) == 2 {
+				switch strings.ToUpper(fields[1]) {
+				case "ASC":
+				case "DESC":
+					term.desc = true
+				default:
+					return nil, fmt.Errorf("unsupported order by direction %q", fields[1])
+				}
+			}
+
+			terms = append(terms, term)
+		}
+	}
+
+	return terms, nil
+}
+
+// sortRows orders rows the same way postgres does for ORDER BY clauses
+// consisting of column names with optional ASC/DESC, NULLs are treated as larger
+// than any other value.
+func sortRows[T any](rows []T, orderBy []string) error {
+	terms, err := parseOrderBy(orderBy)
+	if err != nil {
+		return err
+	}
+
+	if len(terms) == 0 || len(rows) == 0 {
+		return nil
+	}
+
+	sorter := &rowsSorter[T]{rows: rows, terms: terms, keys: make([][]reflect.Value, len(rows))}
+	for i, row := range rows {
+		sorter.keys[i] = make([]reflect.Value, len(terms))
+
+		for k, term := range terms {
+			value, ok := column(row, term.column)
+			if !ok {
+				return fmt.Errorf("column %q does not exist", term.column)
+			}
+
+			sorter.keys[i][k] = value
+		}
+	}
+
+	sort.Stable(sorter)
+
+	return nil
+}
+
+type rowsSorter[T any] struct {
+	rows  []T
+	terms []orderTerm
+	keys  [][]reflect.Value
+}
+
+func (s *rowsSorter[T]) Len() int {
+	return len(s.rows)
+}
+
+func (s *rowsSorter[T]) Swap(i, j int) {
+	s.rows[i], s.rows[j] = s.rows[j], s.rows[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+func (s *rowsSorter[T]) Less(i, j int) bool {
+	for k, term := range s.terms {
+		c := compareValues(s.keys[i][k], s.keys[j][k])
+		if c == 0 {
+			continue
+		}
+		if term.desc {
+			return c > 0
+		}
+		return c < 0
+	}
+
+	return false
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func compareValues(a, b reflect.Value) int {
+	if a.Kind() == reflect.Pointer {
+		switch {
+		case a.IsNil() && b.IsNil():
+			return 0
+		case a.IsNil():
+			return 1
+		case b.IsNil():
+			return -1
+		}
+
+		return compareValues(a.Elem(), b.Elem())
+	}
+
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+	}
+
+	switch a.Kind() {
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareOrdered(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(a.Float(), b.Float())
+	case reflect.Bool:
+		return compareOrdered(boolToInt(a.Bool()), boolToInt(b.Bool()))
+	case reflect.Array, reflect.Slice:
+		return bytes.Compare(toBytes(a), toBytes(b))
+	default:
+		return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+	}
+}
+
+func compareOrdered[V int64 | uint64 | float64](a, b V) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func toBytes(v reflect.Value) []byte {
+	if v.Type().Elem().Kind() != reflect.Uint8 {
+		return []byte(fmt.Sprint(v.Interface()))
+	}
+
+	result := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(result), v)
+
+	return result
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+// crudQ handles common CRUD operations, mirroring postgres.crudQ: filters,
+// limit, offset and order accumulate until Select is called.
+type crudQ[T data.IEntity[ID], ID comparable] struct {
+	q     *mainQ
+	table func(s *store) *table[T, ID]
+
+	filters []func(T) bool
+	orderBy []string
+	limit   *uint64
+	offset  uint64
+}
+
+// newCRUDQ creates a new instance of crudQ, where the T is a
+// POINTER to an entity type and ID is the ID type such as uuid.UUID or uint64, etc.
+func newCRUDQ[T data.IEntity[ID], ID comparable](q *mainQ, table func(s *store) *table[T, ID]) *crudQ[T, ID] {
+	return &crudQ[T, ID]{
+		q:     q,
+		table: table,
+	}
+}
+
+func (r *crudQ[T, ID]) where(filter func(T) bool) {
+	r.filters = append(r.filters, filter)
+}
+
+func (r *crudQ[T, ID]) reset() {
+	r.filters = nil
+	r.orderBy = nil
+	r.limit = nil
+	r.offset = 0
+}
+
+// Insert adds a new record to the table
+func (r *crudQ[T, ID]) Insert(entity T) error {
+	return r.q.write(func(s *store) error {
+		return r.table(s).insert(s, entity)
+	})
+}
+
+// Update modifies an existing record
+func (r *crudQ[T, ID]) Update(entity T) error {
+	return r.q.write(func(s *store) error {
+		return r.table(s).update(s, entity)
+	})
+}
+
+// Get retrieves a single record matching the filters
+func (r *crudQ[T, ID]) Get(result T) (bool, error) {
+	rows, err := r.selectRows()
+	if err != nil {
+		return false, fmt.Errorf("failed to get row: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return false, nil
+	}
+
+	assign(result, rows[0])
+	return true, nil
+}
+
+// Select retrieves multiple records
+func (r *crudQ[T, ID]) Select() ([]T, error) {
+	rows, err := r.selectRows()
+	if err != nil {
+		return nil, err
+	}
+
+	r.reset()
+
+	return rows, nil
+}
+
+// Delete removes a record by ID
+func (r *crudQ[T, ID]) Delete(id ID) error {
+	return r.q.write(func(s *store) error {
+		return r.table(s).delete(s, id)
+	})
+}
+
+func (r *crudQ[T, ID]) Count() (uint64, error) {
+	rows, err := r.selectRows()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(len(rows)), nil
+}
+
+// selectRows returns copies of the rows matching the filters with order, offset
+// and limit applied.
+func (r *crudQ[T, ID]) selectRows() ([]T, error) {
+	var result []T
+
+	err := r.q.read(func(s *store) error {
+		for _, row := range r.table(s).rows {
+			if r.matches(row) {
+				result = append(result, clone(row))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = sortRows(result, r.orderBy); err != nil {
+		return nil, err
+	}
+
+	if r.offset >= uint64(len(result)) {
+		return nil, nil
+	}
+	result = result[r.offset:]
+
+	if r.limit != nil && *r.limit < uint64(len(result)) {
+		result = result[:*r.limit]
+	}
+
+	return result, nil
+}
+
+func (r *crudQ[T, ID]) matches(row T) bool {
+	for _, filter := range r.filters {
+		if !filter(row) {
+			return false
+		}
+	}
+
+	return true
+}
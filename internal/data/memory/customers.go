@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const customersTableName = "customers"
+
+type customersQ struct {
+	*crudQ[*data.Customer, uuid.UUID]
+}
+
+func newCustomersQ(q *mainQ) data.Customers {
+	return &customersQ{
+		newCRUDQ(q, func(s *store) *table[*data.Customer, uuid.UUID] { return s.customers }),
+	}
+}
+
+func (q *customersQ) WhereID(id ...uuid.UUID) data.Customers {
+	q.where(func(c *data.Customer) bool { return containsID(id, c.ID) })
+	return q
+}
+
+func (q *customersQ) WhereEmail(email string) data.Customers {
+	q.where(func(c *data.Customer) bool { return c.Email == email })
+	return q
+}
+
+func (q *customersQ) WhereUsername(username string) data.Customers {
+	q.where(func(c *data.Customer) bool { return c.Username == username })
+	return q
+}
+
+// IsUnique checks that it doesn't exist a customer with the same email or username.
+func (q *customersQ) IsUnique(email, username string) (bool, error) {
+	unique := true
+
+	err := q.q.read(func(s *store) error {
+		for _, c := range s.customers.rows {
+			if c.Email == email || c.Username == username {
+				unique = false
+				break
+			}
+		}
+
+		return nil
+	})
+
+	return unique, err
+}
+
+func checkCustomer(s *store, customer *data.Customer) error {
+	for _, c := range s.customers.rows {
+		if c.ID == customer.ID {
+			continue
+		}
+
+		if c.Email == customer.Email {
+			return uniqueViolation("customers_email_key")
+		}
+
+		if c.Username == customer.Username {
+			return uniqueViolation("customers_username_key")
+		}
+	}
+
+	return nil
+}
+
+func deleteCustomer(s *store, id uuid.UUID) error {
+	if s.customersAccounts.hasCustomer(id) {
+		return restrictViolation(customersTableName,
+			"customers_accounts_customer_fkey_fkey", customersAccountsTableName)
+	}
+
+	for _, l := range s.auditLogs.rows {
+		if l.CustomerID != nil && *l.CustomerID == id {
+			return restrictViolation(customersTableName, "audit_logs_customer_id_fkey", auditLogsTableName)
+		}
+	}
+
+	for _, c := range s.cashOutCodes.rows {
+		if c.CustomerID == id {
+			return restrictViolation(customersTableName, "cash_out_codes_customer_fkey_fkey", cashOutCodesTableName)
+		}
+	}
+
+	for _, j := range s.reportJobs.rows {
+		if j.CustomerID == id {
+			return restrictViolation(customersTableName, "report_jobs_customer_fkey_fkey", reportJobsTableName)
+		}
+	}
+
+	// idempotency keys, access tokens, sessions, second factors and login failures reference customers with ON DELETE CASCADE
+	if err := s.idempotencyKeys.deleteWhere(s, func(k *data.IdempotencyKey) bool { return k.CustomerID == id }); err != nil {
+		return err
+	}
+
+	if err := s.accessTokens.deleteWhere(s, func(t *data.AccessToken) bool { return t.CustomerID == id }); err != nil {
+		return err
+	}
+
+	if err := s.sessions.deleteWhere(s, func(session *data.Session) bool { return session.CustomerID == id }); err != nil {
+		return err
+	}
+
+	if err := s.totpCredentials.deleteWhere(s, func(c *data.TOTPCredential) bool { return c.CustomerID == id }); err != nil {
+		return err
+	}
+
+	if err := s.recoveryCodes.deleteWhere(s, func(c *data.RecoveryCode) bool { return c.CustomerID == id }); err != nil {
+		return err
+	}
+
+	if err := s.loginFailures.deleteWhere(s, func(f *data.LoginFailure) bool {
+		return f.CustomerID != nil && *f.CustomerID == id
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const customersAccountsTableName = "customers_accounts"
+
+type link struct {
+	customerID uuid.UUID
+	accountID  uuid.UUID
+}
+
+// links is the customers_accounts table, keeping the insertion order of the pairs.
+type links struct {
+	rows []link
+}
+
+func newLinks() *links {
+	return &links{}
+}
+
+func (l *links) indexOf(row link) int {
+	for i, candidate := range l.rows {
+		if candidate == row {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (l *links) hasCustomer(customerID uuid.UUID) bool {
+	for _, row := range l.rows {
+		if row.customerID == customerID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *links) hasAccount(accountID uuid.UUID) bool {
+	for _, row := range l.rows {
+		if row.accountID == accountID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *links) insert(s *store, row link) error {
+	if _, ok := s.customers.get(row.customerID); !ok {
+		return foreignKeyViolation(customersAccountsTableName, "customers_accounts_customer_fkey_fkey")
+	}
+
+	if _, ok := s.accounts.get(row.accountID); !ok {
+		return foreignKeyViolation(customersAccountsTableName, "customers_accounts_account_fkey_fkey")
+	}
+
+	if l.indexOf(row) >= 0 {
+		return uniqueViolation("customers_accounts_pkey")
+	}
+
+	l.rows = append(l.rows, row)
+	s.record(func() { l.remove(row) })
+
+	return nil
+}
+
+func (l *links) delete(s *store, row link) {
+	i := l.indexOf(row)
+	if i < 0 {
+		return
+	}
+
+	l.remove(row)
+	s.record(func() {
+		l.rows = append(l.rows, link{})
+		copy(l.rows[i+1:], l.rows[i:])
+		l.rows[i] = row
+	})
+}
+
+func (l *links) remove(row link) {
+	if i := l.indexOf(row); i >= 0 {
+		l.rows = append(l.rows[:i], l.rows[i+1:]...)
+	}
+}
+
+type customersAccountsQ struct {
+	q *mainQ
+}
+
+func newCustomersAccountsQ(q *mainQ) data.CustomersAccounts {
+	return &customersAccountsQ{
+		q: q,
+	}
+}
+
+func (q *customersAccountsQ) insert(rows ...link) error {
+	return q.q.write(func(s *store) error {
+		inserted := make([]link, 0, len(rows))
+		for _, row := range rows {
+			if err := s.customersAccounts.insert(s, row); err != nil {
+				// a statement is atomic, so the rows inserted before the failure are discarded
+				for _, r := range inserted {
+					s.customersAccounts.remove(r)
+				}
+				return err
+			}
+
+			inserted = append(inserted, row)
+		}
+
+		return nil
+	})
+}
+
+func (q *customersAccountsQ) AddCustomersToAccount(accountID uuid.UUID, customersID ...uuid.UUID) error {
+	rows := make([]link, 0, len(customersID))
+	for _, customerID := range customersID {
+		rows = append(rows, link{customerID: customerID, accountID: accountID})
+	}
+
+	return q.insert(rows...)
+}
+
+func (q *customersAccountsQ) AddAccountsToCustomer(customerID uuid.UUID, accountsID ...uuid.UUID) error {
+	rows := make([]link, 0, len(accountsID))
+	for _, accountID := range accountsID {
+		rows = append(rows, link{customerID: customerID, accountID: accountID})
+	}
+
+	return q.insert(rows...)
+}
+
+func (q *customersAccountsQ) RemoveCustomersFromAccount(accountID uuid.UUID, customersID ...uuid.UUID) error {
+	return q.q.write(func(s *store) error {
+		for _, customerID := range customersID {
+			s.customersAccounts.delete(s, link{customerID: customerID, accountID: accountID})
+		}
+
+		return nil
+	})
+}
+
+func (q *customersAccountsQ) RemoveAccountsFromCustomer(customerID uuid.UUID, accountsID ...uuid.UUID) error {
+	return q.q.write(func(s *store) error {
+		for _, accountID := range accountsID {
+			s.customersAccounts.delete(s, link{customerID: customerID, accountID: accountID})
+		}
+
+		return nil
+	})
+}
+
+func (q *customersAccountsQ) GetCustomersByAccount(accountID uuid.UUID) ([]uuid.UUID, error) {
+	var result []uuid.UUID
+
+	err := q.q.read(func(s *store) error {
+		for _, row := range s.customersAccounts.rows {
+			if row.accountID == accountID {
+				result = append(result, row.customerID)
+			}
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+func (q *customersAccountsQ) GetAccountsByCustomer(customerID uuid.UUID) ([]uuid.UUID, error) {
+	var result []uuid.UUID
+
+	err := q.q.read(func(s *store) error {
+		for _, row := range s.customersAccounts.rows {
+			if row.customerID == customerID {
+				result = append(result, row.accountID)
+			}
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+func (q *customersAccountsQ) HasAccount(customerID, accountID uuid.UUID) (bool, error) {
+	var ok bool
+
+	err := q.q.read(func(s *store) error {
+		ok = s.customersAccounts.indexOf(link{customerID: customerID, accountID: accountID}) >= 0
+		return nil
+	})
+
+	return ok, err
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const idempotencyKeysTableName = "idempotency_keys"
+
+type idempotencyKeysQ struct {
+	*crudQ[*data.IdempotencyKey, uuid.UUID]
+}
+
+func newIdempotencyKeysQ(q *mainQ) data.IdempotencyKeys {
+	return &idempotencyKeysQ{
+		newCRUDQ(q, func(s *store) *table[*data.IdempotencyKey, uuid.UUID] { return s.idempotencyKeys }),
+	}
+}
+
+func (q *idempotencyKeysQ) WhereCustomerID(customerID uuid.UUID) data.IdempotencyKeys {
+	q.where(func(k *data.IdempotencyKey) bool { return k.CustomerID == customerID })
+	return q
+}
+
+func (q *idempotencyKeysQ) WhereKey(key string) data.IdempotencyKeys {
+	q.where(func(k *data.IdempotencyKey) bool { return k.Key == key })
+	return q
+}
+
+func (q *idempotencyKeysQ) Reserve(key *data.IdempotencyKey) (bool, error) {
+	err := q.q.write(func(s *store) error {
+		return s.idempotencyKeys.insert(s, key)
+	})
+	if errors.Is(err, ErrUniqueViolation) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func checkIdempotencyKey(s *store, key *data.IdempotencyKey) error {
+	if _, ok := s.customers.get(key.CustomerID); !ok {
+		return foreignKeyViolation(idempotencyKeysTableName, "idempotency_keys_customer_fkey_fkey")
+	}
+
+	for _, k := range s.idempotencyKeys.rows {
+		if k.ID != key.ID && k.CustomerID == key.CustomerID && k.Key == key.Key {
+			return uniqueViolation("unique_idempotency_key_per_customer")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	journalEntriesTableName = "journal_entries"
+	postingsTableName       = "postings"
+)
+
+// ErrUnbalancedJournalEntry mirrors the exception raised by the journal_entry_balanced trigger.
+var ErrUnbalancedJournalEntry = errors.New("journal entry is not balanced")
+
+type ledgerQ struct {
+	q *mainQ
+}
+
+func newLedgerQ(q *mainQ) data.Ledger {
+	return &ledgerQ{
+		q: q,
+	}
+}
+
+// Post inserts the journal entry and all its postings. Unlike postgres, where
+// the balance is checked at commit, the entry is validated as a whole right away.
+func (q *ledgerQ) Post(entry *data.JournalEntry, postings ...*data.Posting) error {
+	var imbalance int
+	for _, posting := range postings {
+		if posting.Side == data.Debit {
+			imbalance += int(posting.Amount)
+		} else {
+			imbalance -= int(posting.Amount)
+		}
+	}
+
+	if imbalance != 0 {
+		return fmt.Errorf("%w: debits and credits differ by %d", ErrUnbalancedJournalEntry, imbalance)
+	}
+
+	return q.q.write(func(s *store) error {
+		if err := s.journalEntries.insert(s, entry); err != nil {
+			return fmt.Errorf("failed to insert journal entry: %w", err)
+		}
+
+		for _, posting := range postings {
+			posting.JournalEntryID = entry.ID
+
+			if err := s.postings.insert(s, posting); err != nil {
+				// a failed statement must not leave a partial entry behind
+				for _, inserted := range postings {
+					s.postings.remove(inserted.ID)
+				}
+				s.journalEntries.remove(entry.ID)
+
+				return fmt.Errorf("failed to insert posting: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (q *ledgerQ) AccountBalance(accountID uuid.UUID) (int, error) {
+	return q.balance(func(p *data.Posting) bool {
+		return p.AccountID != nil && *p.AccountID == accountID
+	}, data.Credit)
+}
+
+func (q *ledgerQ) SystemAccountBalance(account data.SystemAccount) (int, error) {
+	return q.balance(func(p *data.Posting) bool {
+		return p.SystemAccount != nil && *p.SystemAccount == account
+	}, data.Debit)
+}
+
+func (q *ledgerQ) balance(filter func(*data.Posting) bool, normal data.PostingSide) (int, error) {
+	var result int
+
+	err := q.q.read(func(s *store) error {
+		for _, posting := range s.postings.rows {
+			if !filter(posting) {
+				continue
+			}
+
+			if posting.Side == normal {
+				result += int(posting.Amount)
+			} else {
+				result -= int(posting.Amount)
+			}
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+func (q *ledgerQ) Postings() data.Postings {
+	return newPostingsQ(q.q)
+}
+
+type postingsQ struct {
+	*crudQ[*data.Posting, uuid.UUID]
+}
+
+func newPostingsQ(q *mainQ) data.Postings {
+	return &postingsQ{
+		newCRUDQ(q, func(s *store) *table[*data.Posting, uuid.UUID] { return s.postings }),
+	}
+}
+
+func (q *postingsQ) WhereJournalEntry(journalEntryID uuid.UUID) data.Postings {
+	q.where(func(p *data.Posting) bool { return p.JournalEntryID == journalEntryID })
+	return q
+}
+
+func (q *postingsQ) WhereAccount(accountID uuid.UUID) data.Postings {
+	q.where(func(p *data.Posting) bool { return p.AccountID != nil && *p.AccountID == accountID })
+	return q
+}
+
+func (q *postingsQ) WhereSystemAccount(account data.SystemAccount) data.Postings {
+	q.where(func(p *data.Posting) bool { return p.SystemAccount != nil && *p.SystemAccount == account })
+	return q
+}
+
+func checkJournalEntry(s *store, entry *data.JournalEntry) error {
+	if entry.TransactionID == nil {
+		return nil
+	}
+
+	if _, ok := s.transactions.get(*entry.TransactionID); !ok {
+		return foreignKeyViolation(journalEntriesTableName, "journal_entries_transaction_fkey_fkey")
+	}
+
+	return nil
+}
+
+func checkPosting(s *store, posting *data.Posting) error {
+	if (posting.AccountID == nil) == (posting.SystemAccount == nil) {
+		return checkViolation(postingsTableName, "postings_check")
+	}
+
+	if posting.Amount == 0 {
+		return checkViolation(postingsTableName, "postings_amount_check")
+	}
+
+	if posting.Side != data.Debit && posting.Side != data.Credit {
+		return checkViolation(postingsTableName, "postings_side_check")
+	}
+
+	if _, ok := s.journalEntries.get(posting.JournalEntryID); !ok {
+		return foreignKeyViolation(postingsTableName, "postings_journal_entry_fkey_fkey")
+	}
+
+	if posting.AccountID != nil {
+		if _, ok := s.accounts.get(*posting.AccountID); !ok {
+			return foreignKeyViolation(postingsTableName, "postings_account_fkey_fkey")
+		}
+	}
+
+	return nil
+}
+
+func deleteTransaction(s *store, id uuid.UUID) error {
+	for _, entry := range s.journalEntries.rows {
+		if entry.TransactionID != nil && *entry.TransactionID == id {
+			return restrictViolation(transactionsTableName,
+				"journal_entries_transaction_fkey_fkey", journalEntriesTableName)
+		}
+	}
+
+	for _, c := range s.cashOutCodes.rows {
+		if c.TransactionID != nil && *c.TransactionID == id {
+			return restrictViolation(transactionsTableName,
+				"cash_out_codes_transaction_fkey_fkey", cashOutCodesTableName)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const loginFailuresTableName = "login_failures"
+
+type loginFailuresQ struct {
+	*crudQ[*data.LoginFailure, uuid.UUID]
+}
+
+func newLoginFailuresQ(q *mainQ) data.LoginFailures {
+	return &loginFailuresQ{
+		newCRUDQ(q, func(s *store) *table[*data.LoginFailure, uuid.UUID] { return s.loginFailures }),
+	}
+}
+
+func (q *loginFailuresQ) WhereCustomerID(customerID uuid.UUID) data.LoginFailures {
+	q.where(func(f *data.LoginFailure) bool { return f.CustomerID != nil && *f.CustomerID == customerID })
+	return q
+}
+
+func (q *loginFailuresQ) WhereEmailHash(emailHash string) data.LoginFailures {
+	q.where(func(f *data.LoginFailure) bool { return f.EmailHash == emailHash })
+	return q
+}
+
+func (q *loginFailuresQ) WhereIP(ip string) data.LoginFailures {
+	q.where(func(f *data.LoginFailure) bool { return f.IP == ip })
+	return q
+}
+
+func (q *loginFailuresQ) WhereCreatedAfter(t time.Time) data.LoginFailures {
+	q.where(func(f *data.LoginFailure) bool { return f.CreatedAt.After(t) })
+	return q
+}
+
+func (q *loginFailuresQ) OrderBy(orderBy ...string) data.LoginFailures {
+	q.orderBy = append(q.orderBy, orderBy...)
+	return q
+}
+
+func (q *loginFailuresQ) DeleteByEmailHash(emailHash string) error {
+	return q.q.write(func(s *store) error {
+		return s.loginFailures.deleteWhere(s, func(f *data.LoginFailure) bool { return f.EmailHash == emailHash })
+	})
+}
+
+func (q *loginFailuresQ) DeleteByIP(ip string) error {
+	return q.q.write(func(s *store) error {
+		return s.loginFailures.deleteWhere(s, func(f *data.LoginFailure) bool { return f.IP == ip })
+	})
+}
+
+func (q *loginFailuresQ) DeleteCreatedBefore(t time.Time) error {
+	return q.q.write(func(s *store) error {
+		return s.loginFailures.deleteWhere(s, func(f *data.LoginFailure) bool { return f.CreatedAt.Before(t) })
+	})
+}
+
+// Lock does nothing, the transactions are fully serialized already.
+func (q *loginFailuresQ) Lock(_, _ string) error {
+	return nil
+}
+
+func checkLoginFailure(s *store, failure *data.LoginFailure) error {
+	if failure.CustomerID == nil {
+		return nil
+	}
+
+	if _, ok := s.customers.get(*failure.CustomerID); !ok {
+		return foreignKeyViolation(loginFailuresTableName, "login_failures_customer_fkey_fkey")
+	}
+
+	return nil
+}
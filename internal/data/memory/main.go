@@ -0,0 +1,272 @@
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+// store keeps all the tables shared by the mainQ instances. Transactions are
+// serialized with txMu, every mutation performed inside a transaction records
+// an undo function, so rollback restores the exact state before the transaction.
+type store struct {
+	mu   sync.RWMutex
+	txMu sync.Mutex
+
+	txDepth int
+	undo    []func()
+
+	customers          *table[*data.Customer, uuid.UUID]
+	accounts           *table[*data.Account, uuid.UUID]
+	customersAccounts  *links
+	transactions       *table[*data.Transaction, uuid.UUID]
+	auditLogs          *table[*data.AuditLog, uuid.UUID]
+	journalEntries     *table[*data.JournalEntry, uuid.UUID]
+	postings           *table[*data.Posting, uuid.UUID]
+	idempotencyKeys    *table[*data.IdempotencyKey, uuid.UUID]
+	accessTokens       *table[*data.AccessToken, uuid.UUID]
+	sessions           *table[*data.Session, uuid.UUID]
+	totpCredentials    *table[*data.TOTPCredential, uuid.UUID]
+	recoveryCodes      *table[*data.RecoveryCode, uuid.UUID]
+	loginFailures      *table[*data.LoginFailure, uuid.UUID]
+	atms               *table[*data.ATM, uuid.UUID]
+	atmKeys            *table[*data.ATMKey, uuid.UUID]
+	cashOutCodes       *table[*data.CashOutCode, uuid.UUID]
+	atmReconciliations *table[*data.ATMReconciliation, uuid.UUID]
+	atmCashMovements   *table[*data.ATMCashMovement, uuid.UUID]
+	reportJobs         *table[*data.ReportJob, uuid.UUID]
+	reportFiles        *table[*data.ReportFile, uuid.UUID]
+}
+
+func newStore() *store {
+	s := &store{
+		customers:          newTable[*data.Customer](uuid.New),
+		accounts:           newTable[*data.Account](uuid.New),
+		customersAccounts:  newLinks(),
+		transactions:       newTable[*data.Transaction](uuid.New),
+		auditLogs:          newTable[*data.AuditLog](uuid.New),
+		journalEntries:     newTable[*data.JournalEntry](uuid.New),
+		postings:           newTable[*data.Posting](uuid.New),
+		idempotencyKeys:    newTable[*data.IdempotencyKey](uuid.New),
+		accessTokens:       newTable[*data.AccessToken](uuid.New),
+		sessions:           newTable[*data.Session](uuid.New),
+		totpCredentials:    newTable[*data.TOTPCredential](uuid.New),
+		recoveryCodes:      newTable[*data.RecoveryCode](uuid.New),
+		loginFailures:      newTable[*data.LoginFailure](uuid.New),
+		atms:               newTable[*data.ATM](uuid.New),
+		atmKeys:            newTable[*data.ATMKey](uuid.New),
+		cashOutCodes:       newTable[*data.CashOutCode](uuid.New),
+		atmReconciliations: newTable[*data.ATMReconciliation](uuid.New),
+		atmCashMovements:   newTable[*data.ATMCashMovement](uuid.New),
+		reportJobs:         newTable[*data.ReportJob](uuid.New),
+		reportFiles:        newTable[*data.ReportFile](uuid.New),
+	}
+
+	s.customers.check = checkCustomer
+	s.customers.onDelete = deleteCustomer
+	s.accounts.onDelete = deleteAccount
+	s.transactions.check = checkTransaction
+	s.transactions.onDelete = deleteTransaction
+	s.auditLogs.check = checkAuditLog
+	s.journalEntries.check = checkJournalEntry
+	s.postings.check = checkPosting
+	s.idempotencyKeys.check = checkIdempotencyKey
+	s.accessTokens.check = checkAccessToken
+	s.sessions.check = checkSession
+	s.totpCredentials.check = checkTOTPCredential
+	s.recoveryCodes.check = checkRecoveryCode
+	s.loginFailures.check = checkLoginFailure
+	s.atms.check = checkATM
+	s.atms.onDelete = deleteATM
+	s.atmKeys.check = checkATMKey
+	s.cashOutCodes.check = checkCashOutCode
+	s.atmReconciliations.check = checkATMReconciliation
+	s.atmCashMovements.check = checkATMCashMovement
+	s.reportJobs.check = checkReportJob
+	s.reportJobs.onDelete = deleteReportJob
+	s.reportFiles.check = checkReportFile
+
+	return s
+}
+
+// record registers fn to be called if the currently open transaction is rolled back.
+// Must be called with s.mu held.
+func (s *store) record(fn func()) {
+	if s.txDepth > 0 {
+		s.undo = append(s.undo, fn)
+	}
+}
+
+type mainQ struct {
+	store *store
+	inTx  *atomic.Bool
+}
+
+// NewMainQ creates a data.MainQ backed by a fresh in-memory store. It mirrors
+// the behaviour of the postgres implementation, including constraints and
+// transaction rollback, and is intended for unit tests.
+//
+// Like pgdb.DB, a single instance is bound to at most one transaction at a time.
+func NewMainQ() data.MainQ {
+	return &mainQ{
+		store: newStore(),
+		inTx:  new(atomic.Bool),
+	}
+}
+
+// New returns a MainQ sharing the same store, but bound to its own transaction.
+func (q *mainQ) New() data.MainQ {
+	return &mainQ{
+		store: q.store,
+		inTx:  new(atomic.Bool),
+	}
+}
+
+func (q *mainQ) Customers() data.Customers {
+	return newCustomersQ(q)
+}
+
+func (q *mainQ) Accounts() data.Accounts {
+	return newAccountsQ(q)
+}
+
+func (q *mainQ) CustomersAccounts() data.CustomersAccounts {
+	return newCustomersAccountsQ(q)
+}
+
+func (q *mainQ) Transactions() data.Transactions {
+	return newTransactionsQ(q)
+}
+
+func (q *mainQ) AuditLogs() data.AuditLogs {
+	return newAuditLogsQ(q)
+}
+
+func (q *mainQ) Ledger() data.Ledger {
+	return newLedgerQ(q)
+}
+
+func (q *mainQ) IdempotencyKeys() data.IdempotencyKeys {
+	return newIdempotencyKeysQ(q)
+}
+
+func (q *mainQ) AccessTokens() data.AccessTokens {
+	return newAccessTokensQ(q)
+}
+
+func (q *mainQ) Sessions() data.Sessions {
+	return newSessionsQ(q)
+}
+
+func (q *mainQ) TOTPCredentials() data.TOTPCredentials {
+	return newTOTPCredentialsQ(q)
+}
+
+func (q *mainQ) RecoveryCodes() data.RecoveryCodes {
+	return newRecoveryCodesQ(q)
+}
+
+func (q *mainQ) LoginFailures() data.LoginFailures {
+	return newLoginFailuresQ(q)
+}
+
+func (q *mainQ) ATMs() data.ATMs {
+	return newATMsQ(q)
+}
+
+func (q *mainQ) ATMKeys() data.ATMKeys {
+	return newATMKeysQ(q)
+}
+
+func (q *mainQ) CashOutCodes() data.CashOutCodes {
+	return newCashOutCodesQ(q)
+}
+
+func (q *mainQ) ATMReconciliations() data.ATMReconciliations {
+	return newATMReconciliationsQ(q)
+}
+
+func (q *mainQ) ATMCashMovements() data.ATMCashMovements {
+	return newATMCashMovementsQ(q)
+}
+
+func (q *mainQ) ReportJobs() data.ReportJobs {
+	return newReportJobsQ(q)
+}
+
+func (q *mainQ) ReportFiles() data.ReportFiles {
+	return newReportFilesQ(q)
+}
+
+func (q *mainQ) Transaction(fn func() error) error {
+	return q.IsolatedTransaction(sql.LevelDefault, fn)
+}
+
+// IsolatedTransaction runs fn in a transaction. Transactions are fully serialized,
+// so every isolation level behaves as sql.LevelSerializable and there are no
+// serialization failures to retry. Nested calls behave
+// like savepoints: an error rolls back only the changes made by the nested fn.
+func (q *mainQ) IsolatedTransaction(_ sql.IsolationLevel, fn func() error) error {
+	nested := q.inTx.Load()
+	if !nested {
+		q.store.txMu.Lock()
+		defer q.store.txMu.Unlock()
+
+		q.inTx.Store(true)
+		defer q.inTx.Store(false)
+	}
+
+	q.store.mu.Lock()
+	q.store.txDepth++
+	mark := len(q.store.undo)
+	q.store.mu.Unlock()
+
+	err := fn()
+
+	q.store.mu.Lock()
+	defer q.store.mu.Unlock()
+
+	q.store.txDepth--
+	if err != nil {
+		for i := len(q.store.undo) - 1; i >= mark; i-- {
+			q.store.undo[i]()
+		}
+		q.store.undo = q.store.undo[:mark]
+
+		return fmt.Errorf("failed to execute statements: %w", err)
+	}
+
+	if q.store.txDepth == 0 {
+		q.store.undo = nil
+	}
+
+	return nil
+}
+
+// write executes fn under the store write lock. Outside a transaction the write is
+// serialized with the running transactions, just like an autocommit statement
+// waiting for row locks.
+func (q *mainQ) write(fn func(s *store) error) error {
+	if !q.inTx.Load() {
+		q.store.txMu.Lock()
+		defer q.store.txMu.Unlock()
+	}
+
+	q.store.mu.Lock()
+	defer q.store.mu.Unlock()
+
+	return fn(q.store)
+}
+
+// read executes fn under the store read lock.
+func (q *mainQ) read(fn func(s *store) error) error {
+	q.store.mu.RLock()
+	defer q.store.mu.RUnlock()
+
+	return fn(q.store)
+}
@@ -0,0 +1,1008 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+func newTestMainQ(t *testing.T) data.MainQ {
+	t.Helper()
+
+	return NewMainQ()
+}
+
+func TestAccountsCRUD(t *testing.T) {
+	db := newTestMainQ(t)
+	accounts := db.Accounts()
+
+	// Create an account
+	account := &data.Account{
+		Name:    "account1",
+		Balance: 1000,
+	}
+
+	err := accounts.Insert(account)
+	require.NoError(t, err)
+
+	// Retrieve the account
+	fetched := new(data.Account)
+	ok, err := accounts.WhereID(account.ID).Get(fetched)
+	require.NoError(t, err)
+	require.True(t, ok, "inserted account not found")
+	assert.Equal(t, account.Balance, fetched.Balance)
+
+	// Update the account balance
+	account.Balance = 2000
+	err = accounts.Update(account)
+	require.NoError(t, err)
+
+	// Verify the update
+	updated := new(data.Account)
+	ok, err = accounts.WhereID(account.ID).Get(updated)
+	require.NoError(t, err)
+	require.True(t, ok, "updated account not found")
+	assert.Equal(t, 2000, updated.Balance)
+
+	// Delete the account
+	err = accounts.Delete(account.ID)
+	require.NoError(t, err)
+
+	// Verify deletion
+	deleted := new(data.Account)
+	ok, err = accounts.WhereID(account.ID).Get(deleted)
+	require.NoError(t, err)
+	assert.False(t, ok, "deleted account still exists")
+}
+
+func TestCustomersCRUD(t *testing.T) {
+	db := newTestMainQ(t)
+	customers := db.Customers()
+
+	// Create a customer
+	customer := &data.Customer{
+		Email:        "test@example.com",
+		Username:     "testuser",
+		PasswordHash: "hashed_password",
+	}
+	err := customers.Insert(customer)
+	require.NoError(t, err)
+
+	// Retrieve the customer by ID
+	fetched := new(data.Customer)
+	ok, err := customers.WhereID(customer.ID).Get(fetched)
+	require.NoError(t, err)
+	require.True(t, ok, "inserted customer not found")
+	assert.Equal(t, customer.Email, fetched.Email)
+
+	// Update the customer email
+	customer.Email = "updated@example.com"
+	err = customers.Update(customer)
+	require.NoError(t, err)
+
+	// Verify the update
+	updated := new(data.Customer)
+	ok, err = customers.WhereID(customer.ID).Get(updated)
+	require.NoError(t, err)
+	require.True(t, ok, "updated customer not found")
+	assert.Equal(t, "updated@example.com", updated.Email)
+
+	// Delete the customer
+	err = customers.Delete(customer.ID)
+	require.NoError(t, err)
+
+	// Verify deletion
+	deleted := new(data.Customer)
+	ok, err = customers.WhereID(customer.ID).Get(deleted)
+	require.NoError(t, err)
+	assert.False(t, ok, "deleted customer still exists")
+}
+
+func TestTransactionsCRUD(t *testing.T) {
+	db := newTestMainQ(t)
+	transactions := db.Transactions()
+	accounts := db.Accounts()
+
+	// Create sender and recipient accounts.
+	sender := &data.Account{
+		Name:    "sender",
+		Balance: 1000,
+	}
+	recipient := &data.Account{
+		Name:    "recipient",
+		Balance: 500,
+	}
+	err := accounts.Insert(sender)
+	require.NoError(t, err)
+	err = accounts.Insert(recipient)
+	require.NoError(t, err)
+
+	// Insert a valid transfer transaction (both sender and recipient must be non-nil).
+	txn := &data.Transaction{
+		Type:      data.TransferTransaction,
+		Amount:    300,
+		Sender:    sender.ID,
+		Recipient: recipient.ID,
+	}
+	err = transactions.Insert(txn)
+	require.NoError(t, err)
+
+	// Retrieve the transaction using a filter (by sender).
+	fetched := new(data.Transaction)
+	ok, err := transactions.WhereSender(txn.Sender).Get(fetched)
+	require.NoError(t, err)
+	require.True(t, ok, "inserted transaction not found")
+	assert.Equal(t, txn.Amount, fetched.Amount)
+	assert.Equal(t, txn.Type, fetched.Type)
+
+	// Update the transaction amount.
+	txn.Amount = 350
+	err = transactions.Update(txn)
+	require.NoError(t, err)
+
+	// Verify the update.
+	updated := new(data.Transaction)
+	ok, err = transactions.WhereSender(txn.Sender).Get(updated)
+	require.NoError(t, err)
+	require.True(t, ok, "updated transaction not found")
+	assert.Equal(t, uint(350), updated.Amount)
+
+	// Delete the transaction.
+	err = transactions.Delete(txn.ID)
+	require.NoError(t, err)
+
+	// Verify deletion.
+	deleted := new(data.Transaction)
+	ok, err = transactions.WhereSender(txn.Sender).Get(deleted)
+	require.NoError(t, err)
+	assert.False(t, ok, "deleted transaction still exists")
+}
+
+func TestInvalidDeposit(t *testing.T) {
+	db := newTestMainQ(t)
+	transactions := db.Transactions()
+	accounts := db.Accounts()
+
+	// Create a valid recipient account.
+	recipient := &data.Account{
+		Name:    "recipient_invalid_deposit",
+		Balance: 500,
+	}
+	err := accounts.Insert(recipient)
+	require.NoError(t, err)
+
+	// Try to create a deposit with a non-nil sender.
+	txnDeposit := &data.Transaction{
+		Type:         data.DepositTransaction,
+		Amount:       200,
+		Sender:       uuid.New(), // Invalid: sender should be nil for deposits.
+		Recipient:    recipient.ID,
+		ATMSignature: "invalid_deposit",
+	}
+	err = transactions.Insert(txnDeposit)
+	require.Error(t, err, "expected error when inserting deposit with non-null sender")
+}
+
+func TestInvalidWithdrawal(t *testing.T) {
+	db := newTestMainQ(t)
+	transactions := db.Transactions()
+	accounts := db.Accounts()
+
+	// Create a valid sender account.
+	sender := &data.Account{
+		Name:    "sender_invalid_withdrawal",
+		Balance: 1000,
+	}
+	err := accounts.Insert(sender)
+	require.NoError(t, err)
+
+	// Try to create a withdrawal with a non-nil recipient.
+	txnWithdrawal := &data.Transaction{
+		Type:         data.WithdrawalTransaction,
+		Amount:       100,
+		Sender:       sender.ID,
+		Recipient:    uuid.New(), // Invalid: recipient should be nil for withdrawals.
+		ATMSignature: "invalid_withdrawal",
+	}
+	err = transactions.Insert(txnWithdrawal)
+	require.Error(t, err, "expected error when inserting withdrawal with non-null recipient")
+}
+
+func TestInvalidTransfer(t *testing.T) {
+	db := newTestMainQ(t)
+	transactions := db.Transactions()
+	accounts := db.Accounts()
+
+	// Create valid accounts for sender and recipient.
+	sender := &data.Account{
+		Name:    "sender_invalid_transfer",
+		Balance: 1000,
+	}
+	recipient := &data.Account{
+		Name:    "recipient_invalid_transfer",
+		Balance: 500,
+	}
+	err := accounts.Insert(sender)
+	require.NoError(t, err)
+	err = accounts.Insert(recipient)
+	require.NoError(t, err)
+
+	// Try to create a transfer with a missing recipient (recipient nil).
+	txnTransferMissingRecipient := &data.Transaction{
+		Type:      data.TransferTransaction,
+		Amount:    300,
+		Sender:    sender.ID,
+		Recipient: uuid.Nil, // Invalid: transfer must have a non-nil recipient.
+	}
+	err = transactions.Insert(txnTransferMissingRecipient)
+	require.Error(t, err, "expected error when inserting transfer with missing recipient")
+
+	// Also try a transfer with a missing sender.
+	txnTransferMissingSender := &data.Transaction{
+		Type:      data.TransferTransaction,
+		Amount:    300,
+		Sender:    uuid.Nil, // Invalid: transfer must have a non-nil sender.
+		Recipient: recipient.ID,
+	}
+	err = transactions.Insert(txnTransferMissingSender)
+	require.Error(t, err, "expected error when inserting transfer with missing sender")
+}
+
+func TestTransactionsFilters(t *testing.T) {
+	db := newTestMainQ(t)
+	transactions := db.Transactions()
+	accounts := db.Accounts()
+
+	// Create two accounts.
+	account1 := &data.Account{
+		Name:    "account1",
+		Balance: 1000,
+	}
+	account2 := &data.Account{
+		Name:    "account2",
+		Balance: 500,
+	}
+	err := accounts.Insert(account1)
+	require.NoError(t, err)
+	err = accounts.Insert(account2)
+	require.NoError(t, err)
+
+	// Insert a valid deposit: for a deposit, sender must be nil (uuid.Nil) and recipient non-nil.
+	txnDeposit := &data.Transaction{
+		Type:   data.DepositTransaction,
+		Amount: 200,
+		// Sender is omitted (zero value, uuid.Nil) to represent SQL NULL.
+		Recipient:    account1.ID,
+		ATMSignature: "deposit_signature",
+	}
+	err = transactions.Insert(txnDeposit)
+	require.NoError(t, err)
+
+	// Insert a valid withdrawal: for a withdrawal, recipient must be nil (uuid.Nil) and sender non-nil.
+	txnWithdrawal := &data.Transaction{
+		Type:   data.WithdrawalTransaction,
+		Amount: 100,
+		Sender: account1.ID,
+		// Recipient is omitted (zero value) to represent SQL NULL.
+	}
+	err = transactions.Insert(txnWithdrawal)
+	require.NoError(t, err)
+
+	// Insert a valid transfer: both sender and recipient must be non-nil.
+	txnTransfer := &data.Transaction{
+		Type:         data.TransferTransaction,
+		Amount:       300,
+		Sender:       account1.ID,
+		Recipient:    account2.ID,
+		ATMSignature: "transfer_signature",
+	}
+	err = transactions.Insert(txnTransfer)
+	require.NoError(t, err)
+
+	// Test filtering by transaction type.
+	depositTxns, err := transactions.WhereType(data.DepositTransaction).Select()
+	require.NoError(t, err)
+	assert.NotEmpty(t, depositTxns, "expected at least one deposit transaction")
+
+	// Test filtering by sender: account1 should appear in both withdrawal and transfer.
+	senderTxns, err := transactions.WhereSender(account1.ID).Select()
+	require.NoError(t, err)
+	// Deposit should not be returned since its sender is nil.
+	assert.GreaterOrEqual(t, len(senderTxns), 2, "expected at least two transactions with account1 as sender")
+
+	// Test filtering by recipient: account2 should appear in the transfer transaction.
+	recipientTxns, err := transactions.WhereRecipient(account2.ID).Select()
+	require.NoError(t, err)
+	assert.Len(t, recipientTxns, 1, "expected exactly one transaction with account2 as recipient")
+
+	// Test filtering by account: WhereAccount should return transactions where account1 is either sender or recipient.
+	accountTxns, err := transactions.WhereAccount(account1.ID).Select()
+	require.NoError(t, err)
+	// account1 is recipient in the deposit and sender in the withdrawal and transfer.
+	assert.GreaterOrEqual(t, len(accountTxns), 3, "expected at least three transactions involving account1")
+}
+
+func TestCustomersAccountsCRUD(t *testing.T) {
+	db := newTestMainQ(t)
+	customers := db.Customers()
+	accounts := db.Accounts()
+	customersAccounts := db.CustomersAccounts()
+
+	// Create a customer
+	customer := &data.Customer{
+		Email:        "test@example.com",
+		Username:     "testuser",
+		PasswordHash: "hashed_password",
+	}
+	err := customers.Insert(customer)
+	require.NoError(t, err)
+
+	// Create an account
+	account := &data.Account{Name: "account1", Balance: 1000}
+	err = accounts.Insert(account)
+	require.NoError(t, err)
+
+	// Link customer to account
+	err = customersAccounts.AddCustomersToAccount(account.ID, customer.ID)
+	require.NoError(t, err)
+
+	// Retrieve linked customers by account
+	retrievedCustomers, err := customersAccounts.GetCustomersByAccount(account.ID)
+	require.NoError(t, err)
+	assert.Contains(t, retrievedCustomers, customer.ID)
+
+	// Retrieve linked accounts by customer
+	retrievedAccounts, err := customersAccounts.GetAccountsByCustomer(customer.ID)
+	require.NoError(t, err)
+	assert.Contains(t, retrievedAccounts, account.ID)
+
+	// Remove customer from account
+	err = customersAccounts.RemoveCustomersFromAccount(account.ID, customer.ID)
+	require.NoError(t, err)
+
+	// Verify customer removal
+	retrievedCustomers, err = customersAccounts.GetCustomersByAccount(account.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, retrievedCustomers, customer.ID)
+}
+
+func TestCustomerDeletionRestriction(t *testing.T) {
+	db := newTestMainQ(t)
+	customers := db.Customers()
+	accounts := db.Accounts()
+	customersAccounts := db.CustomersAccounts()
+
+	// Create a customer
+	customer := &data.Customer{
+		Email:        "test@example.com",
+		Username:     "testuser",
+		PasswordHash: "hashed_password",
+	}
+	err := customers.Insert(customer)
+	require.NoError(t, err)
+
+	// Create an account
+	account := &data.Account{Name: "account1", Balance: 1000}
+	err = accounts.Insert(account)
+	require.NoError(t, err)
+
+	// Link customer to account
+	err = customersAccounts.AddCustomersToAccount(account.ID, customer.ID)
+	require.NoError(t, err)
+
+	// Attempt to delete customer (should fail due to ON DELETE RESTRICT)
+	err = customers.Delete(customer.ID)
+	require.Error(t, err, "customer deletion should be restricted")
+}
+
+func TestTransactionCommit(t *testing.T) {
+	db := newTestMainQ(t)
+	accounts := db.Accounts()
+
+	// Create an account inside a transaction
+	err := db.Transaction(func() error {
+		account := &data.Account{Name: "account1", Balance: 500}
+		err := accounts.Insert(account)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+
+	require.NoError(t, err, "transaction should commit successfully")
+
+	// Verify the account was created
+	allAccounts, err := accounts.Select()
+	require.NoError(t, err)
+	assert.NotEmpty(t, allAccounts, "account should exist after commit")
+}
+
+func TestTransactionRollback(t *testing.T) {
+	db := newTestMainQ(t)
+	accounts := db.Accounts()
+
+	initialAccounts, err := accounts.Select()
+	require.NoError(t, err)
+
+	err = db.Transaction(func() error {
+		account := &data.Account{Name: "account1", Balance: 1000}
+		err = accounts.Insert(account)
+		if err != nil {
+			return err
+		}
+		return assert.AnError // Force rollback
+	})
+
+	require.Error(t, err, "transaction should be rolled back")
+
+	// Verify that no new account was created
+	afterRollbackAccounts, err := accounts.Select()
+	require.NoError(t, err)
+	assert.Equal(t, len(initialAccounts), len(afterRollbackAccounts), "rollback should not persist changes")
+}
+
+func TestNestedTransactionRollback(t *testing.T) {
+	db := newTestMainQ(t)
+	accounts := db.Accounts()
+
+	outer := &data.Account{Name: "outer"}
+	inner := &data.Account{Name: "inner"}
+
+	err := db.Transaction(func() error {
+		if err := accounts.Insert(outer); err != nil {
+			return err
+		}
+
+		// the failed nested transaction must only discard its own changes
+		nestedErr := db.Transaction(func() error {
+			if err := accounts.Insert(inner); err != nil {
+				return err
+			}
+			return assert.AnError
+		})
+		require.ErrorIs(t, nestedErr, assert.AnError)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	ok, err := accounts.WhereID(outer.ID).Get(new(data.Account))
+	require.NoError(t, err)
+	assert.True(t, ok, "outer account should be committed")
+
+	ok, err = accounts.WhereID(inner.ID).Get(new(data.Account))
+	require.NoError(t, err)
+	assert.False(t, ok, "inner account should be rolled back")
+}
+
+func TestTransactionRollbackRestoresUpdatesAndDeletes(t *testing.T) {
+	db := newTestMainQ(t)
+	accounts := db.Accounts()
+
+	kept := &data.Account{Name: "kept", Balance: 100}
+	removed := &data.Account{Name: "removed", Balance: 200}
+	require.NoError(t, accounts.Insert(kept))
+	require.NoError(t, accounts.Insert(removed))
+
+	err := db.Transaction(func() error {
+		kept.Balance = 0
+		if err := accounts.Update(kept); err != nil {
+			return err
+		}
+		if err := accounts.LDelete(kept.ID); err != nil {
+			return err
+		}
+		if err := accounts.Delete(removed.ID); err != nil {
+			return err
+		}
+		return assert.AnError
+	})
+	require.Error(t, err)
+
+	all, err := accounts.Select()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "kept", all[0].Name)
+	assert.Equal(t, 100, all[0].Balance)
+	assert.False(t, all[0].IsDeleted)
+	assert.Equal(t, "removed", all[1].Name, "insertion order should be restored")
+}
+
+func TestTransactionsOrderLimitOffset(t *testing.T) {
+	db := newTestMainQ(t)
+	accounts := db.Accounts()
+	transactions := db.Transactions()
+
+	account := &data.Account{Name: "account"}
+	require.NoError(t, accounts.Insert(account))
+
+	for _, amount := range []uint{300, 100, 200, 400} {
+		require.NoError(t, transactions.Insert(&data.Transaction{
+			Type:   data.WithdrawalTransaction,
+			Amount: amount,
+			Sender: account.ID,
+		}))
+	}
+
+	page, err := transactions.WhereAccount(account.ID).OrderBy("amount DESC").Limit(2).Offset(1).Select()
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, uint(300), page[0].Amount)
+	assert.Equal(t, uint(200), page[1].Amount)
+
+	count, err := transactions.WhereAccount(account.ID).Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), count)
+
+	// filters are reset after Select, like the postgres implementation
+	all, err := transactions.Select()
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+
+	_, err = transactions.OrderBy("no_such_column").Select()
+	assert.Error(t, err)
+}
+
+func TestTransactionsCreatedBetween(t *testing.T) {
+	db := newTestMainQ(t)
+	accounts := db.Accounts()
+	transactions := db.Transactions()
+
+	account := &data.Account{Name: "account"}
+	require.NoError(t, accounts.Insert(account))
+
+	for _, amount := range []uint{100, 200, 300} {
+		require.NoError(t, transactions.Insert(&data.Transaction{
+			Type:   data.WithdrawalTransaction,
+			Amount: amount,
+			Sender: account.ID,
+		}))
+	}
+
+	all, err := transactions.WhereAccount(account.ID).OrderBy("created_at").Select()
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+
+	amounts := func(list []*data.Transaction) []uint {
+		result := make([]uint, len(list))
+		for i, tx := range list {
+			result[i] = tx.Amount
+		}
+		return result
+	}
+
+	from, err := transactions.WhereAccount(account.ID).WhereCreatedFrom(all[1].CreatedAt).OrderBy("created_at").Select()
+	require.NoError(t, err)
+	assert.Equal(t, []uint{200, 300}, amounts(from), "from is inclusive")
+
+	before, err := transactions.WhereAccount(account.ID).WhereCreatedBefore(all[2].CreatedAt).OrderBy("created_at").Select()
+	require.NoError(t, err)
+	assert.Equal(t, []uint{100, 200}, amounts(before), "to is exclusive")
+
+	between, err := transactions.WhereAccount(account.ID).
+		WhereCreatedFrom(all[1].CreatedAt).
+		WhereCreatedBefore(all[2].CreatedAt).
+		Select()
+	require.NoError(t, err)
+	assert.Equal(t, []uint{200}, amounts(between))
+}
+
+func TestTransactionsEach(t *testing.T) {
+	db := newTestMainQ(t)
+	transactions := db.Transactions()
+
+	account := &data.Account{Name: "account"}
+	require.NoError(t, db.Accounts().Insert(account))
+
+	for _, amount := range []uint{100, 200, 300} {
+		require.NoError(t, transactions.Insert(&data.Transaction{
+			Type:   data.WithdrawalTransaction,
+			Amount: amount,
+			Sender: account.ID,
+		}))
+	}
+
+	var amounts []uint
+	err := transactions.WhereAccount(account.ID).OrderBy("created_at DESC").Each(func(tx *data.Transaction) error {
+		assert.Equal(t, account.ID, tx.Sender)
+		amounts = append(amounts, tx.Amount)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{300, 200, 100}, amounts)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = transactions.WhereAccount(account.ID).Each(func(*data.Transaction) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls, "stops at the first error")
+}
+
+func TestUniqueATMNonce(t *testing.T) {
+	db := newTestMainQ(t)
+
+	account := &data.Account{Name: "account"}
+	require.NoError(t, db.Accounts().Insert(account))
+
+	atm := &data.ATM{Location: "lobby", Status: data.ATMStatusActive}
+	require.NoError(t, db.ATMs().Insert(atm))
+
+	other := &data.ATM{Location: "street", Status: data.ATMStatusActive}
+	require.NoError(t, db.ATMs().Insert(other))
+
+	deposit := func(atmID uuid.UUID, nonce string) error {
+		return db.Transactions().Insert(&data.Transaction{
+			Type:         data.DepositTransaction,
+			Amount:       100,
+			Recipient:    account.ID,
+			ATMSignature: "signature",
+			ATMID:        &atmID,
+			ATMNonce:     nonce,
+		})
+	}
+
+	require.NoError(t, deposit(atm.ID, "nonce"))
+	require.NoError(t, deposit(atm.ID, "other-nonce"), "signatures must not be unique")
+	require.NoError(t, deposit(other.ID, "nonce"), "nonces must be unique per ATM")
+
+	err := deposit(atm.ID, "nonce")
+	require.ErrorIs(t, err, ErrUniqueViolation)
+	assert.Contains(t, err.Error(), "duplicate key value violates unique constraint")
+}
+
+func TestLedger(t *testing.T) {
+	db := newTestMainQ(t)
+
+	account := &data.Account{Name: "ledger account"}
+	require.NoError(t, db.Accounts().Insert(account))
+
+	transaction := &data.Transaction{
+		Type:         data.DepositTransaction,
+		Amount:       300,
+		Recipient:    account.ID,
+		ATMSignature: "ledger_signature",
+	}
+	require.NoError(t, db.Transactions().Insert(transaction))
+
+	atmCash := data.SystemAccountATMCash
+
+	err := db.Transaction(func() error {
+		return db.Ledger().Post(
+			&data.JournalEntry{TransactionID: &transaction.ID, Description: "deposit"},
+			&data.Posting{SystemAccount: &atmCash, Side: data.Debit, Amount: 300},
+			&data.Posting{AccountID: &account.ID, Side: data.Credit, Amount: 300},
+		)
+	})
+	require.NoError(t, err)
+
+	balance, err := db.Ledger().AccountBalance(account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 300, balance)
+
+	balance, err = db.Ledger().SystemAccountBalance(data.SystemAccountATMCash)
+	require.NoError(t, err)
+	assert.Equal(t, 300, balance)
+
+	postings, err := db.Ledger().Postings().WhereAccount(account.ID).Select()
+	require.NoError(t, err)
+	require.Len(t, postings, 1)
+	assert.Equal(t, data.Credit, postings[0].Side)
+
+	// Unbalanced entries are rejected as a whole
+	err = db.Transaction(func() error {
+		return db.Ledger().Post(
+			&data.JournalEntry{Description: "unbalanced"},
+			&data.Posting{SystemAccount: &atmCash, Side: data.Debit, Amount: 100},
+			&data.Posting{AccountID: &account.ID, Side: data.Credit, Amount: 50},
+		)
+	})
+	assert.Error(t, err)
+
+	// A posting must reference exactly one of the account and the system account
+	err = db.Transaction(func() error {
+		return db.Ledger().Post(
+			&data.JournalEntry{Description: "ambiguous"},
+			&data.Posting{AccountID: &account.ID, SystemAccount: &atmCash, Side: data.Debit, Amount: 100},
+			&data.Posting{AccountID: &account.ID, Side: data.Credit, Amount: 100},
+		)
+	})
+	assert.Error(t, err)
+
+	postings, err = db.Ledger().Postings().Select()
+	require.NoError(t, err)
+	assert.Len(t, postings, 2, "rejected entries must not leave postings behind")
+}
+
+func TestATMKeys(t *testing.T) {
+	db := newTestMainQ(t)
+
+	machine := &data.ATM{Location: "lobby", Status: data.ATMStatusActive}
+	require.NoError(t, db.ATMs().Insert(machine))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expired := now.Add(-time.Hour)
+
+	keys := []*data.ATMKey{
+		{ATMID: machine.ID, PublicKey: "expired", SignatureFormat: atm.SignatureFormatBase64, ValidFrom: now.Add(-2 * time.Hour), ValidUntil: &expired},
+		{ATMID: machine.ID, PublicKey: "current", SignatureFormat: atm.SignatureFormatBase64, ValidFrom: now.Add(-time.Minute)},
+		{ATMID: machine.ID, PublicKey: "future", SignatureFormat: atm.SignatureFormatBase64, ValidFrom: now.Add(time.Hour)},
+	}
+	for _, key := range keys {
+		require.NoError(t, db.ATMKeys().Insert(key))
+	}
+
+	valid, err := db.ATMKeys().WhereATMID(machine.ID).WhereValidAt(now).Select()
+	require.NoError(t, err)
+	require.Len(t, valid, 1)
+	assert.Equal(t, "current", valid[0].PublicKey)
+
+	require.Error(t, db.ATMKeys().Insert(&data.ATMKey{ATMID: uuid.New(), PublicKey: "orphan", SignatureFormat: atm.SignatureFormatBase64, ValidFrom: now}),
+		"key of an unknown ATM must be rejected")
+
+	account := &data.Account{Name: "account"}
+	require.NoError(t, db.Accounts().Insert(account))
+
+	require.NoError(t, db.Transactions().Insert(&data.Transaction{
+		Type:         data.DepositTransaction,
+		Amount:       100,
+		Recipient:    account.ID,
+		ATMSignature: "signature",
+		ATMID:        &machine.ID,
+	}))
+
+	require.Error(t, db.ATMs().Delete(machine.ID), "ATM with deposits must not be deleted")
+
+	disabled, err := db.ATMs().WhereStatus(data.ATMStatusDisabled).Count()
+	require.NoError(t, err)
+	assert.Zero(t, disabled)
+}
+
+func TestCashOutCodes(t *testing.T) {
+	db := newTestMainQ(t)
+
+	customer := &data.Customer{Email: "cash-out@example.com", Username: "cash-out", PasswordHash: "hash"}
+	require.NoError(t, db.Customers().Insert(customer))
+
+	account := &data.Account{Name: "account"}
+	require.NoError(t, db.Accounts().Insert(account))
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	newCode := func(codeHash string, expiresAt time.Time) *data.CashOutCode {
+		return &data.CashOutCode{
+			CustomerID: customer.ID,
+			AccountID:  account.ID,
+			Amount:     100,
+			CodeHash:   codeHash,
+			Status:     data.CashOutCodePending,
+			ExpiresAt:  expiresAt,
+		}
+	}
+
+	code := newCode("hash", now.Add(time.Minute))
+	require.NoError(t, db.CashOutCodes().Insert(code))
+
+	err := db.CashOutCodes().Insert(newCode("hash", now.Add(time.Minute)))
+	require.ErrorIs(t, err, ErrUniqueViolation, "pending codes must not collide")
+
+	expired := newCode("expired", now.Add(-time.Minute))
+	require.NoError(t, db.CashOutCodes().Insert(expired))
+
+	due, err := db.CashOutCodes().WhereStatus(data.CashOutCodePending).WhereExpiredAt(now).Select()
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, expired.ID, due[0].ID)
+
+	code.Status = data.CashOutCodeCompleted
+	require.Error(t, db.CashOutCodes().Update(code), "completed code must reference the transaction")
+
+	code.Status = data.CashOutCodeExpired
+	require.NoError(t, db.CashOutCodes().Update(code))
+	require.NoError(t, db.CashOutCodes().Insert(newCode("hash", now.Add(time.Minute))),
+		"hash of the code no longer pending may be reused")
+
+	require.Error(t, db.Accounts().Delete(account.ID), "account with cash-out codes must not be deleted")
+
+	pending, err := db.CashOutCodes().WhereAccountID(account.ID).WhereStatus(data.CashOutCodePending).Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), pending)
+}
+
+func TestATMReconciliations(t *testing.T) {
+	db := newTestMainQ(t)
+
+	atm := &data.ATM{Location: "lobby", Status: data.ATMStatusActive}
+	require.NoError(t, db.ATMs().Insert(atm))
+
+	day := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	newReconciliation := func(businessDate time.Time, expected, counted int) *data.ATMReconciliation {
+		return &data.ATMReconciliation{
+			ATMID:        atm.ID,
+			BusinessDate: businessDate,
+			Expected:     expected,
+			Counted:      counted,
+			Discrepancy:  counted - expected,
+			ATMNonce:     uuid.NewString(),
+			ATMSignature: "signature",
+		}
+	}
+
+	require.NoError(t, db.ATMReconciliations().Insert(newReconciliation(day, 1000, 1000)))
+	require.NoError(t, db.ATMReconciliations().Insert(newReconciliation(day.AddDate(0, 0, 1), 1000, 900)))
+
+	err := db.ATMReconciliations().Insert(newReconciliation(day, 1000, 1000))
+	require.ErrorIs(t, err, ErrUniqueViolation, "ATM is reconciled once per business date")
+
+	invalid := newReconciliation(day.AddDate(0, 0, 2), 1000, 900)
+	invalid.Discrepancy = 100
+	require.Error(t, db.ATMReconciliations().Insert(invalid), "discrepancy must be counted - expected")
+
+	require.Error(t, db.ATMReconciliations().Insert(newReconciliation(day.AddDate(0, 0, 2), 1000, -1)),
+		"counted cash must not be negative")
+
+	discrepancies, err := db.ATMReconciliations().
+		WhereATMID(atm.ID).
+		WhereBusinessDateBetween(day, day.AddDate(0, 0, 1)).
+		WhereDiscrepancy().
+		Select()
+	require.NoError(t, err)
+	require.Len(t, discrepancies, 1)
+	assert.Equal(t, -100, discrepancies[0].Discrepancy)
+	assert.True(t, discrepancies[0].BusinessDate.Equal(day.AddDate(0, 0, 1)))
+
+	later, err := db.ATMReconciliations().WhereATMID(atm.ID).WhereBusinessDateAfter(day).Count()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, later)
+
+	require.Error(t, db.ATMs().Delete(atm.ID), "reconciled ATM must not be deleted")
+}
+
+func TestATMCashMovements(t *testing.T) {
+	db := newTestMainQ(t)
+
+	atm := &data.ATM{Location: "movements", Status: data.ATMStatusActive}
+	require.NoError(t, db.ATMs().Insert(atm))
+
+	endOfDay := time.Now().UTC().Truncate(24 * time.Hour)
+
+	for _, movement := range []*data.ATMCashMovement{
+		{ATMID: atm.ID, Amount: 5000, MovedAt: endOfDay.Add(-time.Hour)},
+		{ATMID: atm.ID, Amount: 1000, MovedAt: endOfDay},
+		{ATMID: atm.ID, Amount: -400, MovedAt: endOfDay.Add(time.Hour)},
+	} {
+		require.NoError(t, db.ATMCashMovements().Insert(movement))
+	}
+
+	require.Error(t, db.ATMCashMovements().Insert(&data.ATMCashMovement{ATMID: uuid.New(), Amount: 1, MovedAt: endOfDay}),
+		"movement must reference the ATM")
+
+	total, err := db.ATMCashMovements().WhereATMID(atm.ID).Sum()
+	require.NoError(t, err)
+	assert.Equal(t, 5600, total)
+
+	since, err := db.ATMCashMovements().WhereATMID(atm.ID).WhereMovedFrom(endOfDay).Sum()
+	require.NoError(t, err)
+	assert.Equal(t, 600, since)
+
+	none, err := db.ATMCashMovements().WhereATMID(uuid.New()).Sum()
+	require.NoError(t, err)
+	assert.Zero(t, none)
+
+	require.Error(t, db.ATMs().Delete(atm.ID), "ATM with cash movements must not be deleted")
+}
+
+func TestLoginFailures(t *testing.T) {
+	db := newTestMainQ(t)
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		require.NoError(t, db.LoginFailures().Insert(&data.LoginFailure{EmailHash: "hash", IP: ip}))
+	}
+
+	require.NoError(t, db.LoginFailures().Lock("hash", "10.0.0.1"))
+
+	require.NoError(t, db.LoginFailures().DeleteCreatedBefore(time.Now().Add(-time.Hour)))
+
+	failures, err := db.LoginFailures().WhereEmailHash("hash").Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), failures, "recent failures are kept")
+
+	require.NoError(t, db.LoginFailures().DeleteCreatedBefore(time.Now().Add(time.Hour)))
+
+	failures, err = db.LoginFailures().WhereEmailHash("hash").Count()
+	require.NoError(t, err)
+	assert.Zero(t, failures)
+}
+
+func TestReportJobs(t *testing.T) {
+	db := newTestMainQ(t)
+
+	customer := &data.Customer{Email: "reports@example.com", Username: "reports", PasswordHash: "hash"}
+	require.NoError(t, db.Customers().Insert(customer))
+
+	account := &data.Account{Name: "account"}
+	require.NoError(t, db.Accounts().Insert(account))
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	job := &data.ReportJob{
+		CustomerID: customer.ID,
+		AccountID:  &account.ID,
+		Format:     "pdf",
+		PeriodTo:   now,
+		Status:     data.ReportJobPending,
+	}
+	require.NoError(t, db.ReportJobs().Insert(job))
+
+	missing := uuid.New()
+	err := db.ReportJobs().Insert(&data.ReportJob{
+		CustomerID: customer.ID,
+		AccountID:  &missing,
+		Format:     "pdf",
+		PeriodTo:   now,
+		Status:     data.ReportJobPending,
+	})
+	require.ErrorIs(t, err, ErrForeignKeyViolation)
+
+	job.Status = data.ReportJobRunning
+	job.StartedAt = &now
+	require.NoError(t, db.ReportJobs().Update(job))
+
+	job.Status = data.ReportJobFailed
+	finished, err := db.ReportJobs().Finish(job, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.False(t, finished, "job claimed at another time")
+
+	finished, err = db.ReportJobs().Finish(job, now)
+	require.NoError(t, err)
+	assert.True(t, finished)
+
+	finished, err = db.ReportJobs().Finish(job, now)
+	require.NoError(t, err)
+	assert.False(t, finished, "job is not running anymore")
+
+	job.Status = data.ReportJobCompleted
+	require.Error(t, db.ReportJobs().Update(job), "completed job must expire")
+
+	expiresAt := now.Add(-time.Minute)
+	job.ExpiresAt = &expiresAt
+	require.NoError(t, db.ReportJobs().Update(job))
+
+	due, err := db.ReportJobs().WhereStatus(data.ReportJobCompleted).WhereExpiredAt(now).Select()
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, job.ID, due[0].ID)
+
+	require.NoError(t, db.ReportFiles().Insert(&data.ReportFile{JobID: job.ID, Content: []byte("report")}))
+	err = db.ReportFiles().Insert(&data.ReportFile{JobID: job.ID, Content: []byte("report")})
+	require.ErrorIs(t, err, ErrUniqueViolation, "job must have a single file of the part")
+	require.NoError(t, db.ReportFiles().Insert(&data.ReportFile{JobID: job.ID, Part: 1, Content: []byte("part")}))
+
+	var part data.ReportFile
+	ok, err := db.ReportFiles().WhereJobID(job.ID).WherePart(1).Get(&part)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []byte("part"), part.Content)
+
+	require.NoError(t, db.ReportFiles().DeleteByJobID(job.ID))
+	require.NoError(t, db.ReportFiles().Insert(&data.ReportFile{JobID: job.ID, Content: []byte("report")}))
+
+	require.Error(t, db.Accounts().Delete(account.ID), "account with report jobs must not be deleted")
+	require.Error(t, db.Customers().Delete(customer.ID), "customer with report jobs must not be deleted")
+
+	require.NoError(t, db.ReportJobs().Delete(job.ID))
+
+	files, err := db.ReportFiles().WhereJobID(job.ID).Count()
+	require.NoError(t, err)
+	assert.Zero(t, files, "files are deleted with the job")
+}
@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	reportJobsTableName  = "report_jobs"
+	reportFilesTableName = "report_files"
+)
+
+type reportJobsQ struct {
+	*crudQ[*data.ReportJob, uuid.UUID]
+}
+
+func newReportJobsQ(q *mainQ) data.ReportJobs {
+	return &reportJobsQ{
+		newCRUDQ(q, func(s *store) *table[*data.ReportJob, uuid.UUID] { return s.reportJobs }),
+	}
+}
+
+func (q *reportJobsQ) WhereID(id ...uuid.UUID) data.ReportJobs {
+	q.where(func(j *data.ReportJob) bool { return containsID(id, j.ID) })
+	return q
+}
+
+func (q *reportJobsQ) WhereCustomerID(customerID uuid.UUID) data.ReportJobs {
+	q.where(func(j *data.ReportJob) bool { return j.CustomerID == customerID })
+	return q
+}
+
+func (q *reportJobsQ) WhereStatus(status data.ReportJobStatus) data.ReportJobs {
+	q.where(func(j *data.ReportJob) bool { return j.Status == status })
+	return q
+}
+
+func (q *reportJobsQ) WhereExpiredAt(t time.Time) data.ReportJobs {
+	q.where(func(j *data.ReportJob) bool { return j.ExpiresAt != nil && !j.ExpiresAt.After(t) })
+	return q
+}
+
+func (q *reportJobsQ) WhereStartedBefore(t time.Time) data.ReportJobs {
+	q.where(func(j *data.ReportJob) bool { return j.StartedAt != nil && j.StartedAt.Before(t) })
+	return q
+}
+
+// ForUpdateSkipLocked is a no-op: transactions are serialized, so no job
+// selected inside a transaction can be locked by another one.
+func (q *reportJobsQ) ForUpdateSkipLocked() data.ReportJobs {
+	return q
+}
+
+func (q *reportJobsQ) Limit(limit uint64) data.ReportJobs {
+	q.limit = &limit
+	return q
+}
+
+func (q *reportJobsQ) OrderBy(orderBy ...string) data.ReportJobs {
+	q.orderBy = append(q.orderBy, orderBy...)
+	return q
+}
+
+func (q *reportJobsQ) Finish(job *data.ReportJob, startedAt time.Time) (bool, error) {
+	finished := false
+
+	err := q.q.write(func(s *store) error {
+		current, ok := s.reportJobs.get(job.ID)
+		if !ok || current.Status != data.ReportJobRunning ||
+			current.StartedAt == nil || !current.StartedAt.Equal(startedAt) {
+			return nil
+		}
+
+		finished = true
+		return s.reportJobs.update(s, job)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return finished, nil
+}
+
+func checkReportJob(s *store, job *data.ReportJob) error {
+	switch job.Status {
+	case data.ReportJobPending, data.ReportJobRunning, data.ReportJobCompleted, data.ReportJobFailed, data.ReportJobExpired:
+	default:
+		return checkViolation(reportJobsTableName, "report_jobs_status")
+	}
+
+	if job.PeriodFrom != nil && !job.PeriodFrom.Before(job.PeriodTo) {
+		return checkViolation(reportJobsTableName, "report_jobs_check")
+	}
+
+	if (job.Status == data.ReportJobCompleted || job.Status == data.ReportJobExpired) && job.ExpiresAt == nil {
+		return checkViolation(reportJobsTableName, "report_jobs_check1")
+	}
+
+	if _, ok := s.customers.get(job.CustomerID); !ok {
+		return foreignKeyViolation(reportJobsTableName, "report_jobs_customer_fkey_fkey")
+	}
+
+	if job.AccountID != nil {
+		if _, ok := s.accounts.get(*job.AccountID); !ok {
+			return foreignKeyViolation(reportJobsTableName, "report_jobs_account_fkey_fkey")
+		}
+	}
+
+	return nil
+}
+
+func deleteReportJob(s *store, id uuid.UUID) error {
+	// report files reference report jobs with ON DELETE CASCADE
+	return s.reportFiles.deleteWhere(s, func(f *data.ReportFile) bool { return f.JobID == id })
+}
+
+type reportFilesQ struct {
+	*crudQ[*data.ReportFile, uuid.UUID]
+}
+
+func newReportFilesQ(q *mainQ) data.ReportFiles {
+	return &reportFilesQ{
+		newCRUDQ(q, func(s *store) *table[*data.ReportFile, uuid.UUID] { return s.reportFiles }),
+	}
+}
+
+func (q *reportFilesQ) WhereJobID(jobID uuid.UUID) data.ReportFiles {
+	q.where(func(f *data.ReportFile) bool { return f.JobID == jobID })
+	return q
+}
+
+func (q *reportFilesQ) WherePart(part int) data.ReportFiles {
+	q.where(func(f *data.ReportFile) bool { return f.Part == part })
+	return q
+}
+
+func (q *reportFilesQ) DeleteByJobID(jobID uuid.UUID) error {
+	return q.q.write(func(s *store) error {
+		return s.reportFiles.deleteWhere(s, func(f *data.ReportFile) bool { return f.JobID == jobID })
+	})
+}
+
+func checkReportFile(s *store, file *data.ReportFile) error {
+	if _, ok := s.reportJobs.get(file.JobID); !ok {
+		return foreignKeyViolation(reportFilesTableName, "report_files_job_fkey_fkey")
+	}
+
+	for _, other := range s.reportFiles.rows {
+		if other.ID != file.ID && other.JobID == file.JobID && other.Part == file.Part {
+			return uniqueViolation("report_files_job_fkey_part_key")
+		}
+	}
+
+	return nil
+}
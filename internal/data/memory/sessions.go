@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const sessionsTableName = "sessions"
+
+type sessionsQ struct {
+	*crudQ[*data.Session, uuid.UUID]
+}
+
+func newSessionsQ(q *mainQ) data.Sessions {
+	return &sessionsQ{
+		newCRUDQ(q, func(s *store) *table[*data.Session, uuid.UUID] { return s.sessions }),
+	}
+}
+
+func (q *sessionsQ) WhereID(id uuid.UUID) data.Sessions {
+	q.where(func(s *data.Session) bool { return s.ID == id })
+	return q
+}
+
+func (q *sessionsQ) WhereCustomerID(customerID uuid.UUID) data.Sessions {
+	q.where(func(s *data.Session) bool { return s.CustomerID == customerID })
+	return q
+}
+
+func (q *sessionsQ) WhereRefreshTokenHash(hash string) data.Sessions {
+	q.where(func(s *data.Session) bool { return s.RefreshTokenHash == hash })
+	return q
+}
+
+func (q *sessionsQ) WherePreviousRefreshTokenHash(hash string) data.Sessions {
+	q.where(func(s *data.Session) bool {
+		return s.PreviousRefreshTokenHash != nil && *s.PreviousRefreshTokenHash == hash
+	})
+	return q
+}
+
+func (q *sessionsQ) WhereActive(now time.Time) data.Sessions {
+	q.where(func(s *data.Session) bool { return s.IsActive(now) })
+	return q
+}
+
+func (q *sessionsQ) OrderBy(orderBy ...string) data.Sessions {
+	q.orderBy = append(q.orderBy, orderBy...)
+	return q
+}
+
+func (q *sessionsQ) Rotate(id uuid.UUID, refreshTokenHash string, expiresAt, at time.Time) error {
+	return q.q.write(func(s *store) error {
+		if err := checkRefreshTokenHash(s, id, refreshTokenHash); err != nil {
+			return err
+		}
+
+		s.sessions.modify(s, id, func(session *data.Session) {
+			previous := session.RefreshTokenHash
+			session.PreviousRefreshTokenHash = &previous
+			session.RefreshTokenHash = refreshTokenHash
+			session.RotatedAt = &at
+			session.LastSeenAt = at
+			session.ExpiresAt = expiresAt
+		})
+		return nil
+	})
+}
+
+func (q *sessionsQ) Touch(id uuid.UUID, at time.Time) error {
+	return q.q.write(func(s *store) error {
+		s.sessions.modify(s, id, func(session *data.Session) { session.LastSeenAt = at })
+		return nil
+	})
+}
+
+func (q *sessionsQ) Revoke(id uuid.UUID, at time.Time) error {
+	return q.q.write(func(s *store) error {
+		s.sessions.modify(s, id, func(session *data.Session) {
+			if session.RevokedAt == nil {
+				session.RevokedAt = &at
+			}
+		})
+		return nil
+	})
+}
+
+func (q *sessionsQ) RevokeAll(customerID uuid.UUID, at time.Time) error {
+	return q.q.write(func(s *store) error {
+		for _, session := range s.sessions.rows {
+			if session.CustomerID == customerID && session.RevokedAt == nil {
+				s.sessions.modify(s, session.ID, func(session *data.Session) { session.RevokedAt = &at })
+			}
+		}
+		return nil
+	})
+}
+
+func checkSession(s *store, session *data.Session) error {
+	if _, ok := s.customers.get(session.CustomerID); !ok {
+		return foreignKeyViolation(sessionsTableName, "sessions_customer_fkey_fkey")
+	}
+
+	return checkRefreshTokenHash(s, session.ID, session.RefreshTokenHash)
+}
+
+func checkRefreshTokenHash(s *store, id uuid.UUID, hash string) error {
+	for _, session := range s.sessions.rows {
+		if session.ID != id && session.RefreshTokenHash == hash {
+			return uniqueViolation("sessions_refresh_token_hash_key")
+		}
+	}
+
+	return nil
+}
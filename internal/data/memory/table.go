@@ -0,0 +1,212 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	idColumnName        = "id"
+	createdAtColumnName = "created_at"
+	updatedAtColumnName = "updated_at"
+)
+
+// Errors mimic the messages returned by postgres, so the code matching on
+// error text (e.g. unique violations) behaves the same for both implementations.
+var (
+	ErrUniqueViolation     = errors.New("duplicate key value violates unique constraint")
+	ErrForeignKeyViolation = errors.New("violates foreign key constraint")
+	ErrCheckViolation      = errors.New("violates check constraint")
+)
+
+func uniqueViolation(constraint string) error {
+	return fmt.Errorf("%w %q", ErrUniqueViolation, constraint)
+}
+
+func foreignKeyViolation(tableName, constraint string) error {
+	return fmt.Errorf("insert or update on table %q %w %q", tableName, ErrForeignKeyViolation, constraint)
+}
+
+func restrictViolation(tableName, constraint, referencing string) error {
+	return fmt.Errorf("update or delete on table %q %w %q on table %q",
+		tableName, ErrForeignKeyViolation, constraint, referencing)
+}
+
+func checkViolation(tableName, constraint string) error {
+	return fmt.Errorf("new row for relation %q %w %q", tableName, ErrCheckViolation, constraint)
+}
+
+// table stores rows in insertion order, which is the order postgres returns
+// rows of a freshly filled heap when no ORDER BY is given.
+type table[T data.IEntity[ID], ID comparable] struct {
+	rows  []T
+	index map[ID]int
+	newID func() ID
+
+	// check validates a row before it is inserted or updated.
+	check func(s *store, row T) error
+	// onDelete enforces foreign keys referencing the row (restrict or cascade).
+	onDelete func(s *store, id ID) error
+}
+
+func newTable[T data.IEntity[ID], ID comparable](newID func() ID) *table[T, ID] {
+	return &table[T, ID]{
+		index: make(map[ID]int),
+		newID: newID,
+	}
+}
+
+func (t *table[T, ID]) get(id ID) (T, bool) {
+	i, ok := t.index[id]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+
+	return t.rows[i], true
+}
+
+func (t *table[T, ID]) insert(s *store, entity T) error {
+	row := clone(entity)
+	*row.GetID() = t.newID()
+
+	now := time.Now().UTC()
+	setColumn(row, createdAtColumnName, now)
+	setColumn(row, updatedAtColumnName, now)
+
+	if t.check != nil {
+		if err := t.check(s, row); err != nil {
+			return err
+		}
+	}
+
+	id := *row.GetID()
+	t.index[id] = len(t.rows)
+	t.rows = append(t.rows, row)
+	s.record(func() { t.remove(id) })
+
+	*entity.GetID() = id
+	return nil
+}
+
+func (t *table[T, ID]) update(s *store, entity T) error {
+	id := *entity.GetID()
+
+	i, ok := t.index[id]
+	if !ok {
+		return nil
+	}
+
+	previous := t.rows[i]
+
+	row := clone(entity)
+	setColumn(row, createdAtColumnName, previous.GetCreatedAt())
+	setColumn(row, updatedAtColumnName, time.Now().UTC())
+
+	if t.check != nil {
+		if err := t.check(s, row); err != nil {
+			return err
+		}
+	}
+
+	t.rows[i] = row
+	s.record(func() { t.rows[t.index[id]] = previous })
+
+	return nil
+}
+
+// modify applies fn to the stored row with the given id, recording the undo.
+func (t *table[T, ID]) modify(s *store, id ID, fn func(row T)) {
+	i, ok := t.index[id]
+	if !ok {
+		return
+	}
+
+	previous := t.rows[i]
+
+	row := clone(previous)
+	fn(row)
+	setColumn(row, updatedAtColumnName, time.Now().UTC())
+
+	t.rows[i] = row
+	s.record(func() { t.rows[t.index[id]] = previous })
+}
+
+func (t *table[T, ID]) delete(s *store, id ID) error {
+	i, ok := t.index[id]
+	if !ok {
+		return nil
+	}
+
+	if t.onDelete != nil {
+		if err := t.onDelete(s, id); err != nil {
+			return err
+		}
+	}
+
+	row := t.rows[i]
+	t.remove(id)
+	s.record(func() { t.restore(i, row) })
+
+	return nil
+}
+
+// deleteWhere deletes all the rows matching the predicate, e.g. to cascade a delete.
+func (t *table[T, ID]) deleteWhere(s *store, pred func(row T) bool) error {
+	var ids []ID
+	for _, row := range t.rows {
+		if pred(row) {
+			ids = append(ids, *row.GetID())
+		}
+	}
+
+	for _, id := range ids {
+		if err := t.delete(s, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *table[T, ID]) remove(id ID) {
+	i, ok := t.index[id]
+	if !ok {
+		return
+	}
+
+	delete(t.index, id)
+	t.rows = append(t.rows[:i], t.rows[i+1:]...)
+	t.reindex(i)
+}
+
+func (t *table[T, ID]) restore(i int, row T) {
+	t.rows = append(t.rows, row)
+	copy(t.rows[i+1:], t.rows[i:])
+	t.rows[i] = row
+	t.reindex(i)
+}
+
+func (t *table[T, ID]) reindex(from int) {
+	for i := from; i < len(t.rows); i++ {
+		t.index[*t.rows[i].GetID()] = i
+	}
+}
+
+// clone returns a shallow copy of the entity the pointer T points to.
+func clone[T any](entity T) T {
+	v := reflect.ValueOf(entity)
+	c := reflect.New(v.Type().Elem())
+	c.Elem().Set(v.Elem())
+
+	return c.Interface().(T)
+}
+
+// assign copies the entity src points to into dst.
+func assign[T any](dst, src T) {
+	reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(src).Elem())
+}
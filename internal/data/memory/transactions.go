@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const transactionsTableName = "transactions"
+
+type transactionsQ struct {
+	*crudQ[*data.Transaction, uuid.UUID]
+}
+
+func newTransactionsQ(q *mainQ) data.Transactions {
+	return &transactionsQ{
+		newCRUDQ(q, func(s *store) *table[*data.Transaction, uuid.UUID] { return s.transactions }),
+	}
+}
+
+func (q *transactionsQ) WhereType(t data.TransactionType) data.Transactions {
+	q.where(func(tx *data.Transaction) bool { return tx.Type == t })
+	return q
+}
+
+func (q *transactionsQ) WhereSender(sender uuid.UUID) data.Transactions {
+	q.where(func(tx *data.Transaction) bool { return tx.Sender == sender })
+	return q
+}
+
+func (q *transactionsQ) WhereRecipient(recipient uuid.UUID) data.Transactions {
+	q.where(func(tx *data.Transaction) bool { return tx.Recipient == recipient })
+	return q
+}
+
+func (q *transactionsQ) WhereAccount(account uuid.UUID) data.Transactions {
+	q.where(func(tx *data.Transaction) bool { return tx.Sender == account || tx.Recipient == account })
+	return q
+}
+
+func (q *transactionsQ) WhereCreatedFrom(from time.Time) data.Transactions {
+	q.where(func(tx *data.Transaction) bool { return !tx.CreatedAt.Before(from) })
+	return q
+}
+
+func (q *transactionsQ) WhereCreatedBefore(to time.Time) data.Transactions {
+	q.where(func(tx *data.Transaction) bool { return tx.CreatedAt.Before(to) })
+	return q
+}
+
+func (q *transactionsQ) Limit(limit uint64) data.Transactions {
+	q.limit = &limit
+	return q
+}
+
+func (q *transactionsQ) Offset(offset uint64) data.Transactions {
+	q.offset = offset
+	return q
+}
+
+func (q *transactionsQ) OrderBy(sort ...string) data.Transactions {
+	q.orderBy = append(q.orderBy, sort...)
+	return q
+}
+
+// Each iterates over the selected rows, the store is in memory anyway.
+func (q *transactionsQ) Each(fn func(*data.Transaction) error) error {
+	transactions, err := q.Select()
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range transactions {
+		if err = fn(tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkTransaction enforces the constraints of the transactions table, where
+// uuid.Nil sender or recipient stands for NULL.
+func checkTransaction(s *store, tx *data.Transaction) error {
+	hasSender, hasRecipient := tx.Sender != uuid.Nil, tx.Recipient != uuid.Nil
+
+	valid := (tx.Type == data.DepositTransaction && !hasSender && hasRecipient) ||
+		(tx.Type == data.WithdrawalTransaction && hasSender && !hasRecipient) ||
+		(tx.Type == data.TransferTransaction && hasSender && hasRecipient)
+	if !valid {
+		return checkViolation(transactionsTableName, "transactions_check")
+	}
+
+	if _, ok := s.accounts.get(tx.Sender); hasSender && !ok {
+		return foreignKeyViolation(transactionsTableName, "transactions_sender_fkey_fkey")
+	}
+
+	if _, ok := s.accounts.get(tx.Recipient); hasRecipient && !ok {
+		return foreignKeyViolation(transactionsTableName, "transactions_recipient_fkey_fkey")
+	}
+
+	if tx.ATMID != nil {
+		if tx.Type == data.TransferTransaction {
+			return checkViolation(transactionsTableName, "transactions_atm_check")
+		}
+
+		if _, ok := s.atms.get(*tx.ATMID); !ok {
+			return foreignKeyViolation(transactionsTableName, "transactions_atm_fkey_fkey")
+		}
+	}
+
+	if tx.ATMID == nil || tx.ATMNonce == "" {
+		return nil
+	}
+
+	for _, other := range s.transactions.rows {
+		if other.ID != tx.ID && other.ATMID != nil && *other.ATMID == *tx.ATMID && other.ATMNonce == tx.ATMNonce {
+			return uniqueViolation("unique_atm_nonce")
+		}
+	}
+
+	return nil
+}
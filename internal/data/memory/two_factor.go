@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	totpCredentialsTableName = "totp_credentials"
+	recoveryCodesTableName   = "recovery_codes"
+)
+
+type totpCredentialsQ struct {
+	*crudQ[*data.TOTPCredential, uuid.UUID]
+}
+
+func newTOTPCredentialsQ(q *mainQ) data.TOTPCredentials {
+	return &totpCredentialsQ{
+		newCRUDQ(q, func(s *store) *table[*data.TOTPCredential, uuid.UUID] { return s.totpCredentials }),
+	}
+}
+
+func (q *totpCredentialsQ) WhereCustomerID(customerID uuid.UUID) data.TOTPCredentials {
+	q.where(func(c *data.TOTPCredential) bool { return c.CustomerID == customerID })
+	return q
+}
+
+func (q *totpCredentialsQ) UseStep(id uuid.UUID, step int64) (bool, error) {
+	var used bool
+
+	err := q.q.write(func(s *store) error {
+		credential, ok := s.totpCredentials.get(id)
+		if !ok || credential.LastUsedStep >= step {
+			return nil
+		}
+
+		s.totpCredentials.modify(s, id, func(c *data.TOTPCredential) { c.LastUsedStep = step })
+		used = true
+		return nil
+	})
+
+	return used, err
+}
+
+func checkTOTPCredential(s *store, credential *data.TOTPCredential) error {
+	if _, ok := s.customers.get(credential.CustomerID); !ok {
+		return foreignKeyViolation(totpCredentialsTableName, "totp_credentials_customer_fkey_fkey")
+	}
+
+	for _, c := range s.totpCredentials.rows {
+		if c.ID != credential.ID && c.CustomerID == credential.CustomerID {
+			return uniqueViolation("totp_credentials_customer_fkey_key")
+		}
+	}
+
+	return nil
+}
+
+type recoveryCodesQ struct {
+	*crudQ[*data.RecoveryCode, uuid.UUID]
+}
+
+func newRecoveryCodesQ(q *mainQ) data.RecoveryCodes {
+	return &recoveryCodesQ{
+		newCRUDQ(q, func(s *store) *table[*data.RecoveryCode, uuid.UUID] { return s.recoveryCodes }),
+	}
+}
+
+func (q *recoveryCodesQ) WhereCustomerID(customerID uuid.UUID) data.RecoveryCodes {
+	q.where(func(c *data.RecoveryCode) bool { return c.CustomerID == customerID })
+	return q
+}
+
+func (q *recoveryCodesQ) WhereUnused() data.RecoveryCodes {
+	q.where(func(c *data.RecoveryCode) bool { return c.UsedAt == nil })
+	return q
+}
+
+func (q *recoveryCodesQ) Use(customerID uuid.UUID, codeHash string, at time.Time) (bool, error) {
+	var used bool
+
+	err := q.q.write(func(s *store) error {
+		for _, c := range s.recoveryCodes.rows {
+			if c.CustomerID == customerID && c.CodeHash == codeHash && c.UsedAt == nil {
+				s.recoveryCodes.modify(s, c.ID, func(c *data.RecoveryCode) { c.UsedAt = &at })
+				used = true
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	return used, err
+}
+
+func (q *recoveryCodesQ) DeleteByCustomer(customerID uuid.UUID) error {
+	return q.q.write(func(s *store) error {
+		return s.recoveryCodes.deleteWhere(s, func(c *data.RecoveryCode) bool { return c.CustomerID == customerID })
+	})
+}
+
+func checkRecoveryCode(s *store, code *data.RecoveryCode) error {
+	if _, ok := s.customers.get(code.CustomerID); !ok {
+		return foreignKeyViolation(recoveryCodesTableName, "recovery_codes_customer_fkey_fkey")
+	}
+
+	for _, c := range s.recoveryCodes.rows {
+		if c.ID != code.ID && c.CustomerID == code.CustomerID && c.CodeHash == code.CodeHash {
+			return uniqueViolation("unique_recovery_code_per_customer")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	accessTokensTableName = "access_tokens"
+
+	tokenHashColumnName  = "token_hash"
+	lastUsedAtColumnName = "last_used_at"
+	revokedAtColumnName  = "revoked_at"
+)
+
+type accessTokensQ struct {
+	*crudQ[*data.AccessToken, uuid.UUID]
+}
+
+func NewAccessTokensQ(db *pgdb.DB) data.AccessTokens {
+	return &accessTokensQ{
+		newCRUDQ[*data.AccessToken, uuid.UUID](db, accessTokensTableName),
+	}
+}
+
+func (q *accessTokensQ) WhereID(id uuid.UUID) data.AccessTokens {
+	q.sel = q.sel.Where(sq.Eq{idColumnName: id})
+	return q
+}
+
+func (q *accessTokensQ) WhereCustomerID(customerID uuid.UUID) data.AccessTokens {
+	q.sel = q.sel.Where(sq.Eq{customerFkeyColumnName: customerID})
+	return q
+}
+
+func (q *accessTokensQ) WhereTokenHash(tokenHash string) data.AccessTokens {
+	q.sel = q.sel.Where(sq.Eq{tokenHashColumnName: tokenHash})
+	return q
+}
+
+func (q *accessTokensQ) OrderBy(orderBy ...string) data.AccessTokens {
+	q.sel = q.sel.OrderBy(orderBy...)
+	return q
+}
+
+func (q *accessTokensQ) Revoke(id uuid.UUID, at time.Time) error {
+	return q.db.Exec(
+		sq.Update(accessTokensTableName).
+			Set(revokedAtColumnName, at).
+			Where(sq.Eq{idColumnName: id, revokedAtColumnName: nil}),
+	)
+}
+
+func (q *accessTokensQ) MarkUsed(id uuid.UUID, at time.Time) error {
+	return q.db.Exec(
+		sq.Update(accessTokensTableName).
+			Set(lastUsedAtColumnName, at).
+			Where(sq.Eq{idColumnName: id}),
+	)
+}
@@ -29,6 +29,11 @@ func (q *accountsQ) WhereID(id ...uuid.UUID) data.Accounts {
 	return q
 }
 
+func (q *accountsQ) ForUpdate() data.Accounts {
+	q.sel = q.sel.Suffix("FOR UPDATE")
+	return q
+}
+
 func (q *accountsQ) LDelete(id uuid.UUID) error {
 	return q.db.Exec(
 		sq.Update(accountsTableName).
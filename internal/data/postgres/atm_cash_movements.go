@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	atmCashMovementsTableName = "atm_cash_movements"
+
+	movedAtColumnName = "moved_at"
+)
+
+type atmCashMovementsQ struct {
+	*crudQ[*data.ATMCashMovement, uuid.UUID]
+}
+
+func NewATMCashMovementsQ(db *pgdb.DB) data.ATMCashMovements {
+	return &atmCashMovementsQ{
+		newCRUDQ[*data.ATMCashMovement, uuid.UUID](db, atmCashMovementsTableName),
+	}
+}
+
+func (q *atmCashMovementsQ) WhereATMID(atmID uuid.UUID) data.ATMCashMovements {
+	q.sel = q.sel.Where(sq.Eq{atmFkeyColumnName: atmID})
+	return q
+}
+
+func (q *atmCashMovementsQ) WhereMovedFrom(from time.Time) data.ATMCashMovements {
+	q.sel = q.sel.Where(sq.GtOrEq{movedAtColumnName: from})
+	return q
+}
+
+func (q *atmCashMovementsQ) Sum() (int, error) {
+	var result int
+
+	if err := q.db.Get(&result,
+		sq.Select(fmt.Sprintf("COALESCE(SUM(%s), 0)", amountColumnName)).
+			FromSelect(q.sel, "filtered_select"),
+	); err != nil {
+		return 0, fmt.Errorf("failed to sum ATM cash movements: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	atmReconciliationsTableName = "atm_reconciliations"
+
+	businessDateColumnName = "business_date"
+	discrepancyColumnName  = "discrepancy"
+)
+
+type atmReconciliationsQ struct {
+	*crudQ[*data.ATMReconciliation, uuid.UUID]
+}
+
+func NewATMReconciliationsQ(db *pgdb.DB) data.ATMReconciliations {
+	return &atmReconciliationsQ{
+		newCRUDQ[*data.ATMReconciliation, uuid.UUID](db, atmReconciliationsTableName),
+	}
+}
+
+func (q *atmReconciliationsQ) WhereID(id ...uuid.UUID) data.ATMReconciliations {
+	q.sel = q.sel.Where(sq.Eq{idColumnName: id})
+	return q
+}
+
+func (q *atmReconciliationsQ) WhereATMID(atmID uuid.UUID) data.ATMReconciliations {
+	q.sel = q.sel.Where(sq.Eq{atmFkeyColumnName: atmID})
+	return q
+}
+
+func (q *atmReconciliationsQ) WhereBusinessDateBetween(from, to time.Time) data.ATMReconciliations {
+	q.sel = q.sel.Where(sq.And{
+		sq.GtOrEq{businessDateColumnName: from},
+		sq.LtOrEq{businessDateColumnName: to},
+	})
+	return q
+}
+
+func (q *atmReconciliationsQ) WhereBusinessDateAfter(date time.Time) data.ATMReconciliations {
+	q.sel = q.sel.Where(sq.Gt{businessDateColumnName: date})
+	return q
+}
+
+func (q *atmReconciliationsQ) WhereDiscrepancy() data.ATMReconciliations {
+	q.sel = q.sel.Where(sq.NotEq{discrepancyColumnName: 0})
+	return q
+}
+
+func (q *atmReconciliationsQ) OrderBy(orderBy ...string) data.ATMReconciliations {
+	q.sel = q.sel.OrderBy(orderBy...)
+	return q
+}
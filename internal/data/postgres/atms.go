@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	atmsTableName    = "atms"
+	atmKeysTableName = "atm_keys"
+
+	statusColumnName     = "status"
+	atmFkeyColumnName    = "atm_fkey"
+	validFromColumnName  = "valid_from"
+	validUntilColumnName = "valid_until"
+)
+
+type atmsQ struct {
+	*crudQ[*data.ATM, uuid.UUID]
+}
+
+func NewATMsQ(db *pgdb.DB) data.ATMs {
+	return &atmsQ{
+		newCRUDQ[*data.ATM, uuid.UUID](db, atmsTableName),
+	}
+}
+
+func (q *atmsQ) WhereID(id ...uuid.UUID) data.ATMs {
+	q.sel = q.sel.Where(sq.Eq{idColumnName: id})
+	return q
+}
+
+func (q *atmsQ) WhereStatus(status data.ATMStatus) data.ATMs {
+	q.sel = q.sel.Where(sq.Eq{statusColumnName: status})
+	return q
+}
+
+func (q *atmsQ) ForUpdate() data.ATMs {
+	q.sel = q.sel.Suffix("FOR UPDATE")
+	return q
+}
+
+func (q *atmsQ) OrderBy(orderBy ...string) data.ATMs {
+	q.sel = q.sel.OrderBy(orderBy...)
+	return q
+}
+
+type atmKeysQ struct {
+	*crudQ[*data.ATMKey, uuid.UUID]
+}
+
+func NewATMKeysQ(db *pgdb.DB) data.ATMKeys {
+	return &atmKeysQ{
+		newCRUDQ[*data.ATMKey, uuid.UUID](db, atmKeysTableName),
+	}
+}
+
+func (q *atmKeysQ) WhereID(id ...uuid.UUID) data.ATMKeys {
+	q.sel = q.sel.Where(sq.Eq{idColumnName: id})
+	return q
+}
+
+func (q *atmKeysQ) WhereATMID(atmID uuid.UUID) data.ATMKeys {
+	q.sel = q.sel.Where(sq.Eq{atmFkeyColumnName: atmID})
+	return q
+}
+
+func (q *atmKeysQ) WhereValidAt(t time.Time) data.ATMKeys {
+	q.sel = q.sel.
+		Where(sq.LtOrEq{validFromColumnName: t}).
+		Where(sq.Or{
+			sq.Eq{validUntilColumnName: nil},
+			sq.Gt{validUntilColumnName: t},
+		})
+	return q
+}
+
+func (q *atmKeysQ) OrderBy(orderBy ...string) data.ATMKeys {
+	q.sel = q.sel.OrderBy(orderBy...)
+	return q
+}
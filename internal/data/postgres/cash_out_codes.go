@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const cashOutCodesTableName = "cash_out_codes"
+
+type cashOutCodesQ struct {
+	*crudQ[*data.CashOutCode, uuid.UUID]
+}
+
+func NewCashOutCodesQ(db *pgdb.DB) data.CashOutCodes {
+	return &cashOutCodesQ{
+		newCRUDQ[*data.CashOutCode, uuid.UUID](db, cashOutCodesTableName),
+	}
+}
+
+func (q *cashOutCodesQ) WhereID(id ...uuid.UUID) data.CashOutCodes {
+	q.sel = q.sel.Where(sq.Eq{idColumnName: id})
+	return q
+}
+
+func (q *cashOutCodesQ) WhereCodeHash(codeHash string) data.CashOutCodes {
+	q.sel = q.sel.Where(sq.Eq{codeHashColumnName: codeHash})
+	return q
+}
+
+func (q *cashOutCodesQ) WhereAccountID(accountID uuid.UUID) data.CashOutCodes {
+	q.sel = q.sel.Where(sq.Eq{accountFkeyColumnName: accountID})
+	return q
+}
+
+func (q *cashOutCodesQ) WhereStatus(status data.CashOutCodeStatus) data.CashOutCodes {
+	q.sel = q.sel.Where(sq.Eq{statusColumnName: status})
+	return q
+}
+
+func (q *cashOutCodesQ) WhereExpiredAt(t time.Time) data.CashOutCodes {
+	q.sel = q.sel.Where(sq.LtOrEq{expiresAtColumnName: t})
+	return q
+}
+
+func (q *cashOutCodesQ) ForUpdate() data.CashOutCodes {
+	q.sel = q.sel.Suffix("FOR UPDATE")
+	return q
+}
+
+func (q *cashOutCodesQ) OrderBy(orderBy ...string) data.CashOutCodes {
+	q.sel = q.sel.OrderBy(orderBy...)
+	return q
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	idempotencyKeysTableName = "idempotency_keys"
+
+	keyColumnName = "key"
+)
+
+type idempotencyKeysQ struct {
+	*crudQ[*data.IdempotencyKey, uuid.UUID]
+}
+
+func NewIdempotencyKeysQ(db *pgdb.DB) data.IdempotencyKeys {
+	return &idempotencyKeysQ{
+		newCRUDQ[*data.IdempotencyKey, uuid.UUID](db, idempotencyKeysTableName),
+	}
+}
+
+func (q *idempotencyKeysQ) WhereCustomerID(customerID uuid.UUID) data.IdempotencyKeys {
+	q.sel = q.sel.Where(sq.Eq{customerFkeyColumnName: customerID})
+	return q
+}
+
+func (q *idempotencyKeysQ) WhereKey(key string) data.IdempotencyKeys {
+	q.sel = q.sel.Where(sq.Eq{keyColumnName: key})
+	return q
+}
+
+func (q *idempotencyKeysQ) Reserve(key *data.IdempotencyKey) (bool, error) {
+	var id uuid.UUID
+
+	err := q.db.Get(&id,
+		sq.Insert(idempotencyKeysTableName).
+			SetMap(structs.Map(key)).
+			Suffix(fmt.Sprintf("ON CONFLICT (%s, %s) DO NOTHING RETURNING %s",
+				customerFkeyColumnName, keyColumnName, idColumnName)),
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to insert idempotency key: %w", err)
+	}
+
+	key.ID = id
+	return true, nil
+}
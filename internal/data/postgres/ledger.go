@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	journalEntriesTableName = "journal_entries"
+	postingsTableName       = "postings"
+
+	journalEntryFkeyColumnName = "journal_entry_fkey"
+	systemAccountColumnName    = "system_account"
+	sideColumnName             = "side"
+	amountColumnName           = "amount"
+)
+
+type ledgerQ struct {
+	db *pgdb.DB
+}
+
+func NewLedgerQ(db *pgdb.DB) data.Ledger {
+	return &ledgerQ{
+		db: db,
+	}
+}
+
+// Post inserts the journal entry and all its postings. The balance of the entry
+// is enforced by the deferred journal_entry_balanced constraint trigger.
+func (q *ledgerQ) Post(entry *data.JournalEntry, postings ...*data.Posting) error {
+	if err := q.db.Get(entry.GetID(),
+		sq.Insert(journalEntriesTableName).
+			SetMap(structs.Map(entry)).
+			Suffix(fmt.Sprintf("RETURNING %s", idColumnName)),
+	); err != nil {
+		return fmt.Errorf("failed to insert journal entry: %w", err)
+	}
+
+	for _, posting := range postings {
+		posting.JournalEntryID = entry.ID
+
+		if err := q.db.Get(posting.GetID(),
+			sq.Insert(postingsTableName).
+				SetMap(structs.Map(posting)).
+				Suffix(fmt.Sprintf("RETURNING %s", idColumnName)),
+		); err != nil {
+			return fmt.Errorf("failed to insert posting: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (q *ledgerQ) AccountBalance(accountID uuid.UUID) (int, error) {
+	return q.balance(sq.Eq{accountFkeyColumnName: accountID}, data.Credit)
+}
+
+func (q *ledgerQ) SystemAccountBalance(account data.SystemAccount) (int, error) {
+	return q.balance(sq.Eq{systemAccountColumnName: account}, data.Debit)
+}
+
+// balance sums the postings matching the predicate, where the postings on the
+// normal side increase the balance and the others decrease it.
+func (q *ledgerQ) balance(pred sq.Eq, normal data.PostingSide) (int, error) {
+	var result int
+
+	if err := q.db.Get(&result,
+		sq.Select(fmt.Sprintf(
+			"COALESCE(SUM(CASE WHEN %s = %d THEN %s ELSE -%s END), 0)",
+			sideColumnName, normal, amountColumnName, amountColumnName,
+		)).
+			From(postingsTableName).
+			Where(pred),
+	); err != nil {
+		return 0, fmt.Errorf("failed to sum postings: %w", err)
+	}
+
+	return result, nil
+}
+
+func (q *ledgerQ) Postings() data.Postings {
+	return NewPostingsQ(q.db)
+}
+
+type postingsQ struct {
+	*crudQ[*data.Posting, uuid.UUID]
+}
+
+func NewPostingsQ(db *pgdb.DB) data.Postings {
+	return &postingsQ{
+		newCRUDQ[*data.Posting, uuid.UUID](db, postingsTableName),
+	}
+}
+
+func (q *postingsQ) WhereJournalEntry(journalEntryID uuid.UUID) data.Postings {
+	q.sel = q.sel.Where(sq.Eq{journalEntryFkeyColumnName: journalEntryID})
+	return q
+}
+
+func (q *postingsQ) WhereAccount(accountID uuid.UUID) data.Postings {
+	q.sel = q.sel.Where(sq.Eq{accountFkeyColumnName: accountID})
+	return q
+}
+
+func (q *postingsQ) WhereSystemAccount(account data.SystemAccount) data.Postings {
+	q.sel = q.sel.Where(sq.Eq{systemAccountColumnName: account})
+	return q
+}
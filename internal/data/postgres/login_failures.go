@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	loginFailuresTableName = "login_failures"
+
+	ipColumnName        = "ip"
+	emailHashColumnName = "email_hash"
+)
+
+type loginFailuresQ struct {
+	*crudQ[*data.LoginFailure, uuid.UUID]
+}
+
+func NewLoginFailuresQ(db *pgdb.DB) data.LoginFailures {
+	return &loginFailuresQ{
+		newCRUDQ[*data.LoginFailure, uuid.UUID](db, loginFailuresTableName),
+	}
+}
+
+func (q *loginFailuresQ) WhereCustomerID(customerID uuid.UUID) data.LoginFailures {
+	q.sel = q.sel.Where(sq.Eq{customerFkeyColumnName: customerID})
+	return q
+}
+
+func (q *loginFailuresQ) WhereEmailHash(emailHash string) data.LoginFailures {
+	q.sel = q.sel.Where(sq.Eq{emailHashColumnName: emailHash})
+	return q
+}
+
+func (q *loginFailuresQ) WhereIP(ip string) data.LoginFailures {
+	q.sel = q.sel.Where(sq.Eq{ipColumnName: ip})
+	return q
+}
+
+func (q *loginFailuresQ) WhereCreatedAfter(t time.Time) data.LoginFailures {
+	q.sel = q.sel.Where(sq.Gt{createAtColumnName: t})
+	return q
+}
+
+func (q *loginFailuresQ) OrderBy(orderBy ...string) data.LoginFailures {
+	q.sel = q.sel.OrderBy(orderBy...)
+	return q
+}
+
+func (q *loginFailuresQ) DeleteByEmailHash(emailHash string) error {
+	return q.db.Exec(
+		sq.Delete(loginFailuresTableName).
+			Where(sq.Eq{emailHashColumnName: emailHash}),
+	)
+}
+
+func (q *loginFailuresQ) DeleteByIP(ip string) error {
+	return q.db.Exec(
+		sq.Delete(loginFailuresTableName).
+			Where(sq.Eq{ipColumnName: ip}),
+	)
+}
+
+func (q *loginFailuresQ) DeleteCreatedBefore(t time.Time) error {
+	return q.db.Exec(
+		sq.Delete(loginFailuresTableName).
+			Where(sq.Lt{createAtColumnName: t}),
+	)
+}
+
+// Lock takes the transaction advisory locks of the email hash and of the IP,
+// always in this order, so the attempts don't deadlock each other.
+func (q *loginFailuresQ) Lock(emailHash, ip string) error {
+	for _, key := range []string{emailHashColumnName + ":" + emailHash, ipColumnName + ":" + ip} {
+		if err := q.db.ExecRaw("SELECT pg_advisory_xact_lock(hashtext($1))", key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
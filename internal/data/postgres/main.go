@@ -2,12 +2,26 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/lib/pq"
 	"gitlab.com/distributed_lab/kit/pgdb"
 
 	"github.com/omegatymbjiep/ilab1/internal/data"
 )
 
+const (
+	// maxTransactionAttempts limits the number of times the transaction is
+	// executed when it fails with a serialization failure or a deadlock.
+	maxTransactionAttempts = 5
+	retryBackoff           = 10 * time.Millisecond
+
+	serializationFailureCode pq.ErrorCode = "40001"
+	deadlockDetectedCode     pq.ErrorCode = "40P01"
+)
+
 type mainQ struct {
 	db *pgdb.DB
 }
@@ -18,6 +32,10 @@ func NewMainQ(db *pgdb.DB) data.MainQ {
 	}
 }
 
+func (q *mainQ) New() data.MainQ {
+	return NewMainQ(q.db.Clone())
+}
+
 func (q *mainQ) Customers() data.Customers {
 	return NewCustomersQ(q.db)
 }
@@ -42,6 +60,83 @@ func (q *mainQ) AuditLogs() data.AuditLogs {
 	return NewAuditLogsQ(q.db)
 }
 
-func (q *mainQ) IsolatedTransaction(isolationLevel sql.IsolationLevel, fn func() error) error {
-	return q.db.TransactionWithOptions(&sql.TxOptions{Isolation: isolationLevel}, fn)
+func (q *mainQ) Ledger() data.Ledger {
+	return NewLedgerQ(q.db)
+}
+
+func (q *mainQ) IdempotencyKeys() data.IdempotencyKeys {
+	return NewIdempotencyKeysQ(q.db)
+}
+
+func (q *mainQ) AccessTokens() data.AccessTokens {
+	return NewAccessTokensQ(q.db)
+}
+
+func (q *mainQ) Sessions() data.Sessions {
+	return NewSessionsQ(q.db)
+}
+
+func (q *mainQ) TOTPCredentials() data.TOTPCredentials {
+	return NewTOTPCredentialsQ(q.db)
+}
+
+func (q *mainQ) RecoveryCodes() data.RecoveryCodes {
+	return NewRecoveryCodesQ(q.db)
+}
+
+func (q *mainQ) LoginFailures() data.LoginFailures {
+	return NewLoginFailuresQ(q.db)
+}
+
+func (q *mainQ) ATMs() data.ATMs {
+	return NewATMsQ(q.db)
+}
+
+func (q *mainQ) ATMKeys() data.ATMKeys {
+	return NewATMKeysQ(q.db)
+}
+
+func (q *mainQ) CashOutCodes() data.CashOutCodes {
+	return NewCashOutCodesQ(q.db)
+}
+
+func (q *mainQ) ATMReconciliations() data.ATMReconciliations {
+	return NewATMReconciliationsQ(q.db)
+}
+
+func (q *mainQ) ATMCashMovements() data.ATMCashMovements {
+	return NewATMCashMovementsQ(q.db)
+}
+
+func (q *mainQ) ReportJobs() data.ReportJobs {
+	return NewReportJobsQ(q.db)
+}
+
+func (q *mainQ) ReportFiles() data.ReportFiles {
+	return NewReportFilesQ(q.db)
+}
+
+// IsolatedTransaction runs fn in a transaction with the given isolation level,
+// the whole transaction is retried when it fails with a serialization failure
+// or a deadlock, so fn must not have side effects outside the database.
+func (q *mainQ) IsolatedTransaction(isolationLevel sql.IsolationLevel, fn func() error) (err error) {
+	for attempt := 1; attempt <= maxTransactionAttempts; attempt++ {
+		err = q.db.TransactionWithOptions(&sql.TxOptions{Isolation: isolationLevel}, fn)
+		if !isRetryableError(err) {
+			return err
+		}
+
+		time.Sleep(time.Duration(attempt) * retryBackoff)
+	}
+
+	return fmt.Errorf("transaction failed after %d attempts: %w", maxTransactionAttempts, err)
+}
+
+func isRetryableError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	return pqErr.Code == serializationFailureCode || pqErr.Code == deadlockDetectedCode
 }
@@ -1,16 +1,31 @@
 package postgres
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
 	migrate "github.com/rubenv/sql-migrate"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gitlab.com/distributed_lab/kit/pgdb"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/omegatymbjiep/ilab1/internal/assets"
+	"github.com/omegatymbjiep/ilab1/internal/atm"
 	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
 )
 
 var _ = assets.Migrations
@@ -350,6 +365,84 @@ func TestTransactionsFilters(t *testing.T) {
 	assert.GreaterOrEqual(t, len(accountTxns), 3, "expected at least three transactions involving account1")
 }
 
+func TestTransactionsCreatedBetween(t *testing.T) {
+	db := newTestMainQ(t)
+	accounts := db.Accounts()
+	transactions := db.Transactions()
+
+	account := &data.Account{Name: "account"}
+	require.NoError(t, accounts.Insert(account))
+
+	for _, amount := range []uint{100, 200, 300} {
+		require.NoError(t, transactions.Insert(&data.Transaction{
+			Type:   data.WithdrawalTransaction,
+			Amount: amount,
+			Sender: account.ID,
+		}))
+	}
+
+	all, err := transactions.WhereAccount(account.ID).OrderBy("created_at").Select()
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+
+	amounts := func(list []*data.Transaction) []uint {
+		result := make([]uint, len(list))
+		for i, tx := range list {
+			result[i] = tx.Amount
+		}
+		return result
+	}
+
+	from, err := transactions.WhereAccount(account.ID).WhereCreatedFrom(all[1].CreatedAt).OrderBy("created_at").Select()
+	require.NoError(t, err)
+	assert.Equal(t, []uint{200, 300}, amounts(from), "from is inclusive")
+
+	before, err := transactions.WhereAccount(account.ID).WhereCreatedBefore(all[2].CreatedAt).OrderBy("created_at").Select()
+	require.NoError(t, err)
+	assert.Equal(t, []uint{100, 200}, amounts(before), "to is exclusive")
+
+	between, err := transactions.WhereAccount(account.ID).
+		WhereCreatedFrom(all[1].CreatedAt).
+		WhereCreatedBefore(all[2].CreatedAt).
+		Select()
+	require.NoError(t, err)
+	assert.Equal(t, []uint{200}, amounts(between))
+}
+
+func TestTransactionsEach(t *testing.T) {
+	db := newTestMainQ(t)
+	transactions := db.Transactions()
+
+	account := &data.Account{Name: "account"}
+	require.NoError(t, db.Accounts().Insert(account))
+
+	for _, amount := range []uint{100, 200, 300} {
+		require.NoError(t, transactions.Insert(&data.Transaction{
+			Type:   data.WithdrawalTransaction,
+			Amount: amount,
+			Sender: account.ID,
+		}))
+	}
+
+	var amounts []uint
+	err := transactions.WhereAccount(account.ID).OrderBy("created_at DESC").Each(func(tx *data.Transaction) error {
+		assert.Equal(t, account.ID, tx.Sender)
+		amounts = append(amounts, tx.Amount)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{300, 200, 100}, amounts)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = transactions.WhereAccount(account.ID).Each(func(*data.Transaction) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls, "stops at the first error")
+}
+
 func TestCustomersAccountsCRUD(t *testing.T) {
 	db := newTestMainQ(t)
 	customers := db.Customers()
@@ -468,3 +561,544 @@ func TestTransactionRollback(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, len(initialAccounts), len(afterRollbackAccounts), "rollback should not persist changes")
 }
+
+func TestLedger(t *testing.T) {
+	db := newTestMainQ(t)
+
+	account := &data.Account{Name: "ledger account"}
+	require.NoError(t, db.Accounts().Insert(account))
+
+	transaction := &data.Transaction{
+		Type:         data.DepositTransaction,
+		Amount:       300,
+		Recipient:    account.ID,
+		ATMSignature: "ledger_signature",
+	}
+	require.NoError(t, db.Transactions().Insert(transaction))
+
+	atmCash := data.SystemAccountATMCash
+
+	err := db.Transaction(func() error {
+		return db.Ledger().Post(
+			&data.JournalEntry{TransactionID: &transaction.ID, Description: "deposit"},
+			&data.Posting{SystemAccount: &atmCash, Side: data.Debit, Amount: 300},
+			&data.Posting{AccountID: &account.ID, Side: data.Credit, Amount: 300},
+		)
+	})
+	require.NoError(t, err)
+
+	balance, err := db.Ledger().AccountBalance(account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 300, balance)
+
+	balance, err = db.Ledger().SystemAccountBalance(data.SystemAccountATMCash)
+	require.NoError(t, err)
+	assert.Equal(t, 300, balance)
+
+	postings, err := db.Ledger().Postings().WhereAccount(account.ID).Select()
+	require.NoError(t, err)
+	require.Len(t, postings, 1)
+	assert.Equal(t, data.Credit, postings[0].Side)
+
+	// Unbalanced entries are rejected as a whole
+	err = db.Transaction(func() error {
+		return db.Ledger().Post(
+			&data.JournalEntry{Description: "unbalanced"},
+			&data.Posting{SystemAccount: &atmCash, Side: data.Debit, Amount: 100},
+			&data.Posting{AccountID: &account.ID, Side: data.Credit, Amount: 50},
+		)
+	})
+	assert.Error(t, err)
+
+	// A posting must reference exactly one of the account and the system account
+	err = db.Transaction(func() error {
+		return db.Ledger().Post(
+			&data.JournalEntry{Description: "ambiguous"},
+			&data.Posting{AccountID: &account.ID, SystemAccount: &atmCash, Side: data.Debit, Amount: 100},
+			&data.Posting{AccountID: &account.ID, Side: data.Credit, Amount: 100},
+		)
+	})
+	assert.Error(t, err)
+
+	postings, err = db.Ledger().Postings().Select()
+	require.NoError(t, err)
+	assert.Len(t, postings, 2, "rejected entries must not leave postings behind")
+}
+
+func TestConcurrentTransfersForUpdate(t *testing.T) {
+	db := newTestMainQ(t)
+
+	first := &data.Account{Name: "first", Balance: 1000}
+	require.NoError(t, db.Accounts().Insert(first))
+	second := &data.Account{Name: "second", Balance: 1000}
+	require.NoError(t, db.Accounts().Insert(second))
+
+	// transfer locks both accounts in the ascending order of their IDs
+	transfer := func(from, to uuid.UUID, amount int) error {
+		tx := db.New()
+
+		return tx.IsolatedTransaction(sql.LevelRepeatableRead, func() error {
+			ids := []uuid.UUID{from, to}
+			if bytes.Compare(ids[0][:], ids[1][:]) > 0 {
+				ids[0], ids[1] = ids[1], ids[0]
+			}
+
+			accounts := make(map[uuid.UUID]*data.Account)
+			for _, id := range ids {
+				account := new(data.Account)
+				if _, err := tx.Accounts().WhereID(id).ForUpdate().Get(account); err != nil {
+					return err
+				}
+				accounts[id] = account
+			}
+
+			accounts[from].Balance -= amount
+			accounts[to].Balance += amount
+
+			if err := tx.Accounts().Update(accounts[from]); err != nil {
+				return err
+			}
+
+			return tx.Accounts().Update(accounts[to])
+		})
+	}
+
+	const transfers = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < transfers; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, transfer(first.ID, second.ID, 3))
+		}()
+
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, transfer(second.ID, first.ID, 1))
+		}()
+	}
+	wg.Wait()
+
+	ok, err := db.Accounts().WhereID(first.ID).Get(first)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 1000-2*transfers, first.Balance)
+
+	ok, err = db.Accounts().WhereID(second.ID).Get(second)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 1000+2*transfers, second.Balance)
+}
+
+func TestATMKeys(t *testing.T) {
+	db := newTestMainQ(t)
+
+	machine := &data.ATM{Location: "lobby", Status: data.ATMStatusActive}
+	require.NoError(t, db.ATMs().Insert(machine))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expired := now.Add(-time.Hour)
+
+	keys := []*data.ATMKey{
+		{ATMID: machine.ID, PublicKey: "expired", SignatureFormat: atm.SignatureFormatBase64, ValidFrom: now.Add(-2 * time.Hour), ValidUntil: &expired},
+		{ATMID: machine.ID, PublicKey: "current", SignatureFormat: atm.SignatureFormatBase64, ValidFrom: now.Add(-time.Minute)},
+		{ATMID: machine.ID, PublicKey: "future", SignatureFormat: atm.SignatureFormatBase64, ValidFrom: now.Add(time.Hour)},
+	}
+	for _, key := range keys {
+		require.NoError(t, db.ATMKeys().Insert(key))
+	}
+
+	valid, err := db.ATMKeys().WhereATMID(machine.ID).WhereValidAt(now).Select()
+	require.NoError(t, err)
+	require.Len(t, valid, 1)
+	assert.Equal(t, "current", valid[0].PublicKey)
+
+	require.Error(t, db.ATMKeys().Insert(&data.ATMKey{ATMID: uuid.New(), PublicKey: "orphan", SignatureFormat: atm.SignatureFormatBase64, ValidFrom: now}),
+		"key of an unknown ATM must be rejected")
+
+	account := &data.Account{Name: "account"}
+	require.NoError(t, db.Accounts().Insert(account))
+
+	require.NoError(t, db.Transactions().Insert(&data.Transaction{
+		Type:         data.DepositTransaction,
+		Amount:       100,
+		Recipient:    account.ID,
+		ATMSignature: "signature",
+		ATMID:        &machine.ID,
+	}))
+
+	require.Error(t, db.ATMs().Delete(machine.ID), "ATM with deposits must not be deleted")
+
+	disabled, err := db.ATMs().WhereStatus(data.ATMStatusDisabled).Count()
+	require.NoError(t, err)
+	assert.Zero(t, disabled)
+}
+
+func TestCashOutCodes(t *testing.T) {
+	db := newTestMainQ(t)
+
+	customer := &data.Customer{Email: "cash-out@example.com", Username: "cash-out", PasswordHash: "hash"}
+	require.NoError(t, db.Customers().Insert(customer))
+
+	account := &data.Account{Name: "account"}
+	require.NoError(t, db.Accounts().Insert(account))
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	newCode := func(codeHash string, expiresAt time.Time) *data.CashOutCode {
+		return &data.CashOutCode{
+			CustomerID: customer.ID,
+			AccountID:  account.ID,
+			Amount:     100,
+			CodeHash:   codeHash,
+			Status:     data.CashOutCodePending,
+			ExpiresAt:  expiresAt,
+		}
+	}
+
+	code := newCode("hash", now.Add(time.Minute))
+	require.NoError(t, db.CashOutCodes().Insert(code))
+
+	err := db.CashOutCodes().Insert(newCode("hash", now.Add(time.Minute)))
+	require.Error(t, err, "pending codes must not collide")
+	assert.Contains(t, err.Error(), "duplicate key value violates unique constraint")
+
+	expired := newCode("expired", now.Add(-time.Minute))
+	require.NoError(t, db.CashOutCodes().Insert(expired))
+
+	due, err := db.CashOutCodes().WhereStatus(data.CashOutCodePending).WhereExpiredAt(now).Select()
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, expired.ID, due[0].ID)
+
+	code.Status = data.CashOutCodeCompleted
+	require.Error(t, db.CashOutCodes().Update(code), "completed code must reference the transaction")
+
+	code.Status = data.CashOutCodeExpired
+	require.NoError(t, db.CashOutCodes().Update(code))
+	require.NoError(t, db.CashOutCodes().Insert(newCode("hash", now.Add(time.Minute))),
+		"hash of the code no longer pending may be reused")
+
+	require.Error(t, db.Accounts().Delete(account.ID), "account with cash-out codes must not be deleted")
+
+	pending, err := db.CashOutCodes().WhereAccountID(account.ID).WhereStatus(data.CashOutCodePending).Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), pending)
+}
+
+func TestATMReconciliations(t *testing.T) {
+	db := newTestMainQ(t)
+
+	atm := &data.ATM{Location: "lobby", Status: data.ATMStatusActive}
+	require.NoError(t, db.ATMs().Insert(atm))
+
+	day := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	newReconciliation := func(businessDate time.Time, expected, counted int) *data.ATMReconciliation {
+		return &data.ATMReconciliation{
+			ATMID:        atm.ID,
+			BusinessDate: businessDate,
+			Expected:     expected,
+			Counted:      counted,
+			Discrepancy:  counted - expected,
+			ATMNonce:     uuid.NewString(),
+			ATMSignature: "signature",
+		}
+	}
+
+	require.NoError(t, db.ATMReconciliations().Insert(newReconciliation(day, 1000, 1000)))
+	require.NoError(t, db.ATMReconciliations().Insert(newReconciliation(day.AddDate(0, 0, 1), 1000, 900)))
+
+	err := db.ATMReconciliations().Insert(newReconciliation(day, 1000, 1000))
+	require.Error(t, err, "ATM is reconciled once per business date")
+	assert.Contains(t, err.Error(), "duplicate key value violates unique constraint")
+
+	invalid := newReconciliation(day.AddDate(0, 0, 2), 1000, 900)
+	invalid.Discrepancy = 100
+	require.Error(t, db.ATMReconciliations().Insert(invalid), "discrepancy must be counted - expected")
+
+	require.Error(t, db.ATMReconciliations().Insert(newReconciliation(day.AddDate(0, 0, 2), 1000, -1)),
+		"counted cash must not be negative")
+
+	discrepancies, err := db.ATMReconciliations().
+		WhereATMID(atm.ID).
+		WhereBusinessDateBetween(day, day.AddDate(0, 0, 1)).
+		WhereDiscrepancy().
+		Select()
+	require.NoError(t, err)
+	require.Len(t, discrepancies, 1)
+	assert.Equal(t, -100, discrepancies[0].Discrepancy)
+	assert.True(t, discrepancies[0].BusinessDate.Equal(day.AddDate(0, 0, 1)))
+
+	later, err := db.ATMReconciliations().WhereATMID(atm.ID).WhereBusinessDateAfter(day).Count()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, later)
+
+	require.Error(t, db.ATMs().Delete(atm.ID), "reconciled ATM must not be deleted")
+}
+
+func TestATMCashMovements(t *testing.T) {
+	db := newTestMainQ(t)
+
+	atm := &data.ATM{Location: "movements", Status: data.ATMStatusActive}
+	require.NoError(t, db.ATMs().Insert(atm))
+
+	endOfDay := time.Now().UTC().Truncate(24 * time.Hour)
+
+	for _, movement := range []*data.ATMCashMovement{
+		{ATMID: atm.ID, Amount: 5000, MovedAt: endOfDay.Add(-time.Hour)},
+		{ATMID: atm.ID, Amount: 1000, MovedAt: endOfDay},
+		{ATMID: atm.ID, Amount: -400, MovedAt: endOfDay.Add(time.Hour)},
+	} {
+		require.NoError(t, db.ATMCashMovements().Insert(movement))
+	}
+
+	require.Error(t, db.ATMCashMovements().Insert(&data.ATMCashMovement{ATMID: uuid.New(), Amount: 1, MovedAt: endOfDay}),
+		"movement must reference the ATM")
+
+	total, err := db.ATMCashMovements().WhereATMID(atm.ID).Sum()
+	require.NoError(t, err)
+	assert.Equal(t, 5600, total)
+
+	since, err := db.ATMCashMovements().WhereATMID(atm.ID).WhereMovedFrom(endOfDay).Sum()
+	require.NoError(t, err)
+	assert.Equal(t, 600, since)
+
+	none, err := db.ATMCashMovements().WhereATMID(uuid.New()).Sum()
+	require.NoError(t, err)
+	assert.Zero(t, none)
+
+	require.Error(t, db.ATMs().Delete(atm.ID), "ATM with cash movements must not be deleted")
+}
+
+func TestLoginFailures(t *testing.T) {
+	db := newTestMainQ(t)
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		require.NoError(t, db.LoginFailures().Insert(&data.LoginFailure{EmailHash: "hash", IP: ip}))
+	}
+
+	require.NoError(t, db.LoginFailures().Lock("hash", "10.0.0.1"))
+
+	require.NoError(t, db.LoginFailures().DeleteCreatedBefore(time.Now().Add(-time.Hour)))
+
+	failures, err := db.LoginFailures().WhereEmailHash("hash").Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), failures, "recent failures are kept")
+
+	require.NoError(t, db.LoginFailures().DeleteCreatedBefore(time.Now().Add(time.Hour)))
+
+	failures, err = db.LoginFailures().WhereEmailHash("hash").Count()
+	require.NoError(t, err)
+	assert.Zero(t, failures)
+}
+
+// TestLoginFailuresLock checks that the attempt for the same email waits for
+// the one holding the lock and sees its failure.
+func TestLoginFailuresLock(t *testing.T) {
+	db := newTestMainQ(t)
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	firstErr := make(chan error, 1)
+
+	go func() {
+		tx := db.New()
+		firstErr <- tx.Transaction(func() error {
+			if err := tx.LoginFailures().Lock("locked", "10.0.0.1"); err != nil {
+				return err
+			}
+			close(locked)
+			<-release
+
+			return tx.LoginFailures().Insert(&data.LoginFailure{EmailHash: "locked", IP: "10.0.0.1"})
+		})
+	}()
+
+	<-locked
+
+	seen := make(chan uint64, 1)
+	secondErr := make(chan error, 1)
+
+	go func() {
+		tx := db.New()
+		secondErr <- tx.Transaction(func() error {
+			if err := tx.LoginFailures().Lock("locked", "10.0.0.2"); err != nil {
+				return err
+			}
+
+			count, err := tx.LoginFailures().WhereEmailHash("locked").Count()
+			seen <- count
+			return err
+		})
+	}()
+
+	select {
+	case <-seen:
+		t.Fatal("the attempt for the same email must wait for the lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-firstErr)
+	require.NoError(t, <-secondErr)
+	assert.Equal(t, uint64(1), <-seen, "the waiting attempt must see the failure of the first one")
+}
+
+func TestReportJobs(t *testing.T) {
+	db := newTestMainQ(t)
+
+	customer := &data.Customer{Email: "reports@example.com", Username: "reports", PasswordHash: "hash"}
+	require.NoError(t, db.Customers().Insert(customer))
+
+	account := &data.Account{Name: "account"}
+	require.NoError(t, db.Accounts().Insert(account))
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	job := &data.ReportJob{
+		CustomerID: customer.ID,
+		AccountID:  &account.ID,
+		Format:     "pdf",
+		PeriodTo:   now,
+		Status:     data.ReportJobPending,
+	}
+	require.NoError(t, db.ReportJobs().Insert(job))
+
+	missing := uuid.New()
+	err := db.ReportJobs().Insert(&data.ReportJob{
+		CustomerID: customer.ID,
+		AccountID:  &missing,
+		Format:     "pdf",
+		PeriodTo:   now,
+		Status:     data.ReportJobPending,
+	})
+	require.Error(t, err, "job must reference the account")
+
+	job.Status = data.ReportJobRunning
+	job.StartedAt = &now
+	require.NoError(t, db.ReportJobs().Update(job))
+
+	job.Status = data.ReportJobFailed
+	finished, err := db.ReportJobs().Finish(job, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.False(t, finished, "job claimed at another time")
+
+	finished, err = db.ReportJobs().Finish(job, now)
+	require.NoError(t, err)
+	assert.True(t, finished)
+
+	finished, err = db.ReportJobs().Finish(job, now)
+	require.NoError(t, err)
+	assert.False(t, finished, "job is not running anymore")
+
+	job.Status = data.ReportJobCompleted
+	require.Error(t, db.ReportJobs().Update(job), "completed job must expire")
+
+	expiresAt := now.Add(-time.Minute)
+	job.ExpiresAt = &expiresAt
+	require.NoError(t, db.ReportJobs().Update(job))
+
+	due, err := db.ReportJobs().WhereStatus(data.ReportJobCompleted).WhereExpiredAt(now).Select()
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, job.ID, due[0].ID)
+
+	require.NoError(t, db.ReportFiles().Insert(&data.ReportFile{JobID: job.ID, Content: []byte("report")}))
+	err = db.ReportFiles().Insert(&data.ReportFile{JobID: job.ID, Content: []byte("report")})
+	require.Error(t, err, "job must have a single file of the part")
+	assert.Contains(t, err.Error(), "duplicate key value violates unique constraint")
+
+	var file data.ReportFile
+	ok, err := db.ReportFiles().WhereJobID(job.ID).Get(&file)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []byte("report"), file.Content)
+
+	require.NoError(t, db.ReportFiles().Insert(&data.ReportFile{JobID: job.ID, Part: 1, Content: []byte("part")}))
+
+	ok, err = db.ReportFiles().WhereJobID(job.ID).WherePart(1).Get(&file)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []byte("part"), file.Content)
+
+	require.NoError(t, db.ReportFiles().DeleteByJobID(job.ID))
+	require.NoError(t, db.ReportFiles().Insert(&data.ReportFile{JobID: job.ID, Content: []byte("report")}))
+
+	require.Error(t, db.Accounts().Delete(account.ID), "account with report jobs must not be deleted")
+	require.Error(t, db.Customers().Delete(customer.ID), "customer with report jobs must not be deleted")
+
+	require.NoError(t, db.ReportJobs().Delete(job.ID))
+
+	files, err := db.ReportFiles().WhereJobID(job.ID).Count()
+	require.NoError(t, err)
+	assert.Zero(t, files, "files are deleted with the job")
+}
+
+// TestPasswordHashes registers and logs in the customers with the Argon2id
+// hashes of the default parameters, which are longer than the bcrypt ones.
+func TestPasswordHashes(t *testing.T) {
+	db := newTestMainQ(t)
+
+	hasher, err := models.NewPasswordHasher(models.PasswordAlgorithmArgon2id, 65536, 3, 2, bcrypt.DefaultCost)
+	require.NoError(t, err)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	key, err := jwk.Import(ecdsaKey)
+	require.NoError(t, err)
+	require.NoError(t, jwk.AssignKeyID(key))
+	require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.ES256()))
+
+	keys := jwk.NewSet()
+	require.NoError(t, keys.AddKey(key))
+	kid, _ := key.KeyID()
+
+	audit := models.NewAuditService(db)
+	loginGuard := models.NewLoginGuard(db, audit, models.LoginThrottlePolicy{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      20,
+		Window:             time.Hour,
+		LockoutDuration:    time.Hour,
+	})
+
+	auth, err := models.NewAuth(db, hasher, models.NewTwoFactor(db, audit), loginGuard, keys, kid, time.Minute, time.Hour)
+	require.NoError(t, err)
+
+	_, err = auth.Register(&requests.Register{
+		Email:    "argon2id@example.com",
+		Username: "argon2id",
+		Password: "Correct-Horse-42",
+	}, models.ClientInfo{})
+	require.NoError(t, err)
+
+	customer := new(data.Customer)
+	ok, err := db.Customers().WhereEmail("argon2id@example.com").Get(customer)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, strings.HasPrefix(customer.PasswordHash, "$argon2id$"))
+	assert.Greater(t, len(customer.PasswordHash), 60, "hash must not fit the bcrypt length")
+
+	// The customer registered while the passwords were hashed with bcrypt
+	legacyHasher, err := models.NewPasswordHasher(models.PasswordAlgorithmBcrypt, 65536, 3, 2, bcrypt.MinCost)
+	require.NoError(t, err)
+	legacyHash, err := legacyHasher.Hash("Correct-Horse-42")
+	require.NoError(t, err)
+
+	legacy := &data.Customer{Email: "legacy@example.com", Username: "legacy", PasswordHash: legacyHash}
+	require.NoError(t, db.Customers().Insert(legacy))
+
+	result, err := auth.Login(&requests.Login{Email: legacy.Email, Password: "Correct-Horse-42"}, models.ClientInfo{})
+	require.NoError(t, err)
+	require.NotNil(t, result.Tokens)
+
+	ok, err = db.Customers().WhereID(legacy.ID).Get(legacy)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, strings.HasPrefix(legacy.PasswordHash, "$argon2id$"), "legacy hash must be upgraded")
+
+	_, err = auth.Login(&requests.Login{Email: legacy.Email, Password: "Correct-Horse-42"}, models.ClientInfo{})
+	require.NoError(t, err, "upgraded hash must still match the password")
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	reportJobsTableName  = "report_jobs"
+	reportFilesTableName = "report_files"
+)
+
+const (
+	jobFkeyColumnName   = "job_fkey"
+	partColumnName      = "part"
+	startedAtColumnName = "started_at"
+)
+
+type reportJobsQ struct {
+	*crudQ[*data.ReportJob, uuid.UUID]
+}
+
+func NewReportJobsQ(db *pgdb.DB) data.ReportJobs {
+	return &reportJobsQ{
+		newCRUDQ[*data.ReportJob, uuid.UUID](db, reportJobsTableName),
+	}
+}
+
+func (q *reportJobsQ) WhereID(id ...uuid.UUID) data.ReportJobs {
+	q.sel = q.sel.Where(sq.Eq{idColumnName: id})
+	return q
+}
+
+func (q *reportJobsQ) WhereCustomerID(customerID uuid.UUID) data.ReportJobs {
+	q.sel = q.sel.Where(sq.Eq{customerFkeyColumnName: customerID})
+	return q
+}
+
+func (q *reportJobsQ) WhereStatus(status data.ReportJobStatus) data.ReportJobs {
+	q.sel = q.sel.Where(sq.Eq{statusColumnName: status})
+	return q
+}
+
+func (q *reportJobsQ) WhereExpiredAt(t time.Time) data.ReportJobs {
+	q.sel = q.sel.Where(sq.LtOrEq{expiresAtColumnName: t})
+	return q
+}
+
+func (q *reportJobsQ) WhereStartedBefore(t time.Time) data.ReportJobs {
+	q.sel = q.sel.Where(sq.Lt{startedAtColumnName: t})
+	return q
+}
+
+func (q *reportJobsQ) ForUpdateSkipLocked() data.ReportJobs {
+	q.sel = q.sel.Suffix("FOR UPDATE SKIP LOCKED")
+	return q
+}
+
+func (q *reportJobsQ) Limit(limit uint64) data.ReportJobs {
+	q.sel = q.sel.Limit(limit)
+	return q
+}
+
+func (q *reportJobsQ) OrderBy(orderBy ...string) data.ReportJobs {
+	q.sel = q.sel.OrderBy(orderBy...)
+	return q
+}
+
+func (q *reportJobsQ) Finish(job *data.ReportJob, startedAt time.Time) (bool, error) {
+	var id uuid.UUID
+
+	err := q.db.Get(&id,
+		sq.Update(reportJobsTableName).
+			SetMap(structs.Map(job)).
+			Where(sq.Eq{
+				idColumnName:        job.ID,
+				statusColumnName:    data.ReportJobRunning,
+				startedAtColumnName: startedAt,
+			}).
+			Suffix(fmt.Sprintf("RETURNING %s", idColumnName)),
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to finish report job: %w", err)
+	}
+
+	return true, nil
+}
+
+type reportFilesQ struct {
+	*crudQ[*data.ReportFile, uuid.UUID]
+}
+
+func NewReportFilesQ(db *pgdb.DB) data.ReportFiles {
+	return &reportFilesQ{
+		newCRUDQ[*data.ReportFile, uuid.UUID](db, reportFilesTableName),
+	}
+}
+
+func (q *reportFilesQ) WhereJobID(jobID uuid.UUID) data.ReportFiles {
+	q.sel = q.sel.Where(sq.Eq{jobFkeyColumnName: jobID})
+	return q
+}
+
+func (q *reportFilesQ) WherePart(part int) data.ReportFiles {
+	q.sel = q.sel.Where(sq.Eq{partColumnName: part})
+	return q
+}
+
+func (q *reportFilesQ) DeleteByJobID(jobID uuid.UUID) error {
+	return q.db.Exec(sq.Delete(reportFilesTableName).Where(sq.Eq{jobFkeyColumnName: jobID}))
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	sessionsTableName = "sessions"
+
+	refreshTokenHashColumnName         = "refresh_token_hash"
+	previousRefreshTokenHashColumnName = "previous_refresh_token_hash"
+	lastSeenAtColumnName               = "last_seen_at"
+	rotatedAtColumnName                = "rotated_at"
+	expiresAtColumnName                = "expires_at"
+)
+
+type sessionsQ struct {
+	*crudQ[*data.Session, uuid.UUID]
+}
+
+func NewSessionsQ(db *pgdb.DB) data.Sessions {
+	return &sessionsQ{
+		newCRUDQ[*data.Session, uuid.UUID](db, sessionsTableName),
+	}
+}
+
+func (q *sessionsQ) WhereID(id uuid.UUID) data.Sessions {
+	q.sel = q.sel.Where(sq.Eq{idColumnName: id})
+	return q
+}
+
+func (q *sessionsQ) WhereCustomerID(customerID uuid.UUID) data.Sessions {
+	q.sel = q.sel.Where(sq.Eq{customerFkeyColumnName: customerID})
+	return q
+}
+
+func (q *sessionsQ) WhereRefreshTokenHash(hash string) data.Sessions {
+	q.sel = q.sel.Where(sq.Eq{refreshTokenHashColumnName: hash})
+	return q
+}
+
+func (q *sessionsQ) WherePreviousRefreshTokenHash(hash string) data.Sessions {
+	q.sel = q.sel.Where(sq.Eq{previousRefreshTokenHashColumnName: hash})
+	return q
+}
+
+func (q *sessionsQ) WhereActive(now time.Time) data.Sessions {
+	q.sel = q.sel.Where(sq.And{
+		sq.Eq{revokedAtColumnName: nil},
+		sq.Gt{expiresAtColumnName: now},
+	})
+	return q
+}
+
+func (q *sessionsQ) OrderBy(orderBy ...string) data.Sessions {
+	q.sel = q.sel.OrderBy(orderBy...)
+	return q
+}
+
+func (q *sessionsQ) Rotate(id uuid.UUID, refreshTokenHash string, expiresAt, at time.Time) error {
+	return q.db.Exec(
+		sq.Update(sessionsTableName).
+			Set(previousRefreshTokenHashColumnName, sq.Expr(refreshTokenHashColumnName)).
+			Set(refreshTokenHashColumnName, refreshTokenHash).
+			Set(rotatedAtColumnName, at).
+			Set(lastSeenAtColumnName, at).
+			Set(expiresAtColumnName, expiresAt).
+			Where(sq.Eq{idColumnName: id}),
+	)
+}
+
+func (q *sessionsQ) Touch(id uuid.UUID, at time.Time) error {
+	return q.db.Exec(
+		sq.Update(sessionsTableName).
+			Set(lastSeenAtColumnName, at).
+			Where(sq.Eq{idColumnName: id}),
+	)
+}
+
+func (q *sessionsQ) Revoke(id uuid.UUID, at time.Time) error {
+	return q.db.Exec(
+		sq.Update(sessionsTableName).
+			Set(revokedAtColumnName, at).
+			Where(sq.Eq{idColumnName: id, revokedAtColumnName: nil}),
+	)
+}
+
+func (q *sessionsQ) RevokeAll(customerID uuid.UUID, at time.Time) error {
+	return q.db.Exec(
+		sq.Update(sessionsTableName).
+			Set(revokedAtColumnName, at).
+			Where(sq.Eq{customerFkeyColumnName: customerID, revokedAtColumnName: nil}),
+	)
+}
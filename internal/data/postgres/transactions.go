@@ -2,10 +2,12 @@ package postgres
 
 import (
 	"fmt"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/fatih/structs"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"gitlab.com/distributed_lab/kit/pgdb"
 
 	"github.com/omegatymbjiep/ilab1/internal/data"
@@ -74,6 +76,16 @@ func (q *transactionsQ) WhereAccount(account uuid.UUID) data.Transactions {
 	return q
 }
 
+func (q *transactionsQ) WhereCreatedFrom(from time.Time) data.Transactions {
+	q.sel = q.sel.Where(sq.GtOrEq{createAtColumnName: from})
+	return q
+}
+
+func (q *transactionsQ) WhereCreatedBefore(to time.Time) data.Transactions {
+	q.sel = q.sel.Where(sq.Lt{createAtColumnName: to})
+	return q
+}
+
 func (q *transactionsQ) Limit(limit uint64) data.Transactions {
 	q.sel = q.sel.Limit(limit)
 	return q
@@ -89,3 +101,36 @@ func (q *transactionsQ) OrderBy(sort ...string) data.Transactions {
 
 	return q
 }
+
+// Each reads the selected rows one at a time from the result set of the
+// query. It runs on the connection pool, outside of the transaction of the Q.
+func (q *transactionsQ) Each(fn func(*data.Transaction) error) error {
+	query, args, err := q.sel.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+	q.sel = emptySelector(transactionsTableName)
+
+	rows, err := sqlx.NewDb(q.db.RawDB(), "postgres").Queryx(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		tx := new(data.Transaction)
+		if err = rows.StructScan(tx); err != nil {
+			return fmt.Errorf("failed to scan transaction: %w", err)
+		}
+
+		if err = fn(tx); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to read transactions: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	totpCredentialsTableName = "totp_credentials"
+	recoveryCodesTableName   = "recovery_codes"
+
+	lastUsedStepColumnName = "last_used_step"
+	codeHashColumnName     = "code_hash"
+	usedAtColumnName       = "used_at"
+)
+
+type totpCredentialsQ struct {
+	*crudQ[*data.TOTPCredential, uuid.UUID]
+}
+
+func NewTOTPCredentialsQ(db *pgdb.DB) data.TOTPCredentials {
+	return &totpCredentialsQ{
+		newCRUDQ[*data.TOTPCredential, uuid.UUID](db, totpCredentialsTableName),
+	}
+}
+
+func (q *totpCredentialsQ) WhereCustomerID(customerID uuid.UUID) data.TOTPCredentials {
+	q.sel = q.sel.Where(sq.Eq{customerFkeyColumnName: customerID})
+	return q
+}
+
+func (q *totpCredentialsQ) UseStep(id uuid.UUID, step int64) (bool, error) {
+	var updated uuid.UUID
+
+	err := q.db.Get(&updated,
+		sq.Update(totpCredentialsTableName).
+			Set(lastUsedStepColumnName, step).
+			Where(sq.Eq{idColumnName: id}).
+			Where(sq.Lt{lastUsedStepColumnName: step}).
+			Suffix(fmt.Sprintf("RETURNING %s", idColumnName)),
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to update last used step: %w", err)
+	}
+
+	return true, nil
+}
+
+type recoveryCodesQ struct {
+	*crudQ[*data.RecoveryCode, uuid.UUID]
+}
+
+func NewRecoveryCodesQ(db *pgdb.DB) data.RecoveryCodes {
+	return &recoveryCodesQ{
+		newCRUDQ[*data.RecoveryCode, uuid.UUID](db, recoveryCodesTableName),
+	}
+}
+
+func (q *recoveryCodesQ) WhereCustomerID(customerID uuid.UUID) data.RecoveryCodes {
+	q.sel = q.sel.Where(sq.Eq{customerFkeyColumnName: customerID})
+	return q
+}
+
+func (q *recoveryCodesQ) WhereUnused() data.RecoveryCodes {
+	q.sel = q.sel.Where(sq.Eq{usedAtColumnName: nil})
+	return q
+}
+
+func (q *recoveryCodesQ) Use(customerID uuid.UUID, codeHash string, at time.Time) (bool, error) {
+	var used uuid.UUID
+
+	err := q.db.Get(&used,
+		sq.Update(recoveryCodesTableName).
+			Set(usedAtColumnName, at).
+			Where(sq.Eq{
+				customerFkeyColumnName: customerID,
+				codeHashColumnName:     codeHash,
+				usedAtColumnName:       nil,
+			}).
+			Suffix(fmt.Sprintf("RETURNING %s", idColumnName)),
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return true, nil
+}
+
+func (q *recoveryCodesQ) DeleteByCustomer(customerID uuid.UUID) error {
+	return q.db.Exec(
+		sq.Delete(recoveryCodesTableName).
+			Where(sq.Eq{customerFkeyColumnName: customerID}),
+	)
+}
@@ -0,0 +1,87 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReportJobStatus string
+
+const (
+	ReportJobPending   ReportJobStatus = "pending"
+	ReportJobRunning   ReportJobStatus = "running"
+	ReportJobCompleted ReportJobStatus = "completed"
+	ReportJobFailed    ReportJobStatus = "failed"
+	ReportJobExpired   ReportJobStatus = "expired"
+)
+
+type ReportJobs interface {
+	CRUDQ[*ReportJob, uuid.UUID]
+
+	WhereID(id ...uuid.UUID) ReportJobs
+	WhereCustomerID(customerID uuid.UUID) ReportJobs
+	WhereStatus(status ReportJobStatus) ReportJobs
+	// WhereExpiredAt selects the jobs whose expires_at is not after the time.
+	WhereExpiredAt(t time.Time) ReportJobs
+	// WhereStartedBefore selects the jobs whose started_at is before the time.
+	WhereStartedBefore(t time.Time) ReportJobs
+
+	// ForUpdateSkipLocked locks the selected jobs, skipping the ones already
+	// locked by the other workers, unlike the ForUpdate of the other queries.
+	ForUpdateSkipLocked() ReportJobs
+	Limit(limit uint64) ReportJobs
+	OrderBy(orderBy ...string) ReportJobs
+
+	// Finish updates the job only if it is still running since startedAt, and
+	// reports whether it was updated: the job may be failed as stale meanwhile.
+	Finish(job *ReportJob, startedAt time.Time) (bool, error)
+}
+
+// ReportJob is the report requested by the customer and generated in the
+// background. The worker takes the pending jobs, the result of the completed
+// job can be downloaded until it expires.
+type ReportJob struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	CustomerID uuid.UUID `db:"customer_fkey" structs:"customer_fkey"`
+	// AccountID is nil for the report of all the accounts of the customer
+	AccountID *uuid.UUID `db:"account_fkey" structs:"account_fkey"`
+	Format    string     `db:"format"       structs:"format"`
+	// PeriodFrom is nil for the period starting with the account
+	PeriodFrom *time.Time      `db:"period_from"  structs:"period_from"`
+	PeriodTo   time.Time       `db:"period_to"    structs:"period_to"`
+	Status     ReportJobStatus `db:"status"       structs:"status"`
+	// Error is the reason of the failure shown to the customer
+	Error       string     `db:"error"        structs:"error"`
+	StartedAt   *time.Time `db:"started_at"   structs:"started_at"`
+	CompletedAt *time.Time `db:"completed_at" structs:"completed_at"`
+	ExpiresAt   *time.Time `db:"expires_at"   structs:"expires_at"`
+}
+
+// IsDownloadableAt reports whether the result of the job can be downloaded at
+// the time.
+func (j *ReportJob) IsDownloadableAt(t time.Time) bool {
+	return j.Status == ReportJobCompleted && j.ExpiresAt != nil && t.Before(*j.ExpiresAt)
+}
+
+type ReportFiles interface {
+	CRUDQ[*ReportFile, uuid.UUID]
+
+	WhereJobID(jobID uuid.UUID) ReportFiles
+	WherePart(part int) ReportFiles
+
+	// DeleteByJobID deletes all the parts of the result of the job.
+	DeleteByJobID(jobID uuid.UUID) error
+}
+
+// ReportFile is the part of the result of the report job kept in the
+// database, when the reports are not stored on the disk. The parts are
+// numbered from 0.
+type ReportFile struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	JobID   uuid.UUID `db:"job_fkey" structs:"job_fkey"`
+	Part    int       `db:"part"     structs:"part"`
+	Content []byte    `db:"content"  structs:"content"`
+}
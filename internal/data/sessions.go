@@ -0,0 +1,51 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Sessions interface {
+	CRUDQ[*Session, uuid.UUID]
+
+	WhereID(id uuid.UUID) Sessions
+	WhereCustomerID(customerID uuid.UUID) Sessions
+	WhereRefreshTokenHash(hash string) Sessions
+	WherePreviousRefreshTokenHash(hash string) Sessions
+	// WhereActive selects the sessions neither revoked nor expired at the given time.
+	WhereActive(now time.Time) Sessions
+	OrderBy(orderBy ...string) Sessions
+
+	// Rotate replaces the refresh token of the session, keeping the replaced
+	// one as the previous token, and extends the session until expiresAt.
+	Rotate(id uuid.UUID, refreshTokenHash string, expiresAt, at time.Time) error
+	// Touch records the time the session was last seen at.
+	Touch(id uuid.UUID, at time.Time) error
+	// Revoke marks the session as revoked at the given time.
+	Revoke(id uuid.UUID, at time.Time) error
+	// RevokeAll revokes all the sessions of the customer that are not revoked yet.
+	RevokeAll(customerID uuid.UUID, at time.Time) error
+}
+
+// Session is a browser login. Only the SHA-256 hashes of the refresh tokens
+// are stored: the current one and the one it replaced, so the reuse of a rotated
+// token can be detected.
+type Session struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	CustomerID               uuid.UUID  `db:"customer_fkey"               structs:"customer_fkey"`
+	RefreshTokenHash         string     `db:"refresh_token_hash"          structs:"refresh_token_hash"`
+	PreviousRefreshTokenHash *string    `db:"previous_refresh_token_hash" structs:"previous_refresh_token_hash"`
+	UserAgent                string     `db:"user_agent"                  structs:"user_agent"`
+	IP                       string     `db:"ip"                          structs:"ip"`
+	LastSeenAt               time.Time  `db:"last_seen_at"                structs:"last_seen_at"`
+	RotatedAt                *time.Time `db:"rotated_at"                  structs:"rotated_at"`
+	ExpiresAt                time.Time  `db:"expires_at"                  structs:"expires_at"`
+	RevokedAt                *time.Time `db:"revoked_at"                  structs:"revoked_at"`
+}
+
+// IsActive reports whether the session is neither revoked nor expired at the given time.
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -1,6 +1,8 @@
 package data
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -32,10 +34,19 @@ type Transactions interface {
 	WhereSender(sender uuid.UUID) Transactions
 	WhereRecipient(recipient uuid.UUID) Transactions
 	WhereAccount(account uuid.UUID) Transactions
+	// WhereCreatedFrom selects the transactions created at or after from
+	WhereCreatedFrom(from time.Time) Transactions
+	// WhereCreatedBefore selects the transactions created before to
+	WhereCreatedBefore(to time.Time) Transactions
 
 	Limit(limit uint64) Transactions
 	Offset(offset uint64) Transactions
 	OrderBy(orderBy ...string) Transactions
+
+	// Each calls fn for the selected transactions one by one in the order of
+	// the query, without loading them all into memory. It stops at the first
+	// error returned by fn.
+	Each(fn func(*Transaction) error) error
 }
 
 type Transaction struct {
@@ -46,4 +57,6 @@ type Transaction struct {
 	Sender       uuid.UUID       `db:"sender_fkey"    structs:"sender_fkey"`
 	Recipient    uuid.UUID       `db:"recipient_fkey" structs:"recipient_fkey"`
 	ATMSignature string          `db:"atm_signature"  structs:"atm_signature"`
+	ATMID        *uuid.UUID      `db:"atm_fkey"       structs:"atm_fkey"` // nil for transfers
+	ATMNonce     string          `db:"atm_nonce"      structs:"atm_nonce"`
 }
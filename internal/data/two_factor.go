@@ -0,0 +1,55 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TOTPCredentials interface {
+	CRUDQ[*TOTPCredential, uuid.UUID]
+
+	WhereCustomerID(customerID uuid.UUID) TOTPCredentials
+
+	// UseStep records the time step a code was accepted for. It reports false
+	// if the step is not later than the last used one, that is the code is replayed.
+	UseStep(id uuid.UUID, step int64) (bool, error)
+}
+
+// TOTPCredential is the RFC 6238 secret of the customer, the second factor is
+// enabled only once the enrollment is confirmed with a valid code.
+type TOTPCredential struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	CustomerID   uuid.UUID  `db:"customer_fkey"  structs:"customer_fkey"`
+	Secret       string     `db:"secret"         structs:"secret"`
+	ConfirmedAt  *time.Time `db:"confirmed_at"   structs:"confirmed_at"`
+	LastUsedStep int64      `db:"last_used_step" structs:"last_used_step"`
+}
+
+func (c *TOTPCredential) IsConfirmed() bool {
+	return c.ConfirmedAt != nil
+}
+
+type RecoveryCodes interface {
+	CRUDQ[*RecoveryCode, uuid.UUID]
+
+	WhereCustomerID(customerID uuid.UUID) RecoveryCodes
+	WhereUnused() RecoveryCodes
+
+	// Use marks the unused code of the customer with the hash as used, it
+	// reports false if there is no such code.
+	Use(customerID uuid.UUID, codeHash string, at time.Time) (bool, error)
+	// DeleteByCustomer deletes all the codes of the customer.
+	DeleteByCustomer(customerID uuid.UUID) error
+}
+
+// RecoveryCode is a one-time code replacing the TOTP code when the
+// authenticator is lost, only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	Entity[uuid.UUID] `structs:"-"`
+
+	CustomerID uuid.UUID  `db:"customer_fkey" structs:"customer_fkey"`
+	CodeHash   string     `db:"code_hash"     structs:"code_hash"`
+	UsedAt     *time.Time `db:"used_at"       structs:"used_at"`
+}
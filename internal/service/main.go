@@ -32,6 +32,8 @@ func Run(ctx context.Context, cfg config.Config) {
 	}
 
 	mvc.Register(api.Router())
+	go mvc.Run(ctx)
+	go mvc.RunReports(ctx)
 
 	api.Run(ctx)
 }
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/responses"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+)
+
+type AccessTokens struct {
+	model *models.AccessTokens
+}
+
+func NewAccessTokens(model *models.AccessTokens) *AccessTokens {
+	return &AccessTokens{
+		model: model,
+	}
+}
+
+func (c *AccessTokens) GetAccessTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := c.model.GetAccessTokens(CustomerID(r))
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to get access tokens: %w", err))
+		return
+	}
+
+	document, err := responses.NewAccessTokenListDocument(tokens)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to marshal access tokens: %w", err))
+		return
+	}
+
+	ape.Render(w, document)
+}
+
+func (c *AccessTokens) CreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewCreateAccessToken(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	plain, token, err := c.model.CreateAccessToken(CustomerID(r), req)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to create access token: %w", err))
+		return
+	}
+
+	document, err := responses.NewCreatedAccessTokenDocument(plain, token)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to marshal access token: %w", err))
+		return
+	}
+
+	ape.Render(w, document)
+}
+
+func (c *AccessTokens) RevokeAccessToken(w http.ResponseWriter, r *http.Request) {
+	tokenID, err := requests.NewAccessTokenID(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	if err = c.model.RevokeAccessToken(CustomerID(r), tokenID); err != nil {
+		if errors.Is(err, models.ErrorAccessTokenNotFound) {
+			ape.RenderErr(w, problems.NotFound())
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to revoke access token: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
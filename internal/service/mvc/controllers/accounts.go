@@ -12,6 +12,7 @@ import (
 	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
 	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/responses"
 	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models/report"
 	"github.com/omegatymbjiep/ilab1/internal/service/mvc/views"
 )
 
@@ -42,6 +43,78 @@ func (c *Accounts) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	ape.Render(w, responses.NewCreateAccount(account))
 }
 
+func (c *Accounts) GetAccountList(w http.ResponseWriter, r *http.Request) {
+	accounts, err := c.model.GetAccountList(CustomerID(r))
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to get accounts: %w", err))
+		return
+	}
+
+	document, err := responses.NewAccountListDocument(accounts)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to marshal accounts: %w", err))
+		return
+	}
+
+	ape.Render(w, document)
+}
+
+func (c *Accounts) GetAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, err := uuid.Parse(r.PathValue("account-id"))
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(errors.New("invalid account id"))...)
+		return
+	}
+
+	account, err := c.model.GetAccount(CustomerID(r), accountID)
+	if err != nil {
+		if errors.Is(err, models.ErrorAccountNotFound) {
+			ape.RenderErr(w, problems.NotFound())
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to get account: %w", err))
+		return
+	}
+
+	document, err := responses.NewAccountDocument(account)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to marshal account: %w", err))
+		return
+	}
+
+	ape.Render(w, document)
+}
+
+func (c *Accounts) GetAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewAccountTransactions(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	transactions, total, err := c.model.ListAccountTransactions(CustomerID(r), req)
+	if err != nil {
+		if errors.Is(err, models.ErrorAccountNotFound) {
+			ape.RenderErr(w, problems.NotFound())
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to get transactions: %w", err))
+		return
+	}
+
+	document, err := responses.NewTransactionListDocument(transactions, pageLinks(r, req.PageParams, total), total)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to marshal transactions: %w", err))
+		return
+	}
+
+	ape.Render(w, document)
+}
+
 func (c *Accounts) AccountListPage(w http.ResponseWriter, r *http.Request) {
 	accounts, err := c.model.GetAccountList(CustomerID(r))
 	if err != nil {
@@ -85,9 +158,16 @@ func (c *Accounts) AccountPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	cashOutCodes, err := c.model.GetPendingCashOutCodes(CustomerID(r), accountID)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to get cash-out codes: %w", err))
+		return
+	}
+
 	viewData := &views.Account{
 		Account:      account,
 		Transactions: transactions,
+		CashOutCodes: cashOutCodes,
 	}
 
 	if err := Templates(r).ExecuteTemplate(w, views.AccountTemplateName, viewData); err != nil {
@@ -116,6 +196,10 @@ func (c *Accounts) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 			Log(r).WithField("reason", err).Debug("forbidden")
 			ape.RenderErr(w, problems.Forbidden())
 			return
+		case errors.Is(err, models.ErrorPendingCashOutCodes):
+			Log(r).WithField("reason", err).Debug("conflict")
+			ape.RenderErr(w, problems.Conflict())
+			return
 		}
 
 		InternalError(w, r, err)
@@ -125,32 +209,137 @@ func (c *Accounts) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ExportStatement renders the statement of the account in the format of the
+// format param: PDF, CSV, OFX or camt.053.
+func (c *Accounts) ExportStatement(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewStatement(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	c.renderStatement(w, r, req)
+}
+
+func (c *Accounts) GenerateStatementPDF(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewStatement(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	req.Format = report.StatementFormatPDF
+	c.renderStatement(w, r, req)
+}
+
+func (c *Accounts) renderStatement(w http.ResponseWriter, r *http.Request, req *requests.Statement) {
+	statement, err := c.model.GenerateStatement(CustomerID(r), req)
+	if err != nil {
+		if errors.Is(err, models.ErrorAccountNotFound) {
+			ape.RenderErr(w, problems.NotFound())
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to generate statement: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", req.Format.ContentType())
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=account_%s_statement.%s", req.AccountID.String(), req.Format.Extension()))
+
+	if _, err = w.Write(statement); err != nil {
+		InternalError(w, r, fmt.Errorf("failed to write statement: %w", err))
+		return
+	}
+}
+
+// GenerateAccountExcel renders the Excel report of the account for the period,
+// with the history filtered by the type and the amount of the transactions.
 func (c *Accounts) GenerateAccountExcel(w http.ResponseWriter, r *http.Request) {
-	accountIDRaw := r.PathValue("account-id")
-	accountID, err := uuid.Parse(accountIDRaw)
+	req, err := requests.NewExcelReport(r)
 	if err != nil {
 		Log(r).WithField("reason", err).Debug("bad request")
-		ape.RenderErr(w, problems.BadRequest(fmt.Errorf("invalid account id"))...)
+		ape.RenderErr(w, requests.BadRequest(err)...)
 		return
 	}
 
-	excelReport, err := c.model.GenerateExcelReport(CustomerID(r), accountID)
+	writeReport, err := c.model.GenerateExcelReport(CustomerID(r), req)
 	if err != nil {
 		if errors.Is(err, models.ErrorAccountNotFound) {
 			ape.RenderErr(w, problems.NotFound())
 			return
 		}
 
-		InternalError(w, r, fmt.Errorf("failed to get account: %w", err))
+		InternalError(w, r, fmt.Errorf("failed to generate Excel report: %w", err))
+		return
+	}
+
+	download := &downloadWriter{
+		ResponseWriter: w,
+		contentType:    report.ExcelContentType,
+		filename:       fmt.Sprintf("account_%s_report.xlsx", req.AccountID.String()),
+	}
+
+	if err = writeReport(download); err != nil {
+		// The workbook is built before it is written, so most errors come
+		// before the download starts and can still be rendered
+		if !download.written {
+			InternalError(w, r, fmt.Errorf("failed to generate Excel report: %w", err))
+			return
+		}
+
+		Log(r).WithError(err).Error("failed to stream Excel report")
 		return
 	}
+}
 
-	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+// GenerateCustomerExcel renders the Excel workbook with all the accounts of
+// the customer for the period.
+func (c *Accounts) GenerateCustomerExcel(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewCustomerExcelReport(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	customerID := CustomerID(r)
+
+	excelReport, err := c.model.GenerateCustomerExcelReport(customerID, req)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to generate customer Excel report: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", report.ExcelContentType)
 	w.Header().Set("Content-Disposition",
-		fmt.Sprintf("attachment; filename=account_%s_report.xlsx", accountID.String()))
+		fmt.Sprintf("attachment; filename=customer_%s_report.xlsx", customerID.String()))
 
 	if _, err = w.Write(excelReport); err != nil {
 		InternalError(w, r, fmt.Errorf("failed to write Excel file: %w", err))
 		return
 	}
 }
+
+// downloadWriter sets the headers of the file download on the first write, so
+// the handler can still render the error until the file starts being sent.
+type downloadWriter struct {
+	http.ResponseWriter
+
+	contentType string
+	filename    string
+	written     bool
+}
+
+func (w *downloadWriter) Write(p []byte) (int, error) {
+	if !w.written {
+		w.written = true
+		w.Header().Set("Content-Type", w.contentType)
+		w.Header().Set("Content-Disposition", "attachment; filename="+w.filename)
+	}
+
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/data/memory"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/views"
+)
+
+var errCursor = errors.New("cursor failed")
+
+// failingTransactionsQ is the MainQ whose transactions can't be iterated.
+type failingTransactionsQ struct {
+	data.MainQ
+}
+
+func (q failingTransactionsQ) New() data.MainQ {
+	return failingTransactionsQ{q.MainQ.New()}
+}
+
+func (q failingTransactionsQ) Transactions() data.Transactions {
+	return failingTransactions{q.MainQ.Transactions()}
+}
+
+type failingTransactions struct {
+	data.Transactions
+}
+
+func (q failingTransactions) WhereAccount(account uuid.UUID) data.Transactions {
+	return failingTransactions{q.Transactions.WhereAccount(account)}
+}
+
+func (q failingTransactions) WhereCreatedFrom(from time.Time) data.Transactions {
+	return failingTransactions{q.Transactions.WhereCreatedFrom(from)}
+}
+
+func (q failingTransactions) OrderBy(orderBy ...string) data.Transactions {
+	return failingTransactions{q.Transactions.OrderBy(orderBy...)}
+}
+
+func (q failingTransactions) Each(func(*data.Transaction) error) error {
+	return errCursor
+}
+
+func TestGenerateAccountExcelFailure(t *testing.T) {
+	db := memory.NewMainQ()
+
+	customer := &data.Customer{
+		Email:        uuid.NewString() + "@example.com",
+		Username:     uuid.NewString(),
+		PasswordHash: "hashed_password",
+	}
+	require.NoError(t, db.Customers().Insert(customer))
+
+	audit := models.NewAuditService(db)
+	account, err := models.NewAccounts(db, audit).CreateAccount(customer.ID, &requests.CreateAccount{Name: "account"})
+	require.NoError(t, err)
+
+	controller := NewAccounts(models.NewAccounts(failingTransactionsQ{db}, audit))
+
+	templates := template.Must(template.New(views.InternalErrorTemplateName).Parse("internal error"))
+
+	r := httptest.NewRequest(http.MethodGet, "http://bank.local/api/v1/accounts/"+account.ID.String()+"/excel", nil)
+	r.SetPathValue("account-id", account.ID.String())
+
+	ctx := CtxLog(logan.New().Level(logan.PanicLevel).WithField("test", t.Name()))(r.Context())
+	ctx = CtxTemplates(templates)(ctx)
+	ctx = context.WithValue(ctx, customerIDCtxKey, customer.ID)
+
+	w := httptest.NewRecorder()
+	controller.GenerateAccountExcel(w, r.WithContext(ctx))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "nothing is sent before the failure")
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "internal error", w.Body.String())
+}
@@ -10,6 +10,8 @@ import (
 	"gitlab.com/distributed_lab/ape/problems"
 
 	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/responses"
 	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
 	"github.com/omegatymbjiep/ilab1/internal/service/mvc/views"
 	"time"
@@ -89,6 +91,36 @@ func (c *ActivityLogs) UserActivityPage(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (c *ActivityLogs) GetUserActivity(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewActivity(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	customerID := CustomerID(r)
+	logs, err := c.auditService.GetUserActivityLogs(customerID, req.Limit, req.Offset())
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to get activity logs: %w", err))
+		return
+	}
+
+	totalCount, err := c.auditService.GetTotalLogsCount(customerID)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to get total logs count: %w", err))
+		return
+	}
+
+	document, err := responses.NewActivityListDocument(logs, pageLinks(r, req.PageParams, totalCount), totalCount)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to marshal activity logs: %w", err))
+		return
+	}
+
+	ape.Render(w, document)
+}
+
 func formatLogsForDisplay(logs []*data.AuditLog) []views.FormattedLog {
 	result := make([]views.FormattedLog, len(logs))
 	for i, log := range logs {
@@ -114,6 +146,10 @@ func formatActionForDisplay(action data.AuditAction) string {
 	switch action {
 	case data.AuditActionLoginSuccess:
 		return "Login"
+	case data.AuditActionLoginFailed:
+		return "Failed Login"
+	case data.AuditActionLoginUnlocked:
+		return "Login Unlocked"
 	case data.AuditActionAccountCreated:
 		return "Account Created"
 	case data.AuditActionAccountDeleted:
@@ -124,8 +160,20 @@ func formatActionForDisplay(action data.AuditAction) string {
 		return "Withdrawal"
 	case data.AuditActionTransferMade:
 		return "Transfer"
+	case data.AuditActionCashOutCodeIssued:
+		return "Cash-Out Code Issued"
+	case data.AuditActionCashOutCodeCancelled:
+		return "Cash-Out Code Cancelled"
+	case data.AuditActionCashOutCodeExpired:
+		return "Cash-Out Code Expired"
 	case data.AuditActionExcelReportGenerated:
 		return "Excel Report Generated"
+	case data.AuditActionStatementGenerated:
+		return "Statement Generated"
+	case data.AuditActionReportRequested:
+		return "Report Requested"
+	case data.AuditActionReportDownloaded:
+		return "Report Downloaded"
 	default:
 		return string(action)
 	}
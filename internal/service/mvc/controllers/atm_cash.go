@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/responses"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+)
+
+type ATMCash struct {
+	model *models.ATMCash
+}
+
+func NewATMCash(model *models.ATMCash) *ATMCash {
+	return &ATMCash{
+		model: model,
+	}
+}
+
+// Reconcile is called by the ATM at the end of the business day with its
+// signed cash count, see CashOut.Withdraw.
+func (c *ATMCash) Reconcile(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewATMCashCount(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	reconciliation, err := c.model.Reconcile(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrorInvalidATMPayload):
+			Log(r).WithField("reason", err).Debug("bad request")
+			ape.RenderErr(w, requests.BadRequest(err)...)
+			return
+		case errors.Is(err, models.ErrorInvalidATMSignature):
+			Log(r).WithField("reason", err).Debug("bad request")
+			ape.RenderErr(w, requests.BadRequest(models.ErrorInvalidATMSignature)...)
+			return
+		case errors.Is(err, models.ErrorATMNotFound):
+			Log(r).WithField("reason", err).Debug("bad request")
+			ape.RenderErr(w, requests.BadRequest(models.ErrorATMNotFound)...)
+			return
+		case errors.Is(err, models.ErrorATMDisabled):
+			Log(r).WithField("reason", err).Debug("forbidden")
+			ape.RenderErr(w, problems.Forbidden())
+			return
+		case errors.Is(err, models.ErrorATMCashAlreadyReconciled), errors.Is(err, models.ErrorATMCashReconciledLater):
+			Log(r).WithField("reason", err).Debug("conflict")
+			ape.RenderErr(w, problems.Conflict())
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to reconcile ATM cash: %w", err))
+		return
+	}
+
+	document, err := responses.NewATMReconciliationDocument(reconciliation)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to marshal ATM reconciliation: %w", err))
+		return
+	}
+
+	ape.Render(w, document)
+}
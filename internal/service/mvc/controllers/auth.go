@@ -1,12 +1,15 @@
 package controllers
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 
-	"github.com/google/uuid"
 	"gitlab.com/distributed_lab/ape"
 	"gitlab.com/distributed_lab/ape/problems"
 
@@ -15,20 +18,29 @@ import (
 	"github.com/omegatymbjiep/ilab1/internal/service/mvc/views"
 )
 
-const JWTCookieName = "jwt"
+const (
+	JWTCookieName                = "jwt"
+	RefreshTokenCookieName       = "refresh_token"
+	TwoFactorChallengeCookieName = "two_factor_challenge"
+)
+
+// twoFactorChallengePath is where the challenge cookie is sent to.
+const twoFactorChallengePath = "/auth/2fa"
 
 type Auth struct {
-	model *models.Auth
+	model        *models.Auth
+	accessTokens *models.AccessTokens
 }
 
-func NewAuth(model *models.Auth) *Auth {
+func NewAuth(model *models.Auth, accessTokens *models.AccessTokens) *Auth {
 	return &Auth{
-		model: model,
+		model:        model,
+		accessTokens: accessTokens,
 	}
 }
 
 func (c *Auth) AuthPage(w http.ResponseWriter, r *http.Request) {
-	if customerID, _ := c.verifyJWT(r); customerID != uuid.Nil {
+	if _, err := c.authenticate(w, r); err == nil {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -48,25 +60,78 @@ func (c *Auth) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jwt, err := c.model.Login(req)
+	result, err := c.model.Login(req, clientInfo(r))
 	if err != nil {
-		if errors.Is(err, models.ErrorUserNotFound) {
-			Log(r).WithError(err).Debug("not found")
-			ape.RenderErr(w, problems.NotFound())
-			return
+		switch {
+		case errors.Is(err, models.ErrorInvalidCredentials):
+			Log(r).WithError(err).Debug("unauthorized")
+			ape.RenderErr(w, problems.Unauthorized())
+		case errors.Is(err, models.ErrorTooManyLoginAttempts):
+			TooManyLoginAttempts(w, r, err)
+		default:
+			InternalError(w, r, fmt.Errorf("failed to login user: %w", err))
 		}
+		return
+	}
+
+	if result.Challenge != nil {
+		http.SetCookie(w, newTwoFactorChallengeCookie(r, result.Challenge))
+		http.Redirect(w, r, twoFactorChallengePath, http.StatusSeeOther)
+		return
+	}
+
+	setSessionCookies(w, r, result.Tokens)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
 
-		if errors.Is(err, models.ErrorInvalidPassword) {
+func (c *Auth) TwoFactorPage(w http.ResponseWriter, r *http.Request) {
+	if _, err := r.Cookie(TwoFactorChallengeCookieName); err != nil {
+		http.Redirect(w, r, "/auth", http.StatusSeeOther)
+		return
+	}
+
+	if err := Templates(r).ExecuteTemplate(w, views.TwoFactorTemplateName, nil); err != nil {
+		Log(r).WithError(err).Error("failed to execute template")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+}
+
+// CompleteLogin is the second login step, it starts the session once the
+// code of the second factor is verified.
+func (c *Auth) CompleteLogin(w http.ResponseWriter, r *http.Request) {
+	challengeCookie, err := r.Cookie(TwoFactorChallengeCookieName)
+	if err != nil {
+		Unauthorized(w, r, errors.New("no two-factor challenge provided"))
+		return
+	}
+
+	req, err := requests.NewLoginTwoFactorCode(r)
+	if err != nil {
+		Log(r).WithError(err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	tokens, err := c.model.CompleteLogin(challengeCookie.Value, req.Code, clientInfo(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrorInvalidTwoFactorChallenge):
+			http.SetCookie(w, newTwoFactorChallengeCookie(r, &models.JWTWithEat{Expiration: time.Now().Add(-time.Hour)}))
+			Unauthorized(w, r, err)
+		case errors.Is(err, models.ErrorInvalidTwoFactorCode):
 			Log(r).WithError(err).Debug("unauthorized")
 			ape.RenderErr(w, problems.Unauthorized())
-			return
+		case errors.Is(err, models.ErrorTooManyLoginAttempts):
+			TooManyLoginAttempts(w, r, err)
+		default:
+			InternalError(w, r, fmt.Errorf("failed to complete login: %w", err))
 		}
-
-		InternalError(w, r, fmt.Errorf("failed to login user: %w", err))
 		return
 	}
 
-	http.SetCookie(w, newJWTCookie(jwt))
+	http.SetCookie(w, newTwoFactorChallengeCookie(r, &models.JWTWithEat{Expiration: time.Now().Add(-time.Hour)}))
+	setSessionCookies(w, r, tokens)
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
@@ -78,7 +143,7 @@ func (c *Auth) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jwt, err := c.model.Register(req)
+	tokens, err := c.model.Register(req, clientInfo(r))
 	if err != nil {
 		if errors.Is(err, models.ErrorEmailOrUsernameTaken) {
 			Log(r).WithField("reason", err).Debug("conflict")
@@ -90,7 +155,7 @@ func (c *Auth) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	http.SetCookie(w, newJWTCookie(jwt))
+	setSessionCookies(w, r, tokens)
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
@@ -100,21 +165,160 @@ func Unauthorized(w http.ResponseWriter, r *http.Request, err error) {
 	return
 }
 
-func LogOut(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, newJWTCookie(&models.JWTWithEat{
+// JWKS publishes the public keys the tokens are signed with, so other services
+// can verify them. Retired keys stay published until they're removed.
+func (c *Auth) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if err := json.NewEncoder(w).Encode(c.model.JWKS()); err != nil {
+		Log(r).WithError(err).Error("failed to encode JWKS")
+	}
+}
+
+// TooManyLoginAttempts renders 429 with the Retry-After header telling when
+// the login is allowed again.
+func TooManyLoginAttempts(w http.ResponseWriter, r *http.Request, err error) {
+	Log(r).WithField("reason", err).Debug("too many login attempts")
+
+	var throttled *models.LoginThrottledError
+	if errors.As(err, &throttled) {
+		retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+
+	ape.RenderErr(w, problems.TooManyRequests())
+}
+
+// APIUnauthorized renders the JSON:API 401 error, which, unlike the redirect
+// of Unauthorized, can be handled by non-browser clients.
+func APIUnauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	Log(r).WithField("reason", err).Debug("unauthorized")
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	ape.RenderErr(w, problems.Unauthorized())
+}
+
+// Refresh exchanges the refresh token cookie for new session cookies, so the
+// API clients can renew the expired access token without the page reload.
+func (c *Auth) Refresh(w http.ResponseWriter, r *http.Request) {
+	refreshCookie, err := r.Cookie(RefreshTokenCookieName)
+	if err != nil {
+		APIUnauthorized(w, r, errors.New("no refresh token provided"))
+		return
+	}
+
+	tokens, err := c.model.Refresh(refreshCookie.Value)
+	if err != nil {
+		if errors.Is(err, models.ErrorInvalidSession) || errors.Is(err, models.ErrorRefreshTokenReused) {
+			clearSessionCookies(w, r)
+			APIUnauthorized(w, r, err)
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to refresh session: %w", err))
+		return
+	}
+
+	setSessionCookies(w, r, tokens)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogOut revokes the current session, so its tokens stop working immediately
+// rather than when they expire.
+func (c *Auth) LogOut(w http.ResponseWriter, r *http.Request) {
+	if err := c.model.Logout(SessionID(r)); err != nil {
+		InternalError(w, r, fmt.Errorf("failed to log out: %w", err))
+		return
+	}
+
+	clearSessionCookies(w, r)
+	http.Redirect(w, r, "/auth", http.StatusSeeOther)
+}
+
+// LogOutEverywhere revokes all the sessions of the customer, including the current one.
+func (c *Auth) LogOutEverywhere(w http.ResponseWriter, r *http.Request) {
+	if err := c.model.LogoutEverywhere(CustomerID(r)); err != nil {
+		InternalError(w, r, fmt.Errorf("failed to log out everywhere: %w", err))
+		return
+	}
+
+	clearSessionCookies(w, r)
+	http.Redirect(w, r, "/auth", http.StatusSeeOther)
+}
+
+func clientInfo(r *http.Request) models.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return models.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IP:        ip,
+	}
+}
+
+// setSessionCookies sets the cookies of the issued tokens, the refresh token
+// cookie is left as is when only the access token was reissued.
+func setSessionCookies(w http.ResponseWriter, r *http.Request, tokens *models.SessionTokens) {
+	http.SetCookie(w, newJWTCookie(r, tokens.Access))
+
+	if tokens.Refresh != nil {
+		http.SetCookie(w, newRefreshTokenCookie(r, tokens.Refresh))
+	}
+}
+
+func clearSessionCookies(w http.ResponseWriter, r *http.Request) {
+	expired := time.Now().Add(-time.Hour)
+
+	http.SetCookie(w, newJWTCookie(r, &models.JWTWithEat{
 		Token:      "",
-		Expiration: time.Now().Add(-time.Hour),
+		Expiration: expired,
+	}))
+	http.SetCookie(w, newRefreshTokenCookie(r, &models.RefreshToken{
+		Token:      "",
+		Expiration: expired,
 	}))
-	http.Redirect(w, r, "/auth", http.StatusSeeOther)
 }
 
-func newJWTCookie(jwt *models.JWTWithEat) *http.Cookie {
-	return &http.Cookie{
+func newJWTCookie(r *http.Request, jwt *models.JWTWithEat) *http.Cookie {
+	return withCookieAttributes(r, &http.Cookie{
 		Name:     JWTCookieName,
 		Value:    jwt.Token,
-		Secure:   false,
 		HttpOnly: true,
 		Expires:  jwt.Expiration,
 		Path:     "/",
-	}
+	})
+}
+
+func newTwoFactorChallengeCookie(r *http.Request, challenge *models.JWTWithEat) *http.Cookie {
+	return withCookieAttributes(r, &http.Cookie{
+		Name:     TwoFactorChallengeCookieName,
+		Value:    challenge.Token,
+		HttpOnly: true,
+		Expires:  challenge.Expiration,
+		Path:     twoFactorChallengePath,
+	})
+}
+
+func newRefreshTokenCookie(r *http.Request, token *models.RefreshToken) *http.Cookie {
+	return withCookieAttributes(r, &http.Cookie{
+		Name:     RefreshTokenCookieName,
+		Value:    token.Token,
+		HttpOnly: true,
+		Expires:  token.Expiration,
+		Path:     "/",
+	})
+}
+
+// withCookieAttributes applies the configured Secure, SameSite and Domain
+// attributes to the cookie.
+func withCookieAttributes(r *http.Request, cookie *http.Cookie) *http.Cookie {
+	attributes := Cookies(r)
+
+	cookie.Secure = attributes.Secure
+	cookie.SameSite = attributes.SameSite
+	cookie.Domain = attributes.Domain
+
+	return cookie
 }
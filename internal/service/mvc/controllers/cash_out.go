@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/responses"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+)
+
+type CashOut struct {
+	model *models.CashOut
+}
+
+func NewCashOut(model *models.CashOut) *CashOut {
+	return &CashOut{
+		model: model,
+	}
+}
+
+func (c *CashOut) IssueCode(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewWithdrawal(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	code, plain, err := c.model.IssueCode(CustomerID(r), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrorAccountNotFound):
+			Log(r).WithField("reason", err).Debug("not found")
+			ape.RenderErr(w, problems.NotFound())
+			return
+		case errors.Is(err, models.ErrorInsufficientFunds):
+			Log(r).WithField("reason", err).Debug("forbidden")
+			ape.RenderErr(w, problems.Forbidden())
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to issue cash-out code: %w", err))
+		return
+	}
+
+	document, err := responses.NewIssuedCashOutCodeDocument(plain, code)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to marshal cash-out code: %w", err))
+		return
+	}
+
+	ape.Render(w, document)
+}
+
+func (c *CashOut) CancelCode(w http.ResponseWriter, r *http.Request) {
+	codeID, err := requests.NewCashOutCodeID(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	if err = c.model.CancelCode(CustomerID(r), codeID); err != nil {
+		if errors.Is(err, models.ErrorCashOutCodeNotFound) {
+			ape.RenderErr(w, problems.NotFound())
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to cancel cash-out code: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Withdraw is called by the ATM, which is authenticated by its signature of
+// the withdrawal payload rather than by the customer session.
+func (c *CashOut) Withdraw(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewATMWithdrawal(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	transaction, err := c.model.Withdraw(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrorInvalidATMPayload):
+			Log(r).WithField("reason", err).Debug("bad request")
+			ape.RenderErr(w, requests.BadRequest(err)...)
+			return
+		case errors.Is(err, models.ErrorInvalidATMSignature):
+			Log(r).WithField("reason", err).Debug("bad request")
+			ape.RenderErr(w, requests.BadRequest(models.ErrorInvalidATMSignature)...)
+			return
+		case errors.Is(err, models.ErrorATMNonceReused):
+			Log(r).WithField("reason", err).Debug("bad request")
+			ape.RenderErr(w, requests.BadRequest(models.ErrorATMNonceReused)...)
+			return
+		case errors.Is(err, models.ErrorATMNotFound):
+			Log(r).WithField("reason", err).Debug("bad request")
+			ape.RenderErr(w, requests.BadRequest(models.ErrorATMNotFound)...)
+			return
+		case errors.Is(err, models.ErrorCashOutAmountMismatch):
+			Log(r).WithField("reason", err).Debug("bad request")
+			ape.RenderErr(w, requests.BadRequest(models.ErrorCashOutAmountMismatch)...)
+			return
+		case errors.Is(err, models.ErrorATMDisabled):
+			Log(r).WithField("reason", err).Debug("forbidden")
+			ape.RenderErr(w, problems.Forbidden())
+			return
+		case errors.Is(err, models.ErrorCashOutCodeNotFound), errors.Is(err, models.ErrorCashOutCodeExpired):
+			// The ATM can't tell the unknown codes from the used or expired ones
+			Log(r).WithField("reason", err).Debug("not found")
+			ape.RenderErr(w, notFound(models.ErrorCashOutCodeNotFound.Error()))
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to withdraw funds: %w", err))
+		return
+	}
+
+	document, err := responses.NewTransactionDocument(transaction)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to marshal transaction: %w", err))
+		return
+	}
+
+	ape.Render(w, document)
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/jsonapi"
+	"gitlab.com/distributed_lab/ape"
+)
+
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFHeaderName = "X-CSRF-Token"
+
+	// csrfFormField is the form field carrying the token for the forms submitted
+	// without JavaScript.
+	csrfFormField = "csrf_token"
+
+	csrfTokenLength = 32
+)
+
+var (
+	errCSRFCrossOrigin  = errors.New("cross-origin request")
+	errCSRFTokenMissing = errors.New("CSRF token is missing")
+	errCSRFTokenInvalid = errors.New("CSRF token is invalid")
+)
+
+// CSRF protects the cookie-authenticated state-changing requests with the
+// double-submit cookie: the token is stored in a cookie readable by the pages
+// and must be echoed in the X-CSRF-Token header or the csrf_token form field,
+// which another site can't do. Cross-origin requests are rejected regardless
+// of the token. Requests authenticated by an access token in the Authorization
+// header are not exposed to CSRF, so they are passed through.
+func CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(CSRFCookieName)
+		if err != nil || len(cookie.Value) != 2*csrfTokenLength {
+			if cookie, err = newCSRFCookie(r); err != nil {
+				InternalError(w, r, err)
+				return
+			}
+
+			http.SetCookie(w, cookie)
+		}
+
+		if isSafeMethod(r.Method) || strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err = checkCSRF(r, cookie.Value); err != nil {
+			Log(r).WithField("reason", err).Debug("forbidden")
+			ape.RenderErr(w, csrfError(err))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func checkCSRF(r *http.Request, token string) error {
+	if err := checkSameOrigin(r); err != nil {
+		return err
+	}
+
+	submitted := r.Header.Get(CSRFHeaderName)
+	if submitted == "" {
+		submitted = r.PostFormValue(csrfFormField)
+	}
+
+	if submitted == "" {
+		return errCSRFTokenMissing
+	}
+
+	if subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+		return errCSRFTokenInvalid
+	}
+
+	return nil
+}
+
+// checkSameOrigin compares the host of the Origin header, or the Referer when
+// the browser omits the Origin, to the requested host. Requests without both
+// headers are not sent by browsers, so they are left to the token check.
+func checkSameOrigin(r *http.Request) error {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+
+	if source == "" {
+		return nil
+	}
+
+	// Opaque origins, e.g. of sandboxed frames, are serialized as "null"
+	origin, err := url.Parse(source)
+	if err != nil || origin.Host == "" {
+		return fmt.Errorf("%w: invalid origin %q", errCSRFCrossOrigin, source)
+	}
+
+	if !strings.EqualFold(origin.Host, r.Host) {
+		return fmt.Errorf("%w: origin %q, host %q", errCSRFCrossOrigin, origin.Host, r.Host)
+	}
+
+	return nil
+}
+
+func newCSRFCookie(r *http.Request) (*http.Cookie, error) {
+	token := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate CSRF token: %w", err)
+	}
+
+	// Not HttpOnly, as the pages read the token to submit it back
+	return withCookieAttributes(r, &http.Cookie{
+		Name:  CSRFCookieName,
+		Value: hex.EncodeToString(token),
+		Path:  "/",
+	}), nil
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
+
+func csrfError(err error) *jsonapi.ErrorObject {
+	return &jsonapi.ErrorObject{
+		Title:  http.StatusText(http.StatusForbidden),
+		Status: fmt.Sprintf("%d", http.StatusForbidden),
+		Detail: err.Error(),
+	}
+}
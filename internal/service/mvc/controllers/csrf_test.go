@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/omegatymbjiep/ilab1/internal/config"
+)
+
+func TestCSRF(t *testing.T) {
+	handler := CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		ctx := CtxLog(logan.New().Level(logan.ErrorLevel).WithField("test", t.Name()))(r.Context())
+		ctx = CtxCookies(&config.Cookies{Secure: true, SameSite: http.SameSiteStrictMode})(ctx)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r.WithContext(ctx))
+		return w
+	}
+
+	page := serve(httptest.NewRequest(http.MethodGet, "http://bank.local/auth", nil))
+	require.Equal(t, http.StatusNoContent, page.Code)
+
+	cookies := page.Result().Cookies()
+	require.Len(t, cookies, 1)
+	token := cookies[0]
+	assert.Equal(t, CSRFCookieName, token.Name)
+	assert.True(t, token.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, token.SameSite)
+	assert.False(t, token.HttpOnly, "pages must be able to read the token")
+
+	tests := []struct {
+		name    string
+		request func() *http.Request
+		status  int
+	}{
+		{
+			name: "token in header",
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "http://bank.local/api/v1/accounts", nil)
+				r.Header.Set("Origin", "http://bank.local")
+				r.Header.Set(CSRFHeaderName, token.Value)
+				return r
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name: "token in form",
+			request: func() *http.Request {
+				form := url.Values{csrfFormField: {token.Value}}
+				r := httptest.NewRequest(http.MethodPost, "http://bank.local/logout/everywhere", strings.NewReader(form.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				r.Header.Set("Referer", "http://bank.local/settings")
+				return r
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name: "missing token",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodDelete, "http://bank.local/api/v1/accounts/1", nil)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "wrong token",
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "http://bank.local/auth/login", nil)
+				r.Header.Set(CSRFHeaderName, strings.Repeat("0", len(token.Value)))
+				return r
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "cross origin",
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "http://bank.local/api/v1/accounts", nil)
+				r.Header.Set("Origin", "http://evil.local")
+				r.Header.Set(CSRFHeaderName, token.Value)
+				return r
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "bearer token",
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "http://bank.local/api/v1/accounts", nil)
+				r.Header.Set("Authorization", "Bearer token")
+				return r
+			},
+			status: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.request()
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+				r.AddCookie(token)
+			}
+
+			w := serve(r)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+}
@@ -7,6 +7,9 @@ import (
 
 	"github.com/google/uuid"
 	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/omegatymbjiep/ilab1/internal/config"
+	"github.com/omegatymbjiep/ilab1/internal/data"
 )
 
 type ctxKey int
@@ -15,6 +18,9 @@ const (
 	logCtxKey ctxKey = iota
 	templatesCtxKey
 	customerIDCtxKey
+	sessionIDCtxKey
+	accessTokenCtxKey
+	cookiesCtxKey
 )
 
 func CtxLog(entry *logan.Entry) func(ctx context.Context) context.Context {
@@ -37,6 +43,30 @@ func Templates(r *http.Request) *template.Template {
 	return r.Context().Value(templatesCtxKey).(*template.Template)
 }
 
+func CtxCookies(cookies *config.Cookies) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, cookiesCtxKey, cookies)
+	}
+}
+
+func Cookies(r *http.Request) *config.Cookies {
+	return r.Context().Value(cookiesCtxKey).(*config.Cookies)
+}
+
 func CustomerID(r *http.Request) uuid.UUID {
 	return r.Context().Value(customerIDCtxKey).(uuid.UUID)
 }
+
+// SessionID returns the session the request is authenticated by, or uuid.Nil
+// for the requests authenticated by an access token.
+func SessionID(r *http.Request) uuid.UUID {
+	sessionID, _ := r.Context().Value(sessionIDCtxKey).(uuid.UUID)
+	return sessionID
+}
+
+// AccessToken returns the access token the request is authenticated by, or nil
+// for the requests authenticated by the browser session.
+func AccessToken(r *http.Request) *data.AccessToken {
+	token, _ := r.Context().Value(accessTokenCtxKey).(*data.AccessToken)
+	return token
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/jsonapi"
+	"gitlab.com/distributed_lab/ape"
+
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+type Idempotency struct {
+	model *models.Idempotency
+}
+
+func NewIdempotency(model *models.Idempotency) *Idempotency {
+	return &Idempotency{
+		model: model,
+	}
+}
+
+// Middleware makes the requests carrying the Idempotency-Key header safe to retry:
+// the response to the first request is stored per customer and replayed for
+// the retries, while reusing the key for a different request is rejected.
+// Requests without the header are passed through unchanged.
+func (c *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			ape.RenderErr(w, requests.BadRequest(
+				fmt.Errorf("%s must not be longer than %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength),
+			)...)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			Log(r).WithField("reason", err).Debug("bad request")
+			ape.RenderErr(w, requests.BadRequest(err)...)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := c.model.Begin(CustomerID(r), key, requestHash(r, body))
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrorIdempotencyKeyReused):
+				Log(r).WithField("reason", err).Debug("unprocessable entity")
+				ape.RenderErr(w, idempotencyError(http.StatusUnprocessableEntity, err))
+				return
+			case errors.Is(err, models.ErrorIdempotencyKeyInProgress):
+				Log(r).WithField("reason", err).Debug("conflict")
+				ape.RenderErr(w, idempotencyError(http.StatusConflict, err))
+				return
+			}
+
+			InternalError(w, r, fmt.Errorf("failed to begin idempotent request: %w", err))
+			return
+		}
+
+		if record.IsCompleted() {
+			if record.ContentType != nil {
+				w.Header().Set("Content-Type", *record.ContentType)
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(*record.StatusCode)
+			_, _ = w.Write(record.ResponseBody)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		// the outcome of a failed request is unknown, so the key is released to allow retries
+		if recorder.status >= http.StatusInternalServerError {
+			if err = c.model.Release(record); err != nil {
+				Log(r).WithError(err).Error("failed to release idempotency key")
+			}
+			return
+		}
+
+		err = c.model.Complete(record, recorder.status, w.Header().Get("Content-Type"), recorder.body.Bytes())
+		if err != nil {
+			Log(r).WithError(err).Error("failed to complete idempotent request")
+		}
+	})
+}
+
+// requestHash fingerprints the request, so the key can't be reused for another one.
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s %s\n", r.Method, r.URL.Path)
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func idempotencyError(status int, err error) *jsonapi.ErrorObject {
+	return &jsonapi.ErrorObject{
+		Title:  http.StatusText(status),
+		Status: fmt.Sprintf("%d", status),
+		Detail: err.Error(),
+	}
+}
+
+// responseRecorder passes the response through, keeping a copy of its status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+
+	return r.ResponseWriter.Write(b)
+}
@@ -5,34 +5,144 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
-	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
 )
 
+const bearerPrefix = "Bearer "
+
 func (c *Auth) VerifyJWT(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		customerID, err := c.verifyJWT(r)
+		claims, err := c.authenticate(w, r)
 		if err != nil {
 			Unauthorized(w, r, fmt.Errorf("invalid jwt: %w", err))
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), customerIDCtxKey, customerID)
-
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
 	})
 }
 
-func (c *Auth) verifyJWT(r *http.Request) (uuid.UUID, error) {
+// authenticate verifies the JWT cookie. When the access token is missing or
+// invalid, the session is refreshed with the refresh token cookie if there is one.
+func (c *Auth) authenticate(w http.ResponseWriter, r *http.Request) (*models.Claims, error) {
+	claims, err := c.verifyJWT(r)
+	if err == nil {
+		return claims, nil
+	}
+
+	refreshCookie, refreshErr := r.Cookie(RefreshTokenCookieName)
+	if refreshErr != nil {
+		return nil, err
+	}
+
+	tokens, err := c.model.Refresh(refreshCookie.Value)
+	if err != nil {
+		clearSessionCookies(w, r)
+		return nil, fmt.Errorf("failed to refresh session: %w", err)
+	}
+
+	setSessionCookies(w, r, tokens)
+
+	claims, err = c.model.VerifyJWT(tokens.Access.Token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid refreshed jwt: %w", err)
+	}
+
+	return claims, nil
+}
+
+func (c *Auth) verifyJWT(r *http.Request) (*models.Claims, error) {
 	jwtCookie, err := r.Cookie(JWTCookieName)
 	if errors.Is(err, http.ErrNoCookie) {
-		return uuid.Nil, fmt.Errorf("no jwt provided")
+		return nil, fmt.Errorf("no jwt provided")
 	}
 
-	customerID, err := c.model.VerifyJWT(jwtCookie.Value)
+	claims, err := c.model.VerifyJWT(jwtCookie.Value)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid jwt: %w", err)
+		return nil, fmt.Errorf("invalid jwt: %w", err)
+	}
+
+	return claims, nil
+}
+
+func withClaims(ctx context.Context, claims *models.Claims) context.Context {
+	ctx = context.WithValue(ctx, customerIDCtxKey, claims.CustomerID)
+	return context.WithValue(ctx, sessionIDCtxKey, claims.SessionID)
+}
+
+// VerifyAPI authenticates the API requests either by the personal access token
+// passed as "Authorization: Bearer", or by the JWT cookie of the browser session.
+// Unlike VerifyJWT, it renders a JSON:API error instead of redirecting to the login page.
+func (c *Auth) VerifyAPI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if header := r.Header.Get("Authorization"); header != "" {
+			plain, ok := strings.CutPrefix(header, bearerPrefix)
+			if !ok {
+				APIUnauthorized(w, r, errors.New("unsupported authorization scheme"))
+				return
+			}
+
+			token, err := c.accessTokens.VerifyAccessToken(plain)
+			if err != nil {
+				if errors.Is(err, models.ErrorInvalidAccessToken) {
+					APIUnauthorized(w, r, err)
+					return
+				}
+
+				InternalError(w, r, fmt.Errorf("failed to verify access token: %w", err))
+				return
+			}
+
+			ctx = context.WithValue(ctx, customerIDCtxKey, token.CustomerID)
+			ctx = context.WithValue(ctx, accessTokenCtxKey, token)
+		} else {
+			claims, err := c.authenticate(w, r)
+			if err != nil {
+				APIUnauthorized(w, r, fmt.Errorf("invalid jwt: %w", err))
+				return
+			}
+
+			ctx = withClaims(ctx, claims)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireScope rejects the requests authenticated by an access token without
+// the scope. Browser sessions are not limited by scopes.
+func RequireScope(scope data.AccessScope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token := AccessToken(r); token != nil && !token.HasScope(scope) {
+				Log(r).WithField("scope", scope).Debug("forbidden")
+				ape.RenderErr(w, problems.Forbidden())
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
 	}
+}
 
-	return customerID, nil
+// RequireSession rejects the requests authenticated by an access token, so the
+// tokens can't be used to manage the tokens themselves.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if AccessToken(r) != nil {
+			Log(r).Debug("forbidden for access tokens")
+			ape.RenderErr(w, problems.Forbidden())
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
 }
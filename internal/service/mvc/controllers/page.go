@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/google/jsonapi"
+
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+)
+
+// pageLinks builds the JSON:API pagination links, keeping the rest of the request query.
+func pageLinks(r *http.Request, page requests.PageParams, total uint64) *jsonapi.Links {
+	link := func(number uint64) string {
+		query := r.URL.Query()
+		query.Set(requests.PageLimitParam, strconv.FormatUint(page.Limit, 10))
+		query.Set(requests.PageNumberParam, strconv.FormatUint(number, 10))
+
+		return r.URL.Path + "?" + query.Encode()
+	}
+
+	var lastPage uint64
+	if total > 0 {
+		lastPage = (total - 1) / page.Limit
+	}
+
+	links := jsonapi.Links{
+		"self":               link(page.Number),
+		jsonapi.KeyFirstPage: link(0),
+		jsonapi.KeyLastPage:  link(lastPage),
+	}
+
+	if page.Number > 0 {
+		links[jsonapi.KeyPreviousPage] = link(page.Number - 1)
+	}
+
+	if page.Offset()+page.Limit < total {
+		links[jsonapi.KeyNextPage] = link(page.Number + 1)
+	}
+
+	return &links
+}
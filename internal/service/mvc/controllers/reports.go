@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/responses"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models/report"
+)
+
+// reportsPath is the path of the report jobs in the API
+const reportsPath = "/api/v1/reports"
+
+type Reports struct {
+	model *models.ReportJobs
+}
+
+func NewReports(model *models.ReportJobs) *Reports {
+	return &Reports{
+		model: model,
+	}
+}
+
+// RequestReport enqueues the report job, its status is polled by GetReport.
+func (c *Reports) RequestReport(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewReportJob(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	job, err := c.model.Enqueue(CustomerID(r), req)
+	if err != nil {
+		if errors.Is(err, models.ErrorAccountNotFound) {
+			ape.RenderErr(w, problems.NotFound())
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to enqueue report job: %w", err))
+		return
+	}
+
+	c.renderJob(w, r, job)
+}
+
+// GetReport renders the status of the report job with the download link once
+// the report is ready.
+func (c *Reports) GetReport(w http.ResponseWriter, r *http.Request) {
+	jobID, err := requests.NewReportJobID(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	job, err := c.model.GetJob(CustomerID(r), jobID)
+	if err != nil {
+		if errors.Is(err, models.ErrorReportJobNotFound) {
+			ape.RenderErr(w, problems.NotFound())
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to get report job: %w", err))
+		return
+	}
+
+	c.renderJob(w, r, job)
+}
+
+// DownloadReport sends the report of the completed job.
+func (c *Reports) DownloadReport(w http.ResponseWriter, r *http.Request) {
+	jobID, err := requests.NewReportJobID(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	job, content, err := c.model.Download(CustomerID(r), jobID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrorReportJobNotFound), errors.Is(err, models.ErrorReportExpired):
+			Log(r).WithField("reason", err).Debug("not found")
+			ape.RenderErr(w, problems.NotFound())
+			return
+		case errors.Is(err, models.ErrorReportNotReady):
+			Log(r).WithField("reason", err).Debug("conflict")
+			ape.RenderErr(w, problems.Conflict())
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to download report: %w", err))
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", reportContentType(job.Format))
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=report_%s.%s", job.ID.String(), reportExtension(job.Format)))
+
+	// The status is already sent once the copy fails
+	if _, err = io.Copy(w, content); err != nil {
+		Log(r).WithError(err).Error("failed to send report")
+		return
+	}
+}
+
+func (c *Reports) renderJob(w http.ResponseWriter, r *http.Request, job *data.ReportJob) {
+	document, err := responses.NewReportJobDocument(job, reportPath(job.ID), reportPath(job.ID)+"/download")
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to marshal report job: %w", err))
+		return
+	}
+
+	ape.Render(w, document)
+}
+
+func reportPath(jobID uuid.UUID) string {
+	return reportsPath + "/" + jobID.String()
+}
+
+func reportContentType(format string) string {
+	if format == report.ExcelFormat {
+		return report.ExcelContentType
+	}
+
+	return report.StatementFormat(format).ContentType()
+}
+
+func reportExtension(format string) string {
+	if format == report.ExcelFormat {
+		return report.ExcelFormat
+	}
+
+	return report.StatementFormat(format).Extension()
+}
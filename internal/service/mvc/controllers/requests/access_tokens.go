@@ -0,0 +1,41 @@
+package requests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type CreateAccessToken struct {
+	Name          string   `json:"name"            validate:"required,min=3,max=100"`
+	Scopes        []string `json:"scopes"          validate:"required,min=1,unique,dive,access_scope"`
+	ExpiresInDays uint     `json:"expires_in_days" validate:"omitempty,max=365"`
+}
+
+// NewCreateAccessToken parses HTTP request and validates input
+func NewCreateAccessToken(r *http.Request) (*CreateAccessToken, error) {
+	var req CreateAccessToken
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("failed to decode json request body: %w", err)
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+// NewAccessTokenID parses the access token ID from the path.
+func NewAccessTokenID(r *http.Request) (uuid.UUID, error) {
+	tokenID, err := uuid.Parse(r.PathValue("token-id"))
+	if err != nil {
+		return uuid.Nil, errors.New("invalid access token id")
+	}
+
+	return tokenID, nil
+}
@@ -0,0 +1,77 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCreateAccessToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "valid input",
+			body: map[string]interface{}{
+				"name":            "ci token",
+				"scopes":          []string{"accounts:read", "transactions:write"},
+				"expires_in_days": 30,
+			},
+			wantErr: false,
+		},
+		{
+			name: "no scopes",
+			body: map[string]interface{}{
+				"name":   "ci token",
+				"scopes": []string{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown scope",
+			body: map[string]interface{}{
+				"name":   "ci token",
+				"scopes": []string{"accounts:admin"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate scopes",
+			body: map[string]interface{}{
+				"name":   "ci token",
+				"scopes": []string{"accounts:read", "accounts:read"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "expiration too long",
+			body: map[string]interface{}{
+				"name":            "ci token",
+				"scopes":          []string{"accounts:read"},
+				"expires_in_days": 1000,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			r, _ := http.NewRequest("POST", "/api/v1/tokens", bytes.NewBuffer(body))
+
+			got, err := NewCreateAccessToken(r)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
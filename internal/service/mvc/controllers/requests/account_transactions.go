@@ -0,0 +1,78 @@
+package requests
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	TypeFilterParam      = "filter[type]"
+	DirectionFilterParam = "filter[direction]"
+
+	DirectionIncoming = "incoming"
+	DirectionOutgoing = "outgoing"
+)
+
+type AccountTransactions struct {
+	AccountID uuid.UUID
+	Type      *data.TransactionType
+	Direction string `validate:"omitempty,oneof=incoming outgoing"`
+	Order     string `validate:"oneof=asc desc"`
+
+	PageParams
+}
+
+// NewAccountTransactions parses the account ID from the path and the filters
+// and pagination params from the query.
+func NewAccountTransactions(r *http.Request) (*AccountTransactions, error) {
+	accountID, err := uuid.Parse(r.PathValue("account-id"))
+	if err != nil {
+		return nil, errors.New("invalid account id")
+	}
+
+	page, err := newPageParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	req := AccountTransactions{
+		AccountID:  accountID,
+		Direction:  r.URL.Query().Get(DirectionFilterParam),
+		Order:      pageOrder(r),
+		PageParams: page,
+	}
+
+	if rawType := r.URL.Query().Get(TypeFilterParam); rawType != "" {
+		transactionType, err := parseTransactionType(rawType)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Type = &transactionType
+	}
+
+	if err = validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+func parseTransactionType(raw string) (data.TransactionType, error) {
+	for _, t := range []data.TransactionType{
+		data.DepositTransaction,
+		data.WithdrawalTransaction,
+		data.TransferTransaction,
+	} {
+		if t.String() == raw {
+			return t, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid %s: %q", TypeFilterParam, raw)
+}
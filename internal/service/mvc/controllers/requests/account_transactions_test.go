@@ -0,0 +1,90 @@
+package requests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+func TestNewAccountTransactions(t *testing.T) {
+	accountID := uuid.New()
+	tests := []struct {
+		name      string
+		accountID string
+		query     string
+		wantErr   bool
+		want      *AccountTransactions
+	}{
+		{
+			name:      "defaults",
+			accountID: accountID.String(),
+			want: &AccountTransactions{
+				AccountID:  accountID,
+				Order:      OrderDesc,
+				PageParams: PageParams{Limit: defaultPageLimit},
+			},
+		},
+		{
+			name:      "filters and pagination",
+			accountID: accountID.String(),
+			query:     "filter[type]=transfer&filter[direction]=outgoing&page[limit]=5&page[number]=2&page[order]=asc",
+			want: &AccountTransactions{
+				AccountID:  accountID,
+				Type:       func() *data.TransactionType { t := data.TransferTransaction; return &t }(),
+				Direction:  DirectionOutgoing,
+				Order:      OrderAsc,
+				PageParams: PageParams{Limit: 5, Number: 2},
+			},
+		},
+		{
+			name:      "invalid account id",
+			accountID: "not-a-uuid",
+			wantErr:   true,
+		},
+		{
+			name:      "unknown type",
+			accountID: accountID.String(),
+			query:     "filter[type]=refund",
+			wantErr:   true,
+		},
+		{
+			name:      "unknown direction",
+			accountID: accountID.String(),
+			query:     "filter[direction]=sideways",
+			wantErr:   true,
+		},
+		{
+			name:      "limit too big",
+			accountID: accountID.String(),
+			query:     "page[limit]=1000",
+			wantErr:   true,
+		},
+		{
+			name:      "invalid order",
+			accountID: accountID.String(),
+			query:     "page[order]=random",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/api/v1/accounts/"+tt.accountID+"/transactions?"+tt.query, nil)
+			r.SetPathValue("account-id", tt.accountID)
+
+			got, err := NewAccountTransactions(r)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.want.Limit*tt.want.Number, got.Offset())
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package requests
+
+import (
+	"net/http"
+)
+
+type Activity struct {
+	PageParams
+}
+
+func NewActivity(r *http.Request) (*Activity, error) {
+	page, err := newPageParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	req := Activity{
+		PageParams: page,
+	}
+
+	if err = validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
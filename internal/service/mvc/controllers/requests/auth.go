@@ -2,26 +2,31 @@ package requests
 
 import (
 	"net/http"
+	"strings"
 )
 
 type Register struct {
-	Email        string `validate:"required,email"`
-	Username     string `validate:"required,min=3,max=50"`
-	PasswordHash string `validate:"required,bcrypt"`
-	FirstName    string `validate:"omitempty,min=2,max=50"`
-	LastName     string `validate:"omitempty,min=2,max=50"`
+	Email     string `validate:"required,email"`
+	Username  string `validate:"required,min=3,max=50"`
+	Password  string `validate:"required,min=8,max=128,password_strength"`
+	FirstName string `validate:"omitempty,min=2,max=50"`
+	LastName  string `validate:"omitempty,min=2,max=50"`
 }
 
 // NewRegister parses HTTP request and validates input
 func NewRegister(r *http.Request) (*Register, error) {
 	req := Register{
-		Email:        r.FormValue("email"),
-		Username:     r.FormValue("username"),
-		PasswordHash: r.FormValue("password_hash"),
-		FirstName:    r.FormValue("first_name"),
-		LastName:     r.FormValue("last_name"),
+		Email:     r.FormValue("email"),
+		Username:  r.FormValue("username"),
+		Password:  r.FormValue("password"),
+		FirstName: r.FormValue("first_name"),
+		LastName:  r.FormValue("last_name"),
 	}
 
+	// The password is hashed without the surrounding spaces, so only the rest
+	// of it must be strong
+	req.Password = strings.TrimSpace(req.Password)
+
 	if err := validate.Struct(req); err != nil {
 		return nil, err
 	}
@@ -31,7 +36,7 @@ func NewRegister(r *http.Request) (*Register, error) {
 
 type Login struct {
 	Email    string `validate:"required,email"`
-	Password string `validate:"required,hexadecimal,len=64"`
+	Password string `validate:"required,max=128"`
 }
 
 // NewLogin parses HTTP request and validates input
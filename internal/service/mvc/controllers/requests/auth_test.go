@@ -10,11 +10,11 @@ import (
 
 func TestNewRegister_ValidInput(t *testing.T) {
 	form := url.Values{
-		"email":         {"test@example.com"},
-		"username":      {"validUser"},
-		"password_hash": {"$2a$12$yUm4iidTxeB/pVObFGnp4OnbfABLRS8Ga01cY3rt5j.oOSjC5aeZu"},
-		"first_name":    {"John"},
-		"last_name":     {"Doe"},
+		"email":      {"test@example.com"},
+		"username":   {"validUser"},
+		"password":   {"Correct-Horse-42"},
+		"first_name": {"John"},
+		"last_name":  {"Doe"},
 	}
 	r, _ := http.NewRequest("POST", "/register", nil)
 	r.PostForm = form
@@ -28,9 +28,9 @@ func TestNewRegister_ValidInput(t *testing.T) {
 
 func TestNewRegister_InvalidEmail(t *testing.T) {
 	form := url.Values{
-		"email":         {"invalid-email"},
-		"username":      {"validUser"},
-		"password_hash": {"$2a$12$yUm4iidTxeB/pVObFGnp4OnbfABLRS8Ga01cY3rt5j.oOSjC5aeZu"},
+		"email":    {"invalid-email"},
+		"username": {"validUser"},
+		"password": {"Correct-Horse-42"},
 	}
 	r, _ := http.NewRequest("POST", "/register", nil)
 	r.PostForm = form
@@ -41,9 +41,9 @@ func TestNewRegister_InvalidEmail(t *testing.T) {
 
 func TestNewRegister_ShortUsername(t *testing.T) {
 	form := url.Values{
-		"email":         {"test@example.com"},
-		"username":      {"ab"}, // Too short
-		"password_hash": {"$2a$12$yUm4iidTxeB/pVObFGnp4OnbfABLRS8Ga01cY3rt5j.oOSjC5aeZu"},
+		"email":    {"test@example.com"},
+		"username": {"ab"}, // Too short
+		"password": {"Correct-Horse-42"},
 	}
 	r, _ := http.NewRequest("POST", "/register", nil)
 	r.PostForm = form
@@ -52,24 +52,40 @@ func TestNewRegister_ShortUsername(t *testing.T) {
 	assert.Error(t, err)
 }
 
-func TestNewRegister_InvalidBcryptHash(t *testing.T) {
-	form := url.Values{
-		"email":         {"test@example.com"},
-		"username":      {"validUser"},
-		"password_hash": {"invalidhash"}, // Not a bcrypt hash
+func TestNewRegister_WeakPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+	}{
+		{name: "too short", password: "Ab1!"},
+		{name: "too few character classes", password: "alllowercase"},
+		{name: "contains username", password: "my-validUser-42"},
+		{name: "contains email name", password: "Test-1234567"},
+		{name: "too short without spaces", password: "   Abc-1   "},
+		{name: "too few character classes without spaces", password: " alllowercase42 "},
 	}
-	r, _ := http.NewRequest("POST", "/register", nil)
-	r.PostForm = form
 
-	_, err := NewRegister(r)
-	assert.Error(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{
+				"email":    {"test@example.com"},
+				"username": {"validUser"},
+				"password": {tt.password},
+			}
+			r, _ := http.NewRequest("POST", "/register", nil)
+			r.PostForm = form
+
+			_, err := NewRegister(r)
+			assert.Error(t, err)
+		})
+	}
 }
 
 func TestNewRegister_ValidOptionalNames(t *testing.T) {
 	form := url.Values{
-		"email":         {"test@example.com"},
-		"username":      {"validUser"},
-		"password_hash": {"$2a$12$yUm4iidTxeB/pVObFGnp4OnbfABLRS8Ga01cY3rt5j.oOSjC5aeZu"},
+		"email":    {"test@example.com"},
+		"username": {"validUser"},
+		"password": {"Correct-Horse-42"},
 	}
 	r, _ := http.NewRequest("POST", "/register", nil)
 	r.PostForm = form
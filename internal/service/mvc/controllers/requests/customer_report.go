@@ -0,0 +1,26 @@
+package requests
+
+import (
+	"net/http"
+	"time"
+)
+
+// CustomerExcelReport is the period of the workbook with all the accounts of
+// the customer, see Statement.
+type CustomerExcelReport struct {
+	From time.Time
+	To   time.Time
+}
+
+// NewCustomerExcelReport parses the period from the query.
+func NewCustomerExcelReport(r *http.Request) (*CustomerExcelReport, error) {
+	from, to, err := parsePeriod(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CustomerExcelReport{
+		From: from,
+		To:   to,
+	}, nil
+}
@@ -0,0 +1,91 @@
+package requests
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	MinAmountFilterParam = "filter[min_amount]"
+	MaxAmountFilterParam = "filter[max_amount]"
+)
+
+// ExcelReport is the period of the Excel report, see Statement, and the
+// filters of the transactions in the history sheet. The amounts are in cents.
+type ExcelReport struct {
+	AccountID uuid.UUID
+	From      time.Time
+	To        time.Time
+
+	Type      *data.TransactionType
+	MinAmount *uint
+	MaxAmount *uint
+}
+
+// NewExcelReport parses the account ID from the path and the period and the
+// filters from the query.
+func NewExcelReport(r *http.Request) (*ExcelReport, error) {
+	accountID, err := uuid.Parse(r.PathValue("account-id"))
+	if err != nil {
+		return nil, errors.New("invalid account id")
+	}
+
+	from, to, err := parsePeriod(r)
+	if err != nil {
+		return nil, err
+	}
+
+	req := ExcelReport{
+		AccountID: accountID,
+		From:      from,
+		To:        to,
+	}
+
+	query := r.URL.Query()
+
+	if rawType := query.Get(TypeFilterParam); rawType != "" {
+		transactionType, err := parseTransactionType(rawType)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Type = &transactionType
+	}
+
+	if req.MinAmount, err = parseAmountFilter(query.Get(MinAmountFilterParam), MinAmountFilterParam); err != nil {
+		return nil, err
+	}
+
+	if req.MaxAmount, err = parseAmountFilter(query.Get(MaxAmountFilterParam), MaxAmountFilterParam); err != nil {
+		return nil, err
+	}
+
+	if req.MinAmount != nil && req.MaxAmount != nil && *req.MinAmount > *req.MaxAmount {
+		return nil, fmt.Errorf("%s must not be greater than %s", MinAmountFilterParam, MaxAmountFilterParam)
+	}
+
+	return &req, nil
+}
+
+// parseAmountFilter parses the amount in cents, nil if the param is not set.
+func parseAmountFilter(raw, param string) (*uint, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	amount, err := strconv.ParseUint(raw, 10, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: expected amount in cents", param)
+	}
+
+	value := uint(amount)
+
+	return &value, nil
+}
@@ -0,0 +1,108 @@
+package requests
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+func TestNewExcelReport(t *testing.T) {
+	accountID := uuid.New()
+	withdrawal := data.WithdrawalTransaction
+	minAmount, maxAmount := uint(100), uint(5000)
+
+	tests := []struct {
+		name    string
+		query   url.Values
+		wantErr bool
+		want    ExcelReport
+	}{
+		{
+			name: "period and filters",
+			query: url.Values{
+				FromParam:            {"2024-03-01"},
+				ToParam:              {"2024-03-31"},
+				TypeFilterParam:      {"withdrawal"},
+				MinAmountFilterParam: {"100"},
+				MaxAmountFilterParam: {"5000"},
+			},
+			want: ExcelReport{
+				AccountID: accountID,
+				From:      time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+				To:        time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+				Type:      &withdrawal,
+				MinAmount: &minAmount,
+				MaxAmount: &maxAmount,
+			},
+		},
+		{
+			name: "min amount only",
+			query: url.Values{
+				FromParam:            {"2024-03-01"},
+				ToParam:              {"2024-03-01"},
+				MinAmountFilterParam: {"100"},
+			},
+			want: ExcelReport{
+				AccountID: accountID,
+				From:      time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+				To:        time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC),
+				MinAmount: &minAmount,
+			},
+		},
+		{
+			name:    "unknown type",
+			query:   url.Values{TypeFilterParam: {"refund"}},
+			wantErr: true,
+		},
+		{
+			name:    "negative amount",
+			query:   url.Values{MinAmountFilterParam: {"-1"}},
+			wantErr: true,
+		},
+		{
+			name:    "decimal amount",
+			query:   url.Values{MaxAmountFilterParam: {"10.50"}},
+			wantErr: true,
+		},
+		{
+			name:    "min above max",
+			query:   url.Values{MinAmountFilterParam: {"5000"}, MaxAmountFilterParam: {"100"}},
+			wantErr: true,
+		},
+		{
+			name:    "from after to",
+			query:   url.Values{FromParam: {"2024-03-02"}, ToParam: {"2024-03-01"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/api/v1/accounts/"+accountID.String()+"/excel?"+tt.query.Encode(), nil)
+			r.SetPathValue("account-id", accountID.String())
+
+			got, err := NewExcelReport(r)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *got)
+		})
+	}
+
+	r, _ := http.NewRequest("GET", "/api/v1/accounts/not-a-uuid/excel", nil)
+	r.SetPathValue("account-id", "not-a-uuid")
+
+	_, err := NewExcelReport(r)
+	assert.Error(t, err)
+}
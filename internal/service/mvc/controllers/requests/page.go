@@ -0,0 +1,60 @@
+package requests
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	PageLimitParam  = "page[limit]"
+	PageNumberParam = "page[number]"
+	PageOrderParam  = "page[order]"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+
+	defaultPageLimit = 10
+)
+
+// PageParams are the JSON:API offset pagination params, page numbers start from 0.
+type PageParams struct {
+	Limit  uint64 `validate:"min=1,max=100"`
+	Number uint64
+}
+
+func (p *PageParams) Offset() uint64 {
+	return p.Limit * p.Number
+}
+
+func newPageParams(r *http.Request) (PageParams, error) {
+	params := PageParams{
+		Limit: defaultPageLimit,
+	}
+
+	query := r.URL.Query()
+
+	var err error
+	if limit := query.Get(PageLimitParam); limit != "" {
+		if params.Limit, err = strconv.ParseUint(limit, 10, 64); err != nil {
+			return params, fmt.Errorf("invalid %s: %w", PageLimitParam, err)
+		}
+	}
+
+	if number := query.Get(PageNumberParam); number != "" {
+		if params.Number, err = strconv.ParseUint(number, 10, 64); err != nil {
+			return params, fmt.Errorf("invalid %s: %w", PageNumberParam, err)
+		}
+	}
+
+	return params, nil
+}
+
+// pageOrder returns the requested sort order by creation time, newest first by default.
+func pageOrder(r *http.Request) string {
+	if order := r.URL.Query().Get(PageOrderParam); order != "" {
+		return order
+	}
+
+	return OrderDesc
+}
@@ -0,0 +1,77 @@
+package requests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models/report"
+)
+
+// ReportJob requests the report generated in the background: the statement
+// of the account in one of the statement formats or the Excel report, of the
+// account or of all the accounts of the customer if AccountID is nil. The
+// period is the same as of Statement.
+type ReportJob struct {
+	AccountID *uuid.UUID
+	Format    string
+	From      time.Time
+	To        time.Time
+}
+
+type reportJobBody struct {
+	AccountID *uuid.UUID `json:"account_id"`
+	Format    string     `json:"format" validate:"required"`
+	// From and To are the days, YYYY-MM-DD in UTC
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// NewReportJob parses the report job from the JSON body.
+func NewReportJob(r *http.Request) (*ReportJob, error) {
+	var body reportJobBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	if err := validate.Struct(body); err != nil {
+		return nil, err
+	}
+
+	isStatement := slices.Contains(report.StatementFormats(), report.StatementFormat(body.Format))
+	if !isStatement && body.Format != report.ExcelFormat {
+		return nil, fmt.Errorf("invalid %s: %q", FormatParam, body.Format)
+	}
+
+	// The statements are of a single account, the Excel report may cover all of them
+	if isStatement && body.AccountID == nil {
+		return nil, fmt.Errorf("account_id is required for the %s format", body.Format)
+	}
+
+	from, to, err := parsePeriodDays(body.From, body.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReportJob{
+		AccountID: body.AccountID,
+		Format:    body.Format,
+		From:      from,
+		To:        to,
+	}, nil
+}
+
+// NewReportJobID parses the report job ID from the path.
+func NewReportJobID(r *http.Request) (uuid.UUID, error) {
+	jobID, err := uuid.Parse(r.PathValue("report-id"))
+	if err != nil {
+		return uuid.Nil, errors.New("invalid report id")
+	}
+
+	return jobID, nil
+}
@@ -0,0 +1,107 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReportJob(t *testing.T) {
+	accountID := uuid.New()
+	tests := []struct {
+		name          string
+		body          map[string]interface{}
+		wantErr       bool
+		wantAccountID *uuid.UUID
+		wantFrom      time.Time
+		wantTo        time.Time
+	}{
+		{
+			name: "statement",
+			body: map[string]interface{}{
+				"account_id": accountID,
+				"format":     "camt053",
+				"from":       "2024-03-01",
+				"to":         "2024-03-31",
+			},
+			wantAccountID: &accountID,
+			wantFrom:      time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:        time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "excel report of all accounts",
+			body: map[string]interface{}{
+				"format": "xlsx",
+				"from":   "2024-03-01",
+				"to":     "2024-03-31",
+			},
+			wantFrom: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "statement of all accounts",
+			body: map[string]interface{}{
+				"format": "pdf",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing format",
+			body: map[string]interface{}{
+				"account_id": accountID,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown format",
+			body: map[string]interface{}{
+				"account_id": accountID,
+				"format":     "qif",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid account id",
+			body: map[string]interface{}{
+				"account_id": "not-a-uuid",
+				"format":     "xlsx",
+			},
+			wantErr: true,
+		},
+		{
+			name: "from after to",
+			body: map[string]interface{}{
+				"format": "xlsx",
+				"from":   "2024-03-02",
+				"to":     "2024-03-01",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			r, _ := http.NewRequest("POST", "/api/v1/reports", bytes.NewBuffer(body))
+
+			got, err := NewReportJob(r)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAccountID, got.AccountID)
+			assert.Equal(t, tt.body["format"], got.Format)
+			assert.Equal(t, tt.wantFrom, got.From)
+			assert.Equal(t, tt.wantTo, got.To)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package requests
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models/report"
+)
+
+const (
+	FromParam   = "from"
+	ToParam     = "to"
+	FormatParam = "format"
+
+	// PeriodDateLayout is the layout of the period bounds, both inclusive
+	PeriodDateLayout = time.DateOnly
+)
+
+// Statement is the period and the format of the account statement. From is
+// the start of the first day, or zero for the start of the account history,
+// and To is the end of the last day, exclusive, or the request time if the
+// last day is not set.
+type Statement struct {
+	AccountID uuid.UUID
+	From      time.Time
+	To        time.Time
+	Format    report.StatementFormat
+}
+
+// NewStatement parses the account ID from the path and the period days,
+// YYYY-MM-DD in UTC, and the format, PDF by default, from the query.
+func NewStatement(r *http.Request) (*Statement, error) {
+	accountID, err := uuid.Parse(r.PathValue("account-id"))
+	if err != nil {
+		return nil, errors.New("invalid account id")
+	}
+
+	from, to, err := parsePeriod(r)
+	if err != nil {
+		return nil, err
+	}
+
+	format := report.StatementFormatPDF
+	if raw := r.URL.Query().Get(FormatParam); raw != "" {
+		format = report.StatementFormat(raw)
+		if !slices.Contains(report.StatementFormats(), format) {
+			return nil, fmt.Errorf("invalid %s: %q", FormatParam, raw)
+		}
+	}
+
+	return &Statement{
+		AccountID: accountID,
+		From:      from,
+		To:        to,
+		Format:    format,
+	}, nil
+}
+
+// parsePeriod parses the from and to days of the query, see Statement.
+func parsePeriod(r *http.Request) (from, to time.Time, err error) {
+	query := r.URL.Query()
+
+	return parsePeriodDays(query.Get(FromParam), query.Get(ToParam))
+}
+
+// parsePeriodDays parses the from and to days, either of them may be empty,
+// see Statement.
+func parsePeriodDays(rawFrom, rawTo string) (from, to time.Time, err error) {
+	if rawFrom != "" {
+		if from, err = time.Parse(PeriodDateLayout, rawFrom); err != nil {
+			return from, to, fmt.Errorf("invalid %s: expected YYYY-MM-DD", FromParam)
+		}
+	}
+
+	to = time.Now().UTC()
+	if rawTo != "" {
+		last, err := time.Parse(PeriodDateLayout, rawTo)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid %s: expected YYYY-MM-DD", ToParam)
+		}
+
+		to = last.AddDate(0, 0, 1)
+	}
+
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("%s must not be after %s", FromParam, ToParam)
+	}
+
+	return from, to, nil
+}
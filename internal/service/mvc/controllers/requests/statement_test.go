@@ -0,0 +1,102 @@
+package requests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models/report"
+)
+
+func TestNewStatement(t *testing.T) {
+	accountID := uuid.New()
+	tests := []struct {
+		name      string
+		accountID string
+		query     string
+		wantErr   bool
+		wantFrom  time.Time
+		wantTo    time.Time
+		want      report.StatementFormat
+	}{
+		{
+			name:      "period",
+			accountID: accountID.String(),
+			query:     "from=2024-03-01&to=2024-03-31",
+			wantFrom:  time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:    time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+			want:      report.StatementFormatPDF,
+		},
+		{
+			name:      "format",
+			accountID: accountID.String(),
+			query:     "from=2024-03-01&to=2024-03-31&format=camt053",
+			wantFrom:  time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:    time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+			want:      report.StatementFormatCAMT053,
+		},
+		{
+			name:      "unknown format",
+			accountID: accountID.String(),
+			query:     "format=qif",
+			wantErr:   true,
+		},
+		{
+			name:      "single day",
+			accountID: accountID.String(),
+			query:     "from=2024-03-01&to=2024-03-01",
+			wantFrom:  time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:    time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC),
+			want:      report.StatementFormatPDF,
+		},
+		{
+			name:      "invalid account id",
+			accountID: "not-a-uuid",
+			wantErr:   true,
+		},
+		{
+			name:      "invalid from",
+			accountID: accountID.String(),
+			query:     "from=01.03.2024",
+			wantErr:   true,
+		},
+		{
+			name:      "from after to",
+			accountID: accountID.String(),
+			query:     "from=2024-03-02&to=2024-03-01",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/api/v1/accounts/"+tt.accountID+"/statement.pdf?"+tt.query, nil)
+			r.SetPathValue("account-id", tt.accountID)
+
+			got, err := NewStatement(r)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, accountID, got.AccountID)
+			assert.Equal(t, tt.wantFrom, got.From)
+			assert.Equal(t, tt.wantTo, got.To)
+			assert.Equal(t, tt.want, got.Format)
+		})
+	}
+
+	r, _ := http.NewRequest("GET", "/api/v1/accounts/"+accountID.String()+"/statement.pdf", nil)
+	r.SetPathValue("account-id", accountID.String())
+
+	got, err := NewStatement(r)
+	require.NoError(t, err)
+	assert.True(t, got.From.IsZero(), "the whole history by default")
+	assert.WithinDuration(t, time.Now(), got.To, time.Minute)
+}
@@ -6,12 +6,13 @@ import (
 	"net/http"
 
 	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
 )
 
+// Deposit carries the payload signed by the ATM, see atm.SignedDeposit.
 type Deposit struct {
-	AccountID    uuid.UUID `json:"account_id" validate:"required,uuid4"`
-	Amount       uint      `json:"amount" validate:"required,gt=0"`
-	ATMSignature string    `json:"atm_signature" validate:"required,max=100"`
+	atm.SignedDeposit
 }
 
 func NewDeposit(r *http.Request) (*Deposit, error) {
@@ -27,6 +28,7 @@ func NewDeposit(r *http.Request) (*Deposit, error) {
 	return &req, nil
 }
 
+// Withdrawal requests the cash-out code for the amount, see ATMWithdrawal.
 type Withdrawal struct {
 	AccountID uuid.UUID `json:"account_id" validate:"required"`
 	Amount    uint      `json:"amount" validate:"required,gt=0"`
@@ -45,6 +47,35 @@ func NewWithdrawal(r *http.Request) (*Withdrawal, error) {
 	return &req, nil
 }
 
+// NewCashOutCodeID parses the cash-out code ID from the path.
+func NewCashOutCodeID(r *http.Request) (uuid.UUID, error) {
+	codeID, err := uuid.Parse(r.PathValue("code-id"))
+	if err != nil {
+		return uuid.Nil, errors.New("invalid cash-out code id")
+	}
+
+	return codeID, nil
+}
+
+// ATMWithdrawal carries the cash-out code entered at the ATM and the payload
+// signed by the ATM, see atm.SignedWithdrawal.
+type ATMWithdrawal struct {
+	atm.SignedWithdrawal
+}
+
+func NewATMWithdrawal(r *http.Request) (*ATMWithdrawal, error) {
+	var req ATMWithdrawal
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
 type Transfer struct {
 	SenderID    uuid.UUID `json:"sender_id" validate:"required"`
 	RecipientID uuid.UUID `json:"recipient_id" validate:"required"`
@@ -67,3 +98,22 @@ func NewTransfer(r *http.Request) (*Transfer, error) {
 
 	return &req, nil
 }
+
+// ATMCashCount carries the end-of-day cash count signed by the ATM, see
+// atm.SignedCashCount.
+type ATMCashCount struct {
+	atm.SignedCashCount
+}
+
+func NewATMCashCount(r *http.Request) (*ATMCashCount, error) {
+	var req ATMCashCount
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
@@ -12,6 +12,7 @@ import (
 
 func TestNewDeposit(t *testing.T) {
 	accountID := uuid.New()
+	atmID := uuid.New()
 	tests := []struct {
 		name    string
 		body    map[string]interface{}
@@ -20,26 +21,68 @@ func TestNewDeposit(t *testing.T) {
 		{
 			name: "valid input",
 			body: map[string]interface{}{
-				"account_id":    accountID,
-				"amount":        1000,
-				"atm_signature": "valid-signature",
+				"account_id":      accountID,
+				"amount":          1000,
+				"atm_id":          atmID,
+				"payload_version": 1,
+				"nonce":           "00112233445566778899aabbccddeeff",
+				"issued_at":       1700000000,
+				"expires_at":      1700000300,
+				"atm_signature":   "valid-signature",
 			},
 			wantErr: false,
 		},
 		{
 			name: "missing account_id",
 			body: map[string]interface{}{
-				"amount":        1000,
-				"atm_signature": "valid-signature",
+				"amount":          1000,
+				"atm_id":          atmID,
+				"payload_version": 1,
+				"nonce":           "00112233445566778899aabbccddeeff",
+				"issued_at":       1700000000,
+				"expires_at":      1700000300,
+				"atm_signature":   "valid-signature",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing atm_id",
+			body: map[string]interface{}{
+				"account_id":      accountID,
+				"amount":          1000,
+				"payload_version": 1,
+				"nonce":           "00112233445566778899aabbccddeeff",
+				"issued_at":       1700000000,
+				"expires_at":      1700000300,
+				"atm_signature":   "valid-signature",
+			},
+			wantErr: true,
+		},
+		{
+			name: "expires before issued",
+			body: map[string]interface{}{
+				"account_id":      accountID,
+				"amount":          1000,
+				"atm_id":          atmID,
+				"payload_version": 1,
+				"nonce":           "00112233445566778899aabbccddeeff",
+				"issued_at":       1700000300,
+				"expires_at":      1700000000,
+				"atm_signature":   "valid-signature",
 			},
 			wantErr: true,
 		},
 		{
 			name: "invalid amount",
 			body: map[string]interface{}{
-				"account_id":    accountID,
-				"amount":        0,
-				"atm_signature": "valid-signature",
+				"account_id":      accountID,
+				"amount":          0,
+				"atm_id":          atmID,
+				"payload_version": 1,
+				"nonce":           "00112233445566778899aabbccddeeff",
+				"issued_at":       1700000000,
+				"expires_at":      1700000300,
+				"atm_signature":   "valid-signature",
 			},
 			wantErr: true,
 		},
@@ -59,6 +102,7 @@ func TestNewDeposit(t *testing.T) {
 				assert.NotNil(t, got)
 				assert.Equal(t, accountID, got.AccountID)
 				assert.Equal(t, uint(1000), got.Amount)
+				assert.Equal(t, atmID, got.ATMID)
 				assert.Equal(t, "valid-signature", got.ATMSignature)
 			}
 		})
@@ -100,7 +144,7 @@ func TestNewWithdrawal(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			body, _ := json.Marshal(tt.body)
-			r, _ := http.NewRequest("POST", "/withdraw", bytes.NewBuffer(body))
+			r, _ := http.NewRequest("POST", "/cash-out-codes", bytes.NewBuffer(body))
 
 			got, err := NewWithdrawal(r)
 			if tt.wantErr {
@@ -116,6 +160,57 @@ func TestNewWithdrawal(t *testing.T) {
 	}
 }
 
+func TestNewATMWithdrawal(t *testing.T) {
+	atmID := uuid.New()
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"atm_id":          atmID,
+			"code":            "01234567",
+			"amount":          500,
+			"payload_version": 1,
+			"nonce":           "00112233445566778899aabbccddeeff",
+			"issued_at":       1700000000,
+			"expires_at":      1700000300,
+			"atm_signature":   "valid-signature",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(body map[string]interface{})
+		wantErr bool
+	}{
+		{"valid input", func(body map[string]interface{}) {}, false},
+		{"missing code", func(body map[string]interface{}) { delete(body, "code") }, true},
+		{"short code", func(body map[string]interface{}) { body["code"] = "0123456" }, true},
+		{"non-numeric code", func(body map[string]interface{}) { body["code"] = "0123456a" }, true},
+		{"missing atm_id", func(body map[string]interface{}) { delete(body, "atm_id") }, true},
+		{"zero amount", func(body map[string]interface{}) { body["amount"] = 0 }, true},
+		{"missing signature", func(body map[string]interface{}) { delete(body, "atm_signature") }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := valid()
+			tt.modify(body)
+
+			raw, _ := json.Marshal(body)
+			r, _ := http.NewRequest("POST", "/atm/v1/withdrawals", bytes.NewBuffer(raw))
+
+			got, err := NewATMWithdrawal(r)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, atmID, got.ATMID)
+				assert.Equal(t, "01234567", got.Code)
+				assert.Equal(t, uint(500), got.Amount)
+			}
+		})
+	}
+}
+
 func TestNewTransfer(t *testing.T) {
 	senderID := uuid.New()
 	recipientID := uuid.New()
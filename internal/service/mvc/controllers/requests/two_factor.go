@@ -0,0 +1,40 @@
+package requests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// TwoFactorCode is either a TOTP code or a recovery code.
+type TwoFactorCode struct {
+	Code string `json:"code" validate:"required,min=6,max=32"`
+}
+
+// NewTwoFactorCode parses HTTP request and validates input
+func NewTwoFactorCode(r *http.Request) (*TwoFactorCode, error) {
+	var req TwoFactorCode
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("failed to decode json request body: %w", err)
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+// NewLoginTwoFactorCode parses the form of the second login step and validates input
+func NewLoginTwoFactorCode(r *http.Request) (*TwoFactorCode, error) {
+	req := TwoFactorCode{
+		Code: r.FormValue("code"),
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
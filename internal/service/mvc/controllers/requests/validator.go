@@ -4,24 +4,65 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"unicode"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/jsonapi"
-	"golang.org/x/crypto/bcrypt"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
 )
 
 var validate = validator.New()
 
 func init() {
-	_ = validate.RegisterValidation("bcrypt", bcryptValidator)
+	_ = validate.RegisterValidation("password_strength", passwordStrengthValidator)
+	_ = validate.RegisterValidation("access_scope", accessScopeValidator)
+
+	validate.RegisterStructValidation(registerValidator, Register{})
 }
 
-func bcryptValidator(fl validator.FieldLevel) bool {
-	hash := fl.Field().String()
+// minPasswordCharacterClasses is how many of the lowercase letters, uppercase
+// letters, digits and other characters the password must contain.
+const minPasswordCharacterClasses = 3
+
+func passwordStrengthValidator(fl validator.FieldLevel) bool {
+	var lower, upper, digit, other int
 
-	_, err := bcrypt.Cost([]byte(hash))
+	for _, r := range fl.Field().String() {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+
+	return lower+upper+digit+other >= minPasswordCharacterClasses
+}
+
+// registerValidator rejects the passwords containing the username or the email name.
+func registerValidator(sl validator.StructLevel) {
+	req := sl.Current().Interface().(Register)
+	password := strings.ToLower(req.Password)
+
+	emailName, _, _ := strings.Cut(req.Email, "@")
+
+	for _, identity := range []string{req.Username, emailName} {
+		if len(identity) >= 3 && strings.Contains(password, strings.ToLower(identity)) {
+			sl.ReportError(req.Password, "Password", "Password", "password_identity", "")
+			return
+		}
+	}
+}
 
-	return err == nil
+func accessScopeValidator(fl validator.FieldLevel) bool {
+	return slices.Contains(data.AccessScopes, data.AccessScope(fl.Field().String()))
 }
 
 func BadRequest(err error) []*jsonapi.ErrorObject {
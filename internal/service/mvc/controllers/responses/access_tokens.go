@@ -0,0 +1,49 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/google/jsonapi"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+type AccessToken struct {
+	ID         string     `jsonapi:"primary,access_tokens"`
+	Name       string     `jsonapi:"attr,name"`
+	Scopes     []string   `jsonapi:"attr,scopes"`
+	Token      string     `jsonapi:"attr,token,omitempty"`
+	CreatedAt  time.Time  `jsonapi:"attr,created_at,iso8601"`
+	ExpiresAt  *time.Time `jsonapi:"attr,expires_at,iso8601,omitempty"`
+	LastUsedAt *time.Time `jsonapi:"attr,last_used_at,iso8601,omitempty"`
+	RevokedAt  *time.Time `jsonapi:"attr,revoked_at,iso8601,omitempty"`
+}
+
+func NewAccessToken(token *data.AccessToken) *AccessToken {
+	return &AccessToken{
+		ID:         token.ID.String(),
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		RevokedAt:  token.RevokedAt,
+	}
+}
+
+// NewCreatedAccessTokenDocument includes the plain token, which is returned only once.
+func NewCreatedAccessTokenDocument(plain string, token *data.AccessToken) (jsonapi.Payloader, error) {
+	resource := NewAccessToken(token)
+	resource.Token = plain
+
+	return jsonapi.Marshal(resource)
+}
+
+func NewAccessTokenListDocument(tokens []*data.AccessToken) (jsonapi.Payloader, error) {
+	resources := make([]*AccessToken, len(tokens))
+	for i, token := range tokens {
+		resources[i] = NewAccessToken(token)
+	}
+
+	return jsonapi.Marshal(resources)
+}
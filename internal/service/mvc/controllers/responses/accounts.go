@@ -0,0 +1,40 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/google/jsonapi"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+type Account struct {
+	ID        string    `jsonapi:"primary,accounts"`
+	Name      string    `jsonapi:"attr,name"`
+	Balance   int       `jsonapi:"attr,balance"`
+	CreatedAt time.Time `jsonapi:"attr,created_at,iso8601"`
+	UpdatedAt time.Time `jsonapi:"attr,updated_at,iso8601"`
+}
+
+func NewAccount(account *data.Account) *Account {
+	return &Account{
+		ID:        account.ID.String(),
+		Name:      account.Name,
+		Balance:   account.Balance,
+		CreatedAt: account.CreatedAt,
+		UpdatedAt: account.UpdatedAt,
+	}
+}
+
+func NewAccountDocument(account *data.Account) (jsonapi.Payloader, error) {
+	return jsonapi.Marshal(NewAccount(account))
+}
+
+func NewAccountListDocument(accounts []*data.Account) (jsonapi.Payloader, error) {
+	resources := make([]*Account, len(accounts))
+	for i, account := range accounts {
+		resources[i] = NewAccount(account)
+	}
+
+	return jsonapi.Marshal(resources)
+}
@@ -0,0 +1,42 @@
+package responses
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/jsonapi"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+type Activity struct {
+	ID        string          `jsonapi:"primary,activity"`
+	Action    string          `jsonapi:"attr,action"`
+	AccountID *string         `jsonapi:"attr,account_id,omitempty"`
+	Details   json.RawMessage `jsonapi:"attr,details,omitempty"`
+	CreatedAt time.Time       `jsonapi:"attr,created_at,iso8601"`
+}
+
+func NewActivity(log *data.AuditLog) *Activity {
+	activity := &Activity{
+		ID:        log.ID.String(),
+		Action:    string(log.Action),
+		Details:   log.Details,
+		CreatedAt: log.CreatedAt,
+	}
+
+	if log.AccountID != nil {
+		activity.AccountID = optionalID(*log.AccountID)
+	}
+
+	return activity
+}
+
+func NewActivityListDocument(logs []*data.AuditLog, links *jsonapi.Links, total uint64) (jsonapi.Payloader, error) {
+	resources := make([]*Activity, len(logs))
+	for i, log := range logs {
+		resources[i] = NewActivity(log)
+	}
+
+	return newPage(resources, links, total)
+}
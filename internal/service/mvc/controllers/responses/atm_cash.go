@@ -0,0 +1,36 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/google/jsonapi"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+type ATMReconciliation struct {
+	ID           string    `jsonapi:"primary,atm_reconciliations"`
+	ATMID        string    `jsonapi:"attr,atm_id"`
+	BusinessDate string    `jsonapi:"attr,business_date"`
+	Expected     int       `jsonapi:"attr,expected"`
+	Counted      int       `jsonapi:"attr,counted"`
+	Discrepancy  int       `jsonapi:"attr,discrepancy"`
+	CreatedAt    time.Time `jsonapi:"attr,created_at,iso8601"`
+}
+
+func NewATMReconciliation(reconciliation *data.ATMReconciliation) *ATMReconciliation {
+	return &ATMReconciliation{
+		ID:           reconciliation.ID.String(),
+		ATMID:        reconciliation.ATMID.String(),
+		BusinessDate: reconciliation.BusinessDate.Format(atm.BusinessDateLayout),
+		Expected:     reconciliation.Expected,
+		Counted:      reconciliation.Counted,
+		Discrepancy:  reconciliation.Discrepancy,
+		CreatedAt:    reconciliation.CreatedAt,
+	}
+}
+
+func NewATMReconciliationDocument(reconciliation *data.ATMReconciliation) (jsonapi.Payloader, error) {
+	return jsonapi.Marshal(NewATMReconciliation(reconciliation))
+}
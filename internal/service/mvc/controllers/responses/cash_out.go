@@ -0,0 +1,42 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/google/jsonapi"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+type CashOutCode struct {
+	ID        string    `jsonapi:"primary,cash_out_codes"`
+	AccountID string    `jsonapi:"attr,account_id"`
+	Amount    uint      `jsonapi:"attr,amount"`
+	Status    string    `jsonapi:"attr,status"`
+	Code      string    `jsonapi:"attr,code,omitempty"`
+	CreatedAt time.Time `jsonapi:"attr,created_at,iso8601"`
+	ExpiresAt time.Time `jsonapi:"attr,expires_at,iso8601"`
+}
+
+func NewCashOutCode(code *data.CashOutCode) *CashOutCode {
+	return &CashOutCode{
+		ID:        code.ID.String(),
+		AccountID: code.AccountID.String(),
+		Amount:    code.Amount,
+		Status:    string(code.Status),
+		CreatedAt: code.CreatedAt,
+		ExpiresAt: code.ExpiresAt,
+	}
+}
+
+// NewIssuedCashOutCodeDocument includes the plain code, which is returned only once.
+func NewIssuedCashOutCodeDocument(plain string, code *data.CashOutCode) (jsonapi.Payloader, error) {
+	resource := NewCashOutCode(code)
+	resource.Code = plain
+
+	return jsonapi.Marshal(resource)
+}
+
+func NewTransactionDocument(transaction *data.Transaction) (jsonapi.Payloader, error) {
+	return jsonapi.Marshal(NewTransaction(transaction))
+}
@@ -0,0 +1,28 @@
+package responses
+
+import (
+	"fmt"
+
+	"github.com/google/jsonapi"
+)
+
+const totalCountMetaKey = "total_count"
+
+// newPage marshals a page of resources with the pagination links and the
+// total number of resources matching the request in the meta.
+func newPage(resources interface{}, links *jsonapi.Links, total uint64) (jsonapi.Payloader, error) {
+	payload, err := jsonapi.Marshal(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	many, ok := payload.(*jsonapi.ManyPayload)
+	if !ok {
+		return nil, fmt.Errorf("expected a collection, got %T", payload)
+	}
+
+	many.Links = links
+	many.Meta = &jsonapi.Meta{totalCountMetaKey: total}
+
+	return many, nil
+}
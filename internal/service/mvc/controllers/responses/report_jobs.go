@@ -0,0 +1,66 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/google/jsonapi"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+// ReportDownloadLink is the key of the link to the report of the completed job.
+const ReportDownloadLink = "download"
+
+type ReportJob struct {
+	ID          string     `jsonapi:"primary,report_jobs"`
+	AccountID   *string    `jsonapi:"attr,account_id,omitempty"`
+	Format      string     `jsonapi:"attr,format"`
+	From        *time.Time `jsonapi:"attr,from,iso8601,omitempty"`
+	To          time.Time  `jsonapi:"attr,to,iso8601"`
+	Status      string     `jsonapi:"attr,status"`
+	Error       string     `jsonapi:"attr,error,omitempty"`
+	CreatedAt   time.Time  `jsonapi:"attr,created_at,iso8601"`
+	StartedAt   *time.Time `jsonapi:"attr,started_at,iso8601,omitempty"`
+	CompletedAt *time.Time `jsonapi:"attr,completed_at,iso8601,omitempty"`
+	ExpiresAt   *time.Time `jsonapi:"attr,expires_at,iso8601,omitempty"`
+
+	links *jsonapi.Links
+}
+
+// JSONAPILinks implements jsonapi.Linkable.
+func (j *ReportJob) JSONAPILinks() *jsonapi.Links {
+	return j.links
+}
+
+// NewReportJob builds the resource of the job, the links are the self link
+// and the download link if the report can be downloaded now.
+func NewReportJob(job *data.ReportJob, self, download string) *ReportJob {
+	resource := &ReportJob{
+		ID:          job.ID.String(),
+		Format:      job.Format,
+		From:        job.PeriodFrom,
+		To:          job.PeriodTo,
+		Status:      string(job.Status),
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		StartedAt:   job.StartedAt,
+		CompletedAt: job.CompletedAt,
+		ExpiresAt:   job.ExpiresAt,
+		links:       &jsonapi.Links{"self": self},
+	}
+
+	if job.AccountID != nil {
+		accountID := job.AccountID.String()
+		resource.AccountID = &accountID
+	}
+
+	if job.IsDownloadableAt(time.Now().UTC()) {
+		(*resource.links)[ReportDownloadLink] = download
+	}
+
+	return resource
+}
+
+func NewReportJobDocument(job *data.ReportJob, self, download string) (jsonapi.Payloader, error) {
+	return jsonapi.Marshal(NewReportJob(job, self, download))
+}
@@ -1,5 +1,14 @@
 package responses
 
+import (
+	"time"
+
+	"github.com/google/jsonapi"
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
 type TransactionResult struct {
 	NewBalance int `json:"new_balance"`
 }
@@ -9,3 +18,42 @@ func NewTransactionResult(balance int) *TransactionResult {
 		NewBalance: balance,
 	}
 }
+
+type Transaction struct {
+	ID          string    `jsonapi:"primary,transactions"`
+	Type        string    `jsonapi:"attr,type"`
+	Amount      uint      `jsonapi:"attr,amount"`
+	SenderID    *string   `jsonapi:"attr,sender_id,omitempty"`
+	RecipientID *string   `jsonapi:"attr,recipient_id,omitempty"`
+	CreatedAt   time.Time `jsonapi:"attr,created_at,iso8601"`
+}
+
+func NewTransaction(transaction *data.Transaction) *Transaction {
+	return &Transaction{
+		ID:          transaction.ID.String(),
+		Type:        transaction.Type.String(),
+		Amount:      transaction.Amount,
+		SenderID:    optionalID(transaction.Sender),
+		RecipientID: optionalID(transaction.Recipient),
+		CreatedAt:   transaction.CreatedAt,
+	}
+}
+
+func NewTransactionListDocument(transactions []*data.Transaction, links *jsonapi.Links, total uint64) (jsonapi.Payloader, error) {
+	resources := make([]*Transaction, len(transactions))
+	for i, transaction := range transactions {
+		resources[i] = NewTransaction(transaction)
+	}
+
+	return newPage(resources, links, total)
+}
+
+// optionalID maps uuid.Nil, which is stored as NULL, to nil.
+func optionalID(id uuid.UUID) *string {
+	if id == uuid.Nil {
+		return nil
+	}
+
+	result := id.String()
+	return &result
+}
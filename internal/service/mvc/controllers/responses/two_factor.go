@@ -0,0 +1,33 @@
+package responses
+
+import (
+	"github.com/google/jsonapi"
+	"github.com/google/uuid"
+)
+
+// TOTPEnrollment is identified by the customer, who can have a single one.
+type TOTPEnrollment struct {
+	ID     string `jsonapi:"primary,totp_enrollments"`
+	Secret string `jsonapi:"attr,secret"`
+	URI    string `jsonapi:"attr,uri"`
+}
+
+func NewTOTPEnrollmentDocument(customerID uuid.UUID, secret, uri string) (jsonapi.Payloader, error) {
+	return jsonapi.Marshal(&TOTPEnrollment{
+		ID:     customerID.String(),
+		Secret: secret,
+		URI:    uri,
+	})
+}
+
+type RecoveryCodes struct {
+	ID    string   `jsonapi:"primary,recovery_codes"`
+	Codes []string `jsonapi:"attr,codes"`
+}
+
+func NewRecoveryCodesDocument(customerID uuid.UUID, codes []string) (jsonapi.Payloader, error) {
+	return jsonapi.Marshal(&RecoveryCodes{
+		ID:    customerID.String(),
+		Codes: codes,
+	})
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/views"
+)
+
+type Settings struct {
+	auth         *models.Auth
+	accessTokens *models.AccessTokens
+	twoFactor    *models.TwoFactor
+}
+
+func NewSettings(auth *models.Auth, accessTokens *models.AccessTokens, twoFactor *models.TwoFactor) *Settings {
+	return &Settings{
+		auth:         auth,
+		accessTokens: accessTokens,
+		twoFactor:    twoFactor,
+	}
+}
+
+func (c *Settings) SettingsPage(w http.ResponseWriter, r *http.Request) {
+	customerID := CustomerID(r)
+
+	tokens, err := c.accessTokens.GetAccessTokens(customerID)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to get access tokens: %w", err))
+		return
+	}
+
+	sessions, err := c.auth.GetSessions(customerID)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to get sessions: %w", err))
+		return
+	}
+
+	twoFactorEnabled, err := c.twoFactor.IsEnabled(customerID)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to check two-factor authentication: %w", err))
+		return
+	}
+
+	viewData := &views.Settings{
+		AccessTokens:     tokens,
+		Scopes:           data.AccessScopes,
+		Sessions:         sessions,
+		CurrentSessionID: SessionID(r),
+		TwoFactorEnabled: twoFactorEnabled,
+	}
+
+	if err = Templates(r).ExecuteTemplate(w, views.SettingsTemplateName, viewData); err != nil {
+		Log(r).WithError(err).Error("failed to execute template")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+}
@@ -43,41 +43,25 @@ func (c *Transactions) DepositFunds(w http.ResponseWriter, r *http.Request) {
 			Log(r).WithField("reason", err).Debug("not found")
 			ape.RenderErr(w, problems.NotFound())
 			return
-		case errors.Is(err, models.ErrorATMSignatureNotUnique):
+		case errors.Is(err, models.ErrorATMNonceReused):
 			Log(r).WithField("reason", err).Debug("bad request")
-			ape.RenderErr(w, requests.BadRequest(models.ErrorATMSignatureNotUnique)...)
+			ape.RenderErr(w, requests.BadRequest(models.ErrorATMNonceReused)...)
 			return
-		}
-
-		InternalError(w, r, fmt.Errorf("failed to deposit funds: %w", err))
-		return
-	}
-
-	ape.Render(w, responses.NewTransactionResult(newBalance))
-}
-
-func (c *Transactions) WithdrawFunds(w http.ResponseWriter, r *http.Request) {
-	req, err := requests.NewWithdrawal(r)
-	if err != nil {
-		Log(r).WithField("reason", err).Debug("bad request")
-		ape.RenderErr(w, requests.BadRequest(err)...)
-		return
-	}
-
-	newBalance, err := c.model.WithdrawFunds(CustomerID(r), req)
-	if err != nil {
-		switch {
-		case errors.Is(err, models.ErrorAccountNotFound):
-			Log(r).WithField("reason", err).Debug("not found")
-			ape.RenderErr(w, problems.NotFound())
+		case errors.Is(err, models.ErrorInvalidATMPayload):
+			Log(r).WithField("reason", err).Debug("bad request")
+			ape.RenderErr(w, requests.BadRequest(err)...)
 			return
-		case errors.Is(err, models.ErrorInsufficientFunds):
+		case errors.Is(err, models.ErrorATMNotFound):
+			Log(r).WithField("reason", err).Debug("bad request")
+			ape.RenderErr(w, requests.BadRequest(models.ErrorATMNotFound)...)
+			return
+		case errors.Is(err, models.ErrorATMDisabled):
 			Log(r).WithField("reason", err).Debug("forbidden")
 			ape.RenderErr(w, problems.Forbidden())
 			return
 		}
 
-		InternalError(w, r, fmt.Errorf("failed to withdraw funds: %w", err))
+		InternalError(w, r, fmt.Errorf("failed to deposit funds: %w", err))
 		return
 	}
 
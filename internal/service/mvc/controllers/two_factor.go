@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/responses"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
+)
+
+type TwoFactor struct {
+	model *models.TwoFactor
+}
+
+func NewTwoFactor(model *models.TwoFactor) *TwoFactor {
+	return &TwoFactor{
+		model: model,
+	}
+}
+
+func (c *TwoFactor) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	customerID := CustomerID(r)
+
+	enrollment, err := c.model.EnrollTOTP(customerID)
+	if err != nil {
+		if errors.Is(err, models.ErrorTwoFactorAlreadyEnabled) {
+			Log(r).WithField("reason", err).Debug("conflict")
+			ape.RenderErr(w, problems.Conflict())
+			return
+		}
+
+		InternalError(w, r, fmt.Errorf("failed to enroll TOTP: %w", err))
+		return
+	}
+
+	document, err := responses.NewTOTPEnrollmentDocument(customerID, enrollment.Secret, enrollment.URI)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to marshal TOTP enrollment: %w", err))
+		return
+	}
+
+	ape.Render(w, document)
+}
+
+func (c *TwoFactor) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewTwoFactorCode(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	customerID := CustomerID(r)
+
+	codes, err := c.model.ConfirmTOTP(customerID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrorTwoFactorNotEnrolled):
+			ape.RenderErr(w, problems.NotFound())
+		case errors.Is(err, models.ErrorTwoFactorAlreadyEnabled):
+			ape.RenderErr(w, problems.Conflict())
+		case errors.Is(err, models.ErrorInvalidTwoFactorCode):
+			Log(r).WithField("reason", err).Debug("forbidden")
+			ape.RenderErr(w, problems.Forbidden())
+		default:
+			InternalError(w, r, fmt.Errorf("failed to confirm TOTP: %w", err))
+		}
+		return
+	}
+
+	document, err := responses.NewRecoveryCodesDocument(customerID, codes)
+	if err != nil {
+		InternalError(w, r, fmt.Errorf("failed to marshal recovery codes: %w", err))
+		return
+	}
+
+	ape.Render(w, document)
+}
+
+func (c *TwoFactor) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewTwoFactorCode(r)
+	if err != nil {
+		Log(r).WithField("reason", err).Debug("bad request")
+		ape.RenderErr(w, requests.BadRequest(err)...)
+		return
+	}
+
+	if err = c.model.DisableTOTP(CustomerID(r), req.Code); err != nil {
+		switch {
+		case errors.Is(err, models.ErrorTwoFactorNotEnrolled):
+			ape.RenderErr(w, problems.NotFound())
+		case errors.Is(err, models.ErrorInvalidTwoFactorCode):
+			Log(r).WithField("reason", err).Debug("forbidden")
+			ape.RenderErr(w, problems.Forbidden())
+		default:
+			InternalError(w, r, fmt.Errorf("failed to disable TOTP: %w", err))
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
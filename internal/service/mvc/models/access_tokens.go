@@ -0,0 +1,178 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+)
+
+// AccessTokenPrefix makes the tokens recognizable, e.g. by secret scanners.
+const AccessTokenPrefix = "ilab1_pat_"
+
+const secretTokenBytes = 32
+
+var ErrorAccessTokenNotFound = errors.New("access token not found")
+var ErrorInvalidAccessToken = errors.New("invalid access token")
+
+type AccessTokens struct {
+	db data.MainQ
+
+	auditService *AuditService
+}
+
+func NewAccessTokens(db data.MainQ, auditService *AuditService) *AccessTokens {
+	return &AccessTokens{
+		db:           db,
+		auditService: auditService,
+	}
+}
+
+// CreateAccessToken mints a new token for the customer. The returned plain token
+// is not stored anywhere, so it can be shown to the customer only once.
+func (m *AccessTokens) CreateAccessToken(
+	customerID uuid.UUID,
+	req *requests.CreateAccessToken,
+) (string, *data.AccessToken, error) {
+	secret, err := newSecretToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	plain := AccessTokenPrefix + secret
+
+	token := &data.AccessToken{
+		CustomerID: customerID,
+		Name:       req.Name,
+		TokenHash:  hashSecretToken(plain),
+		Scopes:     req.Scopes,
+	}
+
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().UTC().AddDate(0, 0, int(req.ExpiresInDays))
+		token.ExpiresAt = &expiresAt
+	}
+
+	db := m.db.New()
+
+	err = db.Transaction(func() error {
+		if err := db.AccessTokens().Insert(token); err != nil {
+			return fmt.Errorf("failed to insert access token: %w", err)
+		}
+
+		if err := m.auditService.withDB(db).logAccessTokenCreated(customerID, token); err != nil {
+			return fmt.Errorf("failed to log audit action: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The token is read back for the values set by the database, it may be
+	// revoked in between
+	ok, err := m.db.AccessTokens().WhereID(token.ID).Get(token)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+	if !ok {
+		return "", nil, ErrorAccessTokenNotFound
+	}
+
+	return plain, token, nil
+}
+
+func (m *AccessTokens) GetAccessTokens(customerID uuid.UUID) ([]*data.AccessToken, error) {
+	tokens, err := m.db.AccessTokens().
+		WhereCustomerID(customerID).
+		OrderBy("created_at DESC").
+		Select()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (m *AccessTokens) RevokeAccessToken(customerID, tokenID uuid.UUID) error {
+	token := new(data.AccessToken)
+
+	ok, err := m.db.AccessTokens().WhereID(tokenID).WhereCustomerID(customerID).Get(token)
+	if err != nil {
+		return fmt.Errorf("failed to get access token: %w", err)
+	}
+	if !ok {
+		return ErrorAccessTokenNotFound
+	}
+
+	if token.RevokedAt != nil {
+		return nil
+	}
+
+	db := m.db.New()
+
+	return db.Transaction(func() error {
+		if err := db.AccessTokens().Revoke(tokenID, time.Now().UTC()); err != nil {
+			return fmt.Errorf("failed to revoke access token: %w", err)
+		}
+
+		if err := m.auditService.withDB(db).logAccessTokenRevoked(customerID, token); err != nil {
+			return fmt.Errorf("failed to log audit action: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// VerifyAccessToken returns the active token matching the plain token and
+// records its usage.
+func (m *AccessTokens) VerifyAccessToken(plain string) (*data.AccessToken, error) {
+	if !strings.HasPrefix(plain, AccessTokenPrefix) {
+		return nil, ErrorInvalidAccessToken
+	}
+
+	token := new(data.AccessToken)
+
+	ok, err := m.db.AccessTokens().WhereTokenHash(hashSecretToken(plain)).Get(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if !ok || !token.IsActive(now) {
+		return nil, ErrorInvalidAccessToken
+	}
+
+	if err = m.db.AccessTokens().MarkUsed(token.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to mark access token used: %w", err)
+	}
+
+	return token, nil
+}
+
+// newSecretToken returns 256 random bits encoded with the URL-safe base64.
+func newSecretToken() (string, error) {
+	secret := make([]byte, secretTokenBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashSecretToken returns the hex SHA-256 of the token. The tokens have 256 bits of
+// entropy, so, unlike passwords, they don't need a slow salted hash.
+func hashSecretToken(plain string) string {
+	hash := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+)
+
+func TestAccessTokens(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accessTokens := NewAccessTokens(env.db, env.audit)
+
+	plain, token, err := accessTokens.CreateAccessToken(customerID, &requests.CreateAccessToken{
+		Name:          "ci",
+		Scopes:        []string{string(data.AccessScopeAccountsRead)},
+		ExpiresInDays: 30,
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, AccessTokenPrefix))
+	assert.NotContains(t, token.TokenHash, plain, "plain token must not be stored")
+	require.NotNil(t, token.ExpiresAt)
+
+	verified, err := accessTokens.VerifyAccessToken(plain)
+	require.NoError(t, err)
+	assert.Equal(t, customerID, verified.CustomerID)
+	assert.True(t, verified.HasScope(data.AccessScopeAccountsRead))
+	assert.False(t, verified.HasScope(data.AccessScopeTransactionsWrite))
+
+	tokens, err := accessTokens.GetAccessTokens(customerID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.NotNil(t, tokens[0].LastUsedAt, "usage must be recorded")
+
+	_, err = accessTokens.VerifyAccessToken(plain + "x")
+	require.ErrorIs(t, err, ErrorInvalidAccessToken)
+
+	_, err = accessTokens.VerifyAccessToken("not a token")
+	require.ErrorIs(t, err, ErrorInvalidAccessToken)
+
+	require.ErrorIs(t, accessTokens.RevokeAccessToken(env.newCustomer(t), token.ID), ErrorAccessTokenNotFound)
+
+	require.NoError(t, accessTokens.RevokeAccessToken(customerID, token.ID))
+
+	_, err = accessTokens.VerifyAccessToken(plain)
+	require.ErrorIs(t, err, ErrorInvalidAccessToken)
+
+	logs, err := env.audit.GetUserActivityLogs(customerID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	assert.Equal(t, data.AuditActionAccessTokenRevoked, logs[0].Action)
+	assert.Equal(t, data.AuditActionAccessTokenCreated, logs[1].Action)
+}
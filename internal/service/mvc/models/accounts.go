@@ -1,8 +1,13 @@
 package models
 
 import (
+	"bytes"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/xuri/excelize/v2"
@@ -14,6 +19,7 @@ import (
 
 var ErrorNonZeroBalance = errors.New("account with non-zero balance")
 var ErrorAccountNotFound = errors.New("account not found")
+var ErrorPendingCashOutCodes = errors.New("account with pending cash-out codes")
 
 type Accounts struct {
 	db data.MainQ
@@ -34,16 +40,18 @@ func (m *Accounts) CreateAccount(customerID uuid.UUID, req *requests.CreateAccou
 		Balance: 0,
 	}
 
-	err := m.db.Transaction(func() error {
-		if err := m.db.Accounts().Insert(account); err != nil {
+	db := m.db.New()
+
+	err := db.Transaction(func() error {
+		if err := db.Accounts().Insert(account); err != nil {
 			return fmt.Errorf("failed to insert account: %w", err)
 		}
 
-		if err := m.db.CustomersAccounts().AddAccountsToCustomer(customerID, account.ID); err != nil {
+		if err := db.CustomersAccounts().AddAccountsToCustomer(customerID, account.ID); err != nil {
 			return fmt.Errorf("failed to add account to customer: %w", err)
 		}
 
-		if err := m.auditService.logAccountCreated(customerID, account.ID); err != nil {
+		if err := m.auditService.withDB(db).logAccountCreated(customerID, account.ID); err != nil {
 			return fmt.Errorf("failed to log audit action: %w", err)
 		}
 
@@ -112,27 +120,129 @@ func (m *Accounts) GetAccountTransactions(customerID, accountID uuid.UUID) ([]*d
 	return transactions, nil
 }
 
-func (m *Accounts) DeleteAccount(customerID, accountID uuid.UUID) error {
-	account, err := m.GetAccount(customerID, accountID)
+// GetPendingCashOutCodes returns the codes of the account which can still be
+// used at an ATM, the newest first.
+func (m *Accounts) GetPendingCashOutCodes(customerID, accountID uuid.UUID) ([]*data.CashOutCode, error) {
+	ok, err := m.db.CustomersAccounts().HasAccount(customerID, accountID)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to check account existence: %w", err)
+	}
+	if !ok {
+		return nil, ErrorAccountNotFound
+	}
+
+	codes, err := m.db.CashOutCodes().
+		WhereAccountID(accountID).
+		WhereStatus(data.CashOutCodePending).
+		OrderBy("created_at DESC").
+		Select()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cash-out codes: %w", err)
+	}
+
+	now := time.Now().UTC()
+
+	usable := make([]*data.CashOutCode, 0, len(codes))
+	for _, code := range codes {
+		if code.IsUsableAt(now) {
+			usable = append(usable, code)
+		}
+	}
+
+	return usable, nil
+}
+
+// ListAccountTransactions returns a page of the account transactions matching
+// the filters and the total number of the matching transactions.
+func (m *Accounts) ListAccountTransactions(
+	customerID uuid.UUID,
+	req *requests.AccountTransactions,
+) ([]*data.Transaction, uint64, error) {
+	ok, err := m.db.CustomersAccounts().HasAccount(customerID, req.AccountID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to check account existence: %w", err)
+	}
+	if !ok {
+		return nil, 0, ErrorAccountNotFound
+	}
+
+	filtered := func() data.Transactions {
+		q := m.db.Transactions().WhereAccount(req.AccountID)
+
+		if req.Type != nil {
+			q = q.WhereType(*req.Type)
+		}
+
+		switch req.Direction {
+		case requests.DirectionIncoming:
+			q = q.WhereRecipient(req.AccountID)
+		case requests.DirectionOutgoing:
+			q = q.WhereSender(req.AccountID)
+		}
+
+		return q
+	}
+
+	total, err := filtered().Count()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count transactions: %w", err)
 	}
 
-	if account.Balance != 0 {
-		return ErrorNonZeroBalance
+	transactions, err := filtered().
+		OrderBy(fmt.Sprintf("created_at %s", req.Order)).
+		Limit(req.Limit).
+		Offset(req.Offset()).
+		Select()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	return transactions, total, nil
+}
+
+func (m *Accounts) DeleteAccount(customerID, accountID uuid.UUID) error {
+	if _, err := m.GetAccount(customerID, accountID); err != nil {
+		return err
 	}
 
-	err = m.db.Transaction(func() error {
-		err = m.db.CustomersAccounts().RemoveAccountsFromCustomer(customerID, accountID)
+	db := m.db.New()
+
+	err := db.Transaction(func() error {
+		// the balance is checked under the lock, so a concurrent deposit can't
+		// slip in between the check and the deletion
+		accounts, err := lockAccounts(db, accountID)
+		if err != nil {
+			return err
+		}
+
+		account, ok := accounts[accountID]
+		if !ok {
+			return ErrorAccountNotFound
+		}
+
+		if account.Balance != 0 {
+			return ErrorNonZeroBalance
+		}
+
+		// the held amounts are not a part of the balance, but still belong to the account
+		pending, err := db.CashOutCodes().WhereAccountID(accountID).WhereStatus(data.CashOutCodePending).Count()
+		if err != nil {
+			return fmt.Errorf("failed to count pending cash-out codes: %w", err)
+		}
+		if pending != 0 {
+			return ErrorPendingCashOutCodes
+		}
+
+		err = db.CustomersAccounts().RemoveAccountsFromCustomer(customerID, accountID)
 		if err != nil {
 			return fmt.Errorf("failed to remove customer association: %w", err)
 		}
 
-		if err = m.db.Accounts().LDelete(accountID); err != nil {
+		if err = db.Accounts().LDelete(accountID); err != nil {
 			return fmt.Errorf("failed to delete account: %w", err)
 		}
 
-		if err = m.auditService.logAccountDeleted(customerID, accountID); err != nil {
+		if err = m.auditService.withDB(db).logAccountDeleted(customerID, accountID); err != nil {
 			return fmt.Errorf("failed to log audit action: %w", err)
 		}
 
@@ -145,13 +255,125 @@ func (m *Accounts) DeleteAccount(customerID, accountID uuid.UUID) error {
 	return nil
 }
 
-func (m *Accounts) GenerateExcelReport(customerID, accountID uuid.UUID) ([]byte, error) {
-	account, err := m.GetAccount(customerID, accountID)
+// GenerateStatement returns the statement of the account for the period in the
+// requested format, see report.WriteStatement.
+func (m *Accounts) GenerateStatement(customerID uuid.UUID, req *requests.Statement) ([]byte, error) {
+	statement, err := m.getStatement(customerID, req.AccountID, req.From, req.To)
 	if err != nil {
 		return nil, err
 	}
 
-	transactions, err := m.GetAccountTransactions(customerID, accountID)
+	buf := new(bytes.Buffer)
+	if err = report.WriteStatement(buf, req.Format, statement, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to create statement: %w", err)
+	}
+
+	err = m.auditService.logStatementGenerated(customerID, req.AccountID, string(req.Format), req.From, req.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// GenerateExcelReport checks the access to the account and returns the
+// function streaming its Excel report for the period to the writer, see
+// report.WriteExcelReport. The transactions are read from the database while
+// the workbook is written.
+func (m *Accounts) GenerateExcelReport(customerID uuid.UUID, req *requests.ExcelReport) (func(w io.Writer) error, error) {
+	account, err := m.GetAccount(customerID, req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := report.TransactionFilter{
+		Type:      req.Type,
+		MinAmount: req.MinAmount,
+		MaxAmount: req.MaxAmount,
+	}
+
+	return func(w io.Writer) error {
+		if err := m.writeExcelReport(w, account, req.From, req.To, filter); err != nil {
+			return err
+		}
+
+		if err := m.auditService.logExcelReportGenerated(customerID, req.AccountID, req.From, req.To); err != nil {
+			return fmt.Errorf("failed to log audit action: %w", err)
+		}
+
+		return nil
+	}, nil
+}
+
+// GenerateCustomerExcelReport returns the workbook with the overview of all
+// the accounts of the customer for the period followed by the summary and the
+// history sheets of every account.
+func (m *Accounts) GenerateCustomerExcelReport(customerID uuid.UUID, req *requests.CustomerExcelReport) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	accountIDs, err := m.writeCustomerExcelReport(buf, customerID, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.auditService.logCustomerExcelReportGenerated(customerID, accountIDs, req.From, req.To); err != nil {
+		return nil, fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeExcelReport streams the Excel report of the account for the period to
+// the writer, see report.WriteExcelReport.
+func (m *Accounts) writeExcelReport(w io.Writer, account *data.Account, from, to time.Time, filter report.TransactionFilter) error {
+	return m.reportSnapshot(func(db data.MainQ) error {
+		account, balance, err := reportAccount(db, account.ID)
+		if err != nil {
+			return err
+		}
+
+		q := db.Transactions().WhereAccount(account.ID)
+		if !from.IsZero() {
+			q = q.WhereCreatedFrom(from)
+		}
+
+		// The balances are calculated back from the current one, newest first
+		cursor := q.OrderBy("created_at DESC").Each
+		if err = report.WriteExcelReport(w, account, balance, from, to, filter, cursor); err != nil {
+			return fmt.Errorf("failed to write Excel report: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// writeCustomerExcelReport writes the workbook of all the accounts of the
+// customer for the period, see GenerateCustomerExcelReport, and returns the
+// IDs of the accounts in it.
+func (m *Accounts) writeCustomerExcelReport(w io.Writer, customerID uuid.UUID, from, to time.Time) ([]uuid.UUID, error) {
+	accounts, err := m.GetAccountList(customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The sheets of the accounts are numbered from the oldest one
+	slices.SortFunc(accounts, func(a, b *data.Account) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	statements := make([]*report.Statement, len(accounts))
+	accountIDs := make([]uuid.UUID, len(accounts))
+
+	err = m.reportSnapshot(func(db data.MainQ) error {
+		for i, account := range accounts {
+			if statements[i], err = loadStatement(db, account.ID, from, to); err != nil {
+				return err
+			}
+			accountIDs[i] = account.ID
+		}
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -163,24 +385,109 @@ func (m *Accounts) GenerateExcelReport(customerID, accountID uuid.UUID) ([]byte,
 		return nil, fmt.Errorf("failed to create styles: %w", err)
 	}
 
-	err = report.CreateAccountSummarySheet(f, account, transactions, styles)
+	if err = report.CreateCustomerOverviewSheet(f, statements, styles); err != nil {
+		return nil, fmt.Errorf("failed to create overview sheet: %w", err)
+	}
+
+	for i, statement := range statements {
+		sheetName := report.AccountSheetName(i+1, statement.Account, "Summary")
+		if err = report.CreateAccountSummarySheet(f, sheetName, statement, styles); err != nil {
+			return nil, fmt.Errorf("failed to create account summary sheet: %w", err)
+		}
+
+		sheetName = report.AccountSheetName(i+1, statement.Account, "History")
+		if err = report.CreateTransactionHistorySheet(f, sheetName, statement, styles); err != nil {
+			return nil, fmt.Errorf("failed to create transaction history sheet: %w", err)
+		}
+	}
+
+	if err = f.Write(w); err != nil {
+		return nil, fmt.Errorf("failed to write workbook: %w", err)
+	}
+
+	return accountIDs, nil
+}
+
+// getStatement returns the statement of the account of the customer for the
+// period, see loadStatement.
+func (m *Accounts) getStatement(customerID, accountID uuid.UUID, from, to time.Time) (*report.Statement, error) {
+	if _, err := m.GetAccount(customerID, accountID); err != nil {
+		return nil, err
+	}
+
+	var statement *report.Statement
+	err := m.reportSnapshot(func(db data.MainQ) error {
+		var err error
+		statement, err = loadStatement(db, accountID, from, to)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create account summary sheet: %w", err)
+		return nil, err
 	}
 
-	err = report.CreateTransactionHistorySheet(f, account, transactions, styles)
+	return statement, nil
+}
+
+// reportSnapshot runs fn in the REPEATABLE READ transaction, so the accounts,
+// their cash-out codes and their transactions read by the report are the
+// ones of the same moment.
+func (m *Accounts) reportSnapshot(fn func(db data.MainQ) error) error {
+	db := m.db.New()
+
+	return db.IsolatedTransaction(sql.LevelRepeatableRead, func() error {
+		return fn(db)
+	})
+}
+
+// loadStatement returns the statement of the account for the period. Only the
+// transactions since the start of the period are loaded, the balances are
+// calculated back from the current one, see reportAccount.
+func loadStatement(db data.MainQ, accountID uuid.UUID, from, to time.Time) (*report.Statement, error) {
+	account, balance, err := reportAccount(db, accountID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create transaction history sheet: %w", err)
+		return nil, err
+	}
+
+	q := db.Transactions().WhereAccount(account.ID)
+	if !from.IsZero() {
+		q = q.WhereCreatedFrom(from)
 	}
 
-	reportBuf, err := f.WriteToBuffer()
+	transactions, err := q.Select()
 	if err != nil {
-		return nil, fmt.Errorf("failed to write to buffer: %w", err)
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
 	}
 
-	if err = m.auditService.logExcelReportGenerated(customerID, accountID); err != nil {
-		return nil, fmt.Errorf("failed to log audit action: %w", err)
+	return report.NewStatement(account, balance, transactions, from, to), nil
+}
+
+// reportAccount returns the account with the balance made by its transactions
+// only. The pending cash-out codes hold their amounts on the account without
+// a transaction until the withdrawal, so the reports walking back from the
+// balance through the transactions add the holds back.
+func reportAccount(db data.MainQ, accountID uuid.UUID) (*data.Account, int, error) {
+	account := new(data.Account)
+
+	ok, err := db.Accounts().WhereID(accountID).Get(account)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get account: %w", err)
+	}
+	if !ok || account.IsDeleted {
+		return nil, 0, ErrorAccountNotFound
+	}
+
+	codes, err := db.CashOutCodes().
+		WhereAccountID(accountID).
+		WhereStatus(data.CashOutCodePending).
+		Select()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get cash-out codes: %w", err)
+	}
+
+	balance := account.Balance
+	for _, code := range codes {
+		balance += int(code.Amount)
 	}
 
-	return reportBuf.Bytes(), nil
+	return account, balance, nil
 }
@@ -0,0 +1,277 @@
+package models
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models/report"
+)
+
+func TestListAccountTransactions(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+	otherID := env.newAccount(t, env.newCustomer(t))
+
+	for _, amount := range []uint{100, 200, 300} {
+		env.deposit(t, customerID, accountID, amount)
+	}
+
+	_, err := env.transactions.TransferFunds(customerID, &requests.Transfer{
+		SenderID:    accountID,
+		RecipientID: otherID,
+		Amount:      50,
+	})
+	require.NoError(t, err)
+
+	env.withdraw(t, customerID, accountID, 10)
+
+	list := func(req *requests.AccountTransactions) ([]*data.Transaction, uint64) {
+		t.Helper()
+
+		req.AccountID = accountID
+		if req.Limit == 0 {
+			req.Limit = 10
+		}
+		if req.Order == "" {
+			req.Order = requests.OrderAsc
+		}
+
+		transactions, total, err := env.accounts.ListAccountTransactions(customerID, req)
+		require.NoError(t, err)
+
+		return transactions, total
+	}
+
+	transactions, total := list(&requests.AccountTransactions{})
+	assert.Equal(t, uint64(5), total)
+	assert.Len(t, transactions, 5)
+
+	deposit := data.DepositTransaction
+	transactions, total = list(&requests.AccountTransactions{
+		Type:       &deposit,
+		PageParams: requests.PageParams{Limit: 2, Number: 1},
+	})
+	assert.Equal(t, uint64(3), total, "total must ignore the pagination")
+	require.Len(t, transactions, 1)
+	assert.Equal(t, uint(300), transactions[0].Amount)
+
+	transactions, total = list(&requests.AccountTransactions{Direction: requests.DirectionOutgoing})
+	assert.Equal(t, uint64(2), total)
+	for _, transaction := range transactions {
+		assert.Equal(t, accountID, transaction.Sender)
+	}
+
+	_, _, err = env.accounts.ListAccountTransactions(env.newCustomer(t), &requests.AccountTransactions{
+		AccountID:  accountID,
+		Order:      requests.OrderAsc,
+		PageParams: requests.PageParams{Limit: 10},
+	})
+	require.ErrorIs(t, err, ErrorAccountNotFound)
+}
+
+func TestGenerateStatementPDF(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	env.deposit(t, customerID, accountID, 1000)
+	env.withdraw(t, customerID, accountID, 250)
+
+	req := &requests.Statement{
+		AccountID: accountID,
+		To:        time.Now().Add(time.Minute),
+		Format:    report.StatementFormatPDF,
+	}
+
+	statement, err := env.accounts.GenerateStatement(customerID, req)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(statement, []byte("%PDF-")))
+	assert.Contains(t, string(statement), "(Opening Balance:)")
+	assert.Contains(t, string(statement), "($7.50)", "closing balance")
+
+	logs, err := env.db.AuditLogs().
+		WhereAccountID(accountID).
+		WhereAction(data.AuditActionStatementGenerated).
+		Select()
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.JSONEq(t, `{"format":"pdf","to":"`+req.To.Format(time.RFC3339Nano)+`"}`, string(logs[0].Details))
+
+	_, err = env.accounts.GenerateStatement(env.newCustomer(t), req)
+	require.ErrorIs(t, err, ErrorAccountNotFound)
+}
+
+func TestGenerateExcelReport(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	env.deposit(t, customerID, accountID, 1000)
+	time.Sleep(10 * time.Millisecond)
+	from := time.Now()
+
+	env.deposit(t, customerID, accountID, 500)
+	env.deposit(t, customerID, accountID, 50)
+	env.withdraw(t, customerID, accountID, 250)
+
+	deposit := data.DepositTransaction
+	minAmount := uint(100)
+	req := &requests.ExcelReport{
+		AccountID: accountID,
+		From:      from,
+		To:        time.Now().Add(time.Minute),
+		Type:      &deposit,
+		MinAmount: &minAmount,
+	}
+
+	writeReport, err := env.accounts.GenerateExcelReport(customerID, req)
+	require.NoError(t, err)
+
+	workbook := new(bytes.Buffer)
+	require.NoError(t, writeReport(workbook))
+
+	f, err := excelize.OpenReader(workbook)
+	require.NoError(t, err)
+	defer f.Close()
+
+	cell := func(sheet, axis string) string {
+		value, err := f.GetCellValue(sheet, axis, excelize.Options{RawCellValue: true})
+		require.NoError(t, err)
+		return value
+	}
+
+	assert.Equal(t, "10", cell(report.AccountSummarySheetName, "B10"), "opening balance")
+	assert.Equal(t, "13", cell(report.AccountSummarySheetName, "B11"), "closing balance")
+	assert.Equal(t, "type deposit, amount from $1.00", cell(report.AccountSummarySheetName, "B12"))
+	assert.Equal(t, "5", cell(report.AccountSummarySheetName, "B15"), "deposits of the period")
+	assert.Equal(t, "0", cell(report.AccountSummarySheetName, "B16"), "withdrawals are filtered out")
+
+	rows, err := f.GetRows(report.TransactionsHistorySheetName)
+	require.NoError(t, err)
+	require.Len(t, rows, 2, "header and the 5.00 deposit")
+
+	logs, err := env.db.AuditLogs().
+		WhereAccountID(accountID).
+		WhereAction(data.AuditActionExcelReportGenerated).
+		Select()
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+
+	_, err = env.accounts.GenerateExcelReport(env.newCustomer(t), req)
+	require.ErrorIs(t, err, ErrorAccountNotFound)
+}
+
+func TestReportBalancesWithHold(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	env.deposit(t, customerID, accountID, 1000)
+	env.withdraw(t, customerID, accountID, 250)
+
+	// The pending code holds the amount without a transaction
+	_, _, err := env.cashOut.IssueCode(customerID, &requests.Withdrawal{AccountID: accountID, Amount: 400})
+	require.NoError(t, err)
+
+	balance, _ := env.balances(t, accountID)
+	require.Equal(t, 350, balance)
+
+	to := time.Now().Add(time.Minute)
+
+	statement, err := env.accounts.getStatement(customerID, accountID, time.Time{}, to)
+	require.NoError(t, err)
+	assert.Equal(t, 0, statement.OpeningBalance)
+	assert.Equal(t, 750, statement.ClosingBalance, "the hold is not a transaction of the statement")
+	require.Len(t, statement.Transactions, 2)
+	assert.Equal(t, 1000, statement.Transactions[0].BalanceAfter)
+	assert.Equal(t, 750, statement.Transactions[1].BalanceAfter)
+
+	writeReport, err := env.accounts.GenerateExcelReport(customerID, &requests.ExcelReport{AccountID: accountID, To: to})
+	require.NoError(t, err)
+
+	workbook := new(bytes.Buffer)
+	require.NoError(t, writeReport(workbook))
+
+	f, err := excelize.OpenReader(workbook)
+	require.NoError(t, err)
+	defer f.Close()
+
+	current, err := f.GetCellValue(report.AccountSummarySheetName, "B4", excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+	assert.Equal(t, "3.5", current, "the summary shows the real balance")
+
+	opening, err := f.GetCellValue(report.AccountSummarySheetName, "B10", excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+	assert.Equal(t, "0", opening)
+
+	closing, err := f.GetCellValue(report.AccountSummarySheetName, "B11", excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+	assert.Equal(t, "7.5", closing)
+}
+
+func TestGenerateCustomerExcelReport(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	checkingID := env.newAccount(t, customerID)
+	savingsID := env.newAccount(t, customerID)
+	otherID := env.newAccount(t, env.newCustomer(t))
+
+	env.deposit(t, customerID, checkingID, 1000)
+
+	for _, transfer := range []requests.Transfer{
+		{SenderID: checkingID, RecipientID: savingsID, Amount: 300},
+		{SenderID: savingsID, RecipientID: checkingID, Amount: 100},
+		{SenderID: checkingID, RecipientID: otherID, Amount: 50},
+	} {
+		_, err := env.transactions.TransferFunds(customerID, &transfer)
+		require.NoError(t, err)
+	}
+
+	req := &requests.CustomerExcelReport{To: time.Now().Add(time.Minute)}
+
+	workbook, err := env.accounts.GenerateCustomerExcelReport(customerID, req)
+	require.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(workbook))
+	require.NoError(t, err)
+	defer f.Close()
+
+	assert.Equal(t, []string{
+		report.CustomerOverviewSheetName,
+		"1 account Summary", "1 account History",
+		"2 account Summary", "2 account History",
+	}, f.GetSheetList())
+
+	rows, err := f.GetRows(report.CustomerOverviewSheetName, excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+
+	// Header, both accounts and the total
+	assert.Equal(t, []string{"account", checkingID.String(), "0", "7.5", "10", "0", "1", "3.5", "-2", "7.5"}, rows[5])
+	assert.Equal(t, []string{"account", savingsID.String(), "0", "2", "0", "0", "3", "1", "2", "2"}, rows[6])
+	assert.Equal(t, []string{"Total", "", "0", "9.5", "10", "0", "4", "4.5", "0", "9.5"}, rows[7])
+
+	// Own transfers by the direction, the one to the other customer is left out
+	assert.Equal(t, []string{"account", "account", "1", "3"}, rows[11])
+	assert.Equal(t, []string{"account", "account", "1", "1"}, rows[12])
+	assert.Len(t, rows, 13)
+
+	history, err := f.GetRows("1 account History")
+	require.NoError(t, err)
+	assert.Len(t, history, 5, "header, deposit and the three transfers")
+
+	logs, err := env.db.AuditLogs().
+		WhereCustomerID(customerID).
+		WhereAction(data.AuditActionExcelReportGenerated).
+		Select()
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Nil(t, logs[0].AccountID)
+}
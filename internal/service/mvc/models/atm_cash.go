@@ -0,0 +1,273 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+)
+
+var ErrorATMCashAlreadyReconciled = errors.New("ATM cash already reconciled for the business date")
+var ErrorATMCashReconciledLater = errors.New("ATM cash already reconciled for a later business date")
+var ErrorInsufficientATMCash = errors.New("insufficient ATM cash")
+
+// ATMCash keeps track of the cash each ATM is expected to hold and reconciles
+// it with the end-of-day counts signed by the ATMs. The reconciliation records
+// the discrepancy and makes the counted cash the expected one, so every
+// discrepancy is that of a single business day.
+type ATMCash struct {
+	db           data.MainQ
+	atms         *ATMs
+	auditService *AuditService
+}
+
+func NewATMCash(db data.MainQ, auditService *AuditService, atms *ATMs) *ATMCash {
+	return &ATMCash{
+		db:           db,
+		atms:         atms,
+		auditService: auditService,
+	}
+}
+
+// Replenish adds the cash loaded into the ATM.
+func (m *ATMCash) Replenish(atmID uuid.UUID, amount uint) (*data.ATM, error) {
+	db := m.db.New()
+
+	var machine *data.ATM
+	err := db.IsolatedTransaction(sql.LevelRepeatableRead, func() error {
+		var err error
+		if machine, err = moveATMCash(db, atmID, int(amount)); err != nil {
+			return err
+		}
+
+		if err = m.auditService.withDB(db).logATMCashReplenished(machine, amount); err != nil {
+			return fmt.Errorf("failed to log ATM cash replenishment: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return machine, nil
+}
+
+// Collect subtracts the cash taken out of the ATM, which can't exceed the
+// cash the ATM is expected to hold.
+func (m *ATMCash) Collect(atmID uuid.UUID, amount uint) (*data.ATM, error) {
+	db := m.db.New()
+
+	var machine *data.ATM
+	err := db.IsolatedTransaction(sql.LevelRepeatableRead, func() error {
+		var err error
+		if machine, err = lockATM(db, atmID); err != nil {
+			return err
+		}
+
+		if machine.CashBalance < int(amount) {
+			return ErrorInsufficientATMCash
+		}
+
+		if machine, err = moveATMCash(db, atmID, -int(amount)); err != nil {
+			return err
+		}
+
+		if err = m.auditService.withDB(db).logATMCashCollected(machine, amount); err != nil {
+			return fmt.Errorf("failed to log ATM cash collection: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return machine, nil
+}
+
+// Reconcile compares the cash count signed by the ATM with the cash it was
+// expected to hold at the end of the business date and records the
+// discrepancy, once per ATM and business date. The cash moved since the end of
+// the business date is kept on top of the count, the business dates before the
+// last reconciled one are rejected.
+func (m *ATMCash) Reconcile(req *requests.ATMCashCount) (*data.ATMReconciliation, error) {
+	payload, err := m.verifySignature(req)
+	if err != nil {
+		return nil, err
+	}
+
+	db := m.db.New()
+
+	var reconciliation *data.ATMReconciliation
+	err = db.IsolatedTransaction(sql.LevelRepeatableRead, func() error {
+		machine, err := lockATM(db, req.ATMID)
+		if err != nil {
+			return err
+		}
+
+		later, err := db.ATMReconciliations().
+			WhereATMID(machine.ID).
+			WhereBusinessDateAfter(payload.BusinessDate).
+			Count()
+		if err != nil {
+			return fmt.Errorf("failed to count ATM reconciliations: %w", err)
+		}
+		if later > 0 {
+			return ErrorATMCashReconciledLater
+		}
+
+		endOfDay := payload.BusinessDate.AddDate(0, 0, 1)
+
+		movedSince, err := db.ATMCashMovements().WhereATMID(machine.ID).WhereMovedFrom(endOfDay).Sum()
+		if err != nil {
+			return err
+		}
+
+		expected := machine.CashBalance - movedSince
+
+		reconciliation = &data.ATMReconciliation{
+			ATMID:        machine.ID,
+			BusinessDate: payload.BusinessDate,
+			Expected:     expected,
+			Counted:      int(payload.Counted),
+			Discrepancy:  int(payload.Counted) - expected,
+			ATMNonce:     req.Nonce,
+			ATMSignature: req.ATMSignature,
+		}
+
+		if err = db.ATMReconciliations().Insert(reconciliation); err != nil {
+			if isATMNotUniqueError(err) {
+				return ErrorATMCashAlreadyReconciled
+			}
+
+			return fmt.Errorf("failed to insert ATM reconciliation: %w", err)
+		}
+
+		// The discrepancy is moved at the end of the business date, so it is
+		// expected by the counts of the following days
+		if !reconciliation.IsBalanced() {
+			if _, err = moveATMCashAt(db, machine.ID, reconciliation.Discrepancy, endOfDay); err != nil {
+				return err
+			}
+		}
+
+		if err = m.auditService.withDB(db).logATMCashReconciled(reconciliation); err != nil {
+			return fmt.Errorf("failed to log ATM reconciliation: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reconciliation, nil
+}
+
+// ReconciliationFilter selects the reconciliations of the business days from
+// and to, inclusive, of the ATM if ATMID is not nil.
+type ReconciliationFilter struct {
+	From            time.Time
+	To              time.Time
+	ATMID           *uuid.UUID
+	DiscrepancyOnly bool
+}
+
+// Reconciliations returns the reconciliations matching the filter ordered by
+// the business date and the ATM.
+func (m *ATMCash) Reconciliations(filter ReconciliationFilter) ([]*data.ATMReconciliation, error) {
+	q := m.db.ATMReconciliations().
+		WhereBusinessDateBetween(atm.BusinessDate(filter.From), atm.BusinessDate(filter.To))
+
+	if filter.ATMID != nil {
+		q = q.WhereATMID(*filter.ATMID)
+	}
+
+	if filter.DiscrepancyOnly {
+		q = q.WhereDiscrepancy()
+	}
+
+	reconciliations, err := q.OrderBy("business_date", "atm_fkey").Select()
+	if err != nil {
+		return nil, fmt.Errorf("failed to select ATM reconciliations: %w", err)
+	}
+
+	return reconciliations, nil
+}
+
+// verifySignature verifies the cash count payload is valid now and signed by
+// one of the valid keys of the active ATM.
+func (m *ATMCash) verifySignature(req *requests.ATMCashCount) (*atm.CashCountPayload, error) {
+	payload, err := req.Payload()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidATMPayload, err)
+	}
+
+	if err = payload.Validate(time.Now()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidATMPayload, err)
+	}
+
+	err = m.atms.VerifySignature(req.ATMID, payload.Canonical(), req.ATMSignature)
+	switch {
+	case err == nil:
+		return payload, nil
+	case errors.Is(err, ErrorATMNotFound), errors.Is(err, ErrorATMDisabled), errors.Is(err, ErrorInvalidATMSignature):
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("failed to verify ATM signature: %w", err)
+}
+
+// lockATM selects the ATM FOR UPDATE. The transactions changing the accounts
+// must lock the ATM after the accounts, see lockAccounts.
+func lockATM(db data.MainQ, atmID uuid.UUID) (*data.ATM, error) {
+	machine := new(data.ATM)
+
+	ok, err := db.ATMs().WhereID(atmID).ForUpdate().Get(machine)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock ATM: %w", err)
+	}
+	if !ok {
+		return nil, ErrorATMNotFound
+	}
+
+	return machine, nil
+}
+
+// moveATMCash adds the amount, negative for the cash taken out, to the cash
+// the ATM is expected to hold, it must be called within a transaction.
+func moveATMCash(db data.MainQ, atmID uuid.UUID, amount int) (*data.ATM, error) {
+	return moveATMCashAt(db, atmID, amount, time.Now().UTC())
+}
+
+// moveATMCashAt is moveATMCash recording the movement made at the time.
+func moveATMCashAt(db data.MainQ, atmID uuid.UUID, amount int, at time.Time) (*data.ATM, error) {
+	machine, err := lockATM(db, atmID)
+	if err != nil {
+		return nil, err
+	}
+
+	machine.CashBalance += amount
+	if err = db.ATMs().Update(machine); err != nil {
+		return nil, fmt.Errorf("failed to update ATM cash balance: %w", err)
+	}
+
+	movement := &data.ATMCashMovement{
+		ATMID:   atmID,
+		Amount:  amount,
+		MovedAt: at,
+	}
+
+	if err = db.ATMCashMovements().Insert(movement); err != nil {
+		return nil, fmt.Errorf("failed to insert ATM cash movement: %w", err)
+	}
+
+	return machine, nil
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+)
+
+func (e *testEnv) cashCountRequest(t *testing.T, businessDate time.Time, counted uint) *requests.ATMCashCount {
+	t.Helper()
+
+	payload, err := atm.NewCashCountPayload(e.atmID, businessDate, counted, time.Minute)
+	require.NoError(t, err)
+
+	signed, err := atm.SignCashCount(e.atmKey, payload)
+	require.NoError(t, err)
+
+	return &requests.ATMCashCount{SignedCashCount: *signed}
+}
+
+func (e *testEnv) atmCashBalance(t *testing.T) int {
+	t.Helper()
+
+	machine, err := e.atms.GetATM(e.atmID)
+	require.NoError(t, err)
+
+	return machine.CashBalance
+}
+
+// backdateATMCash moves the cash movements of the test ATM made so far to the
+// time, as if they were made then.
+func (e *testEnv) backdateATMCash(t *testing.T, at time.Time) {
+	t.Helper()
+
+	movements, err := e.db.ATMCashMovements().WhereATMID(e.atmID).Select()
+	require.NoError(t, err)
+
+	for _, movement := range movements {
+		movement.MovedAt = at
+		require.NoError(t, e.db.ATMCashMovements().Update(movement))
+	}
+}
+
+func TestATMCashBalance(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	_, err := env.atmCash.Replenish(env.atmID, 5000)
+	require.NoError(t, err)
+
+	env.deposit(t, customerID, accountID, 1000)
+	env.withdraw(t, customerID, accountID, 400)
+	assert.Equal(t, 5000+1000-400, env.atmCashBalance(t))
+
+	_, err = env.atmCash.Collect(env.atmID, 5601)
+	require.ErrorIs(t, err, ErrorInsufficientATMCash)
+
+	machine, err := env.atmCash.Collect(env.atmID, 600)
+	require.NoError(t, err)
+	assert.Equal(t, 5000, machine.CashBalance)
+
+	_, err = env.atmCash.Replenish(uuid.New(), 100)
+	require.ErrorIs(t, err, ErrorATMNotFound)
+
+	for action, amount := range map[data.AuditAction]float64{
+		data.AuditActionATMCashReplenished: 5000,
+		data.AuditActionATMCashCollected:   600,
+	} {
+		logs, err := env.db.AuditLogs().WhereAction(action).Select()
+		require.NoError(t, err)
+		require.Len(t, logs, 1, action)
+		assert.Nil(t, logs[0].CustomerID, "ATM cash operations are made on behalf of the bank")
+
+		var details AuditDetails
+		require.NoError(t, json.Unmarshal(logs[0].Details, &details))
+		assert.Equal(t, amount, details["amount"], action)
+	}
+}
+
+func TestATMCashReconcile(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	today := atm.BusinessDate(time.Now())
+	yesterday := today.AddDate(0, 0, -1)
+
+	_, err := env.atmCash.Replenish(env.atmID, 5000)
+	require.NoError(t, err)
+	env.deposit(t, customerID, accountID, 1000)
+	env.backdateATMCash(t, yesterday.Add(12*time.Hour))
+
+	reconciliation, err := env.atmCash.Reconcile(env.cashCountRequest(t, yesterday, 5900))
+	require.NoError(t, err)
+	assert.Equal(t, 6000, reconciliation.Expected)
+	assert.Equal(t, 5900, reconciliation.Counted)
+	assert.Equal(t, -100, reconciliation.Discrepancy)
+	assert.Equal(t, 5900, env.atmCashBalance(t), "the counted cash must become the expected one")
+
+	_, err = env.atmCash.Reconcile(env.cashCountRequest(t, yesterday, 5900))
+	require.ErrorIs(t, err, ErrorATMCashAlreadyReconciled)
+
+	env.withdraw(t, customerID, accountID, 900)
+
+	balanced, err := env.atmCash.Reconcile(env.cashCountRequest(t, today, 5000))
+	require.NoError(t, err)
+	assert.True(t, balanced.IsBalanced())
+
+	t.Run("business date before the last reconciled one", func(t *testing.T) {
+		_, err := env.atmCash.Reconcile(env.cashCountRequest(t, today.AddDate(0, 0, -2), 5000))
+		require.ErrorIs(t, err, ErrorATMCashReconciledLater)
+	})
+
+	t.Run("future business date", func(t *testing.T) {
+		_, err := env.atmCash.Reconcile(env.cashCountRequest(t, today.AddDate(0, 0, 2), 5000))
+		require.ErrorIs(t, err, ErrorInvalidATMPayload)
+	})
+
+	t.Run("tampered count", func(t *testing.T) {
+		req := env.cashCountRequest(t, today.AddDate(0, 0, -2), 5000)
+		req.Counted = 6000
+
+		_, err := env.atmCash.Reconcile(req)
+		require.ErrorIs(t, err, ErrorInvalidATMSignature)
+	})
+
+	reconciliations, err := env.atmCash.Reconciliations(ReconciliationFilter{From: yesterday, To: today})
+	require.NoError(t, err)
+	require.Len(t, reconciliations, 2)
+	assert.Equal(t, reconciliation.ID, reconciliations[0].ID)
+	assert.Equal(t, balanced.ID, reconciliations[1].ID)
+
+	discrepancies, err := env.atmCash.Reconciliations(ReconciliationFilter{
+		From:            yesterday,
+		To:              today,
+		ATMID:           &env.atmID,
+		DiscrepancyOnly: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, discrepancies, 1)
+	assert.Equal(t, reconciliation.ID, discrepancies[0].ID)
+
+	logs, err := env.db.AuditLogs().WhereAction(data.AuditActionATMCashReconciled).Select()
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+}
+
+func TestATMCashReconcileLateCount(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	today := atm.BusinessDate(time.Now())
+	yesterday := today.AddDate(0, 0, -1)
+
+	_, err := env.atmCash.Replenish(env.atmID, 5000)
+	require.NoError(t, err)
+	env.backdateATMCash(t, yesterday.Add(12*time.Hour))
+
+	// The deposit is made after the end of the business day, before the count
+	// of the day is sent
+	env.deposit(t, customerID, accountID, 1000)
+
+	reconciliation, err := env.atmCash.Reconcile(env.cashCountRequest(t, yesterday, 4900))
+	require.NoError(t, err)
+	assert.Equal(t, 5000, reconciliation.Expected, "the cash at the end of the business day")
+	assert.Equal(t, -100, reconciliation.Discrepancy)
+	assert.Equal(t, 4900+1000, env.atmCashBalance(t), "the deposit is kept on top of the count")
+
+	balanced, err := env.atmCash.Reconcile(env.cashCountRequest(t, today, 5900))
+	require.NoError(t, err)
+	assert.Equal(t, 5900, balanced.Expected)
+	assert.True(t, balanced.IsBalanced())
+}
@@ -0,0 +1,226 @@
+package models
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+var ErrorATMNotFound = errors.New("ATM not found")
+var ErrorATMDisabled = errors.New("ATM disabled")
+var ErrorATMKeyNotFound = errors.New("ATM key not found")
+var ErrorInvalidATMPublicKey = errors.New("invalid ATM public key")
+
+// ATMs is the registry of the ATMs allowed to sign the deposits. Every ATM has
+// one or more ECDSA or Ed25519 public keys with validity windows, so its key
+// can be rotated by adding the new key and then revoking the old one. Each key
+// verifies the signatures in its own format, see atm.SignatureFormat.
+type ATMs struct {
+	db data.MainQ
+}
+
+func NewATMs(db data.MainQ) *ATMs {
+	return &ATMs{
+		db: db,
+	}
+}
+
+// Register adds an active ATM with the PEM encoded public key valid from now on.
+func (m *ATMs) Register(location string, publicKeyPEM []byte, format atm.SignatureFormat) (*data.ATM, *data.ATMKey, error) {
+	if _, err := parseATMPublicKey(publicKeyPEM, format); err != nil {
+		return nil, nil, err
+	}
+
+	machine := &data.ATM{
+		Location: location,
+		Status:   data.ATMStatusActive,
+	}
+	key := &data.ATMKey{
+		PublicKey:       string(publicKeyPEM),
+		SignatureFormat: format,
+		ValidFrom:       time.Now().UTC(),
+	}
+
+	db := m.db.New()
+
+	err := db.Transaction(func() error {
+		if err := db.ATMs().Insert(machine); err != nil {
+			return fmt.Errorf("failed to insert ATM: %w", err)
+		}
+
+		key.ATMID = machine.ID
+		if err := db.ATMKeys().Insert(key); err != nil {
+			return fmt.Errorf("failed to insert ATM key: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return machine, key, nil
+}
+
+func (m *ATMs) GetATM(atmID uuid.UUID) (*data.ATM, error) {
+	machine := new(data.ATM)
+
+	ok, err := m.db.ATMs().WhereID(atmID).Get(machine)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ATM: %w", err)
+	}
+	if !ok {
+		return nil, ErrorATMNotFound
+	}
+
+	return machine, nil
+}
+
+func (m *ATMs) GetATMs() ([]*data.ATM, error) {
+	atms, err := m.db.ATMs().OrderBy("created_at").Select()
+	if err != nil {
+		return nil, fmt.Errorf("failed to select ATMs: %w", err)
+	}
+
+	return atms, nil
+}
+
+func (m *ATMs) GetKeys(atmID uuid.UUID) ([]*data.ATMKey, error) {
+	keys, err := m.db.ATMKeys().WhereATMID(atmID).OrderBy("valid_from").Select()
+	if err != nil {
+		return nil, fmt.Errorf("failed to select ATM keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// SetStatus enables or disables the ATM, the deposits signed by a disabled ATM
+// are rejected whatever its keys are.
+func (m *ATMs) SetStatus(atmID uuid.UUID, status data.ATMStatus) error {
+	machine, err := m.GetATM(atmID)
+	if err != nil {
+		return err
+	}
+
+	machine.Status = status
+	if err = m.db.ATMs().Update(machine); err != nil {
+		return fmt.Errorf("failed to update ATM: %w", err)
+	}
+
+	return nil
+}
+
+// AddKey adds the PEM encoded public key to the ATM, validUntil is nil for the
+// key valid until revoked.
+func (m *ATMs) AddKey(
+	atmID uuid.UUID,
+	publicKeyPEM []byte,
+	format atm.SignatureFormat,
+	validFrom time.Time,
+	validUntil *time.Time,
+) (*data.ATMKey, error) {
+	if _, err := parseATMPublicKey(publicKeyPEM, format); err != nil {
+		return nil, err
+	}
+
+	if _, err := m.GetATM(atmID); err != nil {
+		return nil, err
+	}
+
+	key := &data.ATMKey{
+		ATMID:           atmID,
+		PublicKey:       string(publicKeyPEM),
+		SignatureFormat: format,
+		ValidFrom:       validFrom.UTC(),
+		ValidUntil:      validUntil,
+	}
+
+	if err := m.db.ATMKeys().Insert(key); err != nil {
+		return nil, fmt.Errorf("failed to insert ATM key: %w", err)
+	}
+
+	return key, nil
+}
+
+// RevokeKey ends the validity of the key at the given time, unless it already
+// ends earlier.
+func (m *ATMs) RevokeKey(keyID uuid.UUID, at time.Time) error {
+	key := new(data.ATMKey)
+
+	ok, err := m.db.ATMKeys().WhereID(keyID).Get(key)
+	if err != nil {
+		return fmt.Errorf("failed to get ATM key: %w", err)
+	}
+	if !ok {
+		return ErrorATMKeyNotFound
+	}
+
+	at = at.UTC()
+	if key.ValidUntil != nil && !key.ValidUntil.After(at) {
+		return nil
+	}
+
+	// The key revoked before it became valid is never valid
+	if !at.After(key.ValidFrom) {
+		at = key.ValidFrom.Add(time.Microsecond)
+	}
+
+	key.ValidUntil = &at
+	if err = m.db.ATMKeys().Update(key); err != nil {
+		return fmt.Errorf("failed to update ATM key: %w", err)
+	}
+
+	return nil
+}
+
+// VerifySignature checks the signature of the message, see atm.VerifySignature,
+// against the keys of the ATM valid now, each in its own signature format.
+func (m *ATMs) VerifySignature(atmID uuid.UUID, message []byte, signature string) error {
+	machine, err := m.GetATM(atmID)
+	if err != nil {
+		return err
+	}
+
+	if !machine.IsActive() {
+		return ErrorATMDisabled
+	}
+
+	keys, err := m.db.ATMKeys().WhereATMID(atmID).WhereValidAt(time.Now().UTC()).Select()
+	if err != nil {
+		return fmt.Errorf("failed to select ATM keys: %w", err)
+	}
+
+	for _, key := range keys {
+		publicKey, err := parseATMPublicKey([]byte(key.PublicKey), key.SignatureFormat)
+		if err != nil {
+			return fmt.Errorf("failed to parse key %s: %w", key.ID, err)
+		}
+
+		if err = atm.VerifySignature(publicKey, key.SignatureFormat, message, signature); err == nil {
+			return nil
+		}
+	}
+
+	return ErrorInvalidATMSignature
+}
+
+// parseATMPublicKey parses the public key and checks it can verify the
+// signatures of the format.
+func parseATMPublicKey(publicKeyPEM []byte, format atm.SignatureFormat) (crypto.PublicKey, error) {
+	publicKey, err := atm.ParsePublicKey(publicKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidATMPublicKey, err)
+	}
+
+	if err = atm.CheckKey(publicKey, format); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidATMPublicKey, err)
+	}
+
+	return publicKey, nil
+}
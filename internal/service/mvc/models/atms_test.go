@@ -0,0 +1,146 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+func TestATMDeposits(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	deposit := func(atmID uuid.UUID, atmKey *atm.Signer, amount uint) error {
+		_, err := env.transactions.DepositFunds(customerID, signTestDeposit(t, atmKey, atmID, accountID, amount))
+		return err
+	}
+
+	otherKey := newTestATMSigner(t, atm.KeyTypeECDSA, atm.SignatureFormatBase64)
+
+	other, _, err := env.atms.Register("other", testATMPublicKeyPEM(t, otherKey.Public()), otherKey.Format())
+	require.NoError(t, err)
+
+	t.Run("atm is stored on the deposit", func(t *testing.T) {
+		require.NoError(t, deposit(other.ID, otherKey, 100))
+
+		deposits, err := env.db.Transactions().WhereRecipient(accountID).Select()
+		require.NoError(t, err)
+		require.Len(t, deposits, 1)
+		require.NotNil(t, deposits[0].ATMID)
+		assert.Equal(t, other.ID, *deposits[0].ATMID)
+	})
+
+	t.Run("key of another atm", func(t *testing.T) {
+		require.ErrorIs(t, deposit(env.atmID, otherKey, 200), ErrorInvalidATMSignature)
+	})
+
+	t.Run("unknown atm", func(t *testing.T) {
+		require.ErrorIs(t, deposit(uuid.New(), otherKey, 300), ErrorATMNotFound)
+	})
+
+	t.Run("disabled atm", func(t *testing.T) {
+		require.NoError(t, env.atms.SetStatus(other.ID, data.ATMStatusDisabled))
+		require.ErrorIs(t, deposit(other.ID, otherKey, 400), ErrorATMDisabled)
+
+		require.NoError(t, env.atms.SetStatus(other.ID, data.ATMStatusActive))
+		require.NoError(t, deposit(other.ID, otherKey, 400))
+	})
+}
+
+func TestATMKeyRotation(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	deposit := func(atmKey *atm.Signer, amount uint) error {
+		_, err := env.transactions.DepositFunds(customerID, signTestDeposit(t, atmKey, env.atmID, accountID, amount))
+		return err
+	}
+
+	newKey := newTestATMSigner(t, atm.KeyTypeECDSA, atm.SignatureFormatBase64)
+
+	_, err := env.atms.AddKey(env.atmID, []byte("not a key"), atm.SignatureFormatBase64, time.Now(), nil)
+	require.ErrorIs(t, err, ErrorInvalidATMPublicKey)
+
+	future, err := env.atms.AddKey(env.atmID, testATMPublicKeyPEM(t, newKey.Public()), newKey.Format(), time.Now().Add(time.Hour), nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, deposit(newKey, 100), ErrorInvalidATMSignature, "key must not be accepted before it's valid")
+
+	require.NoError(t, env.db.ATMKeys().Delete(future.ID))
+
+	_, err = env.atms.AddKey(env.atmID, testATMPublicKeyPEM(t, newKey.Public()), newKey.Format(), time.Now().Add(-time.Second), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, deposit(env.atmKey, 200), "old key must be accepted until revoked")
+	require.NoError(t, deposit(newKey, 300))
+
+	keys, err := env.atms.GetKeys(env.atmID)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	oldKeyPEM := string(testATMPublicKeyPEM(t, env.atmKey.Public()))
+	for _, key := range keys {
+		if key.PublicKey == oldKeyPEM {
+			require.NoError(t, env.atms.RevokeKey(key.ID, time.Now()))
+		}
+	}
+	require.ErrorIs(t, env.atms.RevokeKey(uuid.New(), time.Now()), ErrorATMKeyNotFound)
+
+	require.ErrorIs(t, deposit(env.atmKey, 400), ErrorInvalidATMSignature)
+	require.NoError(t, deposit(newKey, 500))
+}
+
+func TestATMKeyTypesAndFormats(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	deposit := func(atmID uuid.UUID, atmKey *atm.Signer, amount uint) error {
+		_, err := env.transactions.DepositFunds(customerID, signTestDeposit(t, atmKey, atmID, accountID, amount))
+		return err
+	}
+
+	t.Run("unsupported format", func(t *testing.T) {
+		key := newTestATMSigner(t, atm.KeyTypeEd25519, atm.SignatureFormatBase64)
+
+		_, _, err := env.atms.Register("raw", testATMPublicKeyPEM(t, key.Public()), "raw")
+		require.ErrorIs(t, err, ErrorInvalidATMPublicKey)
+	})
+
+	edPrivateKey, err := atm.GenerateEd25519Key()
+	require.NoError(t, err)
+
+	edKey, err := atm.NewSigner(edPrivateKey, atm.SignatureFormatJWS)
+	require.NoError(t, err)
+
+	edATM, _, err := env.atms.Register("ed25519", testATMPublicKeyPEM(t, edKey.Public()), edKey.Format())
+	require.NoError(t, err)
+
+	t.Run("ed25519 jws", func(t *testing.T) {
+		require.NoError(t, deposit(edATM.ID, edKey, 100))
+	})
+
+	t.Run("signature in another format", func(t *testing.T) {
+		base64Key, err := atm.NewSigner(edPrivateKey, atm.SignatureFormatBase64)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, deposit(edATM.ID, base64Key, 200), ErrorInvalidATMSignature)
+	})
+
+	t.Run("rotation to ecdsa jws", func(t *testing.T) {
+		newKey := newTestATMSigner(t, atm.KeyTypeECDSA, atm.SignatureFormatJWS)
+
+		_, err := env.atms.AddKey(edATM.ID, testATMPublicKeyPEM(t, newKey.Public()), newKey.Format(), time.Now().Add(-time.Second), nil)
+		require.NoError(t, err)
+
+		require.NoError(t, deposit(edATM.ID, edKey, 300), "each key verifies its own format")
+		require.NoError(t, deposit(edATM.ID, newKey, 400))
+	})
+}
@@ -3,7 +3,10 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"time"
+
 	"github.com/google/uuid"
+	"github.com/omegatymbjiep/ilab1/internal/atm"
 	"github.com/omegatymbjiep/ilab1/internal/data"
 )
 
@@ -17,6 +20,12 @@ func NewAuditService(db data.MainQ) *AuditService {
 	}
 }
 
+// withDB returns the AuditService writing through db, so the audit entries
+// become a part of the transaction running on it.
+func (m *AuditService) withDB(db data.MainQ) *AuditService {
+	return NewAuditService(db)
+}
+
 type AuditDetails map[string]interface{}
 
 func (m *AuditService) LogAction(
@@ -24,6 +33,17 @@ func (m *AuditService) LogAction(
 	accountID *uuid.UUID,
 	action data.AuditAction,
 	details AuditDetails,
+) error {
+	return m.insert(&customerID, accountID, action, details)
+}
+
+// insert adds the audit entry, customerID is nil for the operations made on
+// behalf of the bank rather than a customer.
+func (m *AuditService) insert(
+	customerID *uuid.UUID,
+	accountID *uuid.UUID,
+	action data.AuditAction,
+	details AuditDetails,
 ) error {
 	detailsJSON, err := json.Marshal(details)
 	if err != nil {
@@ -142,8 +162,71 @@ func (m *AuditService) logAccountDeleted(customerID uuid.UUID, accountID uuid.UU
 	return nil
 }
 
-func (m *AuditService) logExcelReportGenerated(customerID uuid.UUID, accountID uuid.UUID) error {
-	err := m.LogAction(customerID, &accountID, data.AuditActionExcelReportGenerated, nil)
+func (m *AuditService) logExcelReportGenerated(customerID, accountID uuid.UUID, from, to time.Time) error {
+	details := AuditDetails{"to": to}
+	if !from.IsZero() {
+		details["from"] = from
+	}
+
+	err := m.LogAction(customerID, &accountID, data.AuditActionExcelReportGenerated, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+// logCustomerExcelReportGenerated records the workbook with all the accounts
+// of the customer, it is not bound to a single account.
+func (m *AuditService) logCustomerExcelReportGenerated(customerID uuid.UUID, accountIDs []uuid.UUID, from, to time.Time) error {
+	details := AuditDetails{
+		"account_ids": accountIDs,
+		"to":          to,
+	}
+	if !from.IsZero() {
+		details["from"] = from
+	}
+
+	err := m.LogAction(customerID, nil, data.AuditActionExcelReportGenerated, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+// logStatementGenerated records the statement of the period in the format
+// downloaded by the customer.
+func (m *AuditService) logStatementGenerated(customerID, accountID uuid.UUID, format string, from, to time.Time) error {
+	details := AuditDetails{
+		"format": format,
+		"to":     to,
+	}
+	if !from.IsZero() {
+		details["from"] = from
+	}
+
+	err := m.LogAction(customerID, &accountID, data.AuditActionStatementGenerated, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+// logReportRequested records the report job, it is bound to the account
+// unless the report covers all the accounts of the customer.
+func (m *AuditService) logReportRequested(customerID uuid.UUID, job *data.ReportJob) error {
+	err := m.LogAction(customerID, job.AccountID, data.AuditActionReportRequested, reportJobDetails(job))
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+func (m *AuditService) logReportDownloaded(customerID uuid.UUID, job *data.ReportJob) error {
+	err := m.LogAction(customerID, job.AccountID, data.AuditActionReportDownloaded, reportJobDetails(job))
 	if err != nil {
 		return fmt.Errorf("failed to log audit action: %w", err)
 	}
@@ -151,9 +234,23 @@ func (m *AuditService) logExcelReportGenerated(customerID uuid.UUID, accountID u
 	return nil
 }
 
-func (m *AuditService) logDepositMade(customerID uuid.UUID, accountID uuid.UUID, amount uint) error {
+func reportJobDetails(job *data.ReportJob) AuditDetails {
+	details := AuditDetails{
+		"report_id": job.ID,
+		"format":    job.Format,
+		"to":        job.PeriodTo,
+	}
+	if job.PeriodFrom != nil {
+		details["from"] = *job.PeriodFrom
+	}
+
+	return details
+}
+
+func (m *AuditService) logDepositMade(customerID uuid.UUID, accountID uuid.UUID, atmID uuid.UUID, amount uint) error {
 	details := AuditDetails{
 		"amount": amount,
+		"atm_id": atmID,
 	}
 
 	err := m.LogAction(customerID, &accountID, data.AuditActionDepositMade, details)
@@ -164,9 +261,10 @@ func (m *AuditService) logDepositMade(customerID uuid.UUID, accountID uuid.UUID,
 	return nil
 }
 
-func (m *AuditService) logWithdrawalMade(customerID uuid.UUID, accountID uuid.UUID, amount uint) error {
+func (m *AuditService) logWithdrawalMade(customerID uuid.UUID, accountID uuid.UUID, atmID uuid.UUID, amount uint) error {
 	details := AuditDetails{
 		"amount": amount,
+		"atm_id": atmID,
 	}
 
 	err := m.LogAction(customerID, &accountID, data.AuditActionWithdrawalMade, details)
@@ -196,3 +294,203 @@ func (m *AuditService) logTransferMade(
 
 	return nil
 }
+
+func (m *AuditService) logAccessTokenCreated(customerID uuid.UUID, token *data.AccessToken) error {
+	details := AuditDetails{
+		"token_id": token.ID,
+		"name":     token.Name,
+		"scopes":   token.Scopes,
+	}
+
+	err := m.LogAction(customerID, nil, data.AuditActionAccessTokenCreated, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+func (m *AuditService) logAccessTokenRevoked(customerID uuid.UUID, token *data.AccessToken) error {
+	details := AuditDetails{
+		"token_id": token.ID,
+		"name":     token.Name,
+	}
+
+	err := m.LogAction(customerID, nil, data.AuditActionAccessTokenRevoked, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+func (m *AuditService) logTwoFactorEnabled(customerID uuid.UUID) error {
+	details := AuditDetails{
+		"method": twoFactorMethodTOTP,
+	}
+
+	err := m.LogAction(customerID, nil, data.AuditActionTwoFactorEnabled, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+func (m *AuditService) logTwoFactorDisabled(customerID uuid.UUID) error {
+	details := AuditDetails{
+		"method": twoFactorMethodTOTP,
+	}
+
+	err := m.LogAction(customerID, nil, data.AuditActionTwoFactorDisabled, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+func (m *AuditService) logTwoFactorFailed(customerID uuid.UUID, method string) error {
+	details := AuditDetails{
+		"method": method,
+	}
+
+	err := m.LogAction(customerID, nil, data.AuditActionTwoFactorFailed, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+func (m *AuditService) logLoginSuccess(customerID uuid.UUID, client ClientInfo) error {
+	details := AuditDetails{
+		"ip":         client.IP,
+		"user_agent": client.UserAgent,
+	}
+
+	err := m.LogAction(customerID, nil, data.AuditActionLoginSuccess, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+func (m *AuditService) logLoginFailed(customerID uuid.UUID, client ClientInfo, reason string) error {
+	details := AuditDetails{
+		"ip":         client.IP,
+		"user_agent": client.UserAgent,
+		"reason":     reason,
+	}
+
+	err := m.LogAction(customerID, nil, data.AuditActionLoginFailed, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+func (m *AuditService) logLoginUnlocked(customerID uuid.UUID) error {
+	err := m.LogAction(customerID, nil, data.AuditActionLoginUnlocked, nil)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+func (m *AuditService) logCashOutCodeIssued(customerID uuid.UUID, code *data.CashOutCode) error {
+	details := AuditDetails{
+		"cash_out_code_id": code.ID,
+		"amount":           code.Amount,
+		"expires_at":       code.ExpiresAt,
+	}
+
+	err := m.LogAction(customerID, &code.AccountID, data.AuditActionCashOutCodeIssued, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+func (m *AuditService) logCashOutCodeCancelled(customerID uuid.UUID, code *data.CashOutCode) error {
+	details := AuditDetails{
+		"cash_out_code_id": code.ID,
+		"amount":           code.Amount,
+	}
+
+	err := m.LogAction(customerID, &code.AccountID, data.AuditActionCashOutCodeCancelled, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+// logCashOutCodeExpired is logged on behalf of the customer who issued the code.
+func (m *AuditService) logCashOutCodeExpired(code *data.CashOutCode) error {
+	details := AuditDetails{
+		"cash_out_code_id": code.ID,
+		"amount":           code.Amount,
+	}
+
+	err := m.LogAction(code.CustomerID, &code.AccountID, data.AuditActionCashOutCodeExpired, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+// logATMCashReplenished is logged on behalf of the bank, as are the other ATM
+// cash operations.
+func (m *AuditService) logATMCashReplenished(machine *data.ATM, amount uint) error {
+	details := AuditDetails{
+		"atm_id":       machine.ID,
+		"amount":       amount,
+		"cash_balance": machine.CashBalance,
+	}
+
+	err := m.insert(nil, nil, data.AuditActionATMCashReplenished, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+func (m *AuditService) logATMCashCollected(machine *data.ATM, amount uint) error {
+	details := AuditDetails{
+		"atm_id":       machine.ID,
+		"amount":       amount,
+		"cash_balance": machine.CashBalance,
+	}
+
+	err := m.insert(nil, nil, data.AuditActionATMCashCollected, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
+
+func (m *AuditService) logATMCashReconciled(reconciliation *data.ATMReconciliation) error {
+	details := AuditDetails{
+		"atm_id":            reconciliation.ATMID,
+		"reconciliation_id": reconciliation.ID,
+		"business_date":     reconciliation.BusinessDate.Format(atm.BusinessDateLayout),
+		"expected":          reconciliation.Expected,
+		"counted":           reconciliation.Counted,
+		"discrepancy":       reconciliation.Discrepancy,
+	}
+
+	err := m.insert(nil, nil, data.AuditActionATMCashReconciled, details)
+	if err != nil {
+		return fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -8,46 +10,164 @@ import (
 	"github.com/lestrrat-go/jwx/v3/jwa"
 	"github.com/lestrrat-go/jwx/v3/jwk"
 	"github.com/lestrrat-go/jwx/v3/jwt"
-	"golang.org/x/crypto/bcrypt"
 
 	"github.com/omegatymbjiep/ilab1/internal/data"
 	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
 )
 
-const customerIDJWTKey = "uid"
+const (
+	customerIDJWTKey = "uid"
+	sessionIDJWTKey  = "sid"
+	purposeJWTKey    = "purpose"
+)
+
+// twoFactorPurpose marks the tokens of the login challenge, which can only be
+// exchanged for a session after the second factor is verified.
+const twoFactorPurpose = "2fa"
+
+const (
+	// refreshGracePeriod is how long the replaced refresh token is still accepted,
+	// so the requests racing with the rotation don't log the customer out.
+	refreshGracePeriod = 30 * time.Second
+	// sessionTouchInterval limits how often the last seen time of a session is written.
+	sessionTouchInterval = time.Minute
+	// twoFactorChallengeExpiry is how long the customer has to enter the second factor.
+	twoFactorChallengeExpiry = 5 * time.Minute
+
+	maxUserAgentLength = 512
+)
 
 var ErrorEmailOrUsernameTaken = fmt.Errorf("email or username is already taken")
 var ErrorUserNotFound = fmt.Errorf("user not found")
-var ErrorInvalidPassword = fmt.Errorf("invalid password")
+
+// ErrorInvalidCredentials doesn't tell an unknown email from a wrong password,
+// so the login can't be used to find out the registered emails.
+var ErrorInvalidCredentials = errors.New("invalid email or password")
+var ErrorInvalidSession = errors.New("session is expired or revoked")
+var ErrorRefreshTokenReused = errors.New("refresh token was reused")
+var ErrorInvalidTwoFactorChallenge = errors.New("two-factor challenge is invalid or expired")
 
 type JWTWithEat struct {
 	Token      string
 	Expiration time.Time
 }
 
-type Auth struct {
-	db data.MainQ
+// RefreshToken is an opaque token exchanged for a new access token, it is
+// rotated on every use.
+type RefreshToken struct {
+	Token      string
+	Expiration time.Time
+}
+
+type SessionTokens struct {
+	Access *JWTWithEat
+	// Refresh is nil when only the access token is reissued.
+	Refresh *RefreshToken
+}
 
-	jwtSigningKey   jwk.Key
-	jwtVerifyingKey jwk.Key
-	jwtExpiry       time.Duration
+// LoginResult holds the session tokens, or, when the customer has the second
+// factor enabled, the challenge to be completed with CompleteLogin.
+type LoginResult struct {
+	Tokens    *SessionTokens
+	Challenge *JWTWithEat
 }
 
-func NewAuth(db data.MainQ, jwtSigningKey jwk.Key, jwtExpiry time.Duration) (*Auth, error) {
-	jwtVerifyingKey, err := jwtSigningKey.PublicKey()
+// ClientInfo describes the client the session is started from.
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// Claims are the verified claims of the access token.
+type Claims struct {
+	CustomerID uuid.UUID
+	SessionID  uuid.UUID
+}
+
+type Auth struct {
+	db         data.MainQ
+	hasher     *PasswordHasher
+	twoFactor  *TwoFactor
+	loginGuard *LoginGuard
+
+	// dummyPasswordHash is verified for the unknown emails, so the response
+	// time doesn't tell them apart from the registered ones.
+	dummyPasswordHash string
+
+	// jwtVerifyingKeys are the public keys of all the keys in the key set,
+	// the tokens are verified with the key matching their kid.
+	jwtSigningKey       jwk.Key
+	jwtSigningAlgorithm jwa.SignatureAlgorithm
+	jwtVerifyingKeys    jwk.Set
+
+	jwtExpiry          time.Duration
+	refreshExpiry      time.Duration
+	refreshGracePeriod time.Duration
+}
+
+func NewAuth(
+	db data.MainQ,
+	hasher *PasswordHasher,
+	twoFactor *TwoFactor,
+	loginGuard *LoginGuard,
+	jwtKeys jwk.Set,
+	jwtSigningKeyID string,
+	jwtExpiry, refreshExpiry time.Duration,
+) (*Auth, error) {
+	jwtSigningKey, ok := jwtKeys.LookupKeyID(jwtSigningKeyID)
+	if !ok {
+		return nil, fmt.Errorf("JWT signing key %q not found", jwtSigningKeyID)
+	}
+
+	keyAlgorithm, ok := jwtSigningKey.Algorithm()
+	if !ok {
+		return nil, fmt.Errorf("JWT signing key %q has no algorithm", jwtSigningKeyID)
+	}
+
+	jwtSigningAlgorithm, ok := jwa.LookupSignatureAlgorithm(keyAlgorithm.String())
+	if !ok {
+		return nil, fmt.Errorf("JWT signing key %q has unsupported algorithm %s", jwtSigningKeyID, keyAlgorithm)
+	}
+
+	jwtVerifyingKeys, err := jwk.PublicSetOf(jwtKeys)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get JWT public key: %w", err)
+		return nil, fmt.Errorf("failed to get JWT public keys: %w", err)
+	}
+
+	dummyPassword, err := newSecretToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate dummy password: %w", err)
+	}
+
+	dummyPasswordHash, err := hasher.Hash(dummyPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash dummy password: %w", err)
 	}
 
 	return &Auth{
-		db:              db,
-		jwtSigningKey:   jwtSigningKey,
-		jwtVerifyingKey: jwtVerifyingKey,
-		jwtExpiry:       jwtExpiry,
+		db:                  db,
+		hasher:              hasher,
+		twoFactor:           twoFactor,
+		loginGuard:          loginGuard,
+		dummyPasswordHash:   dummyPasswordHash,
+		jwtSigningKey:       jwtSigningKey,
+		jwtSigningAlgorithm: jwtSigningAlgorithm,
+		jwtVerifyingKeys:    jwtVerifyingKeys,
+		jwtExpiry:           jwtExpiry,
+		refreshExpiry:       refreshExpiry,
+		refreshGracePeriod:  refreshGracePeriod,
 	}, nil
 }
 
-func (a *Auth) Login(req *requests.Login) (*JWTWithEat, error) {
+func (a *Auth) Login(req *requests.Login, client ClientInfo) (*LoginResult, error) {
+	// The password is not checked while throttled, so the guesses made during
+	// the lockout can't tell whether they are right. The email is throttled
+	// whether it is registered or not, so the answer doesn't tell it either
+	attempt, err := a.loginGuard.Begin(req.Email, client)
+	if err != nil {
+		return nil, err
+	}
+
 	customers := a.db.Customers()
 
 	customer := new(data.Customer)
@@ -57,33 +177,143 @@ func (a *Auth) Login(req *requests.Login) (*JWTWithEat, error) {
 	}
 
 	if !ok {
-		return nil, ErrorUserNotFound
+		if _, err = a.hasher.Verify(a.dummyPasswordHash, req.Password); err != nil {
+			return nil, fmt.Errorf("failed to verify password: %w", err)
+		}
+
+		if err = attempt.Fail(nil, client, loginFailureReasonUnknownEmail); err != nil {
+			return nil, fmt.Errorf("failed to record login failure: %w", err)
+		}
+
+		return nil, ErrorInvalidCredentials
+	}
+
+	ok, err = a.hasher.Verify(customer.PasswordHash, req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify password: %w", err)
+	}
+
+	if !ok {
+		if err = attempt.Fail(&customer.ID, client, loginFailureReasonInvalidPassword); err != nil {
+			return nil, fmt.Errorf("failed to record login failure: %w", err)
+		}
+
+		return nil, ErrorInvalidCredentials
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(customer.PasswordHash), []byte(req.Password)) != nil {
-		return nil, ErrorInvalidPassword
+	// The password is known only now, so it's the only time the hash can be upgraded
+	if a.hasher.NeedsRehash(customer.PasswordHash) {
+		if customer.PasswordHash, err = a.hasher.Hash(req.Password); err != nil {
+			return nil, fmt.Errorf("failed to rehash password: %w", err)
+		}
+
+		if err = customers.Update(customer); err != nil {
+			return nil, fmt.Errorf("failed to update password hash: %w", err)
+		}
 	}
 
-	token, err := a.newCustomerJWT(customer.ID)
+	twoFactorEnabled, err := a.twoFactor.IsEnabled(customer.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create JWT: %w", err)
+		return nil, fmt.Errorf("failed to check two-factor authentication: %w", err)
+	}
+
+	if twoFactorEnabled {
+		// The second factor is checked by another attempt
+		if err = attempt.Release(); err != nil {
+			return nil, fmt.Errorf("failed to release login attempt: %w", err)
+		}
+
+		challenge, err := a.newTwoFactorChallenge(customer.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create two-factor challenge: %w", err)
+		}
+
+		return &LoginResult{Challenge: challenge}, nil
+	}
+
+	tokens, err := a.startLoginSession(customer, client)
+	if err != nil {
+		return nil, err
 	}
 
-	return token, nil
+	return &LoginResult{Tokens: tokens}, nil
 }
 
-func (a *Auth) Register(req *requests.Register) (*JWTWithEat, error) {
-	customers := a.db.Customers()
+// CompleteLogin starts the session once the second factor code matches the
+// customer the challenge was issued to.
+func (a *Auth) CompleteLogin(challenge, code string, client ClientInfo) (*SessionTokens, error) {
+	customerID, err := a.verifyTwoFactorChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	customer := new(data.Customer)
+	ok, err := a.db.Customers().WhereID(customerID).Get(customer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+	if !ok {
+		return nil, ErrorInvalidTwoFactorChallenge
+	}
+
+	attempt, err := a.loginGuard.Begin(customer.Email, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.twoFactor.Verify(customerID, code); err != nil {
+		switch {
+		case errors.Is(err, ErrorTwoFactorNotEnrolled):
+			if err := attempt.Release(); err != nil {
+				return nil, fmt.Errorf("failed to release login attempt: %w", err)
+			}
+
+			return nil, ErrorInvalidTwoFactorChallenge
+		case errors.Is(err, ErrorInvalidTwoFactorCode):
+			if err := attempt.Fail(&customerID, client, loginFailureReasonInvalidTwoFactorCode); err != nil {
+				return nil, fmt.Errorf("failed to record login failure: %w", err)
+			}
+		}
+
+		return nil, err
+	}
+
+	return a.startLoginSession(customer, client)
+}
+
+// startLoginSession creates the session of the customer who has passed all
+// the login steps.
+func (a *Auth) startLoginSession(customer *data.Customer, client ClientInfo) (*SessionTokens, error) {
+	if err := a.loginGuard.RecordSuccess(customer, client); err != nil {
+		return nil, fmt.Errorf("failed to record login success: %w", err)
+	}
+
+	tokens, err := a.newSession(customer.ID, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (a *Auth) Register(req *requests.Register, client ClientInfo) (*SessionTokens, error) {
+	passwordHash, err := a.hasher.Hash(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	db := a.db.New()
+	customers := db.Customers()
 
 	customer := &data.Customer{
 		Email:        req.Email,
 		Username:     req.Username,
-		PasswordHash: req.PasswordHash,
+		PasswordHash: passwordHash,
 		FirstName:    &req.FirstName,
 		LastName:     &req.LastName,
 	}
 
-	if err := a.db.Transaction(func() error {
+	if err := db.Transaction(func() error {
 		isUnique, err := customers.IsUnique(req.Email, req.Username)
 		if err != nil {
 			return fmt.Errorf("failed to check uniqueness: %w", err)
@@ -102,19 +332,228 @@ func (a *Auth) Register(req *requests.Register) (*JWTWithEat, error) {
 		return nil, err
 	}
 
-	token, err := a.newCustomerJWT(customer.ID)
+	tokens, err := a.newSession(customer.ID, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Refresh exchanges the refresh token for a new pair of tokens. The replaced
+// refresh token is accepted for a short grace period only to reissue the access
+// token, after that its use means it has leaked, so the whole session is revoked.
+func (a *Auth) Refresh(refreshToken string) (*SessionTokens, error) {
+	hash := hashSecretToken(refreshToken)
+	now := time.Now().UTC()
+
+	var (
+		tokens *SessionTokens
+		reused bool
+	)
+
+	db := a.db.New()
+
+	err := db.IsolatedTransaction(sql.LevelRepeatableRead, func() error {
+		tokens, reused = nil, false
+		session := new(data.Session)
+
+		ok, err := db.Sessions().WhereRefreshTokenHash(hash).Get(session)
+		if err != nil {
+			return fmt.Errorf("failed to get session: %w", err)
+		}
+
+		if ok {
+			if !session.IsActive(now) {
+				return ErrorInvalidSession
+			}
+
+			refresh, err := a.newRefreshToken(now)
+			if err != nil {
+				return fmt.Errorf("failed to create refresh token: %w", err)
+			}
+
+			err = db.Sessions().Rotate(session.ID, hashSecretToken(refresh.Token), refresh.Expiration, now)
+			if err != nil {
+				return fmt.Errorf("failed to rotate refresh token: %w", err)
+			}
+
+			access, err := a.newCustomerJWT(session)
+			if err != nil {
+				return fmt.Errorf("failed to create JWT: %w", err)
+			}
+
+			tokens = &SessionTokens{Access: access, Refresh: refresh}
+			return nil
+		}
+
+		ok, err = db.Sessions().WherePreviousRefreshTokenHash(hash).Get(session)
+		if err != nil {
+			return fmt.Errorf("failed to get session: %w", err)
+		}
+
+		if !ok || !session.IsActive(now) {
+			return ErrorInvalidSession
+		}
+
+		if session.RotatedAt != nil && now.Sub(*session.RotatedAt) < a.refreshGracePeriod {
+			access, err := a.newCustomerJWT(session)
+			if err != nil {
+				return fmt.Errorf("failed to create JWT: %w", err)
+			}
+
+			tokens = &SessionTokens{Access: access}
+			return nil
+		}
+
+		// The revocation must be committed, so the error is returned after the transaction
+		if err = db.Sessions().Revoke(session.ID, now); err != nil {
+			return fmt.Errorf("failed to revoke session: %w", err)
+		}
+
+		reused = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reused {
+		return nil, ErrorRefreshTokenReused
+	}
+
+	return tokens, nil
+}
+
+// Logout revokes the session, so neither its access nor refresh tokens are accepted anymore.
+func (a *Auth) Logout(sessionID uuid.UUID) error {
+	if err := a.db.Sessions().Revoke(sessionID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+// LogoutEverywhere revokes all the sessions of the customer.
+func (a *Auth) LogoutEverywhere(customerID uuid.UUID) error {
+	if err := a.db.Sessions().RevokeAll(customerID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
+
+// GetSessions returns the active sessions of the customer, the most recently seen first.
+func (a *Auth) GetSessions(customerID uuid.UUID) ([]*data.Session, error) {
+	sessions, err := a.db.Sessions().
+		WhereCustomerID(customerID).
+		WhereActive(time.Now().UTC()).
+		OrderBy("last_seen_at DESC").
+		Select()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+func (a *Auth) newSession(customerID uuid.UUID, client ClientInfo) (*SessionTokens, error) {
+	now := time.Now().UTC()
+
+	refresh, err := a.newRefreshToken(now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	session := &data.Session{
+		CustomerID:       customerID,
+		RefreshTokenHash: hashSecretToken(refresh.Token),
+		UserAgent:        userAgent,
+		IP:               client.IP,
+		LastSeenAt:       now,
+		ExpiresAt:        refresh.Expiration,
+	}
+
+	if err = a.db.Sessions().Insert(session); err != nil {
+		return nil, fmt.Errorf("failed to insert session: %w", err)
+	}
+
+	access, err := a.newCustomerJWT(session)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create JWT: %w", err)
 	}
 
-	return token, nil
+	return &SessionTokens{
+		Access:  access,
+		Refresh: refresh,
+	}, nil
+}
+
+func (a *Auth) newRefreshToken(now time.Time) (*RefreshToken, error) {
+	secret, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return &RefreshToken{
+		Token:      secret,
+		Expiration: now.Add(a.refreshExpiry),
+	}, nil
 }
 
-func (a *Auth) newCustomerJWT(customerID uuid.UUID) (*JWTWithEat, error) {
+// JWKS returns the public keys the tokens are verified with.
+func (a *Auth) JWKS() jwk.Set {
+	return a.jwtVerifyingKeys
+}
+
+// signJWT signs the token with the active key, its kid is put into the header,
+// so the token can be verified after the key is retired.
+func (a *Auth) signJWT(token jwt.Token) ([]byte, error) {
+	signed, err := jwt.Sign(token, jwt.WithKey(a.jwtSigningAlgorithm, a.jwtSigningKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return signed, nil
+}
+
+func (a *Auth) newCustomerJWT(session *data.Session) (*JWTWithEat, error) {
 	eat := time.Now().Add(a.jwtExpiry)
 
+	token, err := jwt.NewBuilder().
+		Claim(customerIDJWTKey, session.CustomerID.String()).
+		Claim(sessionIDJWTKey, session.ID.String()).
+		Claim(jwt.JwtIDKey, uuid.NewString()).
+		Claim(jwt.ExpirationKey, eat.Unix()).
+		Claim(jwt.IssuedAtKey, time.Now().Unix()).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build token: %w", err)
+	}
+
+	signed, err := a.signJWT(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWTWithEat{
+		Token:      string(signed),
+		Expiration: eat,
+	}, nil
+}
+
+func (a *Auth) newTwoFactorChallenge(customerID uuid.UUID) (*JWTWithEat, error) {
+	eat := time.Now().Add(twoFactorChallengeExpiry)
+
 	token, err := jwt.NewBuilder().
 		Claim(customerIDJWTKey, customerID.String()).
+		Claim(purposeJWTKey, twoFactorPurpose).
+		Claim(jwt.JwtIDKey, uuid.NewString()).
 		Claim(jwt.ExpirationKey, eat.Unix()).
 		Claim(jwt.IssuedAtKey, time.Now().Unix()).
 		Build()
@@ -122,9 +561,9 @@ func (a *Auth) newCustomerJWT(customerID uuid.UUID) (*JWTWithEat, error) {
 		return nil, fmt.Errorf("failed to build token: %w", err)
 	}
 
-	signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256(), a.jwtSigningKey))
+	signed, err := a.signJWT(token)
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign token: %w", err)
+		return nil, err
 	}
 
 	return &JWTWithEat{
@@ -133,27 +572,79 @@ func (a *Auth) newCustomerJWT(customerID uuid.UUID) (*JWTWithEat, error) {
 	}, nil
 }
 
-func (a *Auth) VerifyJWT(token string) (uuid.UUID, error) {
+func (a *Auth) verifyTwoFactorChallenge(challenge string) (uuid.UUID, error) {
 	parsedToken, err := jwt.Parse(
-		[]byte(token),
+		[]byte(challenge),
 		jwt.WithValidate(true),
-		jwt.WithKey(jwa.ES256(), a.jwtVerifyingKey),
+		jwt.WithKeySet(a.jwtVerifyingKeys),
 		jwt.WithClock(jwt.ClockFunc(time.Now)),
-		jwt.WithMaxDelta(a.jwtExpiry, jwt.ExpirationKey, jwt.IssuedAtKey),
+		jwt.WithClaimValue(purposeJWTKey, twoFactorPurpose),
 	)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to parse/validate token: %w", err)
+		return uuid.Nil, fmt.Errorf("%w: %w", ErrorInvalidTwoFactorChallenge, err)
 	}
 
 	var customerIDRaw string
 	if err = parsedToken.Get(customerIDJWTKey, &customerIDRaw); err != nil {
-		return uuid.Nil, fmt.Errorf("failed to get uid: %w", err)
+		return uuid.Nil, fmt.Errorf("%w: failed to get uid: %w", ErrorInvalidTwoFactorChallenge, err)
 	}
 
 	customerID, err := uuid.Parse(customerIDRaw)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to parse uid: %w", err)
+		return uuid.Nil, fmt.Errorf("%w: failed to parse uid: %w", ErrorInvalidTwoFactorChallenge, err)
 	}
 
 	return customerID, nil
 }
+
+// VerifyJWT validates the access token and checks that its session is still active.
+func (a *Auth) VerifyJWT(token string) (*Claims, error) {
+	parsedToken, err := jwt.Parse(
+		[]byte(token),
+		jwt.WithValidate(true),
+		jwt.WithKeySet(a.jwtVerifyingKeys),
+		jwt.WithClock(jwt.ClockFunc(time.Now)),
+		jwt.WithMaxDelta(a.jwtExpiry, jwt.ExpirationKey, jwt.IssuedAtKey),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse/validate token: %w", err)
+	}
+
+	var customerIDRaw, sessionIDRaw string
+	if err = parsedToken.Get(customerIDJWTKey, &customerIDRaw); err != nil {
+		return nil, fmt.Errorf("failed to get uid: %w", err)
+	}
+
+	if err = parsedToken.Get(sessionIDJWTKey, &sessionIDRaw); err != nil {
+		return nil, fmt.Errorf("failed to get sid: %w", err)
+	}
+
+	claims := new(Claims)
+	if claims.CustomerID, err = uuid.Parse(customerIDRaw); err != nil {
+		return nil, fmt.Errorf("failed to parse uid: %w", err)
+	}
+
+	if claims.SessionID, err = uuid.Parse(sessionIDRaw); err != nil {
+		return nil, fmt.Errorf("failed to parse sid: %w", err)
+	}
+
+	session := new(data.Session)
+
+	ok, err := a.db.Sessions().WhereID(claims.SessionID).Get(session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if !ok || session.CustomerID != claims.CustomerID || !session.IsActive(now) {
+		return nil, ErrorInvalidSession
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err = a.db.Sessions().Touch(session.ID, now); err != nil {
+			return nil, fmt.Errorf("failed to touch session: %w", err)
+		}
+	}
+
+	return claims, nil
+}
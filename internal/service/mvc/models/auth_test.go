@@ -4,75 +4,107 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
-	"database/sql"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v3/jwa"
 	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
 	"github.com/lestrrat-go/jwx/v3/jwt"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/data/memory"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
 )
 
-// Example of a mocked MainQ. If you only care about JWT logic, a nil or no-op mock is fine.
-type mockDB struct{}
-
-func (m *mockDB) Customers() data.Customers                 { return nil }
-func (m *mockDB) Accounts() data.Accounts                   { return nil }
-func (m *mockDB) CustomersAccounts() data.CustomersAccounts { return nil }
-func (m *mockDB) Withdrawals() data.Withdrawals             { return nil }
-func (m *mockDB) Deposits() data.Deposits                   { return nil }
-func (m *mockDB) Transfers() data.Transfers                 { return nil }
-func (m *mockDB) Transaction(fn func() error) error         { return fn() }
-func (m *mockDB) IsolatedTransaction(_ sql.IsolationLevel, fn func() error) error {
-	return fn()
+func newTestAuth(t *testing.T, jwtExpiry time.Duration) (*Auth, data.MainQ) {
+	t.Helper()
+
+	privJWK := newTestJWTKey(t)
+
+	db := memory.NewMainQ()
+
+	hasher, err := NewPasswordHasher(PasswordAlgorithmArgon2id, 1024, 1, 1, bcrypt.MinCost)
+	require.NoError(t, err)
+
+	audit := NewAuditService(db)
+	loginGuard := NewLoginGuard(db, audit, LoginThrottlePolicy{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      20,
+		Window:             time.Hour,
+		LockoutDuration:    time.Hour,
+	})
+
+	keys := jwk.NewSet()
+	require.NoError(t, keys.AddKey(privJWK))
+
+	kid, _ := privJWK.KeyID()
+
+	auth, err := NewAuth(db, hasher, NewTwoFactor(db, audit), loginGuard, keys, kid, jwtExpiry, time.Hour)
+	require.NoError(t, err, "failed to instantiate Auth")
+
+	return auth, db
 }
 
-func TestNewCustomerJWT(t *testing.T) {
-	// Generate an ephemeral ECDSA key pair for testing
+// newTestJWTKey generates an ephemeral ES256 key identified by its thumbprint.
+func newTestJWTKey(t *testing.T) jwk.Key {
+	t.Helper()
+
 	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	require.NoError(t, err, "failed to generate test ECDSA key")
 
-	privJWK, err := jwk.Import(ecdsaKey)
+	key, err := jwk.Import(ecdsaKey)
 	require.NoError(t, err, "failed to parse private key into jwk")
 
-	// Instantiate the Auth model with a short expiry to test
-	auth, err := NewAuth(&mockDB{}, privJWK, 2*time.Minute)
-	require.NoError(t, err, "failed to instantiate Auth")
+	require.NoError(t, jwk.AssignKeyID(key))
+	require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.ES256()))
 
-	// Use any random UUID for the customer
-	custID := uuid.New()
+	return key
+}
 
-	token, err := auth.newCustomerJWT(custID)
-	require.NoError(t, err, "failed to create JWT")
+func newTestCustomer(t *testing.T, db data.MainQ) uuid.UUID {
+	t.Helper()
 
-	require.NotEmpty(t, token, "JWT should not be empty")
+	customer := &data.Customer{
+		Email:        uuid.NewString() + "@example.com",
+		Username:     uuid.NewString(),
+		PasswordHash: "hashed_password",
+	}
+	require.NoError(t, db.Customers().Insert(customer))
+
+	return customer.ID
 }
 
-func TestVerifyJWT(t *testing.T) {
-	// Generate an ephemeral ECDSA key pair for testing
-	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-	require.NoError(t, err, "failed to generate test ECDSA key")
+func TestNewCustomerJWT(t *testing.T) {
+	// Instantiate the Auth model with a short expiry to test
+	auth, db := newTestAuth(t, 2*time.Minute)
 
-	privJWK, err := jwk.Import(ecdsaKey)
-	require.NoError(t, err, "failed to parse private key into jwk")
+	tokens, err := auth.newSession(newTestCustomer(t, db), ClientInfo{UserAgent: "test", IP: "127.0.0.1"})
+	require.NoError(t, err, "failed to create session")
+
+	require.NotEmpty(t, tokens.Access.Token, "JWT should not be empty")
+	require.NotEmpty(t, tokens.Refresh.Token, "refresh token should not be empty")
+}
 
+func TestVerifyJWT(t *testing.T) {
 	// Set a short expiry so we can test expiry scenarios too
-	auth, err := NewAuth(&mockDB{}, privJWK, 2*time.Second)
-	require.NoError(t, err, "failed to instantiate Auth")
+	auth, db := newTestAuth(t, 2*time.Second)
 
 	// Generate a valid token
-	custID := uuid.New()
-	validToken, err := auth.newCustomerJWT(custID)
+	custID := newTestCustomer(t, db)
+	validToken, err := auth.newSession(custID, ClientInfo{})
 	require.NoError(t, err, "failed to create valid JWT")
 
 	t.Run("valid token", func(t *testing.T) {
-		parsedID, err := auth.VerifyJWT(validToken.Token)
+		claims, err := auth.VerifyJWT(validToken.Access.Token)
 		require.NoError(t, err, "valid token should verify without error")
-		require.Equal(t, custID, parsedID, "parsed ID should match the original customer ID")
+		require.Equal(t, custID, claims.CustomerID, "parsed ID should match the original customer ID")
 	})
 
 	t.Run("malformed token", func(t *testing.T) {
@@ -81,8 +113,16 @@ func TestVerifyJWT(t *testing.T) {
 	})
 
 	t.Run("signature changed / tampered token", func(t *testing.T) {
-		// Just remove the last character from the token or otherwise alter it
-		tampered := validToken.Token[:len(validToken.Token)-1] + "x"
+		// Alter the first character of the signature, the last one may only
+		// carry the padding bits, which are ignored by the decoder
+		token := validToken.Access.Token
+		i := strings.LastIndex(token, ".") + 1
+
+		replacement := "A"
+		if token[i] == 'A' {
+			replacement = "B"
+		}
+		tampered := token[:i] + replacement + token[i+1:]
 		_, err := auth.VerifyJWT(tampered)
 		require.Error(t, err, "should fail with a tampered signature")
 	})
@@ -90,8 +130,169 @@ func TestVerifyJWT(t *testing.T) {
 	t.Run("expired token", func(t *testing.T) {
 		// Wait for the token to expire
 		time.Sleep(3 * time.Second)
-		_, err := auth.VerifyJWT(validToken.Token)
+		_, err := auth.VerifyJWT(validToken.Access.Token)
 		require.Error(t, err, "should fail when token is expired")
 		require.True(t, errors.Is(err, jwt.TokenExpiredError()), "expired token error expected")
 	})
 }
+
+func TestLogout(t *testing.T) {
+	auth, db := newTestAuth(t, time.Minute)
+	custID := newTestCustomer(t, db)
+
+	first, err := auth.newSession(custID, ClientInfo{})
+	require.NoError(t, err)
+	second, err := auth.newSession(custID, ClientInfo{})
+	require.NoError(t, err)
+
+	claims, err := auth.VerifyJWT(first.Access.Token)
+	require.NoError(t, err)
+
+	require.NoError(t, auth.Logout(claims.SessionID))
+
+	_, err = auth.VerifyJWT(first.Access.Token)
+	require.ErrorIs(t, err, ErrorInvalidSession, "unexpired token of a revoked session must be rejected")
+
+	_, err = auth.Refresh(first.Refresh.Token)
+	require.ErrorIs(t, err, ErrorInvalidSession)
+
+	_, err = auth.VerifyJWT(second.Access.Token)
+	require.NoError(t, err, "other sessions must stay active")
+
+	require.NoError(t, auth.LogoutEverywhere(custID))
+
+	_, err = auth.VerifyJWT(second.Access.Token)
+	require.ErrorIs(t, err, ErrorInvalidSession)
+
+	sessions, err := auth.GetSessions(custID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestRefresh(t *testing.T) {
+	auth, db := newTestAuth(t, time.Minute)
+	custID := newTestCustomer(t, db)
+
+	initial, err := auth.newSession(custID, ClientInfo{})
+	require.NoError(t, err)
+
+	rotated, err := auth.Refresh(initial.Refresh.Token)
+	require.NoError(t, err)
+	require.NotNil(t, rotated.Refresh)
+	assert.NotEqual(t, initial.Refresh.Token, rotated.Refresh.Token, "refresh token must be rotated")
+
+	claims, err := auth.VerifyJWT(rotated.Access.Token)
+	require.NoError(t, err)
+	assert.Equal(t, custID, claims.CustomerID)
+
+	t.Run("replaced token within grace period", func(t *testing.T) {
+		tokens, err := auth.Refresh(initial.Refresh.Token)
+		require.NoError(t, err)
+		assert.Nil(t, tokens.Refresh, "replaced token must not be rotated again")
+		assert.NotEmpty(t, tokens.Access.Token)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		_, err := auth.Refresh("unknown")
+		require.ErrorIs(t, err, ErrorInvalidSession)
+	})
+
+	t.Run("replaced token reuse revokes session", func(t *testing.T) {
+		auth.refreshGracePeriod = 0
+
+		_, err := auth.Refresh(initial.Refresh.Token)
+		require.ErrorIs(t, err, ErrorRefreshTokenReused)
+
+		_, err = auth.VerifyJWT(rotated.Access.Token)
+		require.ErrorIs(t, err, ErrorInvalidSession)
+
+		_, err = auth.Refresh(rotated.Refresh.Token)
+		require.ErrorIs(t, err, ErrorInvalidSession)
+	})
+}
+
+func TestLoginRehashesPassword(t *testing.T) {
+	auth, db := newTestAuth(t, time.Minute)
+
+	// Hash created by the browser before the hashing moved to the server
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte(prehashPassword("Correct-Horse-42")), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	customer := &data.Customer{
+		Email:        "legacy@example.com",
+		Username:     "legacy",
+		PasswordHash: string(legacyHash),
+	}
+	require.NoError(t, db.Customers().Insert(customer))
+
+	_, err = auth.Login(&requests.Login{Email: customer.Email, Password: "wrong-password"}, ClientInfo{})
+	require.ErrorIs(t, err, ErrorInvalidCredentials)
+
+	result, err := auth.Login(&requests.Login{Email: customer.Email, Password: "Correct-Horse-42"}, ClientInfo{})
+	require.NoError(t, err)
+	require.NotNil(t, result.Tokens)
+	require.Nil(t, result.Challenge, "customer without the second factor must not be challenged")
+
+	ok, err := db.Customers().WhereID(customer.ID).Get(customer)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, strings.HasPrefix(customer.PasswordHash, "$argon2id$"), "legacy hash must be upgraded")
+
+	_, err = auth.Login(&requests.Login{Email: customer.Email, Password: "Correct-Horse-42"}, ClientInfo{})
+	require.NoError(t, err, "upgraded hash must still match the password")
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	oldAuth, db := newTestAuth(t, time.Minute)
+	custID := newTestCustomer(t, db)
+
+	oldKID, _ := oldAuth.jwtSigningKey.KeyID()
+	newKey := newTestJWTKey(t)
+	newKID, _ := newKey.KeyID()
+
+	oldTokens, err := oldAuth.newSession(custID, ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, oldKID, tokenKeyID(t, oldTokens.Access.Token))
+
+	// The new key is promoted, while the old one is kept for verification
+	keys := jwk.NewSet()
+	require.NoError(t, keys.AddKey(oldAuth.jwtSigningKey))
+	require.NoError(t, keys.AddKey(newKey))
+
+	newAuth, err := NewAuth(db, oldAuth.hasher, oldAuth.twoFactor, oldAuth.loginGuard, keys, newKID, time.Minute, time.Hour)
+	require.NoError(t, err)
+
+	_, err = newAuth.VerifyJWT(oldTokens.Access.Token)
+	require.NoError(t, err, "token signed with the retired key must still be valid")
+
+	newTokens, err := newAuth.newSession(custID, ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, newKID, tokenKeyID(t, newTokens.Access.Token))
+
+	_, err = oldAuth.VerifyJWT(newTokens.Access.Token)
+	require.Error(t, err, "token signed with an unknown key must be rejected")
+
+	jwks := newAuth.JWKS()
+	require.Equal(t, 2, jwks.Len())
+	for i := 0; i < jwks.Len(); i++ {
+		key, _ := jwks.Key(i)
+		_, isPrivate := key.(jwk.ECDSAPrivateKey)
+		assert.False(t, isPrivate, "JWKS must contain only public keys")
+	}
+
+	_, err = NewAuth(db, oldAuth.hasher, oldAuth.twoFactor, oldAuth.loginGuard, keys, "unknown", time.Minute, time.Hour)
+	require.Error(t, err)
+}
+
+func tokenKeyID(t *testing.T, token string) string {
+	t.Helper()
+
+	msg, err := jws.Parse([]byte(token))
+	require.NoError(t, err)
+	require.Len(t, msg.Signatures(), 1)
+
+	kid, ok := msg.Signatures()[0].ProtectedHeaders().KeyID()
+	require.True(t, ok, "token must have the kid header")
+
+	return kid
+}
@@ -0,0 +1,379 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+)
+
+// maxCodeAttempts limits the attempts to generate a code not colliding with
+// the pending ones.
+const maxCodeAttempts = 5
+
+var ErrorCashOutCodeNotFound = errors.New("cash-out code not found")
+var ErrorCashOutCodeExpired = errors.New("cash-out code expired")
+var ErrorCashOutAmountMismatch = errors.New("cash-out amount mismatch")
+
+// CashOut implements the cardless withdrawals: the customer gets a one-time
+// code holding the amount on the account and enters it at an ATM, which pays
+// the amount out and confirms it with its signature. The holds of the codes
+// not used in time are released.
+type CashOut struct {
+	db           data.MainQ
+	atms         *ATMs
+	auditService *AuditService
+	codeLifetime time.Duration
+}
+
+func NewCashOut(db data.MainQ, auditService *AuditService, atms *ATMs, codeLifetime time.Duration) *CashOut {
+	return &CashOut{
+		db:           db,
+		atms:         atms,
+		auditService: auditService,
+		codeLifetime: codeLifetime,
+	}
+}
+
+// IssueCode holds the amount on the account and returns the code with its
+// plain value, which is only shown to the customer once.
+func (m *CashOut) IssueCode(customerID uuid.UUID, req *requests.Withdrawal) (*data.CashOutCode, string, error) {
+	ok, err := m.db.CustomersAccounts().HasAccount(customerID, req.AccountID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to check account existence: %w", err)
+	}
+	if !ok {
+		return nil, "", ErrorAccountNotFound
+	}
+
+	plain, codeHash, err := m.newCode()
+	if err != nil {
+		return nil, "", err
+	}
+
+	code := &data.CashOutCode{
+		CustomerID: customerID,
+		AccountID:  req.AccountID,
+		Amount:     req.Amount,
+		CodeHash:   codeHash,
+		Status:     data.CashOutCodePending,
+		ExpiresAt:  time.Now().UTC().Add(m.codeLifetime),
+	}
+
+	db := m.db.New()
+	ledger := NewLedger(db)
+
+	err = db.IsolatedTransaction(sql.LevelRepeatableRead, func() error {
+		accounts, err := lockAccounts(db, req.AccountID)
+		if err != nil {
+			return err
+		}
+
+		account, ok := accounts[req.AccountID]
+		if !ok {
+			return ErrorAccountNotFound
+		}
+
+		balance, err := ledger.balance(account.ID)
+		if err != nil {
+			return err
+		}
+
+		if balance < int(req.Amount) {
+			return ErrorInsufficientFunds
+		}
+
+		if err = db.CashOutCodes().Insert(code); err != nil {
+			return fmt.Errorf("failed to insert cash-out code: %w", err)
+		}
+
+		if err = ledger.hold(code); err != nil {
+			return err
+		}
+
+		account.Balance = balance - int(req.Amount)
+
+		if err = db.Accounts().Update(account); err != nil {
+			return fmt.Errorf("failed to update account balance: %w", err)
+		}
+
+		if err = m.auditService.withDB(db).logCashOutCodeIssued(customerID, code); err != nil {
+			return fmt.Errorf("failed to log cash-out code: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return code, plain, nil
+}
+
+// newCode generates a random code, which is not used by any pending code, and
+// returns it with its hash. The codes are short lived and useless without the
+// ATM signature, so they are hashed like the access tokens.
+func (m *CashOut) newCode() (string, string, error) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(atm.CashOutCodeDigits), nil)
+
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate cash-out code: %w", err)
+		}
+
+		plain := fmt.Sprintf("%0*d", atm.CashOutCodeDigits, n)
+		codeHash := hashSecretToken(plain)
+
+		taken, err := m.db.CashOutCodes().WhereCodeHash(codeHash).WhereStatus(data.CashOutCodePending).Count()
+		if err != nil {
+			return "", "", fmt.Errorf("failed to check cash-out code: %w", err)
+		}
+
+		if taken == 0 {
+			return plain, codeHash, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("failed to generate unique cash-out code in %d attempts", maxCodeAttempts)
+}
+
+// CancelCode releases the hold of the pending code of the customer account.
+func (m *CashOut) CancelCode(customerID, codeID uuid.UUID) error {
+	code := new(data.CashOutCode)
+
+	ok, err := m.db.CashOutCodes().WhereID(codeID).Get(code)
+	if err != nil {
+		return fmt.Errorf("failed to get cash-out code: %w", err)
+	}
+	if !ok {
+		return ErrorCashOutCodeNotFound
+	}
+
+	ok, err = m.db.CustomersAccounts().HasAccount(customerID, code.AccountID)
+	if err != nil {
+		return fmt.Errorf("failed to check account existence: %w", err)
+	}
+	if !ok {
+		return ErrorCashOutCodeNotFound
+	}
+
+	db := m.db.New()
+
+	return db.IsolatedTransaction(sql.LevelRepeatableRead, func() error {
+		code, err := m.release(db, codeID, data.CashOutCodeCancelled)
+		if err != nil {
+			return err
+		}
+
+		if err = m.auditService.withDB(db).logCashOutCodeCancelled(customerID, code); err != nil {
+			return fmt.Errorf("failed to log cash-out code: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ExpireCodes releases the holds of the pending codes expired at the given
+// time and returns the number of the expired codes.
+func (m *CashOut) ExpireCodes(now time.Time) (int, error) {
+	codes, err := m.db.CashOutCodes().
+		WhereStatus(data.CashOutCodePending).
+		WhereExpiredAt(now.UTC()).
+		Select()
+	if err != nil {
+		return 0, fmt.Errorf("failed to select expired cash-out codes: %w", err)
+	}
+
+	expired := 0
+	for _, code := range codes {
+		db := m.db.New()
+
+		err = db.IsolatedTransaction(sql.LevelRepeatableRead, func() error {
+			code, err := m.release(db, code.ID, data.CashOutCodeExpired)
+			if err != nil {
+				return err
+			}
+
+			if err = m.auditService.withDB(db).logCashOutCodeExpired(code); err != nil {
+				return fmt.Errorf("failed to log cash-out code: %w", err)
+			}
+
+			return nil
+		})
+		switch {
+		case err == nil:
+			expired++
+		case errors.Is(err, ErrorCashOutCodeNotFound):
+			// completed or cancelled in the meantime
+		default:
+			return expired, fmt.Errorf("failed to expire cash-out code %s: %w", code.ID, err)
+		}
+	}
+
+	return expired, nil
+}
+
+// release returns the amount of the pending code to the account and moves the
+// code to the given status, it must be called within a transaction.
+func (m *CashOut) release(db data.MainQ, codeID uuid.UUID, status data.CashOutCodeStatus) (*data.CashOutCode, error) {
+	code, account, err := lockPendingCode(db, db.CashOutCodes().WhereID(codeID))
+	if err != nil {
+		return nil, err
+	}
+
+	ledger := NewLedger(db)
+
+	if err = ledger.releaseHold(code); err != nil {
+		return nil, err
+	}
+
+	if account.Balance, err = ledger.balance(account.ID); err != nil {
+		return nil, err
+	}
+
+	if err = db.Accounts().Update(account); err != nil {
+		return nil, fmt.Errorf("failed to update account balance: %w", err)
+	}
+
+	code.Status = status
+	if err = db.CashOutCodes().Update(code); err != nil {
+		return nil, fmt.Errorf("failed to update cash-out code: %w", err)
+	}
+
+	return code, nil
+}
+
+// Withdraw pays out the pending code entered at the ATM: the held amount is
+// recorded as the withdrawal made by the ATM and the code is completed.
+func (m *CashOut) Withdraw(req *requests.ATMWithdrawal) (*data.Transaction, error) {
+	if err := m.verifySignature(req); err != nil {
+		return nil, err
+	}
+
+	db := m.db.New()
+
+	var transaction *data.Transaction
+	err := db.IsolatedTransaction(sql.LevelRepeatableRead, func() error {
+		code, _, err := lockPendingCode(db, db.CashOutCodes().WhereCodeHash(hashSecretToken(req.Code)))
+		if err != nil {
+			return err
+		}
+
+		if !code.IsUsableAt(time.Now().UTC()) {
+			return ErrorCashOutCodeExpired
+		}
+
+		if code.Amount != req.Amount {
+			return ErrorCashOutAmountMismatch
+		}
+
+		transaction = &data.Transaction{
+			Type:         data.WithdrawalTransaction,
+			Amount:       code.Amount,
+			Sender:       code.AccountID,
+			ATMSignature: req.ATMSignature,
+			ATMID:        &req.ATMID,
+			ATMNonce:     req.Nonce,
+		}
+
+		if err = db.Transactions().Insert(transaction); err != nil {
+			if isATMNotUniqueError(err) {
+				return ErrorATMNonceReused
+			}
+
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+
+		if err = NewLedger(db).record(transaction); err != nil {
+			return err
+		}
+
+		if _, err = moveATMCash(db, req.ATMID, -int(code.Amount)); err != nil {
+			return err
+		}
+
+		code.Status = data.CashOutCodeCompleted
+		code.ATMID = &req.ATMID
+		code.TransactionID = &transaction.ID
+
+		if err = db.CashOutCodes().Update(code); err != nil {
+			return fmt.Errorf("failed to update cash-out code: %w", err)
+		}
+
+		err = m.auditService.withDB(db).logWithdrawalMade(code.CustomerID, code.AccountID, req.ATMID, code.Amount)
+		if err != nil {
+			return fmt.Errorf("failed to log withdrawal: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// lockPendingCode locks the account of the pending code selected by q and then
+// the code itself, in the same order as the other account changes, and checks
+// the code is still pending after the locks are acquired.
+func lockPendingCode(db data.MainQ, q data.CashOutCodes) (*data.CashOutCode, *data.Account, error) {
+	code := new(data.CashOutCode)
+
+	ok, err := q.WhereStatus(data.CashOutCodePending).Get(code)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get cash-out code: %w", err)
+	}
+	if !ok {
+		return nil, nil, ErrorCashOutCodeNotFound
+	}
+
+	accounts, err := lockAccounts(db, code.AccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	account, ok := accounts[code.AccountID]
+	if !ok {
+		return nil, nil, ErrorAccountNotFound
+	}
+
+	ok, err = db.CashOutCodes().WhereID(code.ID).ForUpdate().Get(code)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock cash-out code: %w", err)
+	}
+	if !ok || !code.IsPending() {
+		return nil, nil, ErrorCashOutCodeNotFound
+	}
+
+	return code, account, nil
+}
+
+// verifySignature verifies the withdrawal payload is valid now and signed by
+// one of the valid keys of the active ATM.
+func (m *CashOut) verifySignature(req *requests.ATMWithdrawal) error {
+	payload := req.Payload()
+
+	if err := payload.Validate(time.Now()); err != nil {
+		return fmt.Errorf("%w: %v", ErrorInvalidATMPayload, err)
+	}
+
+	err := m.atms.VerifySignature(req.ATMID, payload.Canonical(), req.ATMSignature)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrorATMNotFound), errors.Is(err, ErrorATMDisabled), errors.Is(err, ErrorInvalidATMSignature):
+		return err
+	}
+
+	return fmt.Errorf("failed to verify ATM signature: %w", err)
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+)
+
+func (e *testEnv) balances(t *testing.T, accountID uuid.UUID) (int, int) {
+	t.Helper()
+
+	account := new(data.Account)
+	ok, err := e.db.Accounts().WhereID(accountID).Get(account)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	holds, err := e.db.Ledger().SystemAccountBalance(data.SystemAccountWithdrawalHolds)
+	require.NoError(t, err)
+
+	return account.Balance, -holds
+}
+
+func TestCashOutWithdraw(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+	env.deposit(t, customerID, accountID, 1000)
+
+	_, _, err := env.cashOut.IssueCode(customerID, &requests.Withdrawal{AccountID: accountID, Amount: 1001})
+	require.ErrorIs(t, err, ErrorInsufficientFunds)
+
+	_, _, err = env.cashOut.IssueCode(env.newCustomer(t), &requests.Withdrawal{AccountID: accountID, Amount: 100})
+	require.ErrorIs(t, err, ErrorAccountNotFound)
+
+	code, plain, err := env.cashOut.IssueCode(customerID, &requests.Withdrawal{AccountID: accountID, Amount: 400})
+	require.NoError(t, err)
+	assert.Len(t, plain, 8)
+	assert.NotEqual(t, plain, code.CodeHash, "only the hash of the code must be stored")
+
+	balance, held := env.balances(t, accountID)
+	assert.Equal(t, 600, balance, "the amount must be held")
+	assert.Equal(t, 400, held)
+
+	_, _, err = env.cashOut.IssueCode(customerID, &requests.Withdrawal{AccountID: accountID, Amount: 601})
+	require.ErrorIs(t, err, ErrorInsufficientFunds, "the held amount can't be spent")
+
+	t.Run("amount mismatch", func(t *testing.T) {
+		_, err := env.cashOut.Withdraw(env.withdrawalRequest(t, plain, 500))
+		require.ErrorIs(t, err, ErrorCashOutAmountMismatch)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		req := env.withdrawalRequest(t, plain, 400)
+		req.Code = "00000000"
+
+		_, err := env.cashOut.Withdraw(req)
+		require.ErrorIs(t, err, ErrorInvalidATMSignature)
+	})
+
+	t.Run("unknown code", func(t *testing.T) {
+		unknown := "00000000"
+		if plain == unknown {
+			unknown = "11111111"
+		}
+
+		_, err := env.cashOut.Withdraw(env.withdrawalRequest(t, unknown, 400))
+		require.ErrorIs(t, err, ErrorCashOutCodeNotFound)
+	})
+
+	req := env.withdrawalRequest(t, plain, 400)
+
+	transaction, err := env.cashOut.Withdraw(req)
+	require.NoError(t, err)
+	assert.Equal(t, data.WithdrawalTransaction, transaction.Type)
+	assert.Equal(t, accountID, transaction.Sender)
+	require.NotNil(t, transaction.ATMID)
+	assert.Equal(t, env.atmID, *transaction.ATMID)
+
+	balance, held = env.balances(t, accountID)
+	assert.Equal(t, 600, balance)
+	assert.Zero(t, held, "the hold must be paid out")
+
+	atmCash, err := env.db.Ledger().SystemAccountBalance(data.SystemAccountATMCash)
+	require.NoError(t, err)
+	assert.Equal(t, 600, atmCash)
+
+	_, err = env.cashOut.Withdraw(env.withdrawalRequest(t, plain, 400))
+	require.ErrorIs(t, err, ErrorCashOutCodeNotFound, "the code must be used once")
+
+	logs, err := env.db.AuditLogs().WhereCustomerID(customerID).WhereAction(data.AuditActionWithdrawalMade).Select()
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.JSONEq(t, `{"amount": 400, "atm_id": "`+env.atmID.String()+`"}`, string(logs[0].Details))
+
+	t.Run("replayed nonce", func(t *testing.T) {
+		_, plain, err := env.cashOut.IssueCode(customerID, &requests.Withdrawal{AccountID: accountID, Amount: 100})
+		require.NoError(t, err)
+
+		payload, err := atm.NewWithdrawalPayload(env.atmID, plain, 100, time.Minute)
+		require.NoError(t, err)
+		payload.Nonce = req.Nonce
+
+		_, err = env.cashOut.Withdraw(env.signWithdrawal(t, payload))
+		require.ErrorIs(t, err, ErrorATMNonceReused)
+	})
+
+	mismatches, err := NewLedger(env.db).VerifyBalances()
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestCashOutCodeExpiry(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+	env.deposit(t, customerID, accountID, 1000)
+
+	_, plain, err := env.cashOut.IssueCode(customerID, &requests.Withdrawal{AccountID: accountID, Amount: 300})
+	require.NoError(t, err)
+
+	codes, err := env.accounts.GetPendingCashOutCodes(customerID, accountID)
+	require.NoError(t, err)
+	require.Len(t, codes, 1)
+
+	expired, err := env.cashOut.ExpireCodes(time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, expired, "the code is not expired yet")
+
+	expired, err = env.cashOut.ExpireCodes(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+
+	balance, held := env.balances(t, accountID)
+	assert.Equal(t, 1000, balance, "the hold must be released")
+	assert.Zero(t, held)
+
+	_, err = env.cashOut.Withdraw(env.withdrawalRequest(t, plain, 300))
+	require.ErrorIs(t, err, ErrorCashOutCodeNotFound)
+
+	codes, err = env.accounts.GetPendingCashOutCodes(customerID, accountID)
+	require.NoError(t, err)
+	assert.Empty(t, codes)
+
+	count, err := env.db.AuditLogs().WhereCustomerID(customerID).WhereAction(data.AuditActionCashOutCodeExpired).Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+
+	expired, err = env.cashOut.ExpireCodes(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Zero(t, expired, "the hold must be released once")
+}
+
+func TestCancelCashOutCode(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+	env.deposit(t, customerID, accountID, 500)
+
+	code, plain, err := env.cashOut.IssueCode(customerID, &requests.Withdrawal{AccountID: accountID, Amount: 500})
+	require.NoError(t, err)
+
+	require.ErrorIs(t, env.accounts.DeleteAccount(customerID, accountID), ErrorPendingCashOutCodes,
+		"the account with the held amount can't be deleted")
+
+	require.ErrorIs(t, env.cashOut.CancelCode(env.newCustomer(t), code.ID), ErrorCashOutCodeNotFound)
+	require.NoError(t, env.cashOut.CancelCode(customerID, code.ID))
+	require.ErrorIs(t, env.cashOut.CancelCode(customerID, code.ID), ErrorCashOutCodeNotFound)
+
+	balance, held := env.balances(t, accountID)
+	assert.Equal(t, 500, balance)
+	assert.Zero(t, held)
+
+	_, err = env.cashOut.Withdraw(env.withdrawalRequest(t, plain, 500))
+	require.ErrorIs(t, err, ErrorCashOutCodeNotFound)
+}
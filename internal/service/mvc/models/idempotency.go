@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+var ErrorIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+var ErrorIdempotencyKeyInProgress = errors.New("request with the same idempotency key is in progress")
+
+type Idempotency struct {
+	db data.MainQ
+}
+
+func NewIdempotency(db data.MainQ) *Idempotency {
+	return &Idempotency{
+		db: db,
+	}
+}
+
+// Begin reserves the key for the request identified by requestHash. When the key
+// was already used for the same request, the stored record is returned, and the
+// caller must replay its response if the record is completed.
+func (m *Idempotency) Begin(customerID uuid.UUID, key, requestHash string) (*data.IdempotencyKey, error) {
+	record := &data.IdempotencyKey{
+		CustomerID:  customerID,
+		Key:         key,
+		RequestHash: requestHash,
+	}
+
+	reserved, err := m.db.IdempotencyKeys().Reserve(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if reserved {
+		return record, nil
+	}
+
+	ok, err := m.db.IdempotencyKeys().WhereCustomerID(customerID).WhereKey(key).Get(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	if !ok {
+		// the request holding the key has just failed and released it
+		return nil, ErrorIdempotencyKeyInProgress
+	}
+
+	if record.RequestHash != requestHash {
+		return nil, ErrorIdempotencyKeyReused
+	}
+
+	if !record.IsCompleted() {
+		return nil, ErrorIdempotencyKeyInProgress
+	}
+
+	return record, nil
+}
+
+// Complete stores the response rendered for the request holding the key.
+func (m *Idempotency) Complete(record *data.IdempotencyKey, statusCode int, contentType string, body []byte) error {
+	record.StatusCode = &statusCode
+	record.ContentType = &contentType
+	record.ResponseBody = body
+
+	if err := m.db.IdempotencyKeys().Update(record); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+
+	return nil
+}
+
+// Release frees the key of a request that failed before its outcome was known,
+// so the client can retry it.
+func (m *Idempotency) Release(record *data.IdempotencyKey) error {
+	if err := m.db.IdempotencyKeys().Delete(record.ID); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
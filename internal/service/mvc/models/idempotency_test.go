@@ -0,0 +1,50 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	idempotency := NewIdempotency(env.db)
+
+	record, err := idempotency.Begin(customerID, "key", "hash")
+	require.NoError(t, err)
+	require.False(t, record.IsCompleted())
+
+	_, err = idempotency.Begin(customerID, "key", "hash")
+	require.ErrorIs(t, err, ErrorIdempotencyKeyInProgress)
+
+	require.NoError(t, idempotency.Complete(record, http.StatusOK, "application/json", []byte(`{"new_balance":100}`)))
+
+	replayed, err := idempotency.Begin(customerID, "key", "hash")
+	require.NoError(t, err)
+	require.True(t, replayed.IsCompleted())
+	assert.Equal(t, http.StatusOK, *replayed.StatusCode)
+	assert.Equal(t, "application/json", *replayed.ContentType)
+	assert.Equal(t, []byte(`{"new_balance":100}`), replayed.ResponseBody)
+
+	_, err = idempotency.Begin(customerID, "key", "another hash")
+	require.ErrorIs(t, err, ErrorIdempotencyKeyReused)
+
+	t.Run("keys are scoped per customer", func(t *testing.T) {
+		record, err := idempotency.Begin(env.newCustomer(t), "key", "another hash")
+		require.NoError(t, err)
+		assert.False(t, record.IsCompleted())
+	})
+
+	t.Run("released key can be used again", func(t *testing.T) {
+		record, err := idempotency.Begin(customerID, "released", "hash")
+		require.NoError(t, err)
+		require.NoError(t, idempotency.Release(record))
+
+		record, err = idempotency.Begin(customerID, "released", "another hash")
+		require.NoError(t, err)
+		assert.False(t, record.IsCompleted())
+	})
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+// BalanceMismatch describes an account whose stored balance differs from the
+// balance derived from its ledger postings.
+type BalanceMismatch struct {
+	AccountID uuid.UUID
+	Stored    int
+	Derived   int
+}
+
+type Ledger struct {
+	db data.MainQ
+}
+
+func NewLedger(db data.MainQ) *Ledger {
+	return &Ledger{
+		db: db,
+	}
+}
+
+// record posts the balanced journal entry for the transaction: customer accounts
+// are debited when money leaves them and credited when it comes in, the ATM
+// cash clearing account is the counterparty of deposits and withdrawals. The
+// withdrawals are paid out of the hold placed by the cash-out code, see hold.
+func (m *Ledger) record(transaction *data.Transaction) error {
+	atmCash := data.SystemAccountATMCash
+	holds := data.SystemAccountWithdrawalHolds
+
+	entry := &data.JournalEntry{
+		TransactionID: &transaction.ID,
+	}
+
+	var postings []*data.Posting
+
+	switch transaction.Type {
+	case data.DepositTransaction:
+		entry.Description = "deposit"
+		postings = []*data.Posting{
+			{SystemAccount: &atmCash, Side: data.Debit, Amount: transaction.Amount},
+			{AccountID: &transaction.Recipient, Side: data.Credit, Amount: transaction.Amount},
+		}
+	case data.WithdrawalTransaction:
+		entry.Description = "withdrawal"
+		postings = []*data.Posting{
+			{SystemAccount: &holds, Side: data.Debit, Amount: transaction.Amount},
+			{SystemAccount: &atmCash, Side: data.Credit, Amount: transaction.Amount},
+		}
+	case data.TransferTransaction:
+		entry.Description = "transfer"
+		postings = []*data.Posting{
+			{AccountID: &transaction.Sender, Side: data.Debit, Amount: transaction.Amount},
+			{AccountID: &transaction.Recipient, Side: data.Credit, Amount: transaction.Amount},
+		}
+	default:
+		return fmt.Errorf("unknown transaction type %d", transaction.Type)
+	}
+
+	if err := m.db.Ledger().Post(entry, postings...); err != nil {
+		return fmt.Errorf("failed to post journal entry: %w", err)
+	}
+
+	return nil
+}
+
+// hold moves the amount of the cash-out code from the customer account to the
+// withdrawal holds, so it can't be spent until the hold is paid out or released.
+func (m *Ledger) hold(code *data.CashOutCode) error {
+	holds := data.SystemAccountWithdrawalHolds
+
+	entry := &data.JournalEntry{
+		Description: "withdrawal hold",
+	}
+
+	err := m.db.Ledger().Post(entry,
+		&data.Posting{AccountID: &code.AccountID, Side: data.Debit, Amount: code.Amount},
+		&data.Posting{SystemAccount: &holds, Side: data.Credit, Amount: code.Amount},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to post journal entry: %w", err)
+	}
+
+	return nil
+}
+
+// releaseHold returns the amount of the unused cash-out code to the customer account.
+func (m *Ledger) releaseHold(code *data.CashOutCode) error {
+	holds := data.SystemAccountWithdrawalHolds
+
+	entry := &data.JournalEntry{
+		Description: "withdrawal hold release",
+	}
+
+	err := m.db.Ledger().Post(entry,
+		&data.Posting{SystemAccount: &holds, Side: data.Debit, Amount: code.Amount},
+		&data.Posting{AccountID: &code.AccountID, Side: data.Credit, Amount: code.Amount},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to post journal entry: %w", err)
+	}
+
+	return nil
+}
+
+// balance returns the account balance derived from the postings.
+func (m *Ledger) balance(accountID uuid.UUID) (int, error) {
+	balance, err := m.db.Ledger().AccountBalance(accountID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get account balance: %w", err)
+	}
+
+	return balance, nil
+}
+
+// VerifyBalances compares the stored balance of every account with the balance
+// derived from the ledger and returns the accounts where they differ.
+func (m *Ledger) VerifyBalances() ([]BalanceMismatch, error) {
+	accounts, err := m.db.Accounts().Select()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts: %w", err)
+	}
+
+	var mismatches []BalanceMismatch
+	for _, account := range accounts {
+		derived, err := m.balance(account.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if derived != account.Balance {
+			mismatches = append(mismatches, BalanceMismatch{
+				AccountID: account.ID,
+				Stored:    account.Balance,
+				Derived:   derived,
+			})
+		}
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,298 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	loginFailureReasonUnknownEmail         = "unknown_email"
+	loginFailureReasonInvalidPassword      = "invalid_password"
+	loginFailureReasonInvalidTwoFactorCode = "invalid_two_factor_code"
+)
+
+var ErrorTooManyLoginAttempts = errors.New("too many login attempts")
+
+// LoginThrottledError is returned while the login attempts are delayed or
+// locked out, it matches ErrorTooManyLoginAttempts.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrorTooManyLoginAttempts, e.RetryAfter)
+}
+
+func (e *LoginThrottledError) Is(target error) bool {
+	return target == ErrorTooManyLoginAttempts
+}
+
+// LoginThrottlePolicy is described by config.LoginThrottle.
+type LoginThrottlePolicy struct {
+	MaxAccountFailures uint64
+	MaxIPFailures      uint64
+	Window             time.Duration
+	LockoutDuration    time.Duration
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+}
+
+// LoginGuard tracks the failed login attempts to slow down password guessing
+// against a single account and from a single IP. The account attempts are
+// counted by the email rather than the customer, so an unknown email is
+// throttled exactly like a registered one and the responses don't tell them
+// apart.
+type LoginGuard struct {
+	db data.MainQ
+
+	auditService *AuditService
+	policy       LoginThrottlePolicy
+}
+
+func NewLoginGuard(db data.MainQ, auditService *AuditService, policy LoginThrottlePolicy) *LoginGuard {
+	return &LoginGuard{
+		db:           db,
+		auditService: auditService,
+		policy:       policy,
+	}
+}
+
+// LoginAttempt is the attempt started by LoginGuard.Begin. It is counted as
+// failed from the start, so the parallel attempts see each other, and stays
+// failed unless the login succeeds or the attempt is released.
+type LoginAttempt struct {
+	guard   *LoginGuard
+	failure *data.LoginFailure
+}
+
+// Begin returns LoginThrottledError if the next attempt for the email or from
+// the IP is not allowed yet, otherwise it counts the attempt. The check and
+// the count are serialized per email and per IP, so the parallel attempts
+// can't all pass the check before any of them is counted.
+func (m *LoginGuard) Begin(email string, client ClientInfo) (*LoginAttempt, error) {
+	failure := &data.LoginFailure{
+		EmailHash: loginEmailHash(email),
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	}
+
+	db := m.db.New()
+
+	err := db.Transaction(func() error {
+		if err := db.LoginFailures().Lock(failure.EmailHash, failure.IP); err != nil {
+			return fmt.Errorf("failed to lock login failures: %w", err)
+		}
+
+		if err := m.check(db, failure.EmailHash, failure.IP); err != nil {
+			return err
+		}
+
+		if err := db.LoginFailures().Insert(failure); err != nil {
+			return fmt.Errorf("failed to insert login failure: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginAttempt{guard: m, failure: failure}, nil
+}
+
+// check returns LoginThrottledError if the next attempt for the email hash or
+// from the IP is not allowed yet.
+func (m *LoginGuard) check(db data.MainQ, emailHash, ip string) error {
+	now := time.Now().UTC()
+	since := now.Add(-m.policy.Window)
+
+	retryAt, err := m.ipRetryAt(db, ip, since)
+	if err != nil {
+		return err
+	}
+
+	accountRetryAt, err := m.accountRetryAt(db, emailHash, since)
+	if err != nil {
+		return err
+	}
+
+	if accountRetryAt.After(retryAt) {
+		retryAt = accountRetryAt
+	}
+
+	if retryAt.After(now) {
+		return &LoginThrottledError{RetryAfter: retryAt.Sub(now)}
+	}
+
+	return nil
+}
+
+// ipRetryAt returns the end of the IP lockout, the IP is not delayed
+// progressively, as it may be shared by many customers.
+func (m *LoginGuard) ipRetryAt(db data.MainQ, ip string, since time.Time) (time.Time, error) {
+	count, last, err := m.failures(db.LoginFailures().WhereIP(ip), since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get IP login failures: %w", err)
+	}
+
+	if count < m.policy.MaxIPFailures {
+		return time.Time{}, nil
+	}
+
+	return last.Add(m.policy.LockoutDuration), nil
+}
+
+func (m *LoginGuard) accountRetryAt(db data.MainQ, emailHash string, since time.Time) (time.Time, error) {
+	count, last, err := m.failures(db.LoginFailures().WhereEmailHash(emailHash), since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get account login failures: %w", err)
+	}
+
+	if count == 0 {
+		return time.Time{}, nil
+	}
+
+	if count >= m.policy.MaxAccountFailures {
+		return last.Add(m.policy.LockoutDuration), nil
+	}
+
+	return last.Add(m.delay(count)), nil
+}
+
+// failures returns the number of the failures since the given time and the
+// time of the last one.
+func (m *LoginGuard) failures(q data.LoginFailures, since time.Time) (uint64, time.Time, error) {
+	failures, err := q.WhereCreatedAfter(since).OrderBy("created_at DESC").Select()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	if len(failures) == 0 {
+		return 0, time.Time{}, nil
+	}
+
+	return uint64(len(failures)), failures[0].CreatedAt, nil
+}
+
+// delay doubles with every failure, starting from BaseDelay up to MaxDelay.
+func (m *LoginGuard) delay(failures uint64) time.Duration {
+	delay := m.policy.BaseDelay
+	for i := uint64(1); i < failures && delay < m.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, m.policy.MaxDelay)
+}
+
+// Fail records the failure of the attempt, customerID is nil for an unknown
+// email. The attempts for the existing customers are also recorded in the
+// audit log.
+func (a *LoginAttempt) Fail(customerID *uuid.UUID, client ClientInfo, reason string) error {
+	if customerID == nil {
+		return nil
+	}
+
+	db := a.guard.db.New()
+
+	return db.Transaction(func() error {
+		a.failure.CustomerID = customerID
+		if err := db.LoginFailures().Update(a.failure); err != nil {
+			return fmt.Errorf("failed to update login failure: %w", err)
+		}
+
+		if err := a.guard.auditService.withDB(db).logLoginFailed(*customerID, client, reason); err != nil {
+			return fmt.Errorf("failed to log audit action: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Release forgets the attempt which has neither failed nor completed the
+// login, e.g. the one waiting for the second factor.
+func (a *LoginAttempt) Release() error {
+	if err := a.guard.db.LoginFailures().Delete(a.failure.ID); err != nil {
+		return fmt.Errorf("failed to delete login failure: %w", err)
+	}
+
+	return nil
+}
+
+// RecordSuccess forgets the failures of the customer and records the login in the audit log.
+func (m *LoginGuard) RecordSuccess(customer *data.Customer, client ClientInfo) error {
+	db := m.db.New()
+
+	return db.Transaction(func() error {
+		if err := db.LoginFailures().DeleteByEmailHash(loginEmailHash(customer.Email)); err != nil {
+			return fmt.Errorf("failed to delete login failures: %w", err)
+		}
+
+		if err := m.auditService.withDB(db).logLoginSuccess(customer.ID, client); err != nil {
+			return fmt.Errorf("failed to log audit action: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ExpireFailures deletes the failures which are out of the window at the
+// given time, they are not counted anymore.
+func (m *LoginGuard) ExpireFailures(now time.Time) error {
+	if err := m.db.LoginFailures().DeleteCreatedBefore(now.UTC().Add(-m.policy.Window)); err != nil {
+		return fmt.Errorf("failed to delete login failures: %w", err)
+	}
+
+	return nil
+}
+
+// UnlockCustomer lifts the lockout of the customer with the given email.
+func (m *LoginGuard) UnlockCustomer(email string) error {
+	customer := new(data.Customer)
+
+	ok, err := m.db.Customers().WhereEmail(email).Get(customer)
+	if err != nil {
+		return fmt.Errorf("failed to get customer: %w", err)
+	}
+	if !ok {
+		return ErrorUserNotFound
+	}
+
+	db := m.db.New()
+
+	return db.Transaction(func() error {
+		if err := db.LoginFailures().DeleteByEmailHash(loginEmailHash(customer.Email)); err != nil {
+			return fmt.Errorf("failed to delete login failures: %w", err)
+		}
+
+		if err := m.auditService.withDB(db).logLoginUnlocked(customer.ID); err != nil {
+			return fmt.Errorf("failed to log audit action: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// UnlockIP lifts the lockout of the IP.
+func (m *LoginGuard) UnlockIP(ip string) error {
+	if err := m.db.LoginFailures().DeleteByIP(ip); err != nil {
+		return fmt.Errorf("failed to delete login failures: %w", err)
+	}
+
+	return nil
+}
+
+// loginEmailHash returns the key of the account attempts: the hash of the
+// email, which is case-insensitive, so the attempts of the unknown emails can
+// be counted without storing the emails.
+func loginEmailHash(email string) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,277 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+)
+
+const testPassword = "Correct-Horse-42"
+
+// newTestGuardedAuth returns Auth with the login guard using the policy and
+// a customer with testPassword.
+func newTestGuardedAuth(t *testing.T, policy LoginThrottlePolicy) (*Auth, data.MainQ, *data.Customer) {
+	t.Helper()
+
+	auth, db := newTestAuth(t, time.Minute)
+	auth.loginGuard = NewLoginGuard(db, NewAuditService(db), policy)
+
+	passwordHash, err := auth.hasher.Hash(testPassword)
+	require.NoError(t, err)
+
+	customer := &data.Customer{
+		Email:        "guarded@example.com",
+		Username:     "guarded",
+		PasswordHash: passwordHash,
+	}
+	require.NoError(t, db.Customers().Insert(customer))
+
+	return auth, db, customer
+}
+
+func TestLoginUniformError(t *testing.T) {
+	auth, db, customer := newTestGuardedAuth(t, LoginThrottlePolicy{
+		MaxAccountFailures: 10,
+		MaxIPFailures:      10,
+		Window:             time.Hour,
+		LockoutDuration:    time.Hour,
+	})
+	client := ClientInfo{UserAgent: "test", IP: "10.0.0.1"}
+
+	_, unknownErr := auth.Login(&requests.Login{Email: "unknown@example.com", Password: testPassword}, client)
+	_, wrongErr := auth.Login(&requests.Login{Email: customer.Email, Password: "wrong-password"}, client)
+
+	require.ErrorIs(t, unknownErr, ErrorInvalidCredentials)
+	require.ErrorIs(t, wrongErr, ErrorInvalidCredentials)
+	assert.Equal(t, unknownErr.Error(), wrongErr.Error())
+
+	failures, err := db.LoginFailures().WhereIP(client.IP).Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), failures, "failures for both unknown and known emails must be recorded")
+
+	logs, err := db.AuditLogs().WhereCustomerID(customer.ID).WhereAction(data.AuditActionLoginFailed).Select()
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+
+	var details map[string]string
+	require.NoError(t, json.Unmarshal(logs[0].Details, &details))
+	assert.Equal(t, map[string]string{
+		"ip":         client.IP,
+		"user_agent": client.UserAgent,
+		"reason":     loginFailureReasonInvalidPassword,
+	}, details)
+
+	result, err := auth.Login(&requests.Login{Email: customer.Email, Password: testPassword}, client)
+	require.NoError(t, err)
+	require.NotNil(t, result.Tokens)
+
+	failures, err = db.LoginFailures().WhereCustomerID(customer.ID).Count()
+	require.NoError(t, err)
+	assert.Zero(t, failures, "successful login must reset the failures")
+
+	success, err := db.AuditLogs().WhereCustomerID(customer.ID).WhereAction(data.AuditActionLoginSuccess).Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), success)
+}
+
+func TestLoginProgressiveDelay(t *testing.T) {
+	auth, _, customer := newTestGuardedAuth(t, LoginThrottlePolicy{
+		MaxAccountFailures: 10,
+		MaxIPFailures:      10,
+		Window:             time.Hour,
+		LockoutDuration:    time.Hour,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Hour,
+	})
+
+	_, err := auth.Login(&requests.Login{Email: customer.Email, Password: "wrong-password"}, ClientInfo{})
+	require.ErrorIs(t, err, ErrorInvalidCredentials)
+
+	_, err = auth.Login(&requests.Login{Email: customer.Email, Password: testPassword}, ClientInfo{})
+	require.ErrorIs(t, err, ErrorTooManyLoginAttempts, "even the right password must wait for the delay")
+
+	var throttled *LoginThrottledError
+	require.ErrorAs(t, err, &throttled)
+	assert.InDelta(t, time.Minute.Seconds(), throttled.RetryAfter.Seconds(), 5)
+}
+
+func TestLoginParallelAttempts(t *testing.T) {
+	auth, db, customer := newTestGuardedAuth(t, LoginThrottlePolicy{
+		MaxAccountFailures: 3,
+		MaxIPFailures:      10,
+		Window:             time.Hour,
+		LockoutDuration:    time.Hour,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Hour,
+	})
+
+	const attempts = 8
+
+	errs := make([]error, attempts)
+
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = auth.Login(&requests.Login{Email: customer.Email, Password: "wrong-password"}, ClientInfo{})
+		}()
+	}
+	wg.Wait()
+
+	var invalid int
+	for _, err := range errs {
+		if errors.Is(err, ErrorInvalidCredentials) {
+			invalid++
+			continue
+		}
+
+		require.ErrorIs(t, err, ErrorTooManyLoginAttempts)
+	}
+	assert.Equal(t, 1, invalid, "the parallel attempts must wait for the delay of the first one")
+
+	failures, err := db.LoginFailures().WhereCustomerID(customer.ID).Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), failures)
+}
+
+func TestExpireLoginFailures(t *testing.T) {
+	auth, db, customer := newTestGuardedAuth(t, LoginThrottlePolicy{
+		MaxAccountFailures: 10,
+		MaxIPFailures:      10,
+		Window:             time.Hour,
+		LockoutDuration:    time.Hour,
+	})
+
+	_, err := auth.Login(&requests.Login{Email: customer.Email, Password: "wrong-password"}, ClientInfo{})
+	require.ErrorIs(t, err, ErrorInvalidCredentials)
+
+	require.NoError(t, auth.loginGuard.ExpireFailures(time.Now()))
+
+	failures, err := db.LoginFailures().Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), failures, "the failure is still in the window")
+
+	require.NoError(t, auth.loginGuard.ExpireFailures(time.Now().Add(time.Hour+time.Minute)))
+
+	failures, err = db.LoginFailures().Count()
+	require.NoError(t, err)
+	assert.Zero(t, failures)
+}
+
+func TestLoginThrottleUnknownEmail(t *testing.T) {
+	auth, _, customer := newTestGuardedAuth(t, LoginThrottlePolicy{
+		MaxAccountFailures: 2,
+		MaxIPFailures:      10,
+		Window:             time.Hour,
+		LockoutDuration:    time.Hour,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Hour,
+	})
+
+	// Every attempt comes from another IP, so only the email is throttled
+	attempt := func(email string, ip string) error {
+		_, err := auth.Login(&requests.Login{Email: email, Password: "wrong-password"}, ClientInfo{IP: ip})
+		return err
+	}
+
+	for i, email := range []string{customer.Email, "unknown@example.com"} {
+		prefix := fmt.Sprintf("10.0.%d.", i)
+
+		require.ErrorIs(t, attempt(email, prefix+"1"), ErrorInvalidCredentials, email)
+
+		var throttled *LoginThrottledError
+		require.ErrorAs(t, attempt(email, prefix+"2"), &throttled, "%s must be delayed", email)
+		assert.InDelta(t, time.Minute.Seconds(), throttled.RetryAfter.Seconds(), 5, email)
+
+		// The case of the email doesn't matter
+		require.ErrorAs(t, attempt(strings.ToUpper(email), prefix+"3"), &throttled, email)
+	}
+}
+
+func TestLoginDelay(t *testing.T) {
+	guard := NewLoginGuard(nil, nil, LoginThrottlePolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second})
+
+	tests := []struct {
+		failures uint64
+		delay    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{5, 16 * time.Second},
+		{6, 30 * time.Second},
+		{100, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.delay, guard.delay(tt.failures), "failures %d", tt.failures)
+	}
+}
+
+func TestLoginAccountLockout(t *testing.T) {
+	auth, db, customer := newTestGuardedAuth(t, LoginThrottlePolicy{
+		MaxAccountFailures: 3,
+		MaxIPFailures:      10,
+		Window:             time.Hour,
+		LockoutDuration:    time.Hour,
+	})
+
+	// Every attempt comes from another IP, so only the account is locked
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		_, err := auth.Login(&requests.Login{Email: customer.Email, Password: "wrong-password"}, ClientInfo{IP: ip})
+		require.ErrorIs(t, err, ErrorInvalidCredentials)
+	}
+
+	_, err := auth.Login(&requests.Login{Email: customer.Email, Password: testPassword}, ClientInfo{IP: "10.0.0.4"})
+	require.ErrorIs(t, err, ErrorTooManyLoginAttempts)
+
+	require.ErrorIs(t, auth.loginGuard.UnlockCustomer("unknown@example.com"), ErrorUserNotFound)
+	require.NoError(t, auth.loginGuard.UnlockCustomer(customer.Email))
+
+	_, err = auth.Login(&requests.Login{Email: customer.Email, Password: testPassword}, ClientInfo{IP: "10.0.0.4"})
+	require.NoError(t, err)
+
+	unlocked, err := db.AuditLogs().WhereCustomerID(customer.ID).WhereAction(data.AuditActionLoginUnlocked).Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), unlocked)
+}
+
+func TestLoginIPLockout(t *testing.T) {
+	auth, _, customer := newTestGuardedAuth(t, LoginThrottlePolicy{
+		MaxAccountFailures: 10,
+		MaxIPFailures:      2,
+		Window:             time.Hour,
+		LockoutDuration:    time.Hour,
+	})
+	attacker := ClientInfo{IP: "10.0.0.66"}
+
+	for _, email := range []string{"first@example.com", "second@example.com"} {
+		_, err := auth.Login(&requests.Login{Email: email, Password: "wrong-password"}, attacker)
+		require.ErrorIs(t, err, ErrorInvalidCredentials)
+	}
+
+	_, err := auth.Login(&requests.Login{Email: customer.Email, Password: testPassword}, attacker)
+	require.ErrorIs(t, err, ErrorTooManyLoginAttempts)
+
+	_, err = auth.Login(&requests.Login{Email: "third@example.com", Password: testPassword}, attacker)
+	require.ErrorIs(t, err, ErrorTooManyLoginAttempts, "unknown emails must be throttled as well")
+
+	_, err = auth.Login(&requests.Login{Email: customer.Email, Password: testPassword}, ClientInfo{IP: "10.0.0.1"})
+	require.NoError(t, err, "other IPs must not be affected")
+
+	require.NoError(t, auth.loginGuard.UnlockIP(attacker.IP))
+
+	_, err = auth.Login(&requests.Login{Email: customer.Email, Password: testPassword}, attacker)
+	require.NoError(t, err)
+}
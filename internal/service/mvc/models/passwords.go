@@ -0,0 +1,190 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+// passwordPrehashSalt is the salt the browser used to pre-hash the passwords
+// before the hashing moved to the server, the stored hashes are hashes of the pre-hash.
+const passwordPrehashSalt = "ilab1"
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var ErrorUnsupportedPasswordHash = errors.New("unsupported password hash")
+
+// PasswordHasher hashes the passwords with the configured policy, it verifies
+// the hashes created with any supported policy.
+type PasswordHasher struct {
+	Algorithm string
+
+	// Argon2Memory is the memory used by Argon2id in KiB.
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+
+	BcryptCost int
+}
+
+func NewPasswordHasher(
+	algorithm string,
+	argon2Memory, argon2Iterations uint32, argon2Parallelism uint8,
+	bcryptCost int,
+) (*PasswordHasher, error) {
+	if algorithm != PasswordAlgorithmArgon2id && algorithm != PasswordAlgorithmBcrypt {
+		return nil, fmt.Errorf("unsupported password hashing algorithm %q", algorithm)
+	}
+
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	if argon2Memory == 0 || argon2Iterations == 0 || argon2Parallelism == 0 {
+		return nil, errors.New("argon2 parameters must be positive")
+	}
+
+	return &PasswordHasher{
+		Algorithm:         algorithm,
+		Argon2Memory:      argon2Memory,
+		Argon2Iterations:  argon2Iterations,
+		Argon2Parallelism: argon2Parallelism,
+		BcryptCost:        bcryptCost,
+	}, nil
+}
+
+// Hash returns the hash of the password in the PHC string format for Argon2id,
+// or in the modular crypt format for bcrypt.
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	prehash := prehashPassword(password)
+
+	switch h.Algorithm {
+	case PasswordAlgorithmArgon2id:
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("failed to generate salt: %w", err)
+		}
+
+		key := argon2.IDKey([]byte(prehash), salt,
+			h.Argon2Iterations, h.Argon2Memory, h.Argon2Parallelism, argon2KeyLength)
+
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, h.Argon2Memory, h.Argon2Iterations, h.Argon2Parallelism,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key),
+		), nil
+	case PasswordAlgorithmBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(prehash), h.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+
+		return string(hash), nil
+	default:
+		return "", fmt.Errorf("unsupported password hashing algorithm %q", h.Algorithm)
+	}
+}
+
+// Verify reports whether the password matches the hash.
+func (h *PasswordHasher) Verify(hash, password string) (bool, error) {
+	prehash := prehashPassword(password)
+
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := parseArgon2Hash(hash)
+		if err != nil {
+			return false, err
+		}
+
+		actual := argon2.IDKey([]byte(prehash), salt,
+			params.iterations, params.memory, params.parallelism, uint32(len(key)))
+
+		return subtle.ConstantTimeCompare(actual, key) == 1, nil
+	}
+
+	if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+		return false, ErrorUnsupportedPasswordHash
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(prehash)) == nil, nil
+}
+
+// NeedsRehash reports whether the hash was created with a policy other than the current one.
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	switch h.Algorithm {
+	case PasswordAlgorithmArgon2id:
+		params, _, key, err := parseArgon2Hash(hash)
+		if err != nil {
+			return true
+		}
+
+		return params.memory != h.Argon2Memory ||
+			params.iterations != h.Argon2Iterations ||
+			params.parallelism != h.Argon2Parallelism ||
+			len(key) != argon2KeyLength
+	case PasswordAlgorithmBcrypt:
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.BcryptCost
+	default:
+		return false
+	}
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// parseArgon2Hash parses the $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key> hash.
+func parseArgon2Hash(hash string) (*argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != PasswordAlgorithmArgon2id {
+		return nil, nil, nil, ErrorUnsupportedPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrorUnsupportedPasswordHash
+	}
+
+	params := new(argon2Params)
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism)
+	if err != nil {
+		return nil, nil, nil, ErrorUnsupportedPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrorUnsupportedPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrorUnsupportedPasswordHash
+	}
+
+	return params, salt, key, nil
+}
+
+// prehashPassword computes the SHA3-256 pre-hash the browser used to send
+// instead of the trimmed password, so the hashes of the existing customers still match.
+func prehashPassword(password string) string {
+	hash := sha3.Sum256([]byte(strings.TrimSpace(password) + passwordPrehashSalt))
+	return hex.EncodeToString(hash[:])
+}
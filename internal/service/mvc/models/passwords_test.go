@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	argon, err := NewPasswordHasher(PasswordAlgorithmArgon2id, 1024, 2, 1, bcrypt.MinCost)
+	require.NoError(t, err)
+
+	bcryptHasher, err := NewPasswordHasher(PasswordAlgorithmBcrypt, 1024, 2, 1, bcrypt.MinCost)
+	require.NoError(t, err)
+
+	for _, hasher := range []*PasswordHasher{argon, bcryptHasher} {
+		t.Run(hasher.Algorithm, func(t *testing.T) {
+			hash, err := hasher.Hash("Correct-Horse-42")
+			require.NoError(t, err)
+
+			ok, err := hasher.Verify(hash, "Correct-Horse-42")
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = hasher.Verify(hash, "Correct-Horse-43")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			assert.False(t, hasher.NeedsRehash(hash), "hash of the current policy")
+		})
+	}
+
+	t.Run("policy change", func(t *testing.T) {
+		hash, err := bcryptHasher.Hash("Correct-Horse-42")
+		require.NoError(t, err)
+		assert.True(t, argon.NeedsRehash(hash), "bcrypt hash must be upgraded to argon2id")
+
+		// hashes of the previous policy are still verified
+		ok, err := argon.Verify(hash, "Correct-Horse-42")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		stronger, err := NewPasswordHasher(PasswordAlgorithmArgon2id, 2048, 2, 1, bcrypt.MinCost)
+		require.NoError(t, err)
+
+		hash, err = argon.Hash("Correct-Horse-42")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=2,p=1$"))
+		assert.True(t, stronger.NeedsRehash(hash), "argon2id parameters changed")
+	})
+
+	t.Run("unsupported hash", func(t *testing.T) {
+		_, err := argon.Verify("plain", "Correct-Horse-42")
+		require.ErrorIs(t, err, ErrorUnsupportedPasswordHash)
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := NewPasswordHasher("md5", 1024, 2, 1, bcrypt.MinCost)
+		require.Error(t, err)
+	})
+}
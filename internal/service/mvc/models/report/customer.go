@@ -0,0 +1,205 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const CustomerOverviewSheetName = "Overview"
+
+// maxSheetNameLength is the limit of the sheet names in Excel
+const maxSheetNameLength = 31
+
+// OwnTransfers is the total of the transfers from one account of the
+// customer to another one of the period.
+type OwnTransfers struct {
+	From   *data.Account
+	To     *data.Account
+	Count  int
+	Amount int
+}
+
+// CalculateOwnTransfers totals the transfers between the accounts of the
+// statements by the direction, in the order of the statements.
+func CalculateOwnTransfers(statements []*Statement) []OwnTransfers {
+	accounts := make(map[uuid.UUID]*data.Account, len(statements))
+	for _, statement := range statements {
+		accounts[statement.Account.ID] = statement.Account
+	}
+
+	var result []OwnTransfers
+	for _, statement := range statements {
+		// The transfer is in the statements of both accounts, it is counted
+		// on the sending side only
+		totals := make(map[uuid.UUID]*OwnTransfers)
+		for _, txInfo := range statement.Transactions {
+			tx := txInfo.Transaction
+			recipient, ok := accounts[tx.Recipient]
+			if tx.Type != data.TransferTransaction || tx.Sender != statement.Account.ID || !ok {
+				continue
+			}
+
+			if totals[recipient.ID] == nil {
+				totals[recipient.ID] = &OwnTransfers{From: statement.Account, To: recipient}
+			}
+			totals[recipient.ID].Count++
+			totals[recipient.ID].Amount += int(tx.Amount)
+		}
+
+		for _, other := range statements {
+			if total := totals[other.Account.ID]; total != nil {
+				result = append(result, *total)
+			}
+		}
+	}
+
+	return result
+}
+
+// CreateCustomerOverviewSheet creates the overview sheet of the customer
+// workbook: the balances and the totals of the period of every account and
+// the transfers between them.
+func CreateCustomerOverviewSheet(f *excelize.File, statements []*Statement, styles *ExcelStyles) error {
+	sheetName := CustomerOverviewSheetName
+	if err := newSheet(f, sheetName); err != nil {
+		return err
+	}
+
+	ownTransfers := CalculateOwnTransfers(statements)
+
+	// Net of the transfers between the own accounts by the account
+	netOwnFlows := make(map[uuid.UUID]int, len(statements))
+	for _, transfers := range ownTransfers {
+		netOwnFlows[transfers.From.ID] -= transfers.Amount
+		netOwnFlows[transfers.To.ID] += transfers.Amount
+	}
+
+	f.SetCellValue(sheetName, "A1", "Customer Overview")
+	f.SetCellStyle(sheetName, "A1", "A1", styles.TitleStyle)
+	f.MergeCell(sheetName, "A1", "B1")
+
+	f.SetCellValue(sheetName, "A2", "Period:")
+	if len(statements) > 0 {
+		f.SetCellValue(sheetName, "B2", StatementPeriod(statements[0]))
+	}
+	f.SetCellValue(sheetName, "A3", "Number of Accounts:")
+	f.SetCellValue(sheetName, "B3", len(statements))
+
+	headers := []string{
+		"Account", "Account ID", "Opening Balance", "Closing Balance", "Deposits",
+		"Withdrawals", "Transfers In", "Transfers Out", "Net Own Transfers", "Net Change",
+	}
+	for i, header := range headers {
+		cell := fmt.Sprintf("%c5", 'A'+i)
+		f.SetCellValue(sheetName, cell, header)
+		f.SetCellStyle(sheetName, cell, cell, styles.HeaderStyle)
+	}
+
+	// Columns C to J are the amounts, the last row is their total
+	totals := make([]int, len(headers))
+	row := 6
+	for _, statement := range statements {
+		stats := statement.Stats
+		amounts := []int{
+			statement.OpeningBalance,
+			statement.ClosingBalance,
+			stats.TotalDeposits,
+			stats.TotalWithdrawals,
+			stats.TotalTransfersIn,
+			stats.TotalTransfersOut,
+			netOwnFlows[statement.Account.ID],
+			statement.ClosingBalance - statement.OpeningBalance,
+		}
+
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), statement.Account.Name)
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), statement.Account.ID.String())
+		for i, amount := range amounts {
+			setCurrencyCell(f, sheetName, fmt.Sprintf("%c%d", 'C'+i, row), amount, styles)
+			totals[i] += amount
+		}
+		row++
+	}
+
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "Total")
+	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), styles.HeaderStyle)
+	for i := 0; i < len(headers)-2; i++ {
+		setCurrencyCell(f, sheetName, fmt.Sprintf("%c%d", 'C'+i, row), totals[i], styles)
+	}
+
+	// Transfers between the own accounts section
+	row += 2
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "Transfers Between Own Accounts")
+	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), styles.TitleStyle)
+	f.MergeCell(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row))
+	row++
+
+	if len(ownTransfers) == 0 {
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "No transfers between own accounts in the period")
+	} else {
+		for i, header := range []string{"From", "To", "Number of Transfers", "Amount"} {
+			cell := fmt.Sprintf("%c%d", 'A'+i, row)
+			f.SetCellValue(sheetName, cell, header)
+			f.SetCellStyle(sheetName, cell, cell, styles.HeaderStyle)
+		}
+
+		for _, transfers := range ownTransfers {
+			row++
+			f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), transfers.From.Name)
+			f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), transfers.To.Name)
+			f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), transfers.Count)
+			setCurrencyCell(f, sheetName, fmt.Sprintf("D%d", row), transfers.Amount, styles)
+		}
+	}
+
+	// Auto-fit columns
+	for col := 'A'; col < 'A'+rune(len(headers)); col++ {
+		colName := string(col)
+		width := 20.0
+		if col == 'B' {
+			width = 38.0 // Account IDs
+		}
+		if err := f.SetColWidth(sheetName, colName, colName, width); err != nil {
+			return fmt.Errorf("failed to set column width: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AccountSheetName returns the name of the sheet of the account in the
+// customer workbook, the number of the account keeps the names unique when
+// the account names are cut to the length limit of Excel.
+func AccountSheetName(number int, account *data.Account, suffix string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+		return r
+	}, account.Name)
+	name = strings.Trim(name, "' ")
+
+	if name == "" {
+		return fmt.Sprintf("%d %s", number, suffix)
+	}
+
+	prefix := fmt.Sprintf("%d ", number)
+	suffix = " " + suffix
+
+	room := maxSheetNameLength - utf8.RuneCountInString(prefix) - utf8.RuneCountInString(suffix)
+	if utf8.RuneCountInString(name) > room {
+		name = strings.TrimSpace(string([]rune(name)[:room]))
+	}
+
+	return prefix + name + suffix
+}
+
+func setCurrencyCell(f *excelize.File, sheetName, cell string, cents int, styles *ExcelStyles) {
+	f.SetCellValue(sheetName, cell, float64(cents)/100.0)
+	f.SetCellStyle(sheetName, cell, cell, styles.CurrencyStyle)
+}
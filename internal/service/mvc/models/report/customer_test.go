@@ -0,0 +1,67 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+func TestCalculateOwnTransfers(t *testing.T) {
+	newAccount := func(name string, balance int) *data.Account {
+		account := &data.Account{Name: name, Balance: balance}
+		account.ID = uuid.New()
+		return account
+	}
+	newTx := func(sender, recipient uuid.UUID, amount uint, createdAt time.Time) *data.Transaction {
+		tx := &data.Transaction{Type: data.TransferTransaction, Sender: sender, Recipient: recipient, Amount: amount}
+		tx.ID = uuid.New()
+		tx.CreatedAt = createdAt
+		return tx
+	}
+
+	checking := newAccount("Checking", 700)
+	savings := newAccount("Savings", 300)
+	stranger := uuid.New()
+
+	toSavings := newTx(checking.ID, savings.ID, 200, day(1))
+	toSavingsAgain := newTx(checking.ID, savings.ID, 150, day(2))
+	toChecking := newTx(savings.ID, checking.ID, 50, day(3))
+	toStranger := newTx(checking.ID, stranger, 100, day(4))
+
+	statements := []*Statement{
+		NewStatement(checking, checking.Balance, []*data.Transaction{toSavings, toSavingsAgain, toChecking, toStranger}, time.Time{}, day(5)),
+		NewStatement(savings, savings.Balance, []*data.Transaction{toSavings, toSavingsAgain, toChecking}, time.Time{}, day(5)),
+	}
+
+	assert.Equal(t, []OwnTransfers{
+		{From: checking, To: savings, Count: 2, Amount: 350},
+		{From: savings, To: checking, Count: 1, Amount: 50},
+	}, CalculateOwnTransfers(statements))
+
+	assert.Empty(t, CalculateOwnTransfers(statements[:1]), "the transfers to the accounts out of the report")
+}
+
+func TestAccountSheetName(t *testing.T) {
+	tests := []struct {
+		name    string
+		account string
+		want    string
+	}{
+		{name: "short", account: "Savings", want: "1 Savings History"},
+		{name: "invalid characters", account: "Rent [2024/25]", want: "1 Rent  2024 25 History"},
+		{name: "too long", account: "Emergency fund for the rainy days", want: "1 Emergency fund for th History"},
+		{name: "empty", account: "'?'", want: "1 History"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AccountSheetName(1, &data.Account{Name: tt.account}, "History")
+			assert.Equal(t, tt.want, got)
+			assert.LessOrEqual(t, len([]rune(got)), maxSheetNameLength)
+		})
+	}
+}
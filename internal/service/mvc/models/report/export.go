@@ -0,0 +1,96 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// StatementFormat is the file format of the exported statement.
+type StatementFormat string
+
+const (
+	StatementFormatPDF     StatementFormat = "pdf"
+	StatementFormatCSV     StatementFormat = "csv"
+	StatementFormatOFX     StatementFormat = "ofx"
+	StatementFormatCAMT053 StatementFormat = "camt053"
+)
+
+// Currency of the accounts, the amounts are in its cents
+const Currency = "USD"
+
+// BankID identifies the bank in the OFX and camt.053 statements
+const BankID = "ILAB1"
+
+// StatementFormats lists the supported statement formats.
+func StatementFormats() []StatementFormat {
+	return []StatementFormat{
+		StatementFormatPDF,
+		StatementFormatCSV,
+		StatementFormatOFX,
+		StatementFormatCAMT053,
+	}
+}
+
+func (f StatementFormat) ContentType() string {
+	switch f {
+	case StatementFormatPDF:
+		return "application/pdf"
+	case StatementFormatCSV:
+		return "text/csv; charset=utf-8"
+	case StatementFormatOFX:
+		return "application/x-ofx"
+	case StatementFormatCAMT053:
+		return "application/xml"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Extension returns the file name extension of the format.
+func (f StatementFormat) Extension() string {
+	switch f {
+	case StatementFormatCAMT053:
+		return "xml"
+	default:
+		return string(f)
+	}
+}
+
+// WriteStatement writes the statement in the format.
+func WriteStatement(w io.Writer, format StatementFormat, statement *Statement, generatedAt time.Time) error {
+	switch format {
+	case StatementFormatPDF:
+		return WriteStatementPDF(w, statement, generatedAt)
+	case StatementFormatCSV:
+		return WriteStatementCSV(w, statement)
+	case StatementFormatOFX:
+		return WriteStatementOFX(w, statement, generatedAt)
+	case StatementFormatCAMT053:
+		return WriteStatementCAMT053(w, statement, generatedAt)
+	default:
+		return fmt.Errorf("unsupported statement format %q", format)
+	}
+}
+
+// formatDecimal formats the cents as the plain decimal with the point and two
+// fraction digits, the way the machine readable formats expect it.
+func formatDecimal(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// periodStart returns the start of the statement period, the creation of the
+// account if the period starts with it.
+func periodStart(statement *Statement) time.Time {
+	if statement.From.IsZero() {
+		return statement.Account.CreatedAt
+	}
+
+	return statement.From
+}
@@ -0,0 +1,263 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+const camtDateTimeFormat = "2006-01-02T15:04:05Z"
+
+// ISO 20022 bank to customer statement, camt.053.001.02, reduced to the
+// elements of the booked entries of the account
+type camtDocument struct {
+	XMLName   xml.Name          `xml:"Document"`
+	Namespace string            `xml:"xmlns,attr"`
+	Statement camtBankStatement `xml:"BkToCstmrStmt"`
+}
+
+type camtBankStatement struct {
+	GroupHeader camtGroupHeader `xml:"GrpHdr"`
+	Statement   camtStatement   `xml:"Stmt"`
+}
+
+type camtGroupHeader struct {
+	MessageID string `xml:"MsgId"`
+	CreatedAt string `xml:"CreDtTm"`
+}
+
+type camtStatement struct {
+	ID        string        `xml:"Id"`
+	CreatedAt string        `xml:"CreDtTm"`
+	Period    camtPeriod    `xml:"FrToDt"`
+	Account   camtAccount   `xml:"Acct"`
+	Balances  []camtBalance `xml:"Bal"`
+	Summary   camtTxSummary `xml:"TxsSummry"`
+	Entries   []camtEntry   `xml:"Ntry"`
+}
+
+type camtPeriod struct {
+	From string `xml:"FrDtTm"`
+	To   string `xml:"ToDtTm"`
+}
+
+type camtAccount struct {
+	ID       string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+	Name     string `xml:"Nm,omitempty"`
+	Servicer string `xml:"Svcr>FinInstnId>Othr>Id"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtBalance struct {
+	Code   string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount camtAmount `xml:"Amt"`
+	Side   string     `xml:"CdtDbtInd"`
+	Date   string     `xml:"Dt>DtTm"`
+}
+
+type camtTxSummary struct {
+	Total   camtEntriesTotal    `xml:"TtlNtries"`
+	Credits camtEntriesSubtotal `xml:"TtlCdtNtries"`
+	Debits  camtEntriesSubtotal `xml:"TtlDbtNtries"`
+}
+
+type camtEntriesTotal struct {
+	Count     int    `xml:"NbOfNtries"`
+	Sum       string `xml:"Sum"`
+	NetAmount string `xml:"TtlNetNtryAmt"`
+	Side      string `xml:"CdtDbtInd"`
+}
+
+type camtEntriesSubtotal struct {
+	Count int    `xml:"NbOfNtries"`
+	Sum   string `xml:"Sum"`
+}
+
+type camtEntry struct {
+	Reference     string         `xml:"NtryRef"`
+	Amount        camtAmount     `xml:"Amt"`
+	Side          string         `xml:"CdtDbtInd"`
+	Status        string         `xml:"Sts"`
+	BookingDate   string         `xml:"BookgDt>DtTm"`
+	ValueDate     string         `xml:"ValDt>DtTm"`
+	ServicerRef   string         `xml:"AcctSvcrRef"`
+	BankTxCode    camtBankTxCode `xml:"BkTxCd>Domn"`
+	AdditionalInf string         `xml:"AddtlNtryInf"`
+}
+
+type camtBankTxCode struct {
+	Code          string `xml:"Cd"`
+	FamilyCode    string `xml:"Fmly>Cd"`
+	SubFamilyCode string `xml:"Fmly>SubFmlyCd"`
+}
+
+const (
+	camtCredit = "CRDT"
+	camtDebit  = "DBIT"
+)
+
+// WriteStatementCAMT053 writes the statement as the ISO 20022 camt.053
+// message with the opening and closing booked balances of the period and an
+// entry per transaction.
+func WriteStatementCAMT053(w io.Writer, statement *Statement, generatedAt time.Time) error {
+	account := statement.Account
+	createdAt := formatCAMTDateTime(generatedAt)
+	statementID := camtID(uuid.New())
+
+	entries := make([]camtEntry, len(statement.Transactions))
+	var credits, debits camtEntriesSubtotal
+	var creditSum, debitSum int
+
+	for i, txInfo := range statement.Transactions {
+		tx := txInfo.Transaction
+		amount := SignedAmount(account, tx)
+		_, details := formatTransactionTypeAndDetails(account, tx)
+		family, subFamily := camtTransactionCode(account, tx)
+
+		if amount > 0 {
+			credits.Count++
+			creditSum += amount
+		} else {
+			debits.Count++
+			debitSum -= amount
+		}
+
+		entries[i] = camtEntry{
+			Reference:     camtID(tx.ID),
+			Amount:        newCAMTAmount(amount),
+			Side:          camtSide(amount),
+			Status:        "BOOK",
+			BookingDate:   formatCAMTDateTime(tx.CreatedAt),
+			ValueDate:     formatCAMTDateTime(tx.CreatedAt),
+			ServicerRef:   camtID(tx.ID),
+			BankTxCode:    camtBankTxCode{Code: "PMNT", FamilyCode: family, SubFamilyCode: subFamily},
+			AdditionalInf: details,
+		}
+	}
+	credits.Sum = formatDecimal(creditSum)
+	debits.Sum = formatDecimal(debitSum)
+
+	net := creditSum - debitSum
+
+	document := camtDocument{
+		Namespace: camt053Namespace,
+		Statement: camtBankStatement{
+			GroupHeader: camtGroupHeader{
+				MessageID: statementID,
+				CreatedAt: createdAt,
+			},
+			Statement: camtStatement{
+				ID:        statementID,
+				CreatedAt: createdAt,
+				Period: camtPeriod{
+					From: formatCAMTDateTime(periodStart(statement)),
+					To:   formatCAMTDateTime(statement.To),
+				},
+				Account: camtAccount{
+					ID:       camtID(account.ID),
+					Currency: Currency,
+					Name:     account.Name,
+					Servicer: BankID,
+				},
+				Balances: []camtBalance{
+					newCAMTBalance("OPBD", statement.OpeningBalance, periodStart(statement)),
+					newCAMTBalance("CLBD", statement.ClosingBalance, statement.To),
+				},
+				Summary: camtTxSummary{
+					Total: camtEntriesTotal{
+						Count:     len(entries),
+						Sum:       formatDecimal(creditSum + debitSum),
+						NetAmount: formatDecimal(abs(net)),
+						Side:      camtSide(net),
+					},
+					Credits: credits,
+					Debits:  debits,
+				},
+				Entries: entries,
+			},
+		},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write camt.053 header: %w", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to write camt.053: %w", err)
+	}
+
+	return encoder.Close()
+}
+
+// camtTransactionCode returns the family and the sub-family of the ISO bank
+// transaction code in the payments domain: the cash deposits and withdrawals
+// at the ATMs and the book transfers between the accounts of the bank.
+func camtTransactionCode(account *data.Account, tx *data.Transaction) (string, string) {
+	switch tx.Type {
+	case data.DepositTransaction:
+		return "CNTR", "CDPT"
+	case data.WithdrawalTransaction:
+		return "CNTR", "CWDL"
+	}
+
+	if tx.Recipient == account.ID {
+		return "RCDT", "BOOK"
+	}
+
+	return "ICDT", "BOOK"
+}
+
+func newCAMTBalance(code string, balance int, date time.Time) camtBalance {
+	return camtBalance{
+		Code:   code,
+		Amount: newCAMTAmount(balance),
+		Side:   camtSide(balance),
+		Date:   formatCAMTDateTime(date),
+	}
+}
+
+// newCAMTAmount returns the absolute amount, the sign is in the CdtDbtInd.
+func newCAMTAmount(cents int) camtAmount {
+	return camtAmount{Currency: Currency, Value: formatDecimal(abs(cents))}
+}
+
+func camtSide(cents int) string {
+	if cents < 0 {
+		return camtDebit
+	}
+
+	return camtCredit
+}
+
+// camtID returns the UUID as the 32 hexadecimal digits, the ISO 20022
+// identifiers and references are at most 35 characters long.
+func camtID(id uuid.UUID) string {
+	return strings.ReplaceAll(id.String(), "-", "")
+}
+
+func formatCAMTDateTime(t time.Time) string {
+	return t.UTC().Format(camtDateTimeFormat)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
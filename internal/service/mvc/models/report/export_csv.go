@@ -0,0 +1,47 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+)
+
+var csvHeader = []string{"Transaction ID", "Date", "Type", "Amount", "Balance After", "Details"}
+
+// WriteStatementCSV writes the transactions of the statement in the RFC 4180
+// CSV: the header and a record per transaction in chronological order, with
+// the signed amounts and the running balances as plain decimals and the
+// dates in RFC 3339.
+func WriteStatementCSV(w io.Writer, statement *Statement) error {
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, txInfo := range statement.Transactions {
+		tx := txInfo.Transaction
+		txType, details := formatTransactionTypeAndDetails(statement.Account, tx)
+
+		err := writer.Write([]string{
+			tx.ID.String(),
+			tx.CreatedAt.UTC().Format(time.RFC3339),
+			txType,
+			formatDecimal(SignedAmount(statement.Account, tx)),
+			formatDecimal(txInfo.BalanceAfter),
+			details,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n" +
+	`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+
+const ofxDateTimeFormat = "20060102150405.000[0:GMT]"
+
+// OFX 2.2 bank statement response, reduced to the elements the personal
+// finance tools read
+type ofxDocument struct {
+	XMLName xml.Name             `xml:"OFX"`
+	SignOn  ofxSignOn            `xml:"SIGNONMSGSRSV1>SONRS"`
+	Bank    ofxStatementResponse `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxSignOn struct {
+	Status   ofxStatus `xml:"STATUS"`
+	DTServer string    `xml:"DTSERVER"`
+	Language string    `xml:"LANGUAGE"`
+}
+
+type ofxStatementResponse struct {
+	TransactionUID string       `xml:"TRNUID"`
+	Status         ofxStatus    `xml:"STATUS"`
+	Statement      ofxStatement `xml:"STMTRS"`
+}
+
+type ofxStatement struct {
+	Currency     string             `xml:"CURDEF"`
+	Account      ofxBankAccount     `xml:"BANKACCTFROM"`
+	Transactions ofxTransactionList `xml:"BANKTRANLIST"`
+	LedgerBal    ofxBalance         `xml:"LEDGERBAL"`
+}
+
+type ofxBankAccount struct {
+	BankID      string `xml:"BANKID"`
+	AccountID   string `xml:"ACCTID"`
+	AccountType string `xml:"ACCTTYPE"`
+}
+
+type ofxTransactionList struct {
+	DTStart      string           `xml:"DTSTART"`
+	DTEnd        string           `xml:"DTEND"`
+	Transactions []ofxTransaction `xml:"STMTTRN"`
+}
+
+type ofxTransaction struct {
+	Type     string `xml:"TRNTYPE"`
+	DTPosted string `xml:"DTPOSTED"`
+	Amount   string `xml:"TRNAMT"`
+	FITID    string `xml:"FITID"`
+	Name     string `xml:"NAME"`
+	Memo     string `xml:"MEMO,omitempty"`
+}
+
+type ofxBalance struct {
+	Amount string `xml:"BALAMT"`
+	DTAsOf string `xml:"DTASOF"`
+}
+
+// WriteStatementOFX writes the statement as the OFX 2.2 bank statement
+// response, with the closing balance of the period as the ledger balance.
+func WriteStatementOFX(w io.Writer, statement *Statement, generatedAt time.Time) error {
+	account := statement.Account
+	ok := ofxStatus{Code: 0, Severity: "INFO"}
+
+	transactions := make([]ofxTransaction, len(statement.Transactions))
+	for i, txInfo := range statement.Transactions {
+		tx := txInfo.Transaction
+		txType, details := formatTransactionTypeAndDetails(account, tx)
+
+		transactions[i] = ofxTransaction{
+			Type:     ofxTransactionType(account, tx),
+			DTPosted: formatOFXDateTime(tx.CreatedAt),
+			Amount:   formatDecimal(SignedAmount(account, tx)),
+			FITID:    tx.ID.String(),
+			Name:     txType,
+			Memo:     details,
+		}
+	}
+
+	document := ofxDocument{
+		SignOn: ofxSignOn{
+			Status:   ok,
+			DTServer: formatOFXDateTime(generatedAt),
+			Language: "ENG",
+		},
+		Bank: ofxStatementResponse{
+			TransactionUID: "0",
+			Status:         ok,
+			Statement: ofxStatement{
+				Currency: Currency,
+				Account: ofxBankAccount{
+					BankID:      BankID,
+					AccountID:   account.ID.String(),
+					AccountType: "CHECKING",
+				},
+				Transactions: ofxTransactionList{
+					DTStart:      formatOFXDateTime(periodStart(statement)),
+					DTEnd:        formatOFXDateTime(statement.To),
+					Transactions: transactions,
+				},
+				LedgerBal: ofxBalance{
+					Amount: formatDecimal(statement.ClosingBalance),
+					DTAsOf: formatOFXDateTime(statement.To),
+				},
+			},
+		},
+	}
+
+	if _, err := io.WriteString(w, ofxHeader); err != nil {
+		return fmt.Errorf("failed to write OFX header: %w", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to write OFX: %w", err)
+	}
+
+	return encoder.Close()
+}
+
+// ofxTransactionType returns the OFX type of the transaction: the deposits
+// and the withdrawals are made at the ATMs, the transfers are between the
+// accounts of the bank.
+func ofxTransactionType(account *data.Account, tx *data.Transaction) string {
+	switch tx.Type {
+	case data.DepositTransaction:
+		return "DEP"
+	case data.WithdrawalTransaction:
+		return "ATM"
+	case data.TransferTransaction:
+		return "XFER"
+	}
+
+	if SignedAmount(account, tx) > 0 {
+		return "CREDIT"
+	}
+
+	return "DEBIT"
+}
+
+func formatOFXDateTime(t time.Time) string {
+	return t.UTC().Format(ofxDateTimeFormat)
+}
@@ -0,0 +1,121 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+// newTestStatement returns the statement of days 2 to 4 of the account with a
+// withdrawal, a transfer in and a transfer out in the period.
+func newTestStatement(t *testing.T) *Statement {
+	t.Helper()
+
+	account := &data.Account{Name: "Savings & <more>", Balance: 1550}
+	account.ID = uuid.New()
+	account.CreatedAt = day(1).Add(-time.Hour)
+	other := uuid.New()
+
+	newTx := func(txType data.TransactionType, sender, recipient uuid.UUID, amount uint, createdAt time.Time) *data.Transaction {
+		tx := &data.Transaction{Type: txType, Sender: sender, Recipient: recipient, Amount: amount}
+		tx.ID = uuid.New()
+		tx.CreatedAt = createdAt
+		return tx
+	}
+
+	return NewStatement(account, account.Balance, []*data.Transaction{
+		newTx(data.DepositTransaction, account.ID, account.ID, 1000, day(1)),
+		newTx(data.WithdrawalTransaction, account.ID, account.ID, 200, day(2)),
+		newTx(data.TransferTransaction, other, account.ID, 300, day(3)),
+		newTx(data.TransferTransaction, account.ID, other, 50, day(4)),
+		newTx(data.DepositTransaction, account.ID, account.ID, 500, day(5)),
+	}, day(2).Add(-time.Hour), day(5).Add(-time.Hour))
+}
+
+func TestWriteStatementCSV(t *testing.T) {
+	statement := newTestStatement(t)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, WriteStatement(buf, StatementFormatCSV, statement, day(6)))
+	assert.Contains(t, buf.String(), "\r\n", "RFC 4180 records end with CRLF")
+
+	records, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+
+	assert.Equal(t, csvHeader, records[0])
+	assert.Equal(t, []string{
+		statement.Transactions[0].Transaction.ID.String(), "2024-03-02T12:00:00Z", "Withdrawal", "-2.00", "8.00", "Withdrawal from account",
+	}, records[1])
+	assert.Equal(t, []string{"3.00", "11.00"}, records[2][3:5])
+	assert.Equal(t, []string{"-0.50", "10.50"}, records[3][3:5])
+}
+
+func TestWriteStatementOFX(t *testing.T) {
+	statement := newTestStatement(t)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, WriteStatement(buf, StatementFormatOFX, statement, day(6)))
+	assert.True(t, strings.HasPrefix(buf.String(), `<?xml version="1.0" encoding="UTF-8" standalone="no"?>`+"\n"+`<?OFX OFXHEADER="200" VERSION="220"`))
+
+	var document ofxDocument
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &document))
+
+	stmt := document.Bank.Statement
+	assert.Equal(t, statement.Account.ID.String(), stmt.Account.AccountID)
+	assert.Equal(t, "20240302110000.000[0:GMT]", stmt.Transactions.DTStart)
+	assert.Equal(t, "20240305110000.000[0:GMT]", stmt.Transactions.DTEnd)
+	assert.Equal(t, "10.50", stmt.LedgerBal.Amount)
+
+	require.Len(t, stmt.Transactions.Transactions, 3)
+	assert.Equal(t, ofxTransaction{
+		Type:     "ATM",
+		DTPosted: "20240302120000.000[0:GMT]",
+		Amount:   "-2.00",
+		FITID:    statement.Transactions[0].Transaction.ID.String(),
+		Name:     "Withdrawal",
+		Memo:     "Withdrawal from account",
+	}, stmt.Transactions.Transactions[0])
+	assert.Equal(t, "XFER", stmt.Transactions.Transactions[1].Type)
+	assert.Equal(t, "3.00", stmt.Transactions.Transactions[1].Amount)
+}
+
+func TestWriteStatementCAMT053(t *testing.T) {
+	statement := newTestStatement(t)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, WriteStatement(buf, StatementFormatCAMT053, statement, day(6)))
+	assert.Contains(t, buf.String(), `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">`)
+	assert.Contains(t, buf.String(), "<Nm>Savings &amp; &lt;more&gt;</Nm>")
+
+	var document camtDocument
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &document))
+
+	stmt := document.Statement.Statement
+	assert.LessOrEqual(t, len(stmt.ID), 35)
+	assert.Equal(t, camtID(statement.Account.ID), stmt.Account.ID)
+	assert.Equal(t, []camtBalance{
+		{Code: "OPBD", Amount: camtAmount{Currency: "USD", Value: "10.00"}, Side: "CRDT", Date: "2024-03-02T11:00:00Z"},
+		{Code: "CLBD", Amount: camtAmount{Currency: "USD", Value: "10.50"}, Side: "CRDT", Date: "2024-03-05T11:00:00Z"},
+	}, stmt.Balances)
+	assert.Equal(t, camtEntriesTotal{Count: 3, Sum: "5.50", NetAmount: "0.50", Side: "CRDT"}, stmt.Summary.Total)
+	assert.Equal(t, camtEntriesSubtotal{Count: 1, Sum: "3.00"}, stmt.Summary.Credits)
+	assert.Equal(t, camtEntriesSubtotal{Count: 2, Sum: "2.50"}, stmt.Summary.Debits)
+
+	require.Len(t, stmt.Entries, 3)
+	withdrawal := stmt.Entries[0]
+	assert.Equal(t, camtAmount{Currency: "USD", Value: "2.00"}, withdrawal.Amount)
+	assert.Equal(t, "DBIT", withdrawal.Side)
+	assert.Equal(t, camtBankTxCode{Code: "PMNT", FamilyCode: "CNTR", SubFamilyCode: "CWDL"}, withdrawal.BankTxCode)
+	assert.Equal(t, camtBankTxCode{Code: "PMNT", FamilyCode: "RCDT", SubFamilyCode: "BOOK"}, stmt.Entries[1].BankTxCode)
+	assert.Equal(t, camtBankTxCode{Code: "PMNT", FamilyCode: "ICDT", SubFamilyCode: "BOOK"}, stmt.Entries[2].BankTxCode)
+}
@@ -0,0 +1,182 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// A4 page size in points
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+)
+
+type pdfFont string
+
+// The standard Type 1 fonts every PDF reader has, so nothing is embedded
+const (
+	pdfFontRegular pdfFont = "F1"
+	pdfFontBold    pdfFont = "F2"
+)
+
+var pdfFontNames = map[pdfFont]string{
+	pdfFontRegular: "Helvetica",
+	pdfFontBold:    "Helvetica-Bold",
+}
+
+// pdfDocument is a minimal PDF 1.4 writer of text and lines on A4 pages,
+// enough for the statements without an external dependency.
+type pdfDocument struct {
+	title   string
+	created time.Time
+	pages   []*pdfPage
+}
+
+func newPDFDocument(title string, created time.Time) *pdfDocument {
+	return &pdfDocument{
+		title:   title,
+		created: created,
+	}
+}
+
+// pdfPage draws on the page with the origin in the top left corner, unlike the
+// PDF coordinates starting from the bottom left one.
+type pdfPage struct {
+	content *bytes.Buffer
+}
+
+func (d *pdfDocument) addPage() *pdfPage {
+	page := &pdfPage{content: new(bytes.Buffer)}
+	d.pages = append(d.pages, page)
+
+	return page
+}
+
+// text writes the text with its baseline at y.
+func (p *pdfPage) text(x, y float64, font pdfFont, size float64, text string) {
+	fmt.Fprintf(p.content, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		font, size, x, pdfPageHeight-y, escapePDFString(text))
+}
+
+// textRight writes the text ending at x.
+func (p *pdfPage) textRight(x, y float64, font pdfFont, size float64, text string) {
+	p.text(x-helveticaWidth(text, size), y, font, size, text)
+}
+
+func (p *pdfPage) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(p.content, "0.5 w %.2f %.2f m %.2f %.2f l S\n",
+		x1, pdfPageHeight-y1, x2, pdfPageHeight-y2)
+}
+
+// WriteTo writes the document with its cross-reference table.
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	out := new(bytes.Buffer)
+	var offsets []int
+
+	object := func(body string) int {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+		return len(offsets)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// The catalog and the page tree come first, the pages are numbered after
+	// the fonts and the info as the page and content object pairs
+	const (
+		catalogObject   = 1
+		pagesObject     = 2
+		firstPageObject = 6
+	)
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObject+2*i)
+	}
+
+	object(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObject))
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	regular := object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>",
+		pdfFontNames[pdfFontRegular]))
+	bold := object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>",
+		pdfFontNames[pdfFontBold]))
+	info := object(fmt.Sprintf("<< /Title (%s) /Producer (ilab1) /CreationDate (D:%s) >>",
+		escapePDFString(d.title), d.created.UTC().Format("20060102150405Z")))
+
+	for _, page := range d.pages {
+		content := page.content
+		pageObject := len(offsets) + 1
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /%s %d 0 R /%s %d 0 R >> >> /Contents %d 0 R >>",
+			pagesObject, pdfPageWidth, pdfPageHeight, pdfFontRegular, regular, pdfFontBold, bold, pageObject+1,
+		))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, catalogObject, info, xref)
+
+	return out.WriteTo(w)
+}
+
+// winAnsiExtras are the characters WinAnsi encodes in 0x80-0x9f instead of the
+// Latin-1 control characters.
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b,
+	'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// escapePDFString escapes the literal string and encodes it in WinAnsi, which
+// matches Latin-1 for the printable characters. The standard fonts have no
+// glyphs for the other characters, e.g. Cyrillic or CJK, so without an
+// embedded font they become "?".
+func escapePDFString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		default:
+			if c, ok := winAnsiExtras[r]; ok {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('?')
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// helveticaWidths are the Helvetica glyph widths in 1/1000 of the font size of
+// the characters of the amounts and dates, the others are taken as wide as
+// the digits.
+var helveticaWidths = map[rune]float64{
+	' ': 278, ',': 278, '.': 278, '-': 333, ':': 278, '$': 556,
+}
+
+func helveticaWidth(s string, size float64) float64 {
+	var width float64
+	for _, r := range s {
+		w, ok := helveticaWidths[r]
+		if !ok {
+			w = 556
+		}
+		width += w
+	}
+
+	return width * size / 1000
+}
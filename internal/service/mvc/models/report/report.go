@@ -2,7 +2,7 @@ package report
 
 import (
 	"fmt"
-	"sort"
+	"time"
 
 	"github.com/xuri/excelize/v2"
 
@@ -12,8 +12,18 @@ import (
 const AccountSummarySheetName = "Account Summary"
 const TransactionsHistorySheetName = "Transactions History"
 
+// ExcelFormat is the format of the Excel reports, the report jobs accept it
+// next to the statement formats
+const ExcelFormat = "xlsx"
+
+const ExcelContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// defaultSheetName is the sheet of the new excelize file
+const defaultSheetName = "Sheet1"
+
 // ExcelStyles holds styling information for Excel sheets
 type ExcelStyles struct {
+	TitleStyle    int
 	HeaderStyle   int
 	CurrencyStyle int
 	DateStyle     int
@@ -21,6 +31,17 @@ type ExcelStyles struct {
 
 // CreateExcelStyles creates and returns common styles for Excel sheets
 func CreateExcelStyles(f *excelize.File) (*ExcelStyles, error) {
+	// Title style
+	titleStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold: true,
+			Size: 14,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create title style: %w", err)
+	}
+
 	// Header style
 	headerStyle, err := f.NewStyle(&excelize.Style{
 		Font: &excelize.Font{
@@ -54,31 +75,25 @@ func CreateExcelStyles(f *excelize.File) (*ExcelStyles, error) {
 	}
 
 	return &ExcelStyles{
+		TitleStyle:    titleStyle,
 		HeaderStyle:   headerStyle,
 		CurrencyStyle: currencyStyle,
 		DateStyle:     dateStyle,
 	}, nil
 }
 
-// CreateAccountSummarySheet creates the Account Summary sheet in the Excel file
-func CreateAccountSummarySheet(f *excelize.File, account *data.Account, transactions []*data.Transaction, styles *ExcelStyles) error {
-	sheetName := AccountSummarySheetName
-	if err := f.SetSheetName("Sheet1", sheetName); err != nil {
-		return fmt.Errorf("failed to rename sheet: %w", err)
+// CreateAccountSummarySheet creates the account summary sheet in the Excel file
+// with the balances and the totals of the statement period
+func CreateAccountSummarySheet(f *excelize.File, sheetName string, statement *Statement, styles *ExcelStyles) error {
+	if err := newSheet(f, sheetName); err != nil {
+		return err
 	}
 
+	account := statement.Account
+
 	// Account information section
 	f.SetCellValue(sheetName, "A1", "Account Information")
-	titleStyle, err := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{
-			Bold: true,
-			Size: 14,
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create title style: %w", err)
-	}
-	f.SetCellStyle(sheetName, "A1", "A1", titleStyle)
+	f.SetCellStyle(sheetName, "A1", "A1", styles.TitleStyle)
 	f.MergeCell(sheetName, "A1", "B1")
 
 	// Account Details
@@ -94,75 +109,83 @@ func CreateAccountSummarySheet(f *excelize.File, account *data.Account, transact
 	f.SetCellValue(sheetName, "A6", "Last Updated:")
 	f.SetCellValue(sheetName, "B6", account.UpdatedAt.Format("Jan 02, 2006 15:04:05"))
 
-	// Account Statistics section
-	f.SetCellValue(sheetName, "A8", "Account Statistics")
-	f.SetCellStyle(sheetName, "A8", "A8", titleStyle)
+	// Statement period section
+	f.SetCellValue(sheetName, "A8", "Statement Period")
+	f.SetCellStyle(sheetName, "A8", "A8", styles.TitleStyle)
 	f.MergeCell(sheetName, "A8", "B8")
 
-	// Calculate transaction statistics
-	stats := calculateTransactionStats(account, transactions)
-
-	// Display transaction statistics
-	f.SetCellValue(sheetName, "A9", "Total Deposits:")
-	f.SetCellValue(sheetName, "B9", float64(stats.TotalDeposits)/100.0)
-	f.SetCellStyle(sheetName, "B9", "B9", styles.CurrencyStyle)
+	f.SetCellValue(sheetName, "A9", "Period:")
+	f.SetCellValue(sheetName, "B9", StatementPeriod(statement))
 
-	f.SetCellValue(sheetName, "A10", "Total Withdrawals:")
-	f.SetCellValue(sheetName, "B10", float64(stats.TotalWithdrawals)/100.0)
+	f.SetCellValue(sheetName, "A10", "Opening Balance:")
+	f.SetCellValue(sheetName, "B10", float64(statement.OpeningBalance)/100.0)
 	f.SetCellStyle(sheetName, "B10", "B10", styles.CurrencyStyle)
 
-	f.SetCellValue(sheetName, "A11", "Total Transfers In:")
-	f.SetCellValue(sheetName, "B11", float64(stats.TotalTransfersIn)/100.0)
+	f.SetCellValue(sheetName, "A11", "Closing Balance:")
+	f.SetCellValue(sheetName, "B11", float64(statement.ClosingBalance)/100.0)
 	f.SetCellStyle(sheetName, "B11", "B11", styles.CurrencyStyle)
 
-	f.SetCellValue(sheetName, "A12", "Total Transfers Out:")
-	f.SetCellValue(sheetName, "B12", float64(stats.TotalTransfersOut)/100.0)
-	f.SetCellStyle(sheetName, "B12", "B12", styles.CurrencyStyle)
+	f.SetCellValue(sheetName, "A12", "Filters:")
+	f.SetCellValue(sheetName, "B12", statement.Filter.String())
+
+	// Period Statistics section
+	f.SetCellValue(sheetName, "A14", "Period Statistics")
+	f.SetCellStyle(sheetName, "A14", "A14", styles.TitleStyle)
+	f.MergeCell(sheetName, "A14", "B14")
+
+	stats := statement.Stats
+
+	// Display transaction statistics
+	f.SetCellValue(sheetName, "A15", "Total Deposits:")
+	f.SetCellValue(sheetName, "B15", float64(stats.TotalDeposits)/100.0)
+	f.SetCellStyle(sheetName, "B15", "B15", styles.CurrencyStyle)
 
-	f.SetCellValue(sheetName, "A13", "Number of Deposits:")
-	f.SetCellValue(sheetName, "B13", stats.NumDeposits)
+	f.SetCellValue(sheetName, "A16", "Total Withdrawals:")
+	f.SetCellValue(sheetName, "B16", float64(stats.TotalWithdrawals)/100.0)
+	f.SetCellStyle(sheetName, "B16", "B16", styles.CurrencyStyle)
 
-	f.SetCellValue(sheetName, "A14", "Number of Withdrawals:")
-	f.SetCellValue(sheetName, "B14", stats.NumWithdrawals)
+	f.SetCellValue(sheetName, "A17", "Total Transfers In:")
+	f.SetCellValue(sheetName, "B17", float64(stats.TotalTransfersIn)/100.0)
+	f.SetCellStyle(sheetName, "B17", "B17", styles.CurrencyStyle)
 
-	f.SetCellValue(sheetName, "A15", "Number of Transfers In:")
-	f.SetCellValue(sheetName, "B15", stats.NumTransfersIn)
+	f.SetCellValue(sheetName, "A18", "Total Transfers Out:")
+	f.SetCellValue(sheetName, "B18", float64(stats.TotalTransfersOut)/100.0)
+	f.SetCellStyle(sheetName, "B18", "B18", styles.CurrencyStyle)
 
-	f.SetCellValue(sheetName, "A16", "Number of Transfers Out:")
-	f.SetCellValue(sheetName, "B16", stats.NumTransfersOut)
+	f.SetCellValue(sheetName, "A19", "Number of Deposits:")
+	f.SetCellValue(sheetName, "B19", stats.NumDeposits)
 
-	f.SetCellValue(sheetName, "A17", "Total Number of Transactions:")
-	f.SetCellValue(sheetName, "B17", len(transactions))
+	f.SetCellValue(sheetName, "A20", "Number of Withdrawals:")
+	f.SetCellValue(sheetName, "B20", stats.NumWithdrawals)
+
+	f.SetCellValue(sheetName, "A21", "Number of Transfers In:")
+	f.SetCellValue(sheetName, "B21", stats.NumTransfersIn)
+
+	f.SetCellValue(sheetName, "A22", "Number of Transfers Out:")
+	f.SetCellValue(sheetName, "B22", stats.NumTransfersOut)
+
+	f.SetCellValue(sheetName, "A23", "Total Number of Transactions:")
+	f.SetCellValue(sheetName, "B23", stats.NumTransactions)
 
 	// Add account activity summary (if there are transactions)
-	if len(transactions) > 0 {
-		f.SetCellValue(sheetName, "A19", "Account Activity Summary")
-		f.SetCellStyle(sheetName, "A19", "A19", titleStyle)
-		f.MergeCell(sheetName, "A19", "B19")
-
-		var oldestTx, newestTx *data.Transaction
-		for i, tx := range transactions {
-			if i == 0 || tx.CreatedAt.Before(oldestTx.CreatedAt) {
-				oldestTx = tx
-			}
-			if i == 0 || tx.CreatedAt.After(newestTx.CreatedAt) {
-				newestTx = tx
-			}
-		}
+	if stats.NumTransactions > 0 {
+		f.SetCellValue(sheetName, "A25", "Account Activity Summary")
+		f.SetCellStyle(sheetName, "A25", "A25", styles.TitleStyle)
+		f.MergeCell(sheetName, "A25", "B25")
 
-		f.SetCellValue(sheetName, "A20", "First Transaction:")
-		f.SetCellValue(sheetName, "B20", oldestTx.CreatedAt.Format("Jan 02, 2006"))
+		f.SetCellValue(sheetName, "A26", "First Transaction:")
+		f.SetCellValue(sheetName, "B26", stats.FirstTransactionAt.Format("Jan 02, 2006"))
 
-		f.SetCellValue(sheetName, "A21", "Latest Transaction:")
-		f.SetCellValue(sheetName, "B21", newestTx.CreatedAt.Format("Jan 02, 2006"))
+		f.SetCellValue(sheetName, "A27", "Latest Transaction:")
+		f.SetCellValue(sheetName, "B27", stats.LastTransactionAt.Format("Jan 02, 2006"))
 
-		daysActive := int(newestTx.CreatedAt.Sub(oldestTx.CreatedAt).Hours()/24) + 1
-		f.SetCellValue(sheetName, "A22", "Days Account Active:")
-		f.SetCellValue(sheetName, "B22", daysActive)
+		daysActive := int(stats.LastTransactionAt.Sub(stats.FirstTransactionAt).Hours()/24) + 1
+		f.SetCellValue(sheetName, "A28", "Days Account Active:")
+		f.SetCellValue(sheetName, "B28", daysActive)
 
-		txPerDay := float64(len(transactions)) / float64(daysActive)
-		f.SetCellValue(sheetName, "A23", "Average Transactions Per Day:")
-		f.SetCellValue(sheetName, "B23", txPerDay)
+		txPerDay := float64(stats.NumTransactions) / float64(daysActive)
+		f.SetCellValue(sheetName, "A29", "Average Transactions Per Day:")
+		f.SetCellValue(sheetName, "B29", txPerDay)
 	}
 
 	// Auto-fit columns
@@ -186,6 +209,10 @@ type TransactionStats struct {
 	NumWithdrawals    int
 	NumTransfersIn    int
 	NumTransfersOut   int
+
+	NumTransactions    int
+	FirstTransactionAt time.Time
+	LastTransactionAt  time.Time
 }
 
 // calculateTransactionStats calculates transaction statistics for an account
@@ -193,86 +220,80 @@ func calculateTransactionStats(account *data.Account, transactions []*data.Trans
 	stats := &TransactionStats{}
 
 	for _, tx := range transactions {
-		switch tx.Type {
-		case data.DepositTransaction:
-			stats.TotalDeposits += int(tx.Amount)
-			stats.NumDeposits++
-		case data.WithdrawalTransaction:
-			stats.TotalWithdrawals += int(tx.Amount)
-			stats.NumWithdrawals++
-		case data.TransferTransaction:
-			if tx.Recipient == account.ID {
-				stats.TotalTransfersIn += int(tx.Amount)
-				stats.NumTransfersIn++
-			} else {
-				stats.TotalTransfersOut += int(tx.Amount)
-				stats.NumTransfersOut++
-			}
-		}
+		stats.add(account, tx)
 	}
 
 	return stats
 }
 
-// CreateTransactionHistorySheet creates the Transaction History sheet in the Excel file
-func CreateTransactionHistorySheet(f *excelize.File, account *data.Account, transactions []*data.Transaction, styles *ExcelStyles) error {
-	sheetName := TransactionsHistorySheetName
-	f.NewSheet(sheetName)
-
-	// Set transaction headers
-	headers := []string{"Date", "Type", "Amount", "Balance After", "Details"}
-	for i, header := range headers {
-		cell := fmt.Sprintf("%c1", 'A'+i)
-		f.SetCellValue(sheetName, cell, header)
-		f.SetCellStyle(sheetName, cell, cell, styles.HeaderStyle)
+// add counts the transaction of the account in the statistics
+func (stats *TransactionStats) add(account *data.Account, tx *data.Transaction) {
+	switch tx.Type {
+	case data.DepositTransaction:
+		stats.TotalDeposits += int(tx.Amount)
+		stats.NumDeposits++
+	case data.WithdrawalTransaction:
+		stats.TotalWithdrawals += int(tx.Amount)
+		stats.NumWithdrawals++
+	case data.TransferTransaction:
+		if tx.Recipient == account.ID {
+			stats.TotalTransfersIn += int(tx.Amount)
+			stats.NumTransfersIn++
+		} else {
+			stats.TotalTransfersOut += int(tx.Amount)
+			stats.NumTransfersOut++
+		}
 	}
 
-	// Sort transactions chronologically (newest first)
-	sortedTx := make([]*data.Transaction, len(transactions))
-	copy(sortedTx, transactions)
-	sort.Slice(sortedTx, func(i, j int) bool {
-		return sortedTx[i].CreatedAt.After(sortedTx[j].CreatedAt)
-	})
-
-	// Calculate historical balances
-	txWithBalance := calculateHistoricalBalances(account, sortedTx)
+	if stats.NumTransactions == 0 || tx.CreatedAt.Before(stats.FirstTransactionAt) {
+		stats.FirstTransactionAt = tx.CreatedAt
+	}
+	if stats.NumTransactions == 0 || tx.CreatedAt.After(stats.LastTransactionAt) {
+		stats.LastTransactionAt = tx.CreatedAt
+	}
+	stats.NumTransactions++
+}
 
-	// Add transaction data
-	for i, txInfo := range txWithBalance {
-		row := i + 2 // Start from row 2 (after header)
-		tx := txInfo.Transaction
+// CreateTransactionHistorySheet creates the transaction history sheet in the
+// Excel file with the transactions of the statement, newest first
+func CreateTransactionHistorySheet(f *excelize.File, sheetName string, statement *Statement, styles *ExcelStyles) error {
+	if err := newSheet(f, sheetName); err != nil {
+		return err
+	}
 
-		// Format transaction type and details
-		txType, details := formatTransactionTypeAndDetails(account, tx)
+	history, err := newHistoryWriter(f, sheetName, statement.Account, styles)
+	if err != nil {
+		return err
+	}
 
-		// Set amount with sign
-		var amount float64
-		if tx.Type == data.DepositTransaction || (tx.Type == data.TransferTransaction && tx.Recipient == account.ID) {
-			amount = float64(tx.Amount) / 100.0
-		} else {
-			amount = -float64(tx.Amount) / 100.0
+	// The statement is in chronological order
+	for i := len(statement.Transactions) - 1; i >= 0; i-- {
+		if err = history.writeRow(statement.Transactions[i]); err != nil {
+			return err
 		}
+	}
+
+	return history.flush()
+}
 
-		// Add transaction row
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), tx.CreatedAt.Format("Jan 02, 2006 15:04:05"))
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), txType)
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), amount)
-		f.SetCellStyle(sheetName, fmt.Sprintf("C%d", row), fmt.Sprintf("C%d", row), styles.CurrencyStyle)
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), float64(txInfo.BalanceAfter)/100.0)
-		f.SetCellStyle(sheetName, fmt.Sprintf("D%d", row), fmt.Sprintf("D%d", row), styles.CurrencyStyle)
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), details)
+// newSheet adds the sheet to the Excel file, the first sheet takes the place
+// of the default one of the new file
+func newSheet(f *excelize.File, sheetName string) error {
+	index, err := f.GetSheetIndex(defaultSheetName)
+	if err != nil {
+		return fmt.Errorf("failed to get default sheet: %w", err)
 	}
 
-	// Auto-fit columns
-	for col := 'A'; col <= 'E'; col++ {
-		colName := string(col)
-		width := 20.0
-		if col == 'E' {
-			width = 55.0 // Make details column wider
-		}
-		if err := f.SetColWidth(sheetName, colName, colName, width); err != nil {
-			return fmt.Errorf("failed to set column width: %w", err)
+	if index != -1 {
+		if err = f.SetSheetName(defaultSheetName, sheetName); err != nil {
+			return fmt.Errorf("failed to rename sheet: %w", err)
 		}
+
+		return nil
+	}
+
+	if _, err = f.NewSheet(sheetName); err != nil {
+		return fmt.Errorf("failed to create sheet: %w", err)
 	}
 
 	return nil
@@ -285,11 +306,12 @@ type TransactionWithBalance struct {
 }
 
 // calculateHistoricalBalances calculates the balance after each transaction
-func calculateHistoricalBalances(account *data.Account, transactions []*data.Transaction) []TransactionWithBalance {
+// from the balance after the newest one
+func calculateHistoricalBalances(account *data.Account, balance int, transactions []*data.Transaction) []TransactionWithBalance {
 	result := make([]TransactionWithBalance, len(transactions))
 
 	// Start with current balance and work backwards through sorted transactions (newest first)
-	runningBalance := account.Balance
+	runningBalance := balance
 
 	for i, tx := range transactions {
 		// Calculate balance before this transaction
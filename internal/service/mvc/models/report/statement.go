@@ -0,0 +1,150 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+// Statement is the account history of the period with the balances at its
+// start and end, the base of the printable statements.
+type Statement struct {
+	Account *data.Account
+	// From and To bound the period, From inclusive and To exclusive. The zero
+	// From is the start of the account history.
+	From time.Time
+	To   time.Time
+
+	OpeningBalance int
+	ClosingBalance int
+	// Transactions of the period in chronological order (oldest first)
+	Transactions []TransactionWithBalance
+	Stats        *TransactionStats
+	// Filter applied to the transactions, see ApplyFilter
+	Filter TransactionFilter
+}
+
+// TransactionFilter selects the transactions of the statement by the type and
+// the amount, the nil fields match any transaction.
+type TransactionFilter struct {
+	Type      *data.TransactionType
+	MinAmount *uint
+	MaxAmount *uint
+}
+
+func (f TransactionFilter) matches(tx *data.Transaction) bool {
+	return (f.Type == nil || tx.Type == *f.Type) &&
+		(f.MinAmount == nil || tx.Amount >= *f.MinAmount) &&
+		(f.MaxAmount == nil || tx.Amount <= *f.MaxAmount)
+}
+
+// String describes the filter for the reports, "None" if it matches everything.
+func (f TransactionFilter) String() string {
+	var parts []string
+	if f.Type != nil {
+		parts = append(parts, fmt.Sprintf("type %s", f.Type))
+	}
+	if f.MinAmount != nil {
+		parts = append(parts, fmt.Sprintf("amount from %s", FormatAmount(int(*f.MinAmount))))
+	}
+	if f.MaxAmount != nil {
+		parts = append(parts, fmt.Sprintf("amount up to %s", FormatAmount(int(*f.MaxAmount))))
+	}
+
+	if len(parts) == 0 {
+		return "None"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// NewStatement selects the transactions of the period from all the account
+// transactions. The balances are calculated backwards from the balance made
+// by all the transactions, the same way as in the transaction history sheet:
+// it differs from the balance of the account by the cash-out holds.
+func NewStatement(account *data.Account, balance int, transactions []*data.Transaction, from, to time.Time) *Statement {
+	// Sort transactions chronologically (newest first)
+	sortedTx := make([]*data.Transaction, len(transactions))
+	copy(sortedTx, transactions)
+	sort.Slice(sortedTx, func(i, j int) bool {
+		return sortedTx[i].CreatedAt.After(sortedTx[j].CreatedAt)
+	})
+
+	txWithBalance := calculateHistoricalBalances(account, balance, sortedTx)
+
+	statement := &Statement{
+		Account:        account,
+		From:           from,
+		To:             to,
+		OpeningBalance: balance,
+		ClosingBalance: balance,
+	}
+
+	periodTx := make([]*data.Transaction, 0)
+
+	// Walking back from now, the balance before each transaction is the
+	// closing balance while after the period and the opening one within it
+	for _, txInfo := range txWithBalance {
+		tx := txInfo.Transaction
+
+		if tx.CreatedAt.Before(from) {
+			break
+		}
+
+		if !tx.CreatedAt.Before(to) {
+			statement.ClosingBalance = balanceBefore(account, txInfo)
+			statement.OpeningBalance = statement.ClosingBalance
+			continue
+		}
+
+		statement.Transactions = append(statement.Transactions, txInfo)
+		periodTx = append(periodTx, tx)
+		statement.OpeningBalance = balanceBefore(account, txInfo)
+	}
+
+	// Chronological order for the statement
+	for i, j := 0, len(statement.Transactions)-1; i < j; i, j = i+1, j-1 {
+		statement.Transactions[i], statement.Transactions[j] = statement.Transactions[j], statement.Transactions[i]
+	}
+
+	statement.Stats = calculateTransactionStats(account, periodTx)
+
+	return statement
+}
+
+// ApplyFilter keeps the transactions matching the filter and recalculates the
+// totals. The balances stay those of the account, as the filtered out
+// transactions still changed them.
+func (s *Statement) ApplyFilter(filter TransactionFilter) {
+	kept := make([]TransactionWithBalance, 0, len(s.Transactions))
+	keptTx := make([]*data.Transaction, 0, len(s.Transactions))
+
+	for _, txInfo := range s.Transactions {
+		if filter.matches(txInfo.Transaction) {
+			kept = append(kept, txInfo)
+			keptTx = append(keptTx, txInfo.Transaction)
+		}
+	}
+
+	s.Transactions = kept
+	s.Stats = calculateTransactionStats(s.Account, keptTx)
+	s.Filter = filter
+}
+
+// SignedAmount returns the amount of the transaction as seen by the account:
+// positive for the money coming in and negative for the money going out.
+func SignedAmount(account *data.Account, tx *data.Transaction) int {
+	if tx.Type == data.DepositTransaction || (tx.Type == data.TransferTransaction && tx.Recipient == account.ID) {
+		return int(tx.Amount)
+	}
+
+	return -int(tx.Amount)
+}
+
+// balanceBefore returns the balance of the account before the transaction.
+func balanceBefore(account *data.Account, txInfo TransactionWithBalance) int {
+	return txInfo.BalanceAfter - SignedAmount(account, txInfo.Transaction)
+}
@@ -0,0 +1,176 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Layout of the statement pages in points, from the top left corner
+const (
+	pdfMargin       = 50.0
+	pdfContentWidth = pdfPageWidth - 2*pdfMargin
+	pdfBottom       = pdfPageHeight - 70.0
+	pdfFooterY      = pdfPageHeight - 35.0
+
+	pdfTitleSize = 16.0
+	pdfTextSize  = 10.0
+	pdfTableSize = 8.0
+	pdfLineStep  = 14.0
+	pdfRowStep   = 12.0
+
+	// Left edges of the date, type and details columns and right edges of
+	// the amount and balance ones
+	pdfDateX    = pdfMargin
+	pdfTypeX    = pdfMargin + 85
+	pdfDetailsX = pdfMargin + 145
+	pdfAmountX  = pdfMargin + 420
+	pdfBalanceX = pdfMargin + pdfContentWidth
+
+	pdfDetailsWidth = pdfAmountX - 70 - pdfDetailsX
+)
+
+const statementDateFormat = "Jan 02, 2006"
+
+// WriteStatementPDF writes the statement as an A4 PDF: the account header with
+// the period, the opening and closing balances and the period totals on the
+// first page, then the transactions with the running balance, continued on
+// as many pages as needed with the column headers repeated on each of them.
+func WriteStatementPDF(w io.Writer, statement *Statement, generatedAt time.Time) error {
+	account := statement.Account
+	stats := statement.Stats
+
+	doc := newPDFDocument(fmt.Sprintf("Statement of account %s", account.ID), generatedAt)
+	page := doc.addPage()
+
+	y := pdfMargin + pdfTitleSize
+	page.text(pdfMargin, y, pdfFontBold, pdfTitleSize, "Account Statement")
+	y += 2 * pdfLineStep
+
+	details := [][2]string{
+		{"Account Name:", account.Name},
+		{"Account ID:", account.ID.String()},
+		{"Period:", StatementPeriod(statement)},
+		{"Generated At:", generatedAt.UTC().Format("Jan 02, 2006 15:04:05 MST")},
+	}
+	for _, detail := range details {
+		page.text(pdfMargin, y, pdfFontBold, pdfTextSize, detail[0])
+		page.text(pdfMargin+100, y, pdfFontRegular, pdfTextSize, detail[1])
+		y += pdfLineStep
+	}
+	y += pdfLineStep
+
+	// Balances and totals, in two columns
+	summary := [][2]string{
+		{"Opening Balance:", FormatAmount(statement.OpeningBalance)},
+		{"Closing Balance:", FormatAmount(statement.ClosingBalance)},
+		{fmt.Sprintf("Deposits (%d):", stats.NumDeposits), FormatAmount(stats.TotalDeposits)},
+		{fmt.Sprintf("Withdrawals (%d):", stats.NumWithdrawals), FormatAmount(-stats.TotalWithdrawals)},
+		{fmt.Sprintf("Transfers In (%d):", stats.NumTransfersIn), FormatAmount(stats.TotalTransfersIn)},
+		{fmt.Sprintf("Transfers Out (%d):", stats.NumTransfersOut), FormatAmount(-stats.TotalTransfersOut)},
+	}
+	for i := 0; i < len(summary); i += 2 {
+		for j, item := range summary[i : i+2] {
+			x := pdfMargin + float64(j)*pdfContentWidth/2
+			page.text(x, y, pdfFontBold, pdfTextSize, item[0])
+			page.textRight(x+pdfContentWidth/2-20, y, pdfFontRegular, pdfTextSize, item[1])
+		}
+		y += pdfLineStep
+	}
+	y += pdfLineStep
+
+	y = drawStatementTableHeader(page, y)
+
+	if len(statement.Transactions) == 0 {
+		page.text(pdfMargin, y, pdfFontRegular, pdfTableSize, "No transactions in the period.")
+	}
+
+	for _, txInfo := range statement.Transactions {
+		if y > pdfBottom {
+			page = doc.addPage()
+			y = drawStatementTableHeader(page, pdfMargin+pdfTableSize)
+		}
+
+		tx := txInfo.Transaction
+		txType, txDetails := formatTransactionTypeAndDetails(account, tx)
+
+		page.text(pdfDateX, y, pdfFontRegular, pdfTableSize, tx.CreatedAt.Format("Jan 02, 2006 15:04"))
+		page.text(pdfTypeX, y, pdfFontRegular, pdfTableSize, txType)
+		page.text(pdfDetailsX, y, pdfFontRegular, pdfTableSize, truncateText(txDetails, pdfDetailsWidth, pdfTableSize))
+		page.textRight(pdfAmountX, y, pdfFontRegular, pdfTableSize, FormatAmount(SignedAmount(account, tx)))
+		page.textRight(pdfBalanceX, y, pdfFontRegular, pdfTableSize, FormatAmount(txInfo.BalanceAfter))
+		y += pdfRowStep
+	}
+
+	for i, p := range doc.pages {
+		p.line(pdfMargin, pdfFooterY-pdfRowStep, pdfMargin+pdfContentWidth, pdfFooterY-pdfRowStep)
+		p.text(pdfMargin, pdfFooterY, pdfFontRegular, pdfTableSize, fmt.Sprintf("Account %s", account.ID))
+		p.textRight(pdfBalanceX, pdfFooterY, pdfFontRegular, pdfTableSize, fmt.Sprintf("Page %d of %d", i+1, len(doc.pages)))
+	}
+
+	if _, err := doc.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+
+	return nil
+}
+
+// drawStatementTableHeader draws the column headers with the baseline at y and
+// returns the baseline of the first row.
+func drawStatementTableHeader(page *pdfPage, y float64) float64 {
+	page.text(pdfDateX, y, pdfFontBold, pdfTableSize, "Date")
+	page.text(pdfTypeX, y, pdfFontBold, pdfTableSize, "Type")
+	page.text(pdfDetailsX, y, pdfFontBold, pdfTableSize, "Details")
+	page.textRight(pdfAmountX, y, pdfFontBold, pdfTableSize, "Amount")
+	page.textRight(pdfBalanceX, y, pdfFontBold, pdfTableSize, "Balance After")
+	page.line(pdfMargin, y+4, pdfMargin+pdfContentWidth, y+4)
+
+	return y + 4 + pdfRowStep
+}
+
+// truncateText shortens the text to fit the width, marking the cut with "...".
+func truncateText(text string, width, size float64) string {
+	if helveticaWidth(text, size) <= width {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 && helveticaWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+
+	return string(runes) + "..."
+}
+
+// StatementPeriod returns the period of the statement as the first and the
+// last days, inclusive.
+func StatementPeriod(statement *Statement) string {
+	from := "Account opening"
+	if !statement.From.IsZero() {
+		from = statement.From.Format(statementDateFormat)
+	}
+
+	// To is exclusive, the period ends the day before
+	return fmt.Sprintf("%s - %s", from, statement.To.Add(-time.Nanosecond).Format(statementDateFormat))
+}
+
+// FormatAmount formats the cents the way the currency cells of the Excel
+// reports are formatted, "$#,##0.00;-$#,##0.00".
+func FormatAmount(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	whole := fmt.Sprint(cents / 100)
+	var groups []string
+	for len(whole) > 3 {
+		groups = append([]string{whole[len(whole)-3:]}, groups...)
+		whole = whole[:len(whole)-3]
+	}
+	groups = append([]string{whole}, groups...)
+
+	return fmt.Sprintf("%s$%s.%02d", sign, strings.Join(groups, ","), cents%100)
+}
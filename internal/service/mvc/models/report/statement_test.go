@@ -0,0 +1,183 @@
+package report
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, time.March, d, 12, 0, 0, 0, time.UTC)
+}
+
+func TestNewStatement(t *testing.T) {
+	account := &data.Account{Name: "Savings"}
+	account.ID = uuid.New()
+	other := uuid.New()
+
+	newTx := func(txType data.TransactionType, sender, recipient uuid.UUID, amount uint, createdAt time.Time) *data.Transaction {
+		tx := &data.Transaction{Type: txType, Sender: sender, Recipient: recipient, Amount: amount}
+		tx.CreatedAt = createdAt
+		return tx
+	}
+
+	// 0 -> 1000 -> 800 -> 1100 -> 1050 -> 1550
+	transactions := []*data.Transaction{
+		newTx(data.TransferTransaction, account.ID, other, 50, day(4)),
+		newTx(data.DepositTransaction, account.ID, account.ID, 1000, day(1)),
+		newTx(data.DepositTransaction, account.ID, account.ID, 500, day(5)),
+		newTx(data.WithdrawalTransaction, account.ID, account.ID, 200, day(2)),
+		newTx(data.TransferTransaction, other, account.ID, 300, day(3)),
+	}
+	account.Balance = 1550
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		opening  int
+		closing  int
+		amounts  []int
+		balances []int
+	}{
+		{
+			name:     "whole history",
+			to:       day(6),
+			opening:  0,
+			closing:  1550,
+			amounts:  []int{1000, -200, 300, -50, 500},
+			balances: []int{1000, 800, 1100, 1050, 1550},
+		},
+		{
+			name:     "middle of history",
+			from:     day(2).Add(-time.Hour),
+			to:       day(4),
+			opening:  1000,
+			closing:  1100,
+			amounts:  []int{-200, 300},
+			balances: []int{800, 1100},
+		},
+		{
+			name:    "no transactions in the period",
+			from:    day(3).Add(time.Hour),
+			to:      day(4).Add(-time.Hour),
+			opening: 1100,
+			closing: 1100,
+		},
+		{
+			name:    "before the first transaction",
+			to:      day(1).Add(-time.Hour),
+			opening: 0,
+			closing: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement := NewStatement(account, account.Balance, transactions, tt.from, tt.to)
+
+			assert.Equal(t, tt.opening, statement.OpeningBalance)
+			assert.Equal(t, tt.closing, statement.ClosingBalance)
+			require.Len(t, statement.Transactions, len(tt.amounts))
+
+			for i, txInfo := range statement.Transactions {
+				assert.Equal(t, tt.amounts[i], SignedAmount(account, txInfo.Transaction))
+				assert.Equal(t, tt.balances[i], txInfo.BalanceAfter)
+			}
+		})
+	}
+
+	stats := NewStatement(account, account.Balance, transactions, day(2).Add(-time.Hour), day(6)).Stats
+	assert.Equal(t, &TransactionStats{
+		TotalDeposits:     500,
+		TotalWithdrawals:  200,
+		TotalTransfersIn:  300,
+		TotalTransfersOut: 50,
+		NumDeposits:       1,
+		NumWithdrawals:    1,
+		NumTransfersIn:    1,
+		NumTransfersOut:   1,
+
+		NumTransactions:    4,
+		FirstTransactionAt: day(2),
+		LastTransactionAt:  day(5),
+	}, stats)
+}
+
+func TestWriteStatementPDF(t *testing.T) {
+	account := &data.Account{Name: "Business (main)", Balance: 0}
+	account.ID = uuid.New()
+
+	transactions := make([]*data.Transaction, 200)
+	for i := range transactions {
+		tx := &data.Transaction{Type: data.DepositTransaction, Sender: account.ID, Recipient: account.ID, Amount: 100}
+		tx.CreatedAt = day(1).Add(time.Duration(i) * time.Minute)
+		transactions[i] = tx
+		account.Balance += 100
+	}
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, WriteStatementPDF(buf, NewStatement(account, account.Balance, transactions, time.Time{}, day(2)), day(2)))
+	pdf := buf.Bytes()
+
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), `(Business \(main\))`)
+	assert.Contains(t, string(pdf), "($200.00)", "closing balance")
+
+	pages := regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`).FindSubmatch(pdf)
+	require.NotNil(t, pages)
+	count, err := strconv.Atoi(string(pages[1]))
+	require.NoError(t, err)
+	assert.Greater(t, count, 1, "200 transactions must not fit on one page")
+	assert.Contains(t, string(pdf), "(Page "+strconv.Itoa(count)+" of "+strconv.Itoa(count)+")")
+
+	// Every object must be at the offset the cross-reference table points to
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	require.NotNil(t, startxref)
+	xref, err := strconv.Atoi(string(startxref[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(pdf[xref:], []byte("xref\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(pdf[xref:], -1)
+	require.Len(t, entries, 5+2*count)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(string(entry[1]))
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(pdf[offset:], []byte(strconv.Itoa(i+1)+" 0 obj\n")), "object %d", i+1)
+	}
+}
+
+func TestEscapePDFString(t *testing.T) {
+	tests := []struct {
+		text    string
+		escaped string
+	}{
+		{"Business (main)", `Business \(main\)`},
+		{`C:\path`, `C:\\path`},
+		{"Café Zürich", "Caf\xe9 Z\xfcrich"},
+		{"€5 – “main”", "\x805 \x96 \x93main\x94"},
+		// Not in WinAnsi, the standard fonts have no glyphs for them
+		{"Счёт 1", "???? 1"},
+		{"口座 🏦", "?? ?"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.escaped, escapePDFString(tt.text), tt.text)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "$0.00", FormatAmount(0))
+	assert.Equal(t, "$0.05", FormatAmount(5))
+	assert.Equal(t, "$999.99", FormatAmount(99999))
+	assert.Equal(t, "$1,000.00", FormatAmount(100000))
+	assert.Equal(t, "-$1,234,567.89", FormatAmount(-123456789))
+}
@@ -0,0 +1,176 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+// TransactionCursor calls fn for the transactions one by one, such as
+// data.Transactions.Each.
+type TransactionCursor func(fn func(*data.Transaction) error) error
+
+// WriteExcelReport streams the Excel report of the account for the period to
+// w. The cursor must return the transactions of the account since the start
+// of the period, newest first: the history rows are written while they are
+// read and the summary sheet is filled once the totals are known, so the
+// memory used does not grow with the number of transactions. The balances are
+// calculated back from balance, see NewStatement.
+func WriteExcelReport(
+	w io.Writer,
+	account *data.Account,
+	balance int,
+	from, to time.Time,
+	filter TransactionFilter,
+	cursor TransactionCursor,
+) error {
+	f := excelize.NewFile()
+	// Removes the temporary files of the stream
+	defer f.Close()
+
+	styles, err := CreateExcelStyles(f)
+	if err != nil {
+		return fmt.Errorf("failed to create styles: %w", err)
+	}
+
+	// The summary comes first in the workbook, but is filled at the end
+	if err = newSheet(f, AccountSummarySheetName); err != nil {
+		return err
+	}
+
+	if err = newSheet(f, TransactionsHistorySheetName); err != nil {
+		return err
+	}
+
+	history, err := newHistoryWriter(f, TransactionsHistorySheetName, account, styles)
+	if err != nil {
+		return err
+	}
+
+	statement := &Statement{
+		Account:        account,
+		From:           from,
+		To:             to,
+		OpeningBalance: balance,
+		ClosingBalance: balance,
+		Stats:          &TransactionStats{},
+		Filter:         filter,
+	}
+
+	// Walking back from now, the same way as NewStatement
+	err = cursor(func(tx *data.Transaction) error {
+		txInfo := TransactionWithBalance{Transaction: tx, BalanceAfter: balance}
+		balance = balanceBefore(account, txInfo)
+
+		if tx.CreatedAt.Before(from) {
+			return nil
+		}
+
+		if !tx.CreatedAt.Before(to) {
+			statement.ClosingBalance = balance
+			statement.OpeningBalance = balance
+			return nil
+		}
+
+		statement.OpeningBalance = balance
+		if !filter.matches(tx) {
+			return nil
+		}
+
+		statement.Stats.add(account, tx)
+
+		return history.writeRow(txInfo)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write transaction history: %w", err)
+	}
+
+	if err = history.flush(); err != nil {
+		return err
+	}
+
+	if err = CreateAccountSummarySheet(f, AccountSummarySheetName, statement, styles); err != nil {
+		return fmt.Errorf("failed to create account summary sheet: %w", err)
+	}
+
+	if err = f.Write(w); err != nil {
+		return fmt.Errorf("failed to write Excel file: %w", err)
+	}
+
+	return nil
+}
+
+// historyWriter writes the rows of the transaction history sheet with the
+// StreamWriter of excelize, which keeps the rows in a temporary file instead
+// of the memory once they outgrow its buffer.
+type historyWriter struct {
+	stream  *excelize.StreamWriter
+	account *data.Account
+	styles  *ExcelStyles
+	row     int
+}
+
+func newHistoryWriter(f *excelize.File, sheetName string, account *data.Account, styles *ExcelStyles) (*historyWriter, error) {
+	stream, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream writer: %w", err)
+	}
+
+	// The widths must be set before the first row
+	if err = stream.SetColWidth(1, 4, 20); err != nil {
+		return nil, fmt.Errorf("failed to set column width: %w", err)
+	}
+	// Make details column wider
+	if err = stream.SetColWidth(5, 5, 55); err != nil {
+		return nil, fmt.Errorf("failed to set column width: %w", err)
+	}
+
+	headers := []string{"Date", "Type", "Amount", "Balance After", "Details"}
+	values := make([]interface{}, len(headers))
+	for i, header := range headers {
+		values[i] = excelize.Cell{StyleID: styles.HeaderStyle, Value: header}
+	}
+
+	if err = stream.SetRow("A1", values); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	return &historyWriter{
+		stream:  stream,
+		account: account,
+		styles:  styles,
+		row:     2, // Start from row 2 (after header)
+	}, nil
+}
+
+func (h *historyWriter) writeRow(txInfo TransactionWithBalance) error {
+	tx := txInfo.Transaction
+	txType, details := formatTransactionTypeAndDetails(h.account, tx)
+
+	err := h.stream.SetRow(fmt.Sprintf("A%d", h.row), []interface{}{
+		tx.CreatedAt.Format("Jan 02, 2006 15:04:05"),
+		txType,
+		excelize.Cell{StyleID: h.styles.CurrencyStyle, Value: float64(SignedAmount(h.account, tx)) / 100.0},
+		excelize.Cell{StyleID: h.styles.CurrencyStyle, Value: float64(txInfo.BalanceAfter) / 100.0},
+		details,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write transaction row: %w", err)
+	}
+
+	h.row++
+
+	return nil
+}
+
+func (h *historyWriter) flush() error {
+	if err := h.stream.Flush(); err != nil {
+		return fmt.Errorf("failed to flush transaction history: %w", err)
+	}
+
+	return nil
+}
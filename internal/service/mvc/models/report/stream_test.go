@@ -0,0 +1,165 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+// sliceCursor returns the cursor over the transactions in chronological
+// order, reading them newest first.
+func sliceCursor(transactions []*data.Transaction) TransactionCursor {
+	return func(fn func(*data.Transaction) error) error {
+		for i := len(transactions) - 1; i >= 0; i-- {
+			if err := fn(transactions[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func TestWriteExcelReport(t *testing.T) {
+	statement := newTestStatement(t)
+	account := statement.Account
+	other := uuid.New()
+
+	newTx := func(txType data.TransactionType, sender, recipient uuid.UUID, amount uint, createdAt time.Time) *data.Transaction {
+		tx := &data.Transaction{Type: txType, Sender: sender, Recipient: recipient, Amount: amount}
+		tx.ID = uuid.New()
+		tx.CreatedAt = createdAt
+		return tx
+	}
+
+	// The same history as the test statement, oldest first
+	transactions := []*data.Transaction{
+		newTx(data.DepositTransaction, account.ID, account.ID, 1000, day(1)),
+		newTx(data.WithdrawalTransaction, account.ID, account.ID, 200, day(2)),
+		newTx(data.TransferTransaction, other, account.ID, 300, day(3)),
+		newTx(data.TransferTransaction, account.ID, other, 50, day(4)),
+		newTx(data.DepositTransaction, account.ID, account.ID, 500, day(5)),
+	}
+
+	read := func(t *testing.T, filter TransactionFilter) *excelize.File {
+		buf := new(bytes.Buffer)
+		err := WriteExcelReport(buf, account, account.Balance, statement.From, statement.To, filter, sliceCursor(transactions))
+		require.NoError(t, err)
+
+		f, err := excelize.OpenReader(buf)
+		require.NoError(t, err)
+		t.Cleanup(func() { f.Close() })
+
+		return f
+	}
+
+	t.Run("period", func(t *testing.T) {
+		f := read(t, TransactionFilter{})
+		assert.Equal(t, []string{AccountSummarySheetName, TransactionsHistorySheetName}, f.GetSheetList())
+
+		opening, err := f.GetCellValue(AccountSummarySheetName, "B10", excelize.Options{RawCellValue: true})
+		require.NoError(t, err)
+		assert.Equal(t, strconv.FormatFloat(float64(statement.OpeningBalance)/100, 'f', -1, 64), opening)
+
+		closing, err := f.GetCellValue(AccountSummarySheetName, "B11", excelize.Options{RawCellValue: true})
+		require.NoError(t, err)
+		assert.Equal(t, strconv.FormatFloat(float64(statement.ClosingBalance)/100, 'f', -1, 64), closing)
+
+		count, err := f.GetCellValue(AccountSummarySheetName, "B23")
+		require.NoError(t, err)
+		assert.Equal(t, "3", count)
+
+		rows, err := f.GetRows(TransactionsHistorySheetName, excelize.Options{RawCellValue: true})
+		require.NoError(t, err)
+		require.Len(t, rows, 1+len(statement.Transactions))
+
+		// Newest first, the same balances as the statement
+		for i, txInfo := range statement.Transactions {
+			row := rows[len(rows)-1-i]
+			assert.Equal(t, strconv.FormatFloat(float64(txInfo.BalanceAfter)/100, 'f', -1, 64), row[3])
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		withdrawal := data.WithdrawalTransaction
+		f := read(t, TransactionFilter{Type: &withdrawal})
+
+		rows, err := f.GetRows(TransactionsHistorySheetName)
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.Equal(t, "Withdrawal", rows[1][1])
+
+		filters, err := f.GetCellValue(AccountSummarySheetName, "B12")
+		require.NoError(t, err)
+		assert.Equal(t, "type withdrawal", filters)
+	})
+
+	t.Run("cursor error", func(t *testing.T) {
+		failing := func(fn func(*data.Transaction) error) error {
+			return fmt.Errorf("connection lost")
+		}
+
+		err := WriteExcelReport(io.Discard, account, account.Balance, statement.From, statement.To, TransactionFilter{}, failing)
+		assert.ErrorContains(t, err, "connection lost")
+	})
+}
+
+// BenchmarkWriteExcelReport streams the reports of the growing histories and
+// reports the peak of the heap while writing them. It levels off once the rows
+// outgrow the buffer of the stream writer and go to its temporary file, around
+// 75 MB for the million transactions:
+//
+//	go test -run '^$' -bench WriteExcelReport -benchtime 1x ./internal/service/mvc/models/report
+func BenchmarkWriteExcelReport(b *testing.B) {
+	for _, count := range []int{10_000, 100_000, 1_000_000} {
+		b.Run(strconv.Itoa(count), func(b *testing.B) {
+			account := &data.Account{Name: "Business", Balance: count * 100}
+			account.ID = uuid.New()
+			start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				var peak uint64
+				var stats runtime.MemStats
+
+				// The transactions are made up as they are read, the way the
+				// rows of the database cursor are
+				cursor := func(fn func(*data.Transaction) error) error {
+					tx := &data.Transaction{Type: data.DepositTransaction, Recipient: account.ID, Amount: 100}
+					for n := count; n > 0; n-- {
+						tx.ID = uuid.New()
+						tx.CreatedAt = start.Add(time.Duration(n) * time.Minute)
+						if err := fn(tx); err != nil {
+							return err
+						}
+
+						if n%10_000 == 0 {
+							runtime.ReadMemStats(&stats)
+							peak = max(peak, stats.HeapInuse)
+						}
+					}
+
+					return nil
+				}
+
+				end := start.Add(time.Duration(count+1) * time.Minute)
+				if err := WriteExcelReport(io.Discard, account, account.Balance, start, end, TransactionFilter{}, cursor); err != nil {
+					b.Fatal(err)
+				}
+
+				b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+			}
+		})
+	}
+}
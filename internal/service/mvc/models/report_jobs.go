@@ -0,0 +1,359 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models/report"
+)
+
+// reportJobFailure is the error of the failed job shown to the customer, the
+// cause is logged by the worker.
+const reportJobFailure = "failed to generate report"
+
+var ErrorReportJobNotFound = errors.New("report job not found")
+var ErrorReportNotReady = errors.New("report not ready")
+var ErrorReportExpired = errors.New("report expired")
+
+// ReportJobs generates the requested reports in the background: the customer
+// requests the report, the worker generates the pending ones one by one and
+// stores them, and the customer downloads the result until it expires. The
+// jobs running longer than the timeout are failed, as their worker is gone.
+type ReportJobs struct {
+	db           data.MainQ
+	accounts     *Accounts
+	auditService *AuditService
+	storage      ReportStorage
+	lifetime     time.Duration
+	timeout      time.Duration
+}
+
+func NewReportJobs(
+	db data.MainQ, auditService *AuditService, accounts *Accounts, storage ReportStorage,
+	lifetime, timeout time.Duration,
+) *ReportJobs {
+	return &ReportJobs{
+		db:           db,
+		accounts:     accounts,
+		auditService: auditService,
+		storage:      storage,
+		lifetime:     lifetime,
+		timeout:      timeout,
+	}
+}
+
+// Enqueue creates the pending job of the report.
+func (m *ReportJobs) Enqueue(customerID uuid.UUID, req *requests.ReportJob) (*data.ReportJob, error) {
+	if req.AccountID != nil {
+		if _, err := m.accounts.GetAccount(customerID, *req.AccountID); err != nil {
+			return nil, err
+		}
+	}
+
+	job := &data.ReportJob{
+		CustomerID: customerID,
+		AccountID:  req.AccountID,
+		Format:     req.Format,
+		PeriodTo:   req.To,
+		Status:     data.ReportJobPending,
+	}
+	if !req.From.IsZero() {
+		job.PeriodFrom = &req.From
+	}
+
+	db := m.db.New()
+
+	err := db.Transaction(func() error {
+		if err := db.ReportJobs().Insert(job); err != nil {
+			return fmt.Errorf("failed to insert report job: %w", err)
+		}
+
+		if err := m.auditService.withDB(db).logReportRequested(customerID, job); err != nil {
+			return fmt.Errorf("failed to log audit action: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetJob returns the job of the customer.
+func (m *ReportJobs) GetJob(customerID, jobID uuid.UUID) (*data.ReportJob, error) {
+	job := new(data.ReportJob)
+
+	ok, err := m.db.ReportJobs().WhereID(jobID).WhereCustomerID(customerID).Get(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report job: %w", err)
+	}
+	if !ok {
+		return nil, ErrorReportJobNotFound
+	}
+
+	return job, nil
+}
+
+// Download returns the job and its report, which must be completed and not
+// expired yet. The caller must close the report.
+func (m *ReportJobs) Download(customerID, jobID uuid.UUID) (*data.ReportJob, io.ReadCloser, error) {
+	job, err := m.GetJob(customerID, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case job.IsDownloadableAt(time.Now().UTC()):
+	case job.Status == data.ReportJobCompleted || job.Status == data.ReportJobExpired:
+		return nil, nil, ErrorReportExpired
+	default:
+		return nil, nil, ErrorReportNotReady
+	}
+
+	content, err := m.storage.Open(job.ID)
+	if errors.Is(err, ErrorReportFileNotFound) {
+		// removed by the expiry in the meantime
+		return nil, nil, ErrorReportExpired
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open report: %w", err)
+	}
+
+	if err = m.auditService.logReportDownloaded(customerID, job); err != nil {
+		content.Close()
+		return nil, nil, fmt.Errorf("failed to log audit action: %w", err)
+	}
+
+	return job, content, nil
+}
+
+// ProcessNext generates the report of the oldest pending job, it returns
+// false if there are no pending jobs. The job is completed or failed, the
+// error is returned in both cases. The report not generated within the
+// timeout is failed, the same as the job left running by FailStaleJobs.
+func (m *ReportJobs) ProcessNext(ctx context.Context) (bool, error) {
+	job, err := m.claimNext()
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	startedAt := *job.StartedAt
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	genErr := m.storage.Save(job.ID, func(w io.Writer) error {
+		return m.generate(&contextWriter{ctx: ctx, w: w}, job)
+	})
+
+	now := time.Now().UTC()
+	if genErr == nil {
+		expiresAt := now.Add(m.lifetime)
+
+		job.Status = data.ReportJobCompleted
+		job.CompletedAt = &now
+		job.ExpiresAt = &expiresAt
+	} else {
+		job.Status = data.ReportJobFailed
+		job.CompletedAt = &now
+		job.Error = reportJobFailure
+	}
+
+	finished, err := m.db.ReportJobs().Finish(job, startedAt)
+	if err != nil {
+		return true, fmt.Errorf("failed to update report job %s: %w", job.ID, err)
+	}
+	if !finished {
+		// The job is failed as stale meanwhile, the report is never served
+		if err = m.storage.Delete(job.ID); err != nil {
+			return true, fmt.Errorf("failed to remove report %s: %w", job.ID, err)
+		}
+
+		return true, fmt.Errorf("report job %s is not running anymore", job.ID)
+	}
+
+	if genErr != nil {
+		return true, fmt.Errorf("failed to generate report %s: %w", job.ID, genErr)
+	}
+
+	return true, nil
+}
+
+// claimNext moves the oldest pending job to running, the jobs locked by the
+// other workers are skipped. It returns nil if there are no pending jobs.
+func (m *ReportJobs) claimNext() (*data.ReportJob, error) {
+	var job *data.ReportJob
+
+	db := m.db.New()
+
+	err := db.Transaction(func() error {
+		jobs, err := db.ReportJobs().
+			WhereStatus(data.ReportJobPending).
+			OrderBy("created_at").
+			Limit(1).
+			ForUpdateSkipLocked().
+			Select()
+		if err != nil {
+			return fmt.Errorf("failed to select pending report jobs: %w", err)
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+
+		// Postgres keeps microseconds, the job is finished by its start time
+		now := time.Now().UTC().Truncate(time.Microsecond)
+
+		job = jobs[0]
+		job.Status = data.ReportJobRunning
+		job.StartedAt = &now
+
+		if err = db.ReportJobs().Update(job); err != nil {
+			return fmt.Errorf("failed to update report job: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// contextWriter fails the writes once the context is done, so the report
+// generated past the deadline is not saved.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w *contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return w.w.Write(p)
+}
+
+// generate writes the report of the job: the statement of the account or
+// the Excel report of the account or of all the accounts of the customer.
+func (m *ReportJobs) generate(w io.Writer, job *data.ReportJob) error {
+	var from time.Time
+	if job.PeriodFrom != nil {
+		from = *job.PeriodFrom
+	}
+
+	if job.Format == report.ExcelFormat {
+		if job.AccountID == nil {
+			_, err := m.accounts.writeCustomerExcelReport(w, job.CustomerID, from, job.PeriodTo)
+			return err
+		}
+
+		account, err := m.accounts.GetAccount(job.CustomerID, *job.AccountID)
+		if err != nil {
+			return err
+		}
+
+		return m.accounts.writeExcelReport(w, account, from, job.PeriodTo, report.TransactionFilter{})
+	}
+
+	if job.AccountID == nil {
+		return fmt.Errorf("no account of the %s statement", job.Format)
+	}
+
+	statement, err := m.accounts.getStatement(job.CustomerID, *job.AccountID, from, job.PeriodTo)
+	if err != nil {
+		return err
+	}
+
+	if err = report.WriteStatement(w, report.StatementFormat(job.Format), statement, time.Now()); err != nil {
+		return fmt.Errorf("failed to create statement: %w", err)
+	}
+
+	return nil
+}
+
+// ExpireReports removes the reports of the completed jobs expired by now
+// and returns the number of the expired jobs.
+func (m *ReportJobs) ExpireReports(now time.Time) (int, error) {
+	jobs, err := m.db.ReportJobs().
+		WhereStatus(data.ReportJobCompleted).
+		WhereExpiredAt(now.UTC()).
+		Select()
+	if err != nil {
+		return 0, fmt.Errorf("failed to select expired report jobs: %w", err)
+	}
+
+	expired := 0
+	for _, job := range jobs {
+		// The job is expired first, so the report is not served once it is
+		// being removed
+		job.Status = data.ReportJobExpired
+		if err = m.db.ReportJobs().Update(job); err != nil {
+			return expired, fmt.Errorf("failed to expire report job %s: %w", job.ID, err)
+		}
+
+		if err = m.storage.Delete(job.ID); err != nil {
+			return expired, fmt.Errorf("failed to remove report %s: %w", job.ID, err)
+		}
+
+		expired++
+	}
+
+	return expired, nil
+}
+
+// FailStaleJobs fails the jobs running longer than the timeout by now, left by
+// the workers stopped in the middle of the report, and removes their partial
+// reports. It returns the number of the failed jobs.
+func (m *ReportJobs) FailStaleJobs(now time.Time) (int, error) {
+	var jobs []*data.ReportJob
+
+	db := m.db.New()
+
+	err := db.Transaction(func() error {
+		var err error
+
+		jobs, err = db.ReportJobs().
+			WhereStatus(data.ReportJobRunning).
+			WhereStartedBefore(now.UTC().Add(-m.timeout)).
+			ForUpdateSkipLocked().
+			Select()
+		if err != nil {
+			return fmt.Errorf("failed to select stale report jobs: %w", err)
+		}
+
+		completedAt := now.UTC()
+		for _, job := range jobs {
+			job.Status = data.ReportJobFailed
+			job.CompletedAt = &completedAt
+			job.Error = reportJobFailure
+
+			if err = db.ReportJobs().Update(job); err != nil {
+				return fmt.Errorf("failed to fail report job %s: %w", job.ID, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		if err = m.storage.Delete(job.ID); err != nil {
+			return len(jobs), fmt.Errorf("failed to remove report %s: %w", job.ID, err)
+		}
+	}
+
+	return len(jobs), nil
+}
@@ -0,0 +1,341 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models/report"
+)
+
+func (e *testEnv) download(t *testing.T, reportJobs *ReportJobs, customerID, jobID uuid.UUID) []byte {
+	t.Helper()
+
+	_, content, err := reportJobs.Download(customerID, jobID)
+	require.NoError(t, err)
+	defer content.Close()
+
+	result, err := io.ReadAll(content)
+	require.NoError(t, err)
+
+	return result
+}
+
+func TestReportJobs(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+	env.newAccount(t, customerID)
+
+	env.deposit(t, customerID, accountID, 1000)
+
+	reportJobs := NewReportJobs(env.db, env.audit, env.accounts, NewDBReportStorage(env.db), time.Hour, time.Hour)
+	to := time.Now().Add(time.Minute)
+
+	statementJob, err := reportJobs.Enqueue(customerID, &requests.ReportJob{
+		AccountID: &accountID,
+		Format:    string(report.StatementFormatPDF),
+		To:        to,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, data.ReportJobPending, statementJob.Status)
+
+	excelJob, err := reportJobs.Enqueue(customerID, &requests.ReportJob{Format: report.ExcelFormat, To: to})
+	require.NoError(t, err)
+
+	_, _, err = reportJobs.Download(customerID, statementJob.ID)
+	assert.ErrorIs(t, err, ErrorReportNotReady)
+
+	// The oldest job is taken first, until there are none
+	for range 2 {
+		processed, err := reportJobs.ProcessNext(context.Background())
+		require.NoError(t, err)
+		assert.True(t, processed)
+	}
+
+	processed, err := reportJobs.ProcessNext(context.Background())
+	require.NoError(t, err)
+	assert.False(t, processed)
+
+	job, err := reportJobs.GetJob(customerID, statementJob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, data.ReportJobCompleted, job.Status)
+	assert.NotNil(t, job.StartedAt)
+	require.NotNil(t, job.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *job.ExpiresAt, time.Minute)
+
+	statement := env.download(t, reportJobs, customerID, statementJob.ID)
+	assert.True(t, bytes.HasPrefix(statement, []byte("%PDF")))
+
+	workbook := env.download(t, reportJobs, customerID, excelJob.ID)
+	f, err := excelize.OpenReader(bytes.NewReader(workbook))
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Contains(t, f.GetSheetList(), report.CustomerOverviewSheetName)
+
+	// The jobs of the other customers are not visible
+	otherID := env.newCustomer(t)
+	_, err = reportJobs.GetJob(otherID, statementJob.ID)
+	assert.ErrorIs(t, err, ErrorReportJobNotFound)
+	_, _, err = reportJobs.Download(otherID, statementJob.ID)
+	assert.ErrorIs(t, err, ErrorReportJobNotFound)
+	_, err = reportJobs.Enqueue(otherID, &requests.ReportJob{AccountID: &accountID, Format: report.ExcelFormat, To: to})
+	assert.ErrorIs(t, err, ErrorAccountNotFound)
+
+	requested, err := env.db.AuditLogs().
+		WhereCustomerID(customerID).
+		WhereAction(data.AuditActionReportRequested).
+		Select()
+	require.NoError(t, err)
+	assert.Len(t, requested, 2)
+
+	downloaded, err := env.db.AuditLogs().
+		WhereCustomerID(customerID).
+		WhereAction(data.AuditActionReportDownloaded).
+		Select()
+	require.NoError(t, err)
+	assert.Len(t, downloaded, 2)
+}
+
+func TestReportJobsFailure(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	reportJobs := NewReportJobs(env.db, env.audit, env.accounts, NewDBReportStorage(env.db), time.Hour, time.Hour)
+
+	job, err := reportJobs.Enqueue(customerID, &requests.ReportJob{
+		AccountID: &accountID,
+		Format:    string(report.StatementFormatCSV),
+		To:        time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	// The account is gone by the time the report is generated
+	require.NoError(t, env.accounts.DeleteAccount(customerID, accountID))
+
+	processed, err := reportJobs.ProcessNext(context.Background())
+	assert.True(t, processed)
+	assert.ErrorIs(t, err, ErrorAccountNotFound)
+
+	job, err = reportJobs.GetJob(customerID, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, data.ReportJobFailed, job.Status)
+	assert.NotEmpty(t, job.Error)
+
+	_, _, err = reportJobs.Download(customerID, job.ID)
+	assert.ErrorIs(t, err, ErrorReportNotReady)
+
+	files, err := env.db.ReportFiles().WhereJobID(job.ID).Select()
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestReportJobsExpiry(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	dir := t.TempDir()
+	storage, err := NewDiskReportStorage(dir)
+	require.NoError(t, err)
+
+	reportJobs := NewReportJobs(env.db, env.audit, env.accounts, storage, time.Minute, time.Hour)
+
+	job, err := reportJobs.Enqueue(customerID, &requests.ReportJob{
+		AccountID: &accountID,
+		Format:    report.ExcelFormat,
+		To:        time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = reportJobs.ProcessNext(context.Background())
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, job.ID.String()))
+	require.NoError(t, err)
+
+	expired, err := reportJobs.ExpireReports(time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, expired, "not expired yet")
+
+	expired, err = reportJobs.ExpireReports(time.Now().Add(2 * time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+
+	job, err = reportJobs.GetJob(customerID, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, data.ReportJobExpired, job.Status)
+
+	_, _, err = reportJobs.Download(customerID, job.ID)
+	assert.ErrorIs(t, err, ErrorReportExpired)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestReportJobsStale(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	reportJobs := NewReportJobs(env.db, env.audit, env.accounts, NewDBReportStorage(env.db), time.Hour, time.Minute)
+
+	job, err := reportJobs.Enqueue(customerID, &requests.ReportJob{
+		AccountID: &accountID,
+		Format:    string(report.StatementFormatCSV),
+		To:        time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	// The worker claims the job and stops before the report is saved
+	claimed, err := reportJobs.claimNext()
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, job.ID, claimed.ID)
+
+	failed, err := reportJobs.FailStaleJobs(time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, failed, "not stale yet")
+
+	failed, err = reportJobs.FailStaleJobs(time.Now().Add(2 * time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, failed)
+
+	job, err = reportJobs.GetJob(customerID, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, data.ReportJobFailed, job.Status)
+	assert.Equal(t, reportJobFailure, job.Error)
+	assert.NotNil(t, job.CompletedAt)
+
+	// The failed job is not taken again
+	processed, err := reportJobs.ProcessNext(context.Background())
+	require.NoError(t, err)
+	assert.False(t, processed)
+
+	_, _, err = reportJobs.Download(customerID, job.ID)
+	assert.ErrorIs(t, err, ErrorReportNotReady)
+}
+
+// hookReportStorage calls beforeSave before the report is saved.
+type hookReportStorage struct {
+	ReportStorage
+
+	beforeSave func()
+}
+
+func (s *hookReportStorage) Save(jobID uuid.UUID, write func(w io.Writer) error) error {
+	s.beforeSave()
+	return s.ReportStorage.Save(jobID, write)
+}
+
+func TestReportJobsFailedWhileRunning(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	storage := &hookReportStorage{ReportStorage: NewDBReportStorage(env.db)}
+	reportJobs := NewReportJobs(env.db, env.audit, env.accounts, storage, time.Hour, time.Minute)
+
+	// Another instance takes the job for a stale one while it is generated
+	storage.beforeSave = func() {
+		failed, err := reportJobs.FailStaleJobs(time.Now().Add(2 * time.Minute))
+		require.NoError(t, err)
+		require.Equal(t, 1, failed)
+	}
+
+	job, err := reportJobs.Enqueue(customerID, &requests.ReportJob{
+		AccountID: &accountID,
+		Format:    string(report.StatementFormatCSV),
+		To:        time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	processed, err := reportJobs.ProcessNext(context.Background())
+	assert.True(t, processed)
+	assert.Error(t, err)
+
+	job, err = reportJobs.GetJob(customerID, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, data.ReportJobFailed, job.Status, "the failed job must not be completed")
+
+	files, err := env.db.ReportFiles().WhereJobID(job.ID).Select()
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestReportJobsDeadline(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	reportJobs := NewReportJobs(env.db, env.audit, env.accounts, NewDBReportStorage(env.db), time.Hour, time.Minute)
+
+	job, err := reportJobs.Enqueue(customerID, &requests.ReportJob{
+		AccountID: &accountID,
+		Format:    report.ExcelFormat,
+		To:        time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	processed, err := reportJobs.ProcessNext(ctx)
+	assert.True(t, processed)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	job, err = reportJobs.GetJob(customerID, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, data.ReportJobFailed, job.Status)
+}
+
+func TestDBReportStorageParts(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+
+	job := &data.ReportJob{
+		CustomerID: customerID,
+		Format:     report.ExcelFormat,
+		PeriodTo:   time.Now(),
+		Status:     data.ReportJobPending,
+	}
+	require.NoError(t, env.db.ReportJobs().Insert(job))
+
+	storage := NewDBReportStorage(env.db)
+
+	content := bytes.Repeat([]byte("report"), reportFilePartSize/2)
+	require.NoError(t, storage.Save(job.ID, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	}))
+
+	parts, err := env.db.ReportFiles().WhereJobID(job.ID).Count()
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, parts)
+
+	file, err := storage.Open(job.ID)
+	require.NoError(t, err)
+	defer file.Close()
+
+	saved, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, content, saved)
+
+	require.NoError(t, storage.Delete(job.ID))
+
+	_, err = storage.Open(job.ID)
+	assert.ErrorIs(t, err, ErrorReportFileNotFound)
+}
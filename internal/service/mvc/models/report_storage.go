@@ -0,0 +1,222 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+var ErrorReportFileNotFound = errors.New("report file not found")
+
+// ReportStorage keeps the generated reports until they expire.
+type ReportStorage interface {
+	// Save stores the report written by write, a report failed to be written
+	// is not stored.
+	Save(jobID uuid.UUID, write func(w io.Writer) error) error
+	// Open returns the stored report or ErrorReportFileNotFound.
+	Open(jobID uuid.UUID) (io.ReadCloser, error)
+	// Delete removes the report, if it is stored.
+	Delete(jobID uuid.UUID) error
+}
+
+// DiskReportStorage keeps the reports as the files named by the job IDs in
+// the directory.
+type DiskReportStorage struct {
+	dir string
+}
+
+func NewDiskReportStorage(dir string) (*DiskReportStorage, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create reports dir: %w", err)
+	}
+
+	return &DiskReportStorage{
+		dir: dir,
+	}, nil
+}
+
+func (s *DiskReportStorage) Save(jobID uuid.UUID, write func(w io.Writer) error) error {
+	// The report is written to the temporary file first, so the partially
+	// written one is never served
+	file, err := os.CreateTemp(s.dir, jobID.String()+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if err = write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to close report file: %w", err)
+	}
+
+	if err = os.Rename(file.Name(), s.path(jobID)); err != nil {
+		return fmt.Errorf("failed to rename report file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *DiskReportStorage) Open(jobID uuid.UUID) (io.ReadCloser, error) {
+	file, err := os.Open(s.path(jobID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrorReportFileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open report file: %w", err)
+	}
+
+	return file, nil
+}
+
+func (s *DiskReportStorage) Delete(jobID uuid.UUID) error {
+	err := os.Remove(s.path(jobID))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove report file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *DiskReportStorage) path(jobID uuid.UUID) string {
+	return filepath.Join(s.dir, jobID.String())
+}
+
+// reportFilePartSize is the size of the parts the reports are stored in the
+// database by.
+const reportFilePartSize = 1 << 20
+
+// DBReportStorage keeps the reports in the database, so they are available
+// to every instance of the service. The reports are stored in parts, so only
+// a part of the report is kept in memory at a time.
+type DBReportStorage struct {
+	db data.MainQ
+}
+
+func NewDBReportStorage(db data.MainQ) *DBReportStorage {
+	return &DBReportStorage{
+		db: db,
+	}
+}
+
+func (s *DBReportStorage) Save(jobID uuid.UUID, write func(w io.Writer) error) error {
+	// The report is written to the temporary file first, its size is not
+	// known until it is written
+	file, err := os.CreateTemp("", jobID.String()+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err = write(file); err != nil {
+		return err
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind report file: %w", err)
+	}
+
+	db := s.db.New()
+
+	// The parts are inserted at once, so the partially stored report is never served
+	return db.Transaction(func() error {
+		for part := 0; ; part++ {
+			content := make([]byte, reportFilePartSize)
+
+			n, err := io.ReadFull(file, content)
+			if errors.Is(err, io.EOF) && part > 0 {
+				return nil
+			}
+			if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return fmt.Errorf("failed to read report file: %w", err)
+			}
+
+			reportFile := &data.ReportFile{
+				JobID:   jobID,
+				Part:    part,
+				Content: content[:n],
+			}
+
+			if err := db.ReportFiles().Insert(reportFile); err != nil {
+				return fmt.Errorf("failed to insert report file part: %w", err)
+			}
+
+			if n < reportFilePartSize {
+				return nil
+			}
+		}
+	})
+}
+
+func (s *DBReportStorage) Open(jobID uuid.UUID) (io.ReadCloser, error) {
+	parts, err := s.db.ReportFiles().WhereJobID(jobID).Count()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count report file parts: %w", err)
+	}
+	if parts == 0 {
+		return nil, ErrorReportFileNotFound
+	}
+
+	return &dbReportReader{
+		db:    s.db,
+		jobID: jobID,
+		parts: int(parts),
+	}, nil
+}
+
+func (s *DBReportStorage) Delete(jobID uuid.UUID) error {
+	if err := s.db.ReportFiles().DeleteByJobID(jobID); err != nil {
+		return fmt.Errorf("failed to delete report file: %w", err)
+	}
+
+	return nil
+}
+
+// dbReportReader reads the report stored in the database part by part.
+type dbReportReader struct {
+	db    data.MainQ
+	jobID uuid.UUID
+	parts int
+
+	next    int
+	content *bytes.Reader
+}
+
+func (r *dbReportReader) Read(p []byte) (int, error) {
+	for r.content == nil || r.content.Len() == 0 {
+		if r.next == r.parts {
+			return 0, io.EOF
+		}
+
+		var file data.ReportFile
+
+		ok, err := r.db.ReportFiles().WhereJobID(r.jobID).WherePart(r.next).Get(&file)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get report file part: %w", err)
+		}
+		if !ok {
+			// removed by the expiry in the meantime
+			return 0, ErrorReportFileNotFound
+		}
+
+		r.content = bytes.NewReader(file.Content)
+		r.next++
+	}
+
+	return r.content.Read(p)
+}
+
+func (r *dbReportReader) Close() error {
+	return nil
+}
@@ -1,15 +1,13 @@
 package models
 
 import (
-	"crypto/ecdsa"
-	"crypto/sha256"
-	"encoding/asn1"
-	"encoding/base64"
-	"encoding/json"
+	"bytes"
+	"database/sql"
 	"errors"
 	"fmt"
-	"math/big"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -19,19 +17,20 @@ import (
 
 var ErrorInsufficientFunds = errors.New("insufficient funds")
 var ErrorRecipientNotFound = errors.New("recipient account not found")
-var ErrorATMSignatureNotUnique = errors.New("ATM signature not unique")
+var ErrorATMNonceReused = errors.New("ATM nonce already used")
 var ErrorInvalidATMSignature = errors.New("invalid ATM signature")
+var ErrorInvalidATMPayload = errors.New("invalid ATM payload")
 
 type Transactions struct {
 	db           data.MainQ
-	atmPublicKey *ecdsa.PublicKey
+	atms         *ATMs
 	auditService *AuditService
 }
 
-func NewTransactions(db data.MainQ, auditService *AuditService, atmPublicKey *ecdsa.PublicKey) *Transactions {
+func NewTransactions(db data.MainQ, auditService *AuditService, atms *ATMs) *Transactions {
 	return &Transactions{
 		db:           db,
-		atmPublicKey: atmPublicKey,
+		atms:         atms,
 		auditService: auditService,
 	}
 }
@@ -45,22 +44,21 @@ func (m *Transactions) DepositFunds(customerID uuid.UUID, req *requests.Deposit)
 		return 0, ErrorAccountNotFound
 	}
 
-	ok, err = m.verifySignature(&SignedTransaction{
-		AccountID: req.AccountID.String(),
-		Amount:    req.Amount,
-		Signature: req.ATMSignature,
-	})
-	if !ok || err != nil {
-		return 0, ErrorInvalidATMSignature
+	if err = m.verifySignature(req); err != nil {
+		return 0, err
 	}
 
+	db := m.db.New()
+	ledger := NewLedger(db)
+
 	var newBalance int
-	err = m.db.Transaction(func() error {
-		account := new(data.Account)
-		ok, err = m.db.Accounts().WhereID(req.AccountID).Get(account)
+	err = db.IsolatedTransaction(sql.LevelRepeatableRead, func() error {
+		accounts, err := lockAccounts(db, req.AccountID)
 		if err != nil {
-			return fmt.Errorf("failed to get account: %w", err)
+			return err
 		}
+
+		account, ok := accounts[req.AccountID]
 		if !ok {
 			return ErrorAccountNotFound
 		}
@@ -70,76 +68,36 @@ func (m *Transactions) DepositFunds(customerID uuid.UUID, req *requests.Deposit)
 			Amount:       req.Amount,
 			Recipient:    req.AccountID,
 			ATMSignature: req.ATMSignature,
+			ATMID:        &req.ATMID,
+			ATMNonce:     req.Nonce,
 		}
 
-		if err = m.db.Transactions().Insert(transaction); err != nil {
+		if err = db.Transactions().Insert(transaction); err != nil {
 			if isATMNotUniqueError(err) {
-				return ErrorATMSignatureNotUnique
+				return ErrorATMNonceReused
 			}
 
 			return fmt.Errorf("failed to create transaction: %w", err)
 		}
 
-		account.Balance += int(req.Amount)
-
-		if err = m.db.Accounts().Update(account); err != nil {
-			return fmt.Errorf("failed to update account balance: %w", err)
-		}
-
-		if err = m.auditService.logDepositMade(customerID, account.ID, req.Amount); err != nil {
-			return fmt.Errorf("failed to log deposit: %w", err)
-		}
-
-		newBalance = account.Balance
-		return nil
-	})
-
-	return newBalance, err
-}
-
-func (m *Transactions) WithdrawFunds(customerID uuid.UUID, req *requests.Withdrawal) (int, error) {
-	ok, err := m.db.CustomersAccounts().HasAccount(customerID, req.AccountID)
-	if err != nil {
-		return 0, fmt.Errorf("failed to check account existence: %w", err)
-	}
-	if !ok {
-		return 0, ErrorAccountNotFound
-	}
-
-	var newBalance int
-
-	err = m.db.Transaction(func() error {
-		account := new(data.Account)
-		ok, err = m.db.Accounts().WhereID(req.AccountID).Get(account)
-		if err != nil {
-			return fmt.Errorf("failed to get account: %w", err)
-		}
-		if !ok {
-			return ErrorAccountNotFound
-		}
-
-		if account.Balance < int(req.Amount) {
-			return ErrorInsufficientFunds
+		if err = ledger.record(transaction); err != nil {
+			return err
 		}
 
-		transaction := &data.Transaction{
-			Type:   data.WithdrawalTransaction,
-			Amount: req.Amount,
-			Sender: req.AccountID,
+		if _, err = moveATMCash(db, req.ATMID, int(req.Amount)); err != nil {
+			return err
 		}
 
-		if err = m.db.Transactions().Insert(transaction); err != nil {
-			return fmt.Errorf("failed to create transaction: %w", err)
+		if account.Balance, err = ledger.balance(account.ID); err != nil {
+			return err
 		}
 
-		account.Balance -= int(req.Amount)
-
-		if err = m.db.Accounts().Update(account); err != nil {
+		if err = db.Accounts().Update(account); err != nil {
 			return fmt.Errorf("failed to update account balance: %w", err)
 		}
 
-		if err = m.auditService.logWithdrawalMade(customerID, account.ID, req.Amount); err != nil {
-			return fmt.Errorf("failed to log withdrawal: %w", err)
+		if err = m.auditService.withDB(db).logDepositMade(customerID, account.ID, req.ATMID, req.Amount); err != nil {
+			return fmt.Errorf("failed to log deposit: %w", err)
 		}
 
 		newBalance = account.Balance
@@ -158,28 +116,32 @@ func (m *Transactions) TransferFunds(customerID uuid.UUID, req *requests.Transfe
 		return 0, ErrorAccountNotFound
 	}
 
-	var senderBalance int
+	db := m.db.New()
+	ledger := NewLedger(db)
 
-	err = m.db.Transaction(func() error {
-		sender := new(data.Account)
-		ok, err = m.db.Accounts().WhereID(req.SenderID).Get(sender)
+	var senderBalance int
+	err = db.IsolatedTransaction(sql.LevelRepeatableRead, func() error {
+		accounts, err := lockAccounts(db, req.SenderID, req.RecipientID)
 		if err != nil {
-			return fmt.Errorf("failed to get sender account: %w", err)
+			return err
 		}
+
+		sender, ok := accounts[req.SenderID]
 		if !ok {
 			return ErrorAccountNotFound
 		}
 
-		recipient := new(data.Account)
-		ok, err = m.db.Accounts().WhereID(req.RecipientID).Get(recipient)
-		if err != nil {
-			return fmt.Errorf("failed to get recipient account: %w", err)
-		}
+		recipient, ok := accounts[req.RecipientID]
 		if !ok {
 			return ErrorRecipientNotFound
 		}
 
-		if sender.Balance < int(req.Amount) {
+		balance, err := ledger.balance(sender.ID)
+		if err != nil {
+			return err
+		}
+
+		if balance < int(req.Amount) {
 			return ErrorInsufficientFunds
 		}
 
@@ -190,22 +152,29 @@ func (m *Transactions) TransferFunds(customerID uuid.UUID, req *requests.Transfe
 			Recipient: req.RecipientID,
 		}
 
-		if err = m.db.Transactions().Insert(transaction); err != nil {
+		if err = db.Transactions().Insert(transaction); err != nil {
 			return fmt.Errorf("failed to create transaction: %w", err)
 		}
 
-		sender.Balance -= int(req.Amount)
-		recipient.Balance += int(req.Amount)
+		if err = ledger.record(transaction); err != nil {
+			return err
+		}
+
+		sender.Balance = balance - int(req.Amount)
+
+		if recipient.Balance, err = ledger.balance(recipient.ID); err != nil {
+			return err
+		}
 
-		if err = m.db.Accounts().Update(sender); err != nil {
+		if err = db.Accounts().Update(sender); err != nil {
 			return fmt.Errorf("failed to update sender balance: %w", err)
 		}
 
-		if err = m.db.Accounts().Update(recipient); err != nil {
+		if err = db.Accounts().Update(recipient); err != nil {
 			return fmt.Errorf("failed to update recipient balance: %w", err)
 		}
 
-		if err = m.auditService.logTransferMade(customerID, sender.ID, recipient.ID, req.Amount); err != nil {
+		if err = m.auditService.withDB(db).logTransferMade(customerID, sender.ID, recipient.ID, req.Amount); err != nil {
 			return fmt.Errorf("failed to log transfer: %w", err)
 		}
 
@@ -216,41 +185,50 @@ func (m *Transactions) TransferFunds(customerID uuid.UUID, req *requests.Transfe
 	return senderBalance, err
 }
 
-type SignedTransaction struct {
-	AccountID string `json:"account_id"`
-	Amount    uint   `json:"amount"`
-	Signature string `json:"-"`
-}
+// lockAccounts selects the accounts FOR UPDATE one by one in the ascending order
+// of their IDs, so concurrent transactions touching the same accounts always
+// acquire the locks in the same order and can't deadlock each other.
+// The accounts that don't exist are missing from the result.
+func lockAccounts(db data.MainQ, ids ...uuid.UUID) (map[uuid.UUID]*data.Account, error) {
+	ids = slices.Clone(ids)
+	slices.SortFunc(ids, func(a, b uuid.UUID) int {
+		return bytes.Compare(a[:], b[:])
+	})
 
-type ECDSASignature struct {
-	R, S *big.Int
-}
+	accounts := make(map[uuid.UUID]*data.Account, len(ids))
+	for _, id := range slices.Compact(ids) {
+		account := new(data.Account)
 
-// verifySignature verifies the signature of a signed transaction
-func (m *Transactions) verifySignature(signedTx *SignedTransaction) (bool, error) {
-	// Marshal the transaction to JSON
-	txJSON, err := json.Marshal(signedTx)
-	if err != nil {
-		return false, fmt.Errorf("error marshaling transaction: %v", err)
+		ok, err := db.Accounts().WhereID(id).ForUpdate().Get(account)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock account: %w", err)
+		}
+		if ok {
+			accounts[id] = account
+		}
 	}
 
-	// Calculate SHA-256 hash of the JSON
-	hash := sha256.Sum256(txJSON)
+	return accounts, nil
+}
 
-	// Decode the signature from Base64
-	signatureBytes, err := base64.StdEncoding.DecodeString(signedTx.Signature)
-	if err != nil {
-		return false, fmt.Errorf("error decoding signature: %v", err)
+// verifySignature verifies the deposit payload is valid now and signed by one
+// of the valid keys of the active ATM.
+func (m *Transactions) verifySignature(req *requests.Deposit) error {
+	payload := req.Payload()
+
+	if err := payload.Validate(time.Now()); err != nil {
+		return fmt.Errorf("%w: %v", ErrorInvalidATMPayload, err)
 	}
 
-	// Unmarshal the signature
-	var signature ECDSASignature
-	if _, err := asn1.Unmarshal(signatureBytes, &signature); err != nil {
-		return false, fmt.Errorf("error unmarshaling signature: %v", err)
+	err := m.atms.VerifySignature(req.ATMID, payload.Canonical(), req.ATMSignature)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrorATMNotFound), errors.Is(err, ErrorATMDisabled), errors.Is(err, ErrorInvalidATMSignature):
+		return err
 	}
 
-	// Verify the signature
-	return ecdsa.Verify(m.atmPublicKey, hash[:], signature.R, signature.S), nil
+	return fmt.Errorf("failed to verify ATM signature: %w", err)
 }
 
 func isATMNotUniqueError(err error) bool {
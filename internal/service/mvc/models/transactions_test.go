@@ -0,0 +1,365 @@
+package models
+
+import (
+	"crypto"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/atm"
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/data/memory"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+)
+
+type testEnv struct {
+	db           data.MainQ
+	atmKey       *atm.Signer
+	atmID        uuid.UUID
+	audit        *AuditService
+	atms         *ATMs
+	accounts     *Accounts
+	transactions *Transactions
+	cashOut      *CashOut
+	atmCash      *ATMCash
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	atmKey := newTestATMSigner(t, atm.KeyTypeECDSA, atm.SignatureFormatBase64)
+
+	db := memory.NewMainQ()
+	audit := NewAuditService(db)
+	atms := NewATMs(db)
+
+	machine, _, err := atms.Register("test", testATMPublicKeyPEM(t, atmKey.Public()), atmKey.Format())
+	require.NoError(t, err)
+
+	return &testEnv{
+		db:           db,
+		atmKey:       atmKey,
+		atmID:        machine.ID,
+		audit:        audit,
+		atms:         atms,
+		accounts:     NewAccounts(db, audit),
+		transactions: NewTransactions(db, audit, atms),
+		cashOut:      NewCashOut(db, audit, atms, time.Minute),
+		atmCash:      NewATMCash(db, audit, atms),
+	}
+}
+
+// newTestATMSigner returns a new ATM key of the type signing in the format.
+func newTestATMSigner(t *testing.T, keyType atm.KeyType, format atm.SignatureFormat) *atm.Signer {
+	t.Helper()
+
+	privateKey, err := atm.GenerateKeyOfType(keyType)
+	require.NoError(t, err, "failed to generate test ATM key")
+
+	signer, err := atm.NewSigner(privateKey, format)
+	require.NoError(t, err)
+
+	return signer
+}
+
+func testATMPublicKeyPEM(t *testing.T, publicKey crypto.PublicKey) []byte {
+	t.Helper()
+
+	publicKeyPEM, err := atm.MarshalPublicKey(publicKey)
+	require.NoError(t, err)
+
+	return publicKeyPEM
+}
+
+func (e *testEnv) newCustomer(t *testing.T) uuid.UUID {
+	t.Helper()
+
+	customer := &data.Customer{
+		Email:        uuid.NewString() + "@example.com",
+		Username:     uuid.NewString(),
+		PasswordHash: "hashed_password",
+	}
+	require.NoError(t, e.db.Customers().Insert(customer))
+
+	return customer.ID
+}
+
+func (e *testEnv) newAccount(t *testing.T, customerID uuid.UUID) uuid.UUID {
+	t.Helper()
+
+	account, err := e.accounts.CreateAccount(customerID, &requests.CreateAccount{Name: "account"})
+	require.NoError(t, err)
+
+	return account.ID
+}
+
+// depositRequest returns the deposit signed by the test ATM.
+func (e *testEnv) depositRequest(t *testing.T, accountID uuid.UUID, amount uint) *requests.Deposit {
+	t.Helper()
+
+	return signTestDeposit(t, e.atmKey, e.atmID, accountID, amount)
+}
+
+func signTestDeposit(t *testing.T, atmKey *atm.Signer, atmID, accountID uuid.UUID, amount uint) *requests.Deposit {
+	t.Helper()
+
+	payload, err := atm.NewDepositPayload(atmID, accountID, amount, time.Minute)
+	require.NoError(t, err)
+
+	return signTestPayload(t, atmKey, payload)
+}
+
+func signTestPayload(t *testing.T, atmKey *atm.Signer, payload *atm.DepositPayload) *requests.Deposit {
+	t.Helper()
+
+	signed, err := atm.SignDeposit(atmKey, payload)
+	require.NoError(t, err)
+
+	return &requests.Deposit{SignedDeposit: *signed}
+}
+
+func (e *testEnv) deposit(t *testing.T, customerID, accountID uuid.UUID, amount uint) int {
+	t.Helper()
+
+	balance, err := e.transactions.DepositFunds(customerID, e.depositRequest(t, accountID, amount))
+	require.NoError(t, err)
+
+	return balance
+}
+
+// withdrawalRequest returns the withdrawal of the cash-out code signed by the test ATM.
+func (e *testEnv) withdrawalRequest(t *testing.T, code string, amount uint) *requests.ATMWithdrawal {
+	t.Helper()
+
+	payload, err := atm.NewWithdrawalPayload(e.atmID, code, amount, time.Minute)
+	require.NoError(t, err)
+
+	return e.signWithdrawal(t, payload)
+}
+
+func (e *testEnv) signWithdrawal(t *testing.T, payload *atm.WithdrawalPayload) *requests.ATMWithdrawal {
+	t.Helper()
+
+	signed, err := atm.SignWithdrawal(e.atmKey, payload)
+	require.NoError(t, err)
+
+	return &requests.ATMWithdrawal{SignedWithdrawal: *signed}
+}
+
+// withdraw issues the cash-out code and withdraws it at the test ATM, returning
+// the new account balance.
+func (e *testEnv) withdraw(t *testing.T, customerID, accountID uuid.UUID, amount uint) int {
+	t.Helper()
+
+	_, code, err := e.cashOut.IssueCode(customerID, &requests.Withdrawal{AccountID: accountID, Amount: amount})
+	require.NoError(t, err)
+
+	_, err = e.cashOut.Withdraw(e.withdrawalRequest(t, code, amount))
+	require.NoError(t, err)
+
+	account, err := e.accounts.GetAccount(customerID, accountID)
+	require.NoError(t, err)
+
+	return account.Balance
+}
+
+func TestDepositFunds(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+
+	assert.Equal(t, 1000, env.deposit(t, customerID, accountID, 1000))
+
+	t.Run("replayed nonce", func(t *testing.T) {
+		req := env.depositRequest(t, accountID, 500)
+
+		_, err := env.transactions.DepositFunds(customerID, req)
+		require.NoError(t, err)
+
+		_, err = env.transactions.DepositFunds(customerID, req)
+		require.ErrorIs(t, err, ErrorATMNonceReused)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		req := env.depositRequest(t, accountID, 70)
+		req.Amount = 700
+
+		_, err := env.transactions.DepositFunds(customerID, req)
+		require.ErrorIs(t, err, ErrorInvalidATMSignature)
+	})
+
+	t.Run("expired payload", func(t *testing.T) {
+		payload, err := atm.NewDepositPayload(env.atmID, accountID, 100, time.Minute)
+		require.NoError(t, err)
+		payload.IssuedAt = payload.IssuedAt.Add(-time.Hour)
+		payload.ExpiresAt = payload.ExpiresAt.Add(-time.Hour)
+
+		_, err = env.transactions.DepositFunds(customerID, signTestPayload(t, env.atmKey, payload))
+		require.ErrorIs(t, err, ErrorInvalidATMPayload)
+	})
+
+	t.Run("foreign account", func(t *testing.T) {
+		_, err := env.transactions.DepositFunds(env.newCustomer(t), env.depositRequest(t, accountID, 100))
+		require.ErrorIs(t, err, ErrorAccountNotFound)
+	})
+
+	account, err := env.accounts.GetAccount(customerID, accountID)
+	require.NoError(t, err)
+	assert.Equal(t, 1500, account.Balance)
+}
+
+func TestTransferFunds(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	senderID := env.newAccount(t, customerID)
+	recipientID := env.newAccount(t, env.newCustomer(t))
+	env.deposit(t, customerID, senderID, 1000)
+
+	balance, err := env.transactions.TransferFunds(customerID, &requests.Transfer{
+		SenderID:    senderID,
+		RecipientID: recipientID,
+		Amount:      250,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 750, balance)
+
+	recipient := new(data.Account)
+	ok, err := env.db.Accounts().WhereID(recipientID).Get(recipient)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 250, recipient.Balance)
+
+	_, err = env.transactions.TransferFunds(customerID, &requests.Transfer{
+		SenderID:    senderID,
+		RecipientID: uuid.New(),
+		Amount:      100,
+	})
+	require.ErrorIs(t, err, ErrorRecipientNotFound)
+
+	_, err = env.transactions.TransferFunds(customerID, &requests.Transfer{
+		SenderID:    senderID,
+		RecipientID: recipientID,
+		Amount:      751,
+	})
+	require.ErrorIs(t, err, ErrorInsufficientFunds)
+
+	logs, err := env.audit.GetUserActivityLogs(customerID, 10, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, logs)
+	assert.Equal(t, data.AuditActionTransferMade, logs[0].Action)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	accountID := env.newAccount(t, customerID)
+	env.deposit(t, customerID, accountID, 100)
+
+	require.ErrorIs(t, env.accounts.DeleteAccount(customerID, accountID), ErrorNonZeroBalance)
+
+	env.withdraw(t, customerID, accountID, 100)
+
+	require.NoError(t, env.accounts.DeleteAccount(customerID, accountID))
+
+	accounts, err := env.accounts.GetAccountList(customerID)
+	require.NoError(t, err)
+	assert.Empty(t, accounts)
+
+	count, err := env.audit.GetTotalLogsCount(customerID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), count, "creation, deposit, cash-out code, withdrawal and deletion should be logged")
+}
+
+func TestLedgerVerifyBalances(t *testing.T) {
+	env := newTestEnv(t)
+	customerID := env.newCustomer(t)
+	senderID := env.newAccount(t, customerID)
+	recipientID := env.newAccount(t, env.newCustomer(t))
+	env.deposit(t, customerID, senderID, 1000)
+
+	_, err := env.transactions.TransferFunds(customerID, &requests.Transfer{
+		SenderID:    senderID,
+		RecipientID: recipientID,
+		Amount:      300,
+	})
+	require.NoError(t, err)
+
+	env.withdraw(t, customerID, senderID, 200)
+
+	ledger := NewLedger(env.db)
+
+	mismatches, err := ledger.VerifyBalances()
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	atmCash, err := env.db.Ledger().SystemAccountBalance(data.SystemAccountATMCash)
+	require.NoError(t, err)
+	assert.Equal(t, 800, atmCash, "ATM cash must hold the deposits minus the withdrawals")
+
+	// A balance changed behind the ledger's back is reported
+	sender := new(data.Account)
+	ok, err := env.db.Accounts().WhereID(senderID).Get(sender)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	sender.Balance += 50
+	require.NoError(t, env.db.Accounts().Update(sender))
+
+	mismatches, err = ledger.VerifyBalances()
+	require.NoError(t, err)
+	require.Len(t, mismatches, 1)
+	assert.Equal(t, BalanceMismatch{AccountID: senderID, Stored: 550, Derived: 500}, mismatches[0])
+}
+
+func TestConcurrentTransfers(t *testing.T) {
+	env := newTestEnv(t)
+	firstCustomerID, secondCustomerID := env.newCustomer(t), env.newCustomer(t)
+	firstID, secondID := env.newAccount(t, firstCustomerID), env.newAccount(t, secondCustomerID)
+	env.deposit(t, firstCustomerID, firstID, 1000)
+	env.deposit(t, secondCustomerID, secondID, 1000)
+
+	const transfers = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < transfers; i++ {
+		wg.Add(2)
+
+		// transfers in opposite directions lock the same accounts
+		go func() {
+			defer wg.Done()
+			_, err := env.transactions.TransferFunds(firstCustomerID, &requests.Transfer{
+				SenderID:    firstID,
+				RecipientID: secondID,
+				Amount:      3,
+			})
+			assert.NoError(t, err)
+		}()
+
+		go func() {
+			defer wg.Done()
+			_, err := env.transactions.TransferFunds(secondCustomerID, &requests.Transfer{
+				SenderID:    secondID,
+				RecipientID: firstID,
+				Amount:      1,
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	first, err := env.accounts.GetAccount(firstCustomerID, firstID)
+	require.NoError(t, err)
+	assert.Equal(t, 1000-2*transfers, first.Balance)
+
+	second, err := env.accounts.GetAccount(secondCustomerID, secondID)
+	require.NoError(t, err)
+	assert.Equal(t, 1000+2*transfers, second.Balance)
+
+	mismatches, err := NewLedger(env.db).VerifyBalances()
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
@@ -0,0 +1,318 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+const (
+	recoveryCodesCount   = 10
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+const (
+	twoFactorMethodTOTP         = "totp"
+	twoFactorMethodRecoveryCode = "recovery_code"
+)
+
+var ErrorTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+var ErrorTwoFactorNotEnrolled = errors.New("two-factor authentication is not enrolled")
+var ErrorInvalidTwoFactorCode = errors.New("invalid two-factor code")
+
+// TOTPEnrollment is the secret to be added to the authenticator app, either
+// by scanning the QR code of the URI or by typing the secret in.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+type TwoFactor struct {
+	db data.MainQ
+
+	auditService *AuditService
+}
+
+func NewTwoFactor(db data.MainQ, auditService *AuditService) *TwoFactor {
+	return &TwoFactor{
+		db:           db,
+		auditService: auditService,
+	}
+}
+
+// IsEnabled reports whether the customer has confirmed the TOTP enrollment.
+func (m *TwoFactor) IsEnabled(customerID uuid.UUID) (bool, error) {
+	credential := new(data.TOTPCredential)
+
+	ok, err := m.db.TOTPCredentials().WhereCustomerID(customerID).Get(credential)
+	if err != nil {
+		return false, fmt.Errorf("failed to get TOTP credential: %w", err)
+	}
+
+	return ok && credential.IsConfirmed(), nil
+}
+
+// EnrollTOTP generates a new secret for the customer, replacing the one of an
+// unconfirmed enrollment. The second factor is enabled only by ConfirmTOTP.
+func (m *TwoFactor) EnrollTOTP(customerID uuid.UUID) (*TOTPEnrollment, error) {
+	customer := new(data.Customer)
+
+	ok, err := m.db.Customers().WhereID(customerID).Get(customer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+	if !ok {
+		return nil, ErrorUserNotFound
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+
+	db := m.db.New()
+
+	err = db.Transaction(func() error {
+		existing := new(data.TOTPCredential)
+
+		ok, err := db.TOTPCredentials().WhereCustomerID(customerID).Get(existing)
+		if err != nil {
+			return fmt.Errorf("failed to get TOTP credential: %w", err)
+		}
+
+		if ok {
+			if existing.IsConfirmed() {
+				return ErrorTwoFactorAlreadyEnabled
+			}
+
+			if err = db.TOTPCredentials().Delete(existing.ID); err != nil {
+				return fmt.Errorf("failed to delete unconfirmed TOTP credential: %w", err)
+			}
+		}
+
+		if err = db.TOTPCredentials().Insert(&data.TOTPCredential{
+			CustomerID: customerID,
+			Secret:     secret,
+		}); err != nil {
+			return fmt.Errorf("failed to insert TOTP credential: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    totpProvisioningURI(customer.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables the second factor once the code from the authenticator
+// app matches. It returns the plain recovery codes, which are shown only once.
+func (m *TwoFactor) ConfirmTOTP(customerID uuid.UUID, code string) ([]string, error) {
+	credential := new(data.TOTPCredential)
+
+	ok, err := m.db.TOTPCredentials().WhereCustomerID(customerID).Get(credential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TOTP credential: %w", err)
+	}
+	if !ok {
+		return nil, ErrorTwoFactorNotEnrolled
+	}
+	if credential.IsConfirmed() {
+		return nil, ErrorTwoFactorAlreadyEnabled
+	}
+
+	if ok, err = m.useTOTP(credential, code); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrorInvalidTwoFactorCode
+	}
+
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	db := m.db.New()
+
+	err = db.Transaction(func() error {
+		now := time.Now().UTC()
+		credential.ConfirmedAt = &now
+
+		if err := db.TOTPCredentials().Update(credential); err != nil {
+			return fmt.Errorf("failed to confirm TOTP credential: %w", err)
+		}
+
+		if err := db.RecoveryCodes().DeleteByCustomer(customerID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+
+		for _, code := range codes {
+			if err := db.RecoveryCodes().Insert(&data.RecoveryCode{
+				CustomerID: customerID,
+				CodeHash:   hashSecretToken(normalizeRecoveryCode(code)),
+			}); err != nil {
+				return fmt.Errorf("failed to insert recovery code: %w", err)
+			}
+		}
+
+		if err := m.auditService.withDB(db).logTwoFactorEnabled(customerID); err != nil {
+			return fmt.Errorf("failed to log audit action: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP disables the second factor, the customer must prove the
+// possession of it with either a TOTP or a recovery code.
+func (m *TwoFactor) DisableTOTP(customerID uuid.UUID, code string) error {
+	if err := m.Verify(customerID, code); err != nil {
+		return err
+	}
+
+	db := m.db.New()
+
+	return db.Transaction(func() error {
+		credential := new(data.TOTPCredential)
+
+		ok, err := db.TOTPCredentials().WhereCustomerID(customerID).Get(credential)
+		if err != nil {
+			return fmt.Errorf("failed to get TOTP credential: %w", err)
+		}
+
+		if ok {
+			if err = db.TOTPCredentials().Delete(credential.ID); err != nil {
+				return fmt.Errorf("failed to delete TOTP credential: %w", err)
+			}
+		}
+
+		if err = db.RecoveryCodes().DeleteByCustomer(customerID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+
+		if err = m.auditService.withDB(db).logTwoFactorDisabled(customerID); err != nil {
+			return fmt.Errorf("failed to log audit action: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Verify checks the TOTP code, or the recovery code, which is used up. Each
+// TOTP code is accepted only once. The failures are recorded in the audit log.
+func (m *TwoFactor) Verify(customerID uuid.UUID, code string) error {
+	credential := new(data.TOTPCredential)
+
+	ok, err := m.db.TOTPCredentials().WhereCustomerID(customerID).Get(credential)
+	if err != nil {
+		return fmt.Errorf("failed to get TOTP credential: %w", err)
+	}
+	if !ok || !credential.IsConfirmed() {
+		return ErrorTwoFactorNotEnrolled
+	}
+
+	method := twoFactorMethodTOTP
+	if !isTOTPCode(code) {
+		method = twoFactorMethodRecoveryCode
+	}
+
+	if method == twoFactorMethodTOTP {
+		ok, err = m.useTOTP(credential, code)
+	} else {
+		ok, err = m.db.RecoveryCodes().Use(customerID, hashSecretToken(normalizeRecoveryCode(code)), time.Now().UTC())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", method, err)
+	}
+
+	if !ok {
+		if err = m.auditService.logTwoFactorFailed(customerID, method); err != nil {
+			return fmt.Errorf("failed to log audit action: %w", err)
+		}
+
+		return ErrorInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// useTOTP verifies the code and records its time step, so the code can't be replayed.
+func (m *TwoFactor) useTOTP(credential *data.TOTPCredential, code string) (bool, error) {
+	step, ok, err := verifyTOTP(credential.Secret, code, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to verify TOTP code: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	used, err := m.db.TOTPCredentials().UseStep(credential.ID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to use TOTP step: %w", err)
+	}
+
+	return used, nil
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRecoveryCodes returns the codes formatted as xxxxx-xxxxx, the alphabet
+// has no characters that are easy to confuse, e.g. 0 and o.
+func newRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodesCount)
+	alphabetSize := big.NewInt(int64(len(recoveryCodeAlphabet)))
+
+	for i := range codes {
+		var code strings.Builder
+		for j := 0; j < recoveryCodeLength; j++ {
+			if j == recoveryCodeLength/2 {
+				code.WriteByte('-')
+			}
+
+			n, err := rand.Int(rand.Reader, alphabetSize)
+			if err != nil {
+				return nil, err
+			}
+			code.WriteByte(recoveryCodeAlphabet[n.Int64()])
+		}
+
+		codes[i] = code.String()
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
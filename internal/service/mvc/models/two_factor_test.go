@@ -0,0 +1,185 @@
+package models
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers/requests"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 Appendix B, SHA1 test vectors truncated to 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := totpCode(secret, totpStep(time.Unix(tt.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tt.code, code, "time %d", tt.unix)
+	}
+
+	now := time.Unix(1111111109, 0)
+
+	step, ok, err := verifyTOTP(secret, "081804", now.Add(totpPeriod))
+	require.NoError(t, err)
+	assert.True(t, ok, "code of the previous step must be accepted")
+	assert.Equal(t, totpStep(now), step)
+
+	_, ok, err = verifyTOTP(secret, "081804", now.Add(2*totpPeriod))
+	require.NoError(t, err)
+	assert.False(t, ok, "code older than the skew must be rejected")
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(totpProvisioningURI("user@example.com", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/ilab1:user@example.com", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, "ilab1", uri.Query().Get("issuer"))
+}
+
+// enableTestTwoFactor enrolls and confirms TOTP for the customer, returning
+// the secret and the recovery codes.
+func enableTestTwoFactor(t *testing.T, twoFactor *TwoFactor, customerID uuid.UUID) (string, []string) {
+	t.Helper()
+
+	enrollment, err := twoFactor.EnrollTOTP(customerID)
+	require.NoError(t, err)
+
+	codes, err := twoFactor.ConfirmTOTP(customerID, currentTOTPCode(t, enrollment.Secret, 0))
+	require.NoError(t, err)
+
+	return enrollment.Secret, codes
+}
+
+// currentTOTPCode returns the code for the current time step shifted by offset.
+func currentTOTPCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	code, err := totpCode(secret, totpStep(time.Now())+offset)
+	require.NoError(t, err)
+
+	return code
+}
+
+func TestTwoFactor(t *testing.T) {
+	env := newTestEnv(t)
+	twoFactor := NewTwoFactor(env.db, env.audit)
+	customerID := env.newCustomer(t)
+
+	enrollment, err := twoFactor.EnrollTOTP(customerID)
+	require.NoError(t, err)
+
+	enabled, err := twoFactor.IsEnabled(customerID)
+	require.NoError(t, err)
+	assert.False(t, enabled, "unconfirmed enrollment must not enable the second factor")
+
+	_, err = twoFactor.ConfirmTOTP(customerID, "000000")
+	require.ErrorIs(t, err, ErrorInvalidTwoFactorCode)
+
+	codes, err := twoFactor.ConfirmTOTP(customerID, currentTOTPCode(t, enrollment.Secret, 0))
+	require.NoError(t, err)
+	assert.Len(t, codes, recoveryCodesCount)
+
+	enabled, err = twoFactor.IsEnabled(customerID)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	_, err = twoFactor.EnrollTOTP(customerID)
+	require.ErrorIs(t, err, ErrorTwoFactorAlreadyEnabled)
+
+	t.Run("totp code is accepted once", func(t *testing.T) {
+		code := currentTOTPCode(t, enrollment.Secret, 1)
+
+		require.NoError(t, twoFactor.Verify(customerID, code))
+		require.ErrorIs(t, twoFactor.Verify(customerID, code), ErrorInvalidTwoFactorCode)
+	})
+
+	t.Run("older totp code is rejected after a newer one", func(t *testing.T) {
+		require.ErrorIs(t, twoFactor.Verify(customerID, currentTOTPCode(t, enrollment.Secret, 0)), ErrorInvalidTwoFactorCode)
+	})
+
+	t.Run("recovery code is accepted once", func(t *testing.T) {
+		require.NoError(t, twoFactor.Verify(customerID, " "+codes[0]+" "))
+		require.ErrorIs(t, twoFactor.Verify(customerID, codes[0]), ErrorInvalidTwoFactorCode)
+	})
+
+	t.Run("failures are audited", func(t *testing.T) {
+		logs, err := env.audit.GetUserActivityLogs(customerID, 1, 0)
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+		assert.Equal(t, data.AuditActionTwoFactorFailed, logs[0].Action)
+	})
+
+	require.ErrorIs(t, twoFactor.DisableTOTP(customerID, "wrong-code"), ErrorInvalidTwoFactorCode)
+	require.NoError(t, twoFactor.DisableTOTP(customerID, codes[1]))
+
+	enabled, err = twoFactor.IsEnabled(customerID)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	unused, err := env.db.RecoveryCodes().WhereCustomerID(customerID).Count()
+	require.NoError(t, err)
+	assert.Zero(t, unused, "recovery codes must be deleted with the second factor")
+}
+
+func TestLoginWithTwoFactor(t *testing.T) {
+	auth, db := newTestAuth(t, time.Minute)
+
+	passwordHash, err := auth.hasher.Hash("Correct-Horse-42")
+	require.NoError(t, err)
+
+	customer := &data.Customer{
+		Email:        "2fa@example.com",
+		Username:     "2fa",
+		PasswordHash: passwordHash,
+	}
+	require.NoError(t, db.Customers().Insert(customer))
+
+	secret, codes := enableTestTwoFactor(t, auth.twoFactor, customer.ID)
+
+	result, err := auth.Login(&requests.Login{Email: customer.Email, Password: "Correct-Horse-42"}, ClientInfo{})
+	require.NoError(t, err)
+	require.Nil(t, result.Tokens, "session must not be started before the second factor")
+	require.NotNil(t, result.Challenge)
+
+	failures, err := db.LoginFailures().WhereEmailHash(loginEmailHash(customer.Email)).Count()
+	require.NoError(t, err)
+	assert.Zero(t, failures, "the attempt passed to the second factor is not a failure")
+
+	_, err = auth.CompleteLogin("invalid", codes[0], ClientInfo{})
+	require.ErrorIs(t, err, ErrorInvalidTwoFactorChallenge)
+
+	_, err = auth.CompleteLogin(result.Challenge.Token, "000000", ClientInfo{})
+	require.ErrorIs(t, err, ErrorInvalidTwoFactorCode)
+
+	tokens, err := auth.CompleteLogin(result.Challenge.Token, currentTOTPCode(t, secret, 1), ClientInfo{})
+	require.NoError(t, err)
+
+	claims, err := auth.VerifyJWT(tokens.Access.Token)
+	require.NoError(t, err)
+	assert.Equal(t, customer.ID, claims.CustomerID)
+
+	_, err = auth.VerifyJWT(result.Challenge.Token)
+	require.Error(t, err, "challenge must not be accepted as an access token")
+}
@@ -1,14 +1,17 @@
 package mvc
 
 import (
+	"context"
 	"fmt"
 	"html/template"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"gitlab.com/distributed_lab/ape"
 	"gitlab.com/distributed_lab/logan/v3"
 
 	"github.com/omegatymbjiep/ilab1/internal/config"
+	"github.com/omegatymbjiep/ilab1/internal/data"
 	"github.com/omegatymbjiep/ilab1/internal/data/postgres"
 	"github.com/omegatymbjiep/ilab1/internal/service/mvc/controllers"
 	"github.com/omegatymbjiep/ilab1/internal/service/mvc/models"
@@ -22,8 +25,24 @@ type MVC struct {
 	accounts     *controllers.Accounts
 	transactions *controllers.Transactions
 	activityLogs *controllers.ActivityLogs
+	accessTokens *controllers.AccessTokens
+	twoFactor    *controllers.TwoFactor
+	settings     *controllers.Settings
+	idempotency  *controllers.Idempotency
+	cashOut      *controllers.CashOut
+	atmCash      *controllers.ATMCash
+	reports      *controllers.Reports
+
+	cashOutModel          *models.CashOut
+	cashOutExpiryInterval time.Duration
+
+	loginGuard *models.LoginGuard
+
+	reportJobsModel     *models.ReportJobs
+	reportsPollInterval time.Duration
 
 	templates *template.Template
+	cookies   *config.Cookies
 }
 
 func NewMVC(log *logan.Entry, cfg config.Config) (*MVC, error) {
@@ -34,36 +53,186 @@ func NewMVC(log *logan.Entry, cfg config.Config) (*MVC, error) {
 		return nil, fmt.Errorf("failed to parse templates: %w", err)
 	}
 
-	authModel, err := models.NewAuth(db, cfg.JWT().SigningKey, cfg.JWT().Expiry)
+	passwords := cfg.Passwords()
+
+	hasher, err := models.NewPasswordHasher(
+		passwords.Algorithm,
+		passwords.Argon2Memory, passwords.Argon2Iterations, uint8(passwords.Argon2Parallelism),
+		passwords.BcryptCost,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to init auth model: %w", err)
+		return nil, fmt.Errorf("failed to init password hasher: %w", err)
 	}
 
 	auditService := models.NewAuditService(db)
+	accessTokensModel := models.NewAccessTokens(db, auditService)
+	twoFactorModel := models.NewTwoFactor(db, auditService)
+
+	loginThrottle := cfg.LoginThrottle()
+	loginGuard := models.NewLoginGuard(db, auditService, models.LoginThrottlePolicy{
+		MaxAccountFailures: loginThrottle.MaxAccountFailures,
+		MaxIPFailures:      loginThrottle.MaxIPFailures,
+		Window:             loginThrottle.Window,
+		LockoutDuration:    loginThrottle.LockoutDuration,
+		BaseDelay:          loginThrottle.BaseDelay,
+		MaxDelay:           loginThrottle.MaxDelay,
+	})
+
+	jwtConfig := cfg.JWT()
+
+	authModel, err := models.NewAuth(
+		db, hasher, twoFactorModel, loginGuard,
+		jwtConfig.KeySet.Keys, jwtConfig.KeySet.ActiveKeyID,
+		jwtConfig.Expiry, jwtConfig.RefreshExpiry,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init auth model: %w", err)
+	}
+
+	atmsModel := models.NewATMs(db)
+	cashOutModel := models.NewCashOut(db, auditService, atmsModel, cfg.CashOut().CodeLifetime)
+	accountsModel := models.NewAccounts(db, auditService)
+
+	reports := cfg.Reports()
+
+	var reportStorage models.ReportStorage = models.NewDBReportStorage(db)
+	if reports.Storage == config.ReportStorageDisk {
+		if reportStorage, err = models.NewDiskReportStorage(reports.Dir); err != nil {
+			return nil, fmt.Errorf("failed to init report storage: %w", err)
+		}
+	}
+
+	reportJobsModel := models.NewReportJobs(db, auditService, accountsModel, reportStorage, reports.Lifetime, reports.JobTimeout)
 
 	return &MVC{
 		log:          log,
-		auth:         controllers.NewAuth(authModel),
-		accounts:     controllers.NewAccounts(models.NewAccounts(db, auditService)),
-		transactions: controllers.NewTransactions(models.NewTransactions(db, auditService, cfg.ATM().PublicKey)),
+		auth:         controllers.NewAuth(authModel, accessTokensModel),
+		accounts:     controllers.NewAccounts(accountsModel),
+		transactions: controllers.NewTransactions(models.NewTransactions(db, auditService, atmsModel)),
 		activityLogs: controllers.NewActivityLogs(auditService),
-		templates:    templates,
+		accessTokens: controllers.NewAccessTokens(accessTokensModel),
+		twoFactor:    controllers.NewTwoFactor(twoFactorModel),
+		settings:     controllers.NewSettings(authModel, accessTokensModel, twoFactorModel),
+		idempotency:  controllers.NewIdempotency(models.NewIdempotency(db)),
+		cashOut:      controllers.NewCashOut(cashOutModel),
+		atmCash:      controllers.NewATMCash(models.NewATMCash(db, auditService, atmsModel)),
+		reports:      controllers.NewReports(reportJobsModel),
+
+		cashOutModel:          cashOutModel,
+		cashOutExpiryInterval: cfg.CashOut().ExpiryInterval,
+
+		loginGuard: loginGuard,
+
+		reportJobsModel:     reportJobsModel,
+		reportsPollInterval: reports.PollInterval,
+
+		templates: templates,
+		cookies:   cfg.Cookies(),
 	}, nil
 }
 
+// Run releases the holds of the expired cash-out codes and deletes the expired
+// login failures periodically until the context is done.
+func (m *MVC) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cashOutExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			expired, err := m.cashOutModel.ExpireCodes(now)
+			if err != nil {
+				m.log.WithError(err).Error("failed to expire cash-out codes")
+			}
+
+			if expired > 0 {
+				m.log.WithField("expired", expired).Info("expired cash-out codes")
+			}
+
+			if err = m.loginGuard.ExpireFailures(now); err != nil {
+				m.log.WithError(err).Error("failed to expire login failures")
+			}
+		}
+	}
+}
+
+// RunReports fails the stale reports, generates the pending ones and removes
+// the expired ones periodically until the context is done.
+func (m *MVC) RunReports(ctx context.Context) {
+	ticker := time.NewTicker(m.reportsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			stale, err := m.reportJobsModel.FailStaleJobs(now)
+			if err != nil {
+				m.log.WithError(err).Error("failed to fail stale report jobs")
+			}
+
+			if stale > 0 {
+				m.log.WithField("stale", stale).Warn("failed stale report jobs")
+			}
+
+			// The failed job doesn't stop the queue, the next one is taken
+			for ctx.Err() == nil {
+				processed, err := m.reportJobsModel.ProcessNext(ctx)
+				if err != nil {
+					m.log.WithError(err).Error("failed to process report job")
+				}
+
+				if !processed {
+					break
+				}
+			}
+
+			expired, err := m.reportJobsModel.ExpireReports(now)
+			if err != nil {
+				m.log.WithError(err).Error("failed to expire reports")
+			}
+
+			if expired > 0 {
+				m.log.WithField("expired", expired).Info("expired reports")
+			}
+		}
+	}
+}
+
 func (m *MVC) Register(r chi.Router) {
+	// The ATMs are authenticated by the signatures of the payloads, they have
+	// no cookies to protect from CSRF
+	r.Group(func(r chi.Router) {
+		r.Use(ape.CtxMiddleware(controllers.CtxLog(m.log)))
+
+		r.Route("/atm/v1", func(r chi.Router) {
+			r.Post("/withdrawals", m.cashOut.Withdraw)
+			r.Post("/cash-counts", m.atmCash.Reconcile)
+		})
+	})
+
 	r.Group(func(r chi.Router) {
 		r.Use(
 			ape.CtxMiddleware(
 				controllers.CtxLog(m.log),
 				controllers.CtxTemplates(m.templates),
+				controllers.CtxCookies(m.cookies),
 			),
+			controllers.CSRF,
 		)
 
+		r.Get("/.well-known/jwks.json", m.auth.JWKS)
+
 		r.Route("/auth", func(r chi.Router) {
 			r.Get("/", m.auth.AuthPage)
 			r.Post("/login", m.auth.Login)
+			r.Get("/2fa", m.auth.TwoFactorPage)
+			r.Post("/2fa", m.auth.CompleteLogin)
 			r.Post("/register", m.auth.Register)
+			r.Post("/refresh", m.auth.Refresh)
 		})
 
 		r.Route("/", func(r chi.Router) {
@@ -73,23 +242,57 @@ func (m *MVC) Register(r chi.Router) {
 				r.Route("/account", func(r chi.Router) {
 					r.Get("/{account-id}", m.accounts.AccountPage)
 				})
-				r.Get("/logout", controllers.LogOut)
+				r.Post("/logout", m.auth.LogOut)
+				r.Post("/logout/everywhere", m.auth.LogOutEverywhere)
 				r.Get("/", m.accounts.AccountListPage)
 
 				r.Get("/activity", m.activityLogs.UserActivityPage)
+				r.Get("/settings", m.settings.SettingsPage)
 			})
 		})
 
-		r.With(m.auth.VerifyJWT).Route("/api/v1", func(r chi.Router) {
-			r.Route("/transactions", func(r chi.Router) {
+		r.With(m.auth.VerifyAPI).Route("/api/v1", func(r chi.Router) {
+			r.With(
+				controllers.RequireScope(data.AccessScopeTransactionsWrite),
+				m.idempotency.Middleware,
+			).Route("/transactions", func(r chi.Router) {
 				r.Post("/deposit", m.transactions.DepositFunds)
-				r.Post("/withdraw", m.transactions.WithdrawFunds)
 				r.Post("/transfer", m.transactions.TransferFunds)
 			})
+			// Not idempotent by the Idempotency-Key, the stored response would keep the plain code
+			r.With(controllers.RequireScope(data.AccessScopeTransactionsWrite)).Route("/cash-out-codes", func(r chi.Router) {
+				r.Post("/", m.cashOut.IssueCode)
+				r.Delete("/{code-id}", m.cashOut.CancelCode)
+			})
 			r.Route("/accounts", func(r chi.Router) {
-				r.Post("/", m.accounts.CreateAccount)
-				r.Delete("/{account-id}", m.accounts.DeleteAccount)
-				r.Get("/{account-id}/excel", m.accounts.GenerateAccountExcel)
+				read := controllers.RequireScope(data.AccessScopeAccountsRead)
+				write := controllers.RequireScope(data.AccessScopeAccountsWrite)
+
+				r.With(read).Get("/", m.accounts.GetAccountList)
+				r.With(write).Post("/", m.accounts.CreateAccount)
+				r.With(read).Get("/excel", m.accounts.GenerateCustomerExcel)
+				r.With(read).Get("/{account-id}", m.accounts.GetAccount)
+				r.With(write).Delete("/{account-id}", m.accounts.DeleteAccount)
+				r.With(read).Get("/{account-id}/transactions", m.accounts.GetAccountTransactions)
+				r.With(read).Get("/{account-id}/excel", m.accounts.GenerateAccountExcel)
+				r.With(read).Get("/{account-id}/statement.pdf", m.accounts.GenerateStatementPDF)
+				r.With(read).Get("/{account-id}/export", m.accounts.ExportStatement)
+			})
+			r.With(controllers.RequireScope(data.AccessScopeAccountsRead)).Route("/reports", func(r chi.Router) {
+				r.Post("/", m.reports.RequestReport)
+				r.Get("/{report-id}", m.reports.GetReport)
+				r.Get("/{report-id}/download", m.reports.DownloadReport)
+			})
+			r.With(controllers.RequireScope(data.AccessScopeActivityRead)).Get("/activity", m.activityLogs.GetUserActivity)
+			r.With(controllers.RequireSession).Route("/tokens", func(r chi.Router) {
+				r.Get("/", m.accessTokens.GetAccessTokens)
+				r.Post("/", m.accessTokens.CreateAccessToken)
+				r.Delete("/{token-id}", m.accessTokens.RevokeAccessToken)
+			})
+			r.With(controllers.RequireSession).Route("/2fa/totp", func(r chi.Router) {
+				r.Post("/", m.twoFactor.EnrollTOTP)
+				r.Post("/confirm", m.twoFactor.ConfirmTOTP)
+				r.Post("/disable", m.twoFactor.DisableTOTP)
 			})
 		})
 	})
@@ -7,6 +7,8 @@ type AccountsList struct {
 }
 
 type Account struct {
-	Account  *data.Account
+	Account      *data.Account
 	Transactions []*data.Transaction
+	// CashOutCodes are the pending codes of the account
+	CashOutCodes []*data.CashOutCode
 }
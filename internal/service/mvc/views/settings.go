@@ -0,0 +1,17 @@
+package views
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/omegatymbjiep/ilab1/internal/data"
+)
+
+type Settings struct {
+	AccessTokens []*data.AccessToken
+	Scopes       []data.AccessScope
+
+	Sessions         []*data.Session
+	CurrentSessionID uuid.UUID
+
+	TwoFactorEnabled bool
+}
@@ -11,9 +11,11 @@ const (
 	InternalErrorTemplateName = "internal_error.html"
 
 	AuthTemplateName         = "auth.html"
+	TwoFactorTemplateName    = "two_factor.html"
 	AccountsTemplateName     = "accounts.html"
 	AccountTemplateName      = "account.html"
 	ActivityLogsTemplateName = "activity_logs.html"
+	SettingsTemplateName     = "settings.html"
 
 	HomepageTemplateName = "homepage.html"
 )